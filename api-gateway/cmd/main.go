@@ -11,12 +11,15 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/segmentio/kafka-go"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/yourusername/fortexa/api-gateway/internal/config"
 	"github.com/yourusername/fortexa/api-gateway/internal/handlers"
 	"github.com/yourusername/fortexa/api-gateway/internal/middleware"
+	"github.com/yourusername/fortexa/api-gateway/internal/repository"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
 // @title Fortexa Payment API
@@ -44,6 +47,12 @@ func main() {
 	// Create a new Gin router
 	router := gin.Default()
 
+	// Only take the client IP from forwarding headers set by known proxies;
+	// it is attached to payments for fraud screening
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
 	// Setup CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -62,16 +71,54 @@ func main() {
 	rateLimiter := middleware.NewRateLimiter(time.Minute)
 	router.Use(rateLimiter.RateLimit())
 
-	// Create a Kafka writer
-	kafkaWriter := &kafka.Writer{
-		Addr:         kafka.TCP(cfg.Kafka.Brokers...),
-		Topic:        cfg.Kafka.PaymentsTopic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireAll,
-		Async:        false,
+	var repo repository.Repository
+
+	// Check if running in mock mode
+	if cfg.Server.MockMode {
+		log.Println("Running in MOCK MODE - No database connection required")
+		repo = repository.NewMockRepository()
+	} else {
+		dbRepo, err := repository.NewDBRepository(cfg.Database.ConnectionString())
+		if err != nil {
+			log.Fatalf("Failed to initialize database repository: %v", err)
+		}
+		defer dbRepo.Close()
+		repo = dbRepo
 	}
+
+	// Bank account numbers are encrypted with a key shared with the settlement
+	// engine; mock mode makes up a key if none is set
+	accountKey := cfg.BankAccounts.EncryptionKey
+	if accountKey == "" && cfg.Server.MockMode {
+		log.Println("Warning: BANK_ACCOUNT_ENCRYPTION_KEY not set, encrypting bank accounts with a temporary key")
+		var err error
+		if accountKey, err = bankaccount.GenerateKey(); err != nil {
+			log.Fatalf("Failed to generate bank account encryption key: %v", err)
+		}
+	}
+	accountCipher, err := bankaccount.NewCipher(accountKey)
+	if err != nil {
+		log.Fatalf("Invalid bank account encryption key: %v", err)
+	}
+
+	// Create a Kafka writer for relaying events from the outbox
+	kafkaWriter := outbox.NewWriter(cfg.Kafka.Brokers...)
 	defer kafkaWriter.Close()
 
+	// Start the outbox relay
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	relay := outbox.NewRelay(
+		repo.Outbox(),
+		kafkaWriter,
+		time.Duration(cfg.Outbox.PollIntervalMs)*time.Millisecond,
+		cfg.Outbox.BatchSize,
+	)
+	go relay.Run(relayCtx)
+
+	// Events published by handlers are stamped with the gateway's name
+	producer := events.NewProducer(cfg.Server.Name)
+
 	// Create authentication middleware
 	authMiddleware := middleware.NewAuthMiddleware()
 
@@ -85,8 +132,12 @@ func main() {
 		protected := v1.Group("")
 		protected.Use(authMiddleware.RequireAuth())
 		{
-			handlers.RegisterPaymentRoutes(protected, kafkaWriter)
+			handlers.RegisterPaymentRoutes(protected, repo, producer, cfg.Kafka.PaymentCommandsTopic, cfg.Kafka.PaymentEventsTopic, cfg.Kafka.FraudTopic)
 			handlers.RegisterWebhookRoutes(protected)
+			handlers.RegisterFraudListRoutes(protected, repo)
+			handlers.RegisterFraudCaseRoutes(protected, repo, producer, cfg.Kafka.PaymentCommandsTopic, cfg.Kafka.FraudTopic)
+			handlers.RegisterSettlementRoutes(protected, repo)
+			handlers.RegisterBankAccountRoutes(protected, repo, accountCipher, bankaccount.NewStubBank())
 		}
 	}
 
@@ -1,117 +1,56 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
+	sharedconfig "github.com/yourusername/fortexa/pkg/config"
 )
 
 // Config holds all configuration for the service
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Kafka    KafkaConfig
-	Redis    RedisConfig
+	Server       ServerConfig              `yaml:"server"`
+	Database     sharedconfig.Database     `yaml:"database"`
+	Kafka        KafkaConfig               `yaml:"kafka"`
+	Redis        RedisConfig               `yaml:"redis"`
+	Outbox       sharedconfig.Outbox       `yaml:"outbox"`
+	BankAccounts sharedconfig.BankAccounts `yaml:"bank_accounts"`
 }
 
 // ServerConfig holds the configuration for the HTTP server
 type ServerConfig struct {
-	Port            string
-	Mode            string
-	AllowedOrigins  []string
-	RequestTimeout  int
-	ShutdownTimeout int
-}
-
-// DatabaseConfig holds the configuration for the database
-type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Name            string   `yaml:"name" env:"APP_NAME" default:"api-gateway" required:"true"`
+	Port            string   `yaml:"port" env:"SERVER_PORT" default:"8000" required:"true"`
+	Mode            string   `yaml:"mode" env:"GIN_MODE" default:"debug" oneof:"debug|release|test"`
+	AllowedOrigins  []string `yaml:"allowed_origins" env:"ALLOWED_ORIGINS" default:"*"`
+	RequestTimeout  int      `yaml:"request_timeout" env:"REQUEST_TIMEOUT" default:"30" min:"1"`
+	ShutdownTimeout int      `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"5" min:"1"`
+	MockMode        bool     `yaml:"mock_mode" env:"MOCK_MODE"`
+	// TrustedProxies are the proxies whose X-Forwarded-For header is believed
+	// when determining the client IP; without any the peer address is used
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
 }
 
 // KafkaConfig holds the configuration for Kafka
 type KafkaConfig struct {
-	Brokers         []string
-	PaymentsTopic   string
-	SettlementTopic string
-	FraudTopic      string
-	ConsumerGroup   string
+	Brokers              []string `yaml:"brokers" env:"KAFKA_BROKERS" default:"localhost:9092" required:"true"`
+	PaymentCommandsTopic string   `yaml:"payment_commands_topic" env:"KAFKA_PAYMENT_COMMANDS_TOPIC" default:"payments.commands" required:"true"`
+	PaymentEventsTopic   string   `yaml:"payment_events_topic" env:"KAFKA_PAYMENT_EVENTS_TOPIC" default:"payments.events" required:"true"`
+	SettlementTopic      string   `yaml:"settlement_topic" env:"KAFKA_SETTLEMENT_TOPIC" default:"settlements.events"`
+	FraudTopic           string   `yaml:"fraud_topic" env:"KAFKA_FRAUD_TOPIC" default:"fraud.events"`
+	ConsumerGroup        string   `yaml:"consumer_group" env:"KAFKA_CONSUMER_GROUP" default:"api-gateway"`
 }
 
 // RedisConfig holds the configuration for Redis
 type RedisConfig struct {
-	Host     string
-	Port     string
-	Password string
-	DB       int
+	Host     string `yaml:"host" env:"REDIS_HOST" default:"localhost"`
+	Port     string `yaml:"port" env:"REDIS_PORT" default:"6379"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" env:"REDIS_DB" default:"0" min:"0"`
 }
 
-// New returns a new Config struct
+// New loads the configuration from defaults, the optional config file and the
+// environment. It exits on invalid configuration, and prints the configuration
+// and exits when run with --print-config.
 func New() *Config {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
-	}
-
-	return &Config{
-		Server: ServerConfig{
-			Port:            getEnv("SERVER_PORT", "8000"),
-			Mode:            getEnv("GIN_MODE", "debug"),
-			AllowedOrigins:  getEnvAsSlice("ALLOWED_ORIGINS", []string{"*"}),
-			RequestTimeout:  getEnvAsInt("REQUEST_TIMEOUT", 30),
-			ShutdownTimeout: getEnvAsInt("SHUTDOWN_TIMEOUT", 5),
-		},
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "fortexa"),
-			Password: getEnv("DB_PASSWORD", "fortexa123"),
-			DBName:   getEnv("DB_NAME", "fortexa"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
-		},
-		Kafka: KafkaConfig{
-			Brokers:         getEnvAsSlice("KAFKA_BROKERS", []string{"localhost:9092"}),
-			PaymentsTopic:   getEnv("KAFKA_PAYMENTS_TOPIC", "payments"),
-			SettlementTopic: getEnv("KAFKA_SETTLEMENT_TOPIC", "settlements"),
-			FraudTopic:      getEnv("KAFKA_FRAUD_TOPIC", "fraud"),
-			ConsumerGroup:   getEnv("KAFKA_CONSUMER_GROUP", "api-gateway"),
-		},
-		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnv("REDIS_PORT", "6379"),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvAsInt("REDIS_DB", 0),
-		},
-	}
+	cfg := &Config{}
+	sharedconfig.MustLoad(cfg)
+	return cfg
 }
-
-// Simple helper function to read an environment or return a default value
-func getEnv(key string, defaultVal string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultVal
-}
-
-// Simple helper function to read an environment variable into a string slice or return a default value
-func getEnvAsSlice(key string, defaultVal []string) []string {
-	if value, exists := os.LookupEnv(key); exists {
-		return []string{value}
-	}
-	return defaultVal
-}
-
-// Simple helper function to read an environment variable into an integer or return a default value
-func getEnvAsInt(key string, defaultVal int) int {
-	valueStr := getEnv(key, "")
-	if value, err := strconv.Atoi(valueStr); err == nil {
-		return value
-	}
-	return defaultVal
-} 
\ No newline at end of file
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/api-gateway/internal/models"
+	"github.com/yourusername/fortexa/api-gateway/internal/repository"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+)
+
+// BankAccountHandler handles the endpoints of the bank accounts merchants are
+// paid out to. Account numbers are encrypted before they are stored and are
+// never returned.
+type BankAccountHandler struct {
+	repository repository.Repository
+	cipher     *bankaccount.Cipher
+	bank       bankaccount.Bank
+}
+
+// NewBankAccountHandler creates a new BankAccountHandler, encrypting account
+// numbers with cipher and verifying accounts with penny drops at bank
+func NewBankAccountHandler(repository repository.Repository, cipher *bankaccount.Cipher, bank bankaccount.Bank) *BankAccountHandler {
+	return &BankAccountHandler{repository: repository, cipher: cipher, bank: bank}
+}
+
+// RegisterBankAccount registers a bank account for a merchant
+// @Summary Register a bank account
+// @Description Register a bank account to pay a merchant out to: an account number with the IFSC of its branch, or an IBAN with an optional BIC. The account is pending until a penny drop verifies it.
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Param id path string true "Merchant ID"
+// @Param account body models.BankAccountRequest true "Bank account"
+// @Success 201 {object} models.BankAccount
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/merchants/{id}/bank-accounts [post]
+func (h *BankAccountHandler) RegisterBankAccount(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		return
+	}
+
+	var req models.BankAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	details, err := req.Account().Normalize()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	encrypted, err := h.cipher.Encrypt(details.Number)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt account number"})
+		return
+	}
+
+	now := time.Now()
+	account := models.BankAccount{
+		ID:                     uuid.New(),
+		MerchantID:             merchantID,
+		HolderName:             details.HolderName,
+		Scheme:                 details.Scheme,
+		AccountNumberEncrypted: encrypted,
+		AccountNumberLast4:     bankaccount.Last4(details.Number),
+		IFSC:                   details.IFSC,
+		BIC:                    details.BIC,
+		Status:                 models.BankAccountStatusPending,
+		CreatedAt:              now,
+		UpdatedAt:              now,
+	}
+	err = h.repository.CreateBankAccount(c.Request.Context(), account)
+	if errors.Is(err, repository.ErrMerchantNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Merchant not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register bank account"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// ListBankAccounts lists a merchant's bank accounts
+// @Summary List bank accounts
+// @Description List a merchant's bank accounts, newest first. The VERIFIED account is the one payouts go to.
+// @Tags merchants
+// @Produce json
+// @Param id path string true "Merchant ID"
+// @Success 200 {array} models.BankAccount
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/merchants/{id}/bank-accounts [get]
+func (h *BankAccountHandler) ListBankAccounts(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		return
+	}
+
+	accounts, err := h.repository.BankAccounts(c.Request.Context(), merchantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list bank accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// VerifyBankAccount verifies a pending bank account with a penny drop
+// @Summary Verify a bank account
+// @Description Verify a pending bank account by depositing a token amount into it. The account is VERIFIED if it takes the deposit and the name the bank has on it matches the holder name, and replaces the merchant's payout account; otherwise it is FAILED with the reason. Payouts to an account that replaced another wait for a cooldown.
+// @Tags merchants
+// @Produce json
+// @Param id path string true "Merchant ID"
+// @Param account_id path string true "Bank account ID"
+// @Success 200 {object} models.BankAccount
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Failure 502 {object} gin.H
+// @Router /api/v1/merchants/{id}/bank-accounts/{account_id}/verify [post]
+func (h *BankAccountHandler) VerifyBankAccount(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		return
+	}
+	accountID, err := uuid.Parse(c.Param("account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bank account ID"})
+		return
+	}
+
+	account, err := h.repository.GetBankAccount(c.Request.Context(), merchantID, accountID)
+	if errors.Is(err, repository.ErrBankAccountNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get bank account"})
+		return
+	}
+	if account.Status != models.BankAccountStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bank account is not pending verification"})
+		return
+	}
+
+	number, err := h.cipher.Decrypt(account.AccountNumberEncrypted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decrypt account number"})
+		return
+	}
+	penny, err := bankaccount.PennyDrop(h.bank, bankaccount.Account{
+		HolderName: account.HolderName,
+		Scheme:     account.Scheme,
+		Number:     number,
+		IFSC:       account.IFSC,
+		BIC:        account.BIC,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Penny drop failed, try again later"})
+		return
+	}
+
+	verified, err := h.repository.RecordBankAccountVerification(c.Request.Context(), account.ID, models.BankAccountVerification{
+		Reference:      penny.Reference,
+		Amount:         penny.Amount,
+		Currency:       penny.Currency,
+		RegisteredName: penny.RegisteredName,
+		Verified:       penny.Verified,
+		Reason:         penny.Reason,
+		CompletedAt:    time.Now(),
+	})
+	if errors.Is(err, repository.ErrBankAccountNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bank account is not pending verification"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record bank account verification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, verified)
+}
+
+// RegisterBankAccountRoutes registers the bank account routes with the given router group
+func RegisterBankAccountRoutes(router *gin.RouterGroup, repository repository.Repository, cipher *bankaccount.Cipher, bank bankaccount.Bank) {
+	h := NewBankAccountHandler(repository, cipher, bank)
+
+	accounts := router.Group("/merchants/:id/bank-accounts")
+	{
+		accounts.POST("", h.RegisterBankAccount)
+		accounts.GET("", h.ListBankAccounts)
+		accounts.POST("/:account_id/verify", h.VerifyBankAccount)
+	}
+}
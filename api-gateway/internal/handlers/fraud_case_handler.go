@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/api-gateway/internal/models"
+	"github.com/yourusername/fortexa/api-gateway/internal/repository"
+	"github.com/yourusername/fortexa/pkg/events"
+)
+
+// FraudCaseHandler handles the fraud review queue endpoints
+type FraudCaseHandler struct {
+	repository    repository.Repository
+	producer      events.Producer
+	commandsTopic string
+	fraudTopic    string
+}
+
+// NewFraudCaseHandler creates a new FraudCaseHandler. The commands releasing
+// or declining reviewed payments go to commandsTopic, and the review
+// decisions to fraudTopic.
+func NewFraudCaseHandler(repository repository.Repository, producer events.Producer, commandsTopic, fraudTopic string) *FraudCaseHandler {
+	return &FraudCaseHandler{
+		repository:    repository,
+		producer:      producer,
+		commandsTopic: commandsTopic,
+		fraudTopic:    fraudTopic,
+	}
+}
+
+// ListCases lists fraud review cases
+// @Summary List fraud cases
+// @Description List the cases opened for payments held for fraud review, oldest first, so open cases come in the order they should be reviewed
+// @Tags fraud
+// @Produce json
+// @Param status query string false "OPEN, APPROVED or REJECTED"
+// @Param merchant_id query string false "Merchant ID"
+// @Param payment_id query string false "Payment ID"
+// @Param limit query int false "Maximum number of cases, 100 by default"
+// @Param offset query int false "Number of cases to skip"
+// @Success 200 {array} models.FraudCase
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/cases [get]
+func (h *FraudCaseHandler) ListCases(c *gin.Context) {
+	var filter models.FraudCaseFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.MerchantID != "" {
+		merchantID, err := uuid.Parse(filter.MerchantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid merchant_id"})
+			return
+		}
+		filter.MerchantID = merchantID.String()
+	}
+	if filter.PaymentID != "" {
+		paymentID, err := uuid.Parse(filter.PaymentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment_id"})
+			return
+		}
+		filter.PaymentID = paymentID.String()
+	}
+
+	cases, err := h.repository.ListFraudCases(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list fraud cases"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cases)
+}
+
+// GetCase returns a fraud case with the payment and fraud check behind it
+// @Summary Get a fraud case
+// @Description Get a fraud case with its payment and the full fraud check, including every check and model score that went into the decision
+// @Tags fraud
+// @Produce json
+// @Param id path string true "Fraud case ID"
+// @Success 200 {object} models.FraudCaseDetail
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/cases/{id} [get]
+func (h *FraudCaseHandler) GetCase(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fraud case ID"})
+		return
+	}
+
+	detail, err := h.repository.GetFraudCase(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrFraudCaseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fraud case not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fraud case"})
+		return
+	}
+
+	payment, err := h.repository.GetPayment(c.Request.Context(), detail.PaymentID)
+	if err != nil && !errors.Is(err, repository.ErrPaymentNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+		return
+	}
+	if err == nil {
+		response := payment.Response()
+		detail.Payment = &response
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// ReviewCase records an analyst's decision on a fraud case
+// @Summary Resolve a fraud case
+// @Description Approve a fraud case, which sends its payment on to authorization and labels it legitimate, or reject it, which declines the payment and labels it fraudulent
+// @Tags fraud
+// @Accept json
+// @Produce json
+// @Param id path string true "Fraud case ID"
+// @Param review body models.ReviewRequest true "Review decision"
+// @Success 202 {object} models.FraudCase
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/cases/{id}/review [post]
+func (h *FraudCaseHandler) ReviewCase(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fraud case ID"})
+		return
+	}
+
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	detail, err := h.repository.GetFraudCase(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrFraudCaseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fraud case not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fraud case"})
+		return
+	}
+	if detail.Status != models.FraudCaseStatusOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "Fraud case is already resolved"})
+		return
+	}
+
+	_, resolution, ok := h.resolve(c, detail.FraudCase, req)
+	if !ok {
+		return
+	}
+
+	fraudCase := detail.FraudCase
+	fraudCase.Status = resolution.Status
+	fraudCase.Reviewer = resolution.Reviewer
+	fraudCase.Note = resolution.Note
+	fraudCase.ResolvedAt = &resolution.ResolvedAt
+	c.JSON(http.StatusAccepted, fraudCase)
+}
+
+// GetTrainingData returns labeled fraud checks for training fraud models
+// @Summary Get fraud training data
+// @Description List screened payments with their latest fraud label, and the feature vector and risk score screening saw, oldest label first
+// @Tags fraud
+// @Produce json
+// @Param merchant_id query string false "Merchant ID"
+// @Param since query string false "Only payments labeled at or after this time, in RFC 3339 format"
+// @Param limit query int false "Maximum number of examples, 1000 by default"
+// @Param offset query int false "Number of examples to skip"
+// @Success 200 {array} models.TrainingExample
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/training-data [get]
+func (h *FraudCaseHandler) GetTrainingData(c *gin.Context) {
+	var filter models.TrainingDataFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.MerchantID != "" {
+		merchantID, err := uuid.Parse(filter.MerchantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid merchant_id"})
+			return
+		}
+		filter.MerchantID = merchantID.String()
+	}
+
+	examples, err := h.repository.TrainingExamples(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get training data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, examples)
+}
+
+// GetMerchantRates returns the fraud rates of merchants
+// @Summary Get merchant fraud rates
+// @Description List the share of each merchant's payments screened in the last 90 days that are labeled fraudulent, by review, chargeback, fraudulent refund or merchant report, highest first
+// @Tags fraud
+// @Produce json
+// @Param merchant_id query string false "Merchant ID"
+// @Success 200 {array} models.MerchantFraudRate
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/merchant-rates [get]
+func (h *FraudCaseHandler) GetMerchantRates(c *gin.Context) {
+	merchantID := c.Query("merchant_id")
+	if merchantID != "" {
+		id, err := uuid.Parse(merchantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+			return
+		}
+		merchantID = id.String()
+	}
+
+	rates, err := h.repository.MerchantFraudRates(c.Request.Context(), merchantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get merchant fraud rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// GetDecisionReport reports how well past fraud decisions singled out fraud
+// @Summary Get a fraud decision report
+// @Description Compare the decisions on labeled payments with their labels, giving the precision and recall of the REVIEW and BLOCK decisions, of flagging payments above each risk score threshold and of each rule
+// @Tags fraud
+// @Produce json
+// @Param merchant_id query string false "Merchant ID"
+// @Param since query string false "Only payments screened at or after this time, in RFC 3339 format"
+// @Param until query string false "Only payments screened before this time, in RFC 3339 format"
+// @Param thresholds query []number false "Risk score thresholds, repeated; 0.1 to 0.9 by default"
+// @Success 200 {object} models.DecisionReport
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/reports/decisions [get]
+func (h *FraudCaseHandler) GetDecisionReport(c *gin.Context) {
+	var filter models.DecisionReportFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.MerchantID != "" {
+		merchantID, err := uuid.Parse(filter.MerchantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid merchant_id"})
+			return
+		}
+		filter.MerchantID = merchantID.String()
+	}
+	thresholds := filter.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = models.DefaultReportThresholds
+	}
+
+	checks, err := h.repository.LabeledChecks(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get labeled fraud checks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewDecisionReport(checks, thresholds))
+}
+
+// resolve applies an analyst's decision to the payment of an open fraud case.
+// Approval releases the payment to authorization and rejection declines it;
+// either way the case is closed, the payment labeled and the decision
+// published. It writes the error response and returns false if the case
+// cannot be resolved.
+func (h *FraudCaseHandler) resolve(c *gin.Context, fraudCase models.FraudCase, req models.ReviewRequest) (models.Payment, models.FraudCaseResolution, bool) {
+	payment, err := h.repository.GetPayment(c.Request.Context(), fraudCase.PaymentID)
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+	if payment.Status != models.PaymentStatusReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment is not held for review"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+
+	// Approval releases the payment to authorization; rejection declines it
+	resolution := req.Resolution(time.Now())
+	commandType := events.TypePaymentAuthorizationRequested
+	payment.Status = models.PaymentStatusInitiated
+	if req.Decision == models.ReviewDecisionReject {
+		commandType = events.TypePaymentDeclineRequested
+		payment.Status = models.PaymentStatusFailed
+	}
+	payment.UpdatedAt = resolution.ResolvedAt
+
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]interface{})
+	}
+	payment.Metadata["review_decision"] = req.Decision
+	payment.Metadata["reviewed_by"] = req.Reviewer
+	payment.Metadata["reviewed_at"] = payment.UpdatedAt.Format(time.RFC3339)
+	if req.Note != "" {
+		payment.Metadata["review_note"] = req.Note
+	}
+
+	command, err := h.producer.New(commandType, payment.Event())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment command"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+	commandMessage, err := command.Message(h.commandsTopic, payment.ID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment command"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+	review, err := h.producer.New(events.TypeFraudReviewCompleted, fraudCase.ReviewEvent(resolution))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize fraud review"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+	reviewMessage, err := review.Message(h.fraudTopic, payment.ID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize fraud review"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+
+	err = h.repository.ResolveReview(c.Request.Context(), fraudCase.ID, payment, resolution, commandMessage, reviewMessage)
+	if errors.Is(err, repository.ErrPaymentNotInReview) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment is not held for review"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve review"})
+		return models.Payment{}, models.FraudCaseResolution{}, false
+	}
+
+	return payment, resolution, true
+}
+
+// RegisterFraudCaseRoutes registers the fraud review queue routes with the given router group
+func RegisterFraudCaseRoutes(router *gin.RouterGroup, repository repository.Repository, producer events.Producer, commandsTopic, fraudTopic string) {
+	h := NewFraudCaseHandler(repository, producer, commandsTopic, fraudTopic)
+
+	cases := router.Group("/fraud/cases")
+	{
+		cases.GET("", h.ListCases)
+		cases.GET("/:id", h.GetCase)
+		cases.POST("/:id/review", h.ReviewCase)
+	}
+
+	router.GET("/fraud/training-data", h.GetTrainingData)
+	router.GET("/fraud/merchant-rates", h.GetMerchantRates)
+	router.GET("/fraud/reports/decisions", h.GetDecisionReport)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/api-gateway/internal/models"
+	"github.com/yourusername/fortexa/api-gateway/internal/repository"
+)
+
+// FraudListHandler handles the fraud blocklist and allowlist endpoints
+type FraudListHandler struct {
+	repository repository.Repository
+}
+
+// NewFraudListHandler creates a new FraudListHandler
+func NewFraudListHandler(repository repository.Repository) *FraudListHandler {
+	return &FraudListHandler{repository: repository}
+}
+
+// AddEntry adds an entry to the blocklist or allowlist
+// @Summary Add a fraud list entry
+// @Description Block or trust a card fingerprint, email, VPA, IP address, device or customer, for one merchant or all of them. Adding a listed value again renews its entry.
+// @Tags fraud
+// @Accept json
+// @Produce json
+// @Param entry body models.ListEntryRequest true "List entry"
+// @Success 201 {object} models.ListEntry
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/lists/entries [post]
+func (h *FraudListHandler) AddEntry(c *gin.Context) {
+	var req models.ListEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, err := models.NormalizeListValue(req.Type, req.Value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry := models.ListEntry{
+		List:       req.List,
+		Type:       req.Type,
+		Value:      value,
+		MerchantID: req.MerchantID,
+		Reason:     req.Reason,
+		Source:     models.ListEntrySourceManual,
+		CreatedBy:  req.Actor,
+	}
+	if req.TTLSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		entry.ExpiresAt = &expiresAt
+	}
+
+	stored, err := h.repository.AddListEntry(c.Request.Context(), entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add list entry"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, stored)
+}
+
+// ListEntries lists the live entries of the blocklist and allowlist
+// @Summary List fraud list entries
+// @Description List the entries of the fraud lists that have not been removed or expired, newest first
+// @Tags fraud
+// @Produce json
+// @Param list query string false "BLOCK or ALLOW"
+// @Param type query string false "CARD, EMAIL, VPA, IP, DEVICE or CUSTOMER"
+// @Param value query string false "Listed value"
+// @Param merchant_id query string false "Merchant ID"
+// @Param global query bool false "Only entries that apply to all merchants"
+// @Param limit query int false "Maximum number of entries, 100 by default"
+// @Success 200 {array} models.ListEntry
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/lists/entries [get]
+func (h *FraudListHandler) ListEntries(c *gin.Context) {
+	var filter models.ListEntryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.MerchantID != "" {
+		merchantID, err := uuid.Parse(filter.MerchantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid merchant_id"})
+			return
+		}
+		filter.MerchantID = merchantID.String()
+	}
+	if filter.Value != "" && filter.Type != "" {
+		value, err := models.NormalizeListValue(filter.Type, filter.Value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.Value = value
+	}
+
+	entries, err := h.repository.ListEntries(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// RemoveEntry removes an entry from the blocklist or allowlist
+// @Summary Remove a fraud list entry
+// @Description Remove an entry from the fraud lists; it stays in the audit trail
+// @Tags fraud
+// @Accept json
+// @Produce json
+// @Param id path string true "List entry ID"
+// @Param removal body models.ListEntryRemoval true "Removal"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/lists/entries/{id} [delete]
+func (h *FraudListHandler) RemoveEntry(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list entry ID"})
+		return
+	}
+
+	var req models.ListEntryRemoval
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.repository.RemoveListEntry(c.Request.Context(), id, req)
+	if errors.Is(err, repository.ErrListEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List entry not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove list entry"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetEntryAudit returns the audit trail of a list entry
+// @Summary Get the audit trail of a fraud list entry
+// @Description List who added, renewed and removed a list entry, and why, oldest first
+// @Tags fraud
+// @Produce json
+// @Param id path string true "List entry ID"
+// @Success 200 {array} models.ListAuditRecord
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/fraud/lists/entries/{id}/audit [get]
+func (h *FraudListHandler) GetEntryAudit(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list entry ID"})
+		return
+	}
+
+	records, err := h.repository.ListEntryAudit(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrListEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List entry not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get list entry audit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// RegisterFraudListRoutes registers the fraud list routes with the given router group
+func RegisterFraudListRoutes(router *gin.RouterGroup, repository repository.Repository) {
+	h := NewFraudListHandler(repository)
+
+	entries := router.Group("/fraud/lists/entries")
+	{
+		entries.POST("", h.AddEntry)
+		entries.GET("", h.ListEntries)
+		entries.DELETE("/:id", h.RemoveEntry)
+		entries.GET("/:id/audit", h.GetEntryAudit)
+	}
+}
@@ -1,25 +1,37 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/segmentio/kafka-go"
 	"github.com/yourusername/fortexa/api-gateway/internal/models"
+	"github.com/yourusername/fortexa/api-gateway/internal/repository"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/money"
 )
 
 // PaymentHandler handles payment-related API endpoints
 type PaymentHandler struct {
-	kafkaWriter *kafka.Writer
+	repository    repository.Repository
+	producer      events.Producer
+	commandsTopic string
+	eventsTopic   string
+	cases         *FraudCaseHandler
 }
 
-// NewPaymentHandler creates a new PaymentHandler
-func NewPaymentHandler(kafkaWriter *kafka.Writer) *PaymentHandler {
+// NewPaymentHandler creates a new PaymentHandler. Commands for the payment
+// engine, such as the outcome of a fraud review, go to commandsTopic, facts
+// about payments go to eventsTopic and fraud review decisions to fraudTopic.
+func NewPaymentHandler(repository repository.Repository, producer events.Producer, commandsTopic, eventsTopic, fraudTopic string) *PaymentHandler {
 	return &PaymentHandler{
-		kafkaWriter: kafkaWriter,
+		repository:    repository,
+		producer:      producer,
+		commandsTopic: commandsTopic,
+		eventsTopic:   eventsTopic,
+		cases:         NewFraudCaseHandler(repository, producer, commandsTopic, fraudTopic),
 	}
 }
 
@@ -40,6 +52,10 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := models.ValidateAmount(req.Amount, req.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Generate a new payment ID
 	paymentID := uuid.New()
@@ -56,6 +72,11 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 		Metadata:         req.Metadata,
 		IdempotencyKey:   req.IdempotencyKey,
 		ReferenceID:      req.ReferenceID,
+		ClientIP:         c.ClientIP(),
+		BillingCountry:   req.BillingCountry,
+		CardIssuingCountry: req.CardIssuingCountry,
+		Device:           req.Device,
+		Session:          req.Session,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
@@ -68,44 +89,33 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 		payment.PaymentMethodID = req.PaymentMethodID
 	}
 
-	// Create a payment event to publish to Kafka
-	event := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      "payment.initiated",
-		Payment:   payment,
-		Timestamp: time.Now(),
+	// Without a user agent from the merchant's client, use the caller's
+	if payment.Device != nil && payment.Device.UserAgent == "" {
+		payment.Device.UserAgent = c.Request.UserAgent()
 	}
 
-	// Serialize the event to JSON
-	eventJSON, err := json.Marshal(event)
+	// Announce the payment; fraud detection screens it and then asks the
+	// payment engine to authorize, hold or decline it
+	initiated, err := h.producer.New(events.TypePaymentInitiated, payment.Event())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment event"})
 		return
 	}
-
-	// Publish the event to Kafka
-	err = h.kafkaWriter.WriteMessages(c.Request.Context(), kafka.Message{
-		Key:   []byte(payment.ID.String()),
-		Value: eventJSON,
-	})
-
+	message, err := initiated.Message(h.eventsTopic, payment.ID.String())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish payment event"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment event"})
+		return
+	}
+
+	// Store the payment together with its event; the outbox relay publishes
+	// the event, so the request does not depend on Kafka being available
+	if err := h.repository.CreatePayment(c.Request.Context(), payment, message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
 		return
 	}
 
 	// Return the payment response
-	c.JSON(http.StatusOK, models.PaymentResponse{
-		ID:               payment.ID,
-		MerchantID:       payment.MerchantID,
-		Amount:           payment.Amount,
-		Currency:         payment.Currency,
-		Status:           payment.Status,
-		PaymentMethodType: payment.PaymentMethodType,
-		Description:      payment.Description,
-		ReferenceID:      payment.ReferenceID,
-		CreatedAt:        payment.CreatedAt,
-	})
+	c.JSON(http.StatusOK, payment.Response())
 }
 
 // GetPaymentStatus retrieves the status of a payment
@@ -116,41 +126,181 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Payment ID"
 // @Success 200 {object} models.PaymentResponse
+// @Failure 400 {object} gin.H
 // @Failure 404 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /api/v1/payments/{id} [get]
 func (h *PaymentHandler) GetPaymentStatus(c *gin.Context) {
-	// In a real implementation, this would fetch the payment from a database
-	// For the MVP, we'll return a mock response
 	paymentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
 		return
 	}
 
-	// Mock payment response
-	payment := models.PaymentResponse{
-		ID:               paymentID,
-		MerchantID:       uuid.MustParse("00000000-0000-0000-0000-000000000000"),
-		Amount:           100.00,
-		Currency:         "INR",
-		Status:           models.PaymentStatusAuthorized,
-		PaymentMethodType: models.PaymentMethodCreditCard,
-		CreatedAt:        time.Now().Add(-time.Hour),
+	payment, err := h.repository.GetPayment(c.Request.Context(), paymentID)
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payment.Response())
+}
+
+// ReviewPayment records an analyst's decision on a payment held for fraud review
+// @Summary Resolve a fraud review
+// @Description Approve a payment held for fraud review, which sends it on to authorization, or reject it, which declines it. Either way the payment's fraud case is resolved and the payment labeled.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param review body models.ReviewRequest true "Review decision"
+// @Success 202 {object} models.PaymentResponse
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/payments/{id}/review [post]
+func (h *PaymentHandler) ReviewPayment(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The review resolves the payment's open fraud case
+	cases, err := h.repository.ListFraudCases(c.Request.Context(), models.FraudCaseFilter{
+		Status:    models.FraudCaseStatusOpen,
+		PaymentID: paymentID.String(),
+		Limit:     1,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fraud case"})
+		return
+	}
+	if len(cases) == 0 {
+		_, err := h.repository.GetPayment(c.Request.Context(), paymentID)
+		if errors.Is(err, repository.ErrPaymentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment is not held for review"})
+		return
+	}
+
+	payment, _, ok := h.cases.resolve(c, cases[0], req)
+	if !ok {
+		return
 	}
 
-	c.JSON(http.StatusOK, payment)
+	c.JSON(http.StatusAccepted, payment.Response())
+}
+
+// RecordChargeback records a chargeback confirmed on a captured payment
+// @Summary Record a chargeback
+// @Description Record a chargeback confirmed by the card network or bank on a captured or settled payment. Fraud chargebacks, the default, label the payment as fraud and fraud detection adds its card, email, VPA and device to the blocklist; disputes label it legitimate.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param chargeback body models.ChargebackRequest true "Chargeback"
+// @Success 202 {object} models.PaymentResponse
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/payments/{id}/chargeback [post]
+func (h *PaymentHandler) RecordChargeback(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var req models.ChargebackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := h.repository.GetPayment(c.Request.Context(), paymentID)
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+		return
+	}
+	if payment.Status != models.PaymentStatusCaptured && payment.Status != models.PaymentStatusSettled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment has not been captured"})
+		return
+	}
+
+	payment.Status = models.PaymentStatusChargeback
+	payment.UpdatedAt = time.Now()
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]interface{})
+	}
+	if req.Category == "" {
+		req.Category = models.ChargebackCategoryFraud
+	}
+	payment.Metadata["chargeback_reason_code"] = req.ReasonCode
+	payment.Metadata["chargeback_category"] = req.Category
+	payment.Metadata["chargeback_recorded_by"] = req.Actor
+	payment.Metadata["chargeback_recorded_at"] = payment.UpdatedAt.Format(time.RFC3339)
+	if req.Reason != "" {
+		payment.Metadata["chargeback_reason"] = req.Reason
+	}
+
+	chargedBack, err := h.producer.New(events.TypePaymentChargedBack, payment.Event())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment event"})
+		return
+	}
+	message, err := chargedBack.Message(h.eventsTopic, payment.ID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment event"})
+		return
+	}
+
+	err = h.repository.RecordChargeback(c.Request.Context(), payment, message)
+	if errors.Is(err, repository.ErrPaymentNotCaptured) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment has not been captured"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record chargeback"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, payment.Response())
 }
 
 // RequestRefund handles payment refund requests
 // @Summary Request a refund
-// @Description Process a refund for a payment
+// @Description Request a refund of a captured or settled payment, or another partial refund of a refunded one, up to the amount not refunded yet. The payment engine refunds it with the payment provider and moves it to REFUNDED. Refunds marked fraudulent label the payment as fraud, and fraud detection adds its card, email, VPA and device to the blocklist.
 // @Tags payments
 // @Accept json
 // @Produce json
 // @Param refund body models.RefundRequest true "Refund Request"
-// @Success 200 {object} models.RefundResponse
+// @Success 202 {object} models.RefundResponse
 // @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /api/v1/refunds [post]
 func (h *PaymentHandler) RequestRefund(c *gin.Context) {
@@ -160,36 +310,167 @@ func (h *PaymentHandler) RequestRefund(c *gin.Context) {
 		return
 	}
 
-	// Generate a new refund ID
-	refundID := uuid.New()
+	payment, err := h.repository.GetPayment(c.Request.Context(), req.PaymentID)
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+		return
+	}
+	if payment.Status != models.PaymentStatusCaptured && payment.Status != models.PaymentStatusSettled &&
+		payment.Status != models.PaymentStatusRefunded {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment has not been captured"})
+		return
+	}
+	if err := models.ValidateAmount(req.Amount, payment.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// The payment engine records the total of a payment's refunds as they go through
+	remaining := payment.Amount
+	if refunded, ok := payment.Metadata["refunded_amount"].(string); ok {
+		if amount, err := money.Parse(refunded); err == nil {
+			remaining = remaining.Sub(amount)
+		}
+	}
+	if req.Amount.Cmp(remaining) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refund amount exceeds the amount not refunded yet"})
+		return
+	}
 
-	// Create refund response
 	refund := models.RefundResponse{
-		ID:        refundID,
-		PaymentID: req.PaymentID,
+		ID:        uuid.New(),
+		PaymentID: payment.ID,
 		Amount:    req.Amount,
-		Status:    models.PaymentStatusRefunded,
+		Status:    models.RefundStatusPending,
 		CreatedAt: time.Now(),
 	}
 
-	// In a real implementation, this would:
-	// 1. Validate the refund request
-	// 2. Check if the payment exists and can be refunded
-	// 3. Process the refund with the payment provider
-	// 4. Update the payment status
-	// 5. Publish an event to Kafka
+	payment.UpdatedAt = refund.CreatedAt
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]interface{})
+	}
+	payment.Metadata["refund_id"] = refund.ID.String()
+	payment.Metadata["refund_amount"] = req.Amount.String()
+	payment.Metadata["refund_requested_at"] = refund.CreatedAt.Format(time.RFC3339)
+	if req.Reason != "" {
+		payment.Metadata["refund_reason"] = req.Reason
+	}
+	if req.Actor != "" {
+		payment.Metadata["refund_requested_by"] = req.Actor
+	}
+	if req.Fraudulent {
+		payment.Metadata["refund_fraudulent"] = "true"
+	}
+
+	command, err := h.producer.New(events.TypePaymentRefundRequested, payment.Event())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment command"})
+		return
+	}
+	message, err := command.Message(h.commandsTopic, payment.ID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment command"})
+		return
+	}
+
+	err = h.repository.RequestRefund(c.Request.Context(), payment, message)
+	if errors.Is(err, repository.ErrPaymentNotCaptured) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment has not been captured"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request refund"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, refund)
+}
+
+// ReportFraud records a merchant's report that a payment was fraudulent or legitimate
+// @Summary Report fraud on a payment
+// @Description Report that a payment turned out fraudulent or, for a payment fraud screening stopped, legitimate. Fraud detection labels the payment, and adds the card, email, VPA and device of fraudulent ones to the blocklist.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param report body models.FraudReportRequest true "Fraud report"
+// @Success 202 {object} models.PaymentResponse
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/payments/{id}/fraud-report [post]
+func (h *PaymentHandler) ReportFraud(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var req models.FraudReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := h.repository.GetPayment(c.Request.Context(), paymentID)
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+		return
+	}
+
+	payment.UpdatedAt = time.Now()
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]interface{})
+	}
+	payment.Metadata["fraud_report_label"] = string(req.Label)
+	payment.Metadata["fraud_reported_by"] = req.Actor
+	payment.Metadata["fraud_reported_at"] = payment.UpdatedAt.Format(time.RFC3339)
+	if req.Note != "" {
+		payment.Metadata["fraud_report_note"] = req.Note
+	}
+
+	reported, err := h.producer.New(events.TypePaymentFraudReported, payment.Event())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment event"})
+		return
+	}
+	message, err := reported.Message(h.eventsTopic, payment.ID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize payment event"})
+		return
+	}
+
+	err = h.repository.RecordFraudReport(c.Request.Context(), payment, message)
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record fraud report"})
+		return
+	}
 
-	c.JSON(http.StatusOK, refund)
+	c.JSON(http.StatusAccepted, payment.Response())
 }
 
 // RegisterPaymentRoutes registers the payment routes with the given router group
-func RegisterPaymentRoutes(router *gin.RouterGroup, kafkaWriter *kafka.Writer) {
-	h := NewPaymentHandler(kafkaWriter)
+func RegisterPaymentRoutes(router *gin.RouterGroup, repository repository.Repository, producer events.Producer, commandsTopic, eventsTopic, fraudTopic string) {
+	h := NewPaymentHandler(repository, producer, commandsTopic, eventsTopic, fraudTopic)
 
 	payments := router.Group("/payments")
 	{
 		payments.POST("/initiate", h.InitiatePayment)
 		payments.GET("/:id", h.GetPaymentStatus)
+		payments.POST("/:id/review", h.ReviewPayment)
+		payments.POST("/:id/chargeback", h.RecordChargeback)
+		payments.POST("/:id/fraud-report", h.ReportFraud)
 	}
 
 	refunds := router.Group("/refunds")
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/api-gateway/internal/repository"
+)
+
+// SettlementHandler handles the settlement endpoints. Settlements are created
+// by the settlement engine; the gateway only reads them.
+type SettlementHandler struct {
+	repository repository.Repository
+}
+
+// NewSettlementHandler creates a new SettlementHandler
+func NewSettlementHandler(repository repository.Repository) *SettlementHandler {
+	return &SettlementHandler{repository: repository}
+}
+
+// GetItems lists the payments a settlement paid out
+// @Summary List settlement items
+// @Description List the payments a settlement paid out, oldest first, with the fee and tax charged on each and the net amount paid for it. The settlement's amounts are the sums of its items'.
+// @Tags settlements
+// @Produce json
+// @Param id path string true "Settlement ID"
+// @Success 200 {array} models.SettlementItem
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1/settlements/{id}/items [get]
+func (h *SettlementHandler) GetItems(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settlement ID"})
+		return
+	}
+
+	items, err := h.repository.SettlementItems(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrSettlementNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settlement items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// RegisterSettlementRoutes registers the settlement routes with the given router group
+func RegisterSettlementRoutes(router *gin.RouterGroup, repository repository.Repository) {
+	h := NewSettlementHandler(repository)
+
+	settlements := router.Group("/settlements")
+	{
+		settlements.GET("/:id/items", h.GetItems)
+	}
+}
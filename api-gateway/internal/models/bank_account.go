@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// BankAccountStatus is where a bank account is in its verification
+type BankAccountStatus string
+
+// Bank account statuses
+const (
+	BankAccountStatusPending  BankAccountStatus = "PENDING"
+	BankAccountStatusVerified BankAccountStatus = "VERIFIED"
+	BankAccountStatusFailed   BankAccountStatus = "FAILED"
+	BankAccountStatusReplaced BankAccountStatus = "REPLACED"
+)
+
+// BankAccount is a bank account a merchant registered to be paid out to. Its
+// account number, or IBAN, is only kept encrypted. Once a penny drop verifies
+// it, it is the merchant's payout account, replacing ReplacesAccountID.
+type BankAccount struct {
+	ID                     uuid.UUID          `json:"id"`
+	MerchantID             uuid.UUID          `json:"merchant_id"`
+	HolderName             string             `json:"holder_name"`
+	Scheme                 bankaccount.Scheme `json:"scheme"`
+	AccountNumberEncrypted string             `json:"-"`
+	AccountNumberLast4     string             `json:"account_number_last4"`
+	IFSC                   string             `json:"ifsc,omitempty"`
+	BIC                    string             `json:"bic,omitempty"`
+	Status                 BankAccountStatus  `json:"status"`
+	VerificationReference  string             `json:"verification_reference,omitempty"`
+	RegisteredName         string             `json:"registered_name,omitempty"`
+	FailureReason          string             `json:"failure_reason,omitempty"`
+	VerifiedAt             *time.Time         `json:"verified_at,omitempty"`
+	ReplacesAccountID      *uuid.UUID         `json:"replaces_account_id,omitempty"`
+	ReplacedAt             *time.Time         `json:"replaced_at,omitempty"`
+	CreatedAt              time.Time          `json:"created_at"`
+	UpdatedAt              time.Time          `json:"updated_at"`
+}
+
+// BankAccountRequest registers a bank account: an Indian account number with
+// the IFSC of its branch, or an IBAN with an optional BIC
+type BankAccountRequest struct {
+	HolderName    string `json:"holder_name" binding:"required"`
+	AccountNumber string `json:"account_number" binding:"required_with=IFSC,excluded_with=IBAN"`
+	IFSC          string `json:"ifsc" binding:"required_with=AccountNumber,excluded_with=IBAN"`
+	IBAN          string `json:"iban" binding:"required_without=AccountNumber"`
+	BIC           string `json:"bic"`
+}
+
+// Account returns the account details of the request
+func (r BankAccountRequest) Account() bankaccount.Account {
+	account := bankaccount.Account{
+		HolderName: r.HolderName,
+		Scheme:     bankaccount.SchemeIFSC,
+		Number:     r.AccountNumber,
+		IFSC:       r.IFSC,
+		BIC:        r.BIC,
+	}
+	if r.IBAN != "" {
+		account.Scheme = bankaccount.SchemeIBAN
+		account.Number = r.IBAN
+	}
+	return account
+}
+
+// BankAccountVerification is the outcome of the penny drop verifying a bank
+// account
+type BankAccountVerification struct {
+	Reference      string        `json:"reference"`
+	Amount         money.Decimal `json:"amount"`
+	Currency       string        `json:"currency"`
+	RegisteredName string        `json:"registered_name"`
+	Verified       bool          `json:"verified"`
+	Reason         string        `json:"reason,omitempty"`
+	CompletedAt    time.Time     `json:"completed_at"`
+}
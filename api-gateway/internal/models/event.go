@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/events"
+)
+
+// Event converts the payment to the shared payment event payload
+func (p Payment) Event() events.Payment {
+	event := events.Payment{
+		ID:                 p.ID,
+		MerchantID:         p.MerchantID,
+		Currency:           p.Currency,
+		Status:             string(p.Status),
+		PaymentMethodType:  string(p.PaymentMethodType),
+		PaymentMethodID:    p.PaymentMethodID,
+		Description:        p.Description,
+		Metadata:           eventMetadata(p.Metadata),
+		IdempotencyKey:     p.IdempotencyKey,
+		ReferenceID:        p.ReferenceID,
+		DeclineCode:        p.DeclineCode,
+		ClientIP:           p.ClientIP,
+		BillingCountry:     p.BillingCountry,
+		CardIssuingCountry: p.CardIssuingCountry,
+		CreatedAt:          p.CreatedAt,
+		UpdatedAt:          p.UpdatedAt,
+	}
+	event.SetMoney(p.Amount)
+	if p.CustomerID != uuid.Nil {
+		customerID := p.CustomerID
+		event.CustomerID = &customerID
+	}
+	if p.Device != nil {
+		event.Device = &events.Device{
+			ID:        p.Device.ID,
+			UserAgent: p.Device.UserAgent,
+			Screen:    p.Device.Screen,
+			Timezone:  p.Device.Timezone,
+		}
+	}
+	if p.Session != nil {
+		event.Session = &events.Session{
+			ID:             p.Session.ID,
+			AccountAgeDays: p.Session.AccountAgeDays,
+		}
+	}
+	return event
+}
+
+// eventMetadata converts metadata to the string map used in events. String
+// values are kept as they are and other values are JSON-encoded.
+func eventMetadata(metadata map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if s, ok := value.(string); ok {
+			result[key] = s
+			continue
+		}
+		if encoded, err := json.Marshal(value); err == nil {
+			result[key] = string(encoded)
+		}
+	}
+	return result
+}
+
+// ReviewEvent converts a case's resolution to the shared fraud review event payload
+func (c FraudCase) ReviewEvent(resolution FraudCaseResolution) events.FraudReview {
+	return events.FraudReview{
+		CaseID:     c.ID,
+		PaymentID:  c.PaymentID,
+		MerchantID: c.MerchantID,
+		Decision:   resolution.Decision,
+		Label:      string(resolution.Label),
+		Reviewer:   resolution.Reviewer,
+		Note:       resolution.Note,
+		ReviewedAt: resolution.ResolvedAt,
+	}
+}
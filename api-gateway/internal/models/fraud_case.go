@@ -0,0 +1,150 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FraudCaseStatus is the state of a fraud review case
+type FraudCaseStatus string
+
+// Fraud case statuses
+const (
+	FraudCaseStatusOpen     FraudCaseStatus = "OPEN"
+	FraudCaseStatusApproved FraudCaseStatus = "APPROVED"
+	FraudCaseStatusRejected FraudCaseStatus = "REJECTED"
+)
+
+// FraudLabel says whether a payment turned out fraudulent
+type FraudLabel string
+
+// Fraud labels
+const (
+	FraudLabelFraud      FraudLabel = "FRAUD"
+	FraudLabelLegitimate FraudLabel = "LEGITIMATE"
+)
+
+// LabelSource is where a fraud label came from
+type LabelSource string
+
+// Label sources
+const (
+	LabelSourceReview         LabelSource = "REVIEW"
+	LabelSourceChargeback     LabelSource = "CHARGEBACK"
+	LabelSourceRefund         LabelSource = "REFUND"
+	LabelSourceMerchantReport LabelSource = "MERCHANT_REPORT"
+)
+
+// FraudCase is a payment held by fraud screening for an analyst to review
+type FraudCase struct {
+	ID          uuid.UUID       `json:"id"`
+	PaymentID   uuid.UUID       `json:"payment_id"`
+	MerchantID  uuid.UUID       `json:"merchant_id"`
+	Status      FraudCaseStatus `json:"status"`
+	RiskScore   float64         `json:"risk_score"`
+	Reason      string          `json:"reason,omitempty"`
+	ReasonCodes []string        `json:"reason_codes,omitempty"`
+	Reviewer    string          `json:"reviewer,omitempty"`
+	Note        string          `json:"note,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ResolvedAt  *time.Time      `json:"resolved_at,omitempty"`
+}
+
+// FraudCaseDetail is a case with the payment and the fraud check behind it
+type FraudCaseDetail struct {
+	FraudCase
+	Payment *PaymentResponse `json:"payment,omitempty"`
+	Check   FraudCheck       `json:"check"`
+}
+
+// FraudCheck is the outcome of screening a payment for fraud
+type FraudCheck struct {
+	ID          uuid.UUID        `json:"id"`
+	PaymentID   uuid.UUID        `json:"payment_id"`
+	RiskScore   float64          `json:"risk_score"`
+	Decision    string           `json:"decision"`
+	Reason      string           `json:"reason,omitempty"`
+	ReasonCodes []string         `json:"reason_codes,omitempty"`
+	Checks      []FraudCheckItem `json:"checks"`
+	ModelScores []ModelScore     `json:"model_scores,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// FraudCheckItem is one check that went into a payment's risk score
+type FraudCheckItem struct {
+	Type   string   `json:"type"`
+	Score  float64  `json:"score"`
+	Weight float64  `json:"weight"`
+	Info   string   `json:"info,omitempty"`
+	Rules  []string `json:"rules,omitempty"`
+}
+
+// ModelScore is the score a machine-learning model gave a payment. A shadow
+// model's score did not affect the decision.
+type ModelScore struct {
+	Model    string  `json:"model"`
+	Version  string  `json:"version,omitempty"`
+	Score    float64 `json:"score"`
+	Shadow   bool    `json:"shadow"`
+	Decision string  `json:"decision"`
+}
+
+// FraudCaseFilter selects fraud cases. Zero fields match everything.
+type FraudCaseFilter struct {
+	Status     FraudCaseStatus `form:"status" binding:"omitempty,oneof=OPEN APPROVED REJECTED"`
+	MerchantID string          `form:"merchant_id" binding:"omitempty,uuid"`
+	PaymentID  string          `form:"payment_id" binding:"omitempty,uuid"`
+	Limit      int             `form:"limit" binding:"omitempty,min=1,max=1000"`
+	Offset     int             `form:"offset" binding:"omitempty,min=0"`
+}
+
+// FraudCaseResolution is an analyst's decision on a fraud case and the label
+// it gives the payment
+type FraudCaseResolution struct {
+	Decision   string
+	Status     FraudCaseStatus
+	Label      FraudLabel
+	Reviewer   string
+	Note       string
+	ResolvedAt time.Time
+}
+
+// Resolution returns the resolution of a case reviewed at resolvedAt.
+// Approved payments are labeled legitimate and rejected ones fraudulent.
+func (r ReviewRequest) Resolution(resolvedAt time.Time) FraudCaseResolution {
+	resolution := FraudCaseResolution{
+		Decision:   r.Decision,
+		Status:     FraudCaseStatusApproved,
+		Label:      FraudLabelLegitimate,
+		Reviewer:   r.Reviewer,
+		Note:       r.Note,
+		ResolvedAt: resolvedAt,
+	}
+	if r.Decision == ReviewDecisionReject {
+		resolution.Status = FraudCaseStatusRejected
+		resolution.Label = FraudLabelFraud
+	}
+	return resolution
+}
+
+// TrainingExample is a screened payment with its latest fraud label, and the
+// feature vector and risk score screening saw
+type TrainingExample struct {
+	PaymentID  uuid.UUID          `json:"payment_id"`
+	MerchantID uuid.UUID          `json:"merchant_id"`
+	Features   map[string]float64 `json:"features"`
+	RiskScore  float64            `json:"risk_score"`
+	Decision   string             `json:"decision"`
+	Label      FraudLabel         `json:"label"`
+	Source     LabelSource        `json:"source"`
+	LabeledAt  time.Time          `json:"labeled_at"`
+}
+
+// TrainingDataFilter selects training examples. Zero fields match everything.
+type TrainingDataFilter struct {
+	MerchantID string    `form:"merchant_id" binding:"omitempty,uuid"`
+	Since      time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit      int       `form:"limit" binding:"omitempty,min=1,max=10000"`
+	Offset     int       `form:"offset" binding:"omitempty,min=0"`
+}
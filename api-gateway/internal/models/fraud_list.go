@@ -0,0 +1,137 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FraudList is a list consulted by fraud screening before a payment is scored
+type FraudList string
+
+// Fraud lists
+const (
+	FraudListBlock FraudList = "BLOCK"
+	FraudListAllow FraudList = "ALLOW"
+)
+
+// ListEntryType is what a list entry matches
+type ListEntryType string
+
+// List entry types
+const (
+	ListEntryTypeCard     ListEntryType = "CARD"
+	ListEntryTypeEmail    ListEntryType = "EMAIL"
+	ListEntryTypeVPA      ListEntryType = "VPA"
+	ListEntryTypeIP       ListEntryType = "IP"
+	ListEntryTypeDevice   ListEntryType = "DEVICE"
+	ListEntryTypeCustomer ListEntryType = "CUSTOMER"
+)
+
+// ListEntrySource is where a list entry came from
+type ListEntrySource string
+
+// List entry sources
+const (
+	ListEntrySourceManual         ListEntrySource = "MANUAL"
+	ListEntrySourceChargeback     ListEntrySource = "CHARGEBACK"
+	ListEntrySourceRefund         ListEntrySource = "REFUND"
+	ListEntrySourceMerchantReport ListEntrySource = "MERCHANT_REPORT"
+)
+
+// ListAuditAction is a change to a list entry
+type ListAuditAction string
+
+// List audit actions
+const (
+	ListAuditActionAdded   ListAuditAction = "ADDED"
+	ListAuditActionRemoved ListAuditAction = "REMOVED"
+)
+
+// ListEntry blocks or trusts a card fingerprint, email, VPA, IP address,
+// device or customer. Entries without a merchant apply to every merchant;
+// entries with an expiry stop applying once it has passed.
+type ListEntry struct {
+	ID         uuid.UUID       `json:"id"`
+	List       FraudList       `json:"list"`
+	Type       ListEntryType   `json:"type"`
+	Value      string          `json:"value"`
+	MerchantID *uuid.UUID      `json:"merchant_id,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	Source     ListEntrySource `json:"source"`
+	CreatedBy  string          `json:"created_by"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// ListEntryRequest represents a request to add an entry to a fraud list.
+// Without a merchant ID the entry applies to all merchants, and without a TTL
+// it applies until removed.
+type ListEntryRequest struct {
+	List       FraudList     `json:"list" binding:"required,oneof=BLOCK ALLOW"`
+	Type       ListEntryType `json:"type" binding:"required,oneof=CARD EMAIL VPA IP DEVICE CUSTOMER"`
+	Value      string        `json:"value" binding:"required,max=256"`
+	MerchantID *uuid.UUID    `json:"merchant_id"`
+	TTLSeconds int64         `json:"ttl_seconds" binding:"omitempty,min=1"`
+	Reason     string        `json:"reason" binding:"max=500"`
+	Actor      string        `json:"actor" binding:"required,max=100"`
+}
+
+// ListEntryRemoval represents a request to remove an entry from a fraud list
+type ListEntryRemoval struct {
+	Actor  string `json:"actor" binding:"required,max=100"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ListEntryFilter selects list entries. Zero fields match everything; Global
+// selects only entries that apply to all merchants.
+type ListEntryFilter struct {
+	List       FraudList     `form:"list" binding:"omitempty,oneof=BLOCK ALLOW"`
+	Type       ListEntryType `form:"type" binding:"omitempty,oneof=CARD EMAIL VPA IP DEVICE CUSTOMER"`
+	Value      string        `form:"value"`
+	MerchantID string        `form:"merchant_id" binding:"omitempty,uuid"`
+	Global     bool          `form:"global"`
+	Limit      int           `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// ListAuditRecord is one change to a list entry
+type ListAuditRecord struct {
+	ID        uuid.UUID       `json:"id"`
+	EntryID   uuid.UUID       `json:"entry_id"`
+	Action    ListAuditAction `json:"action"`
+	Actor     string          `json:"actor"`
+	Reason    string          `json:"reason,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NormalizeListValue returns the form a value of the given type is listed
+// and matched in: emails and VPAs in lower case, IP addresses in canonical
+// form and customer IDs as UUIDs
+func NormalizeListValue(entryType ListEntryType, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch entryType {
+	case ListEntryTypeEmail, ListEntryTypeVPA:
+		value = strings.ToLower(value)
+	case ListEntryTypeIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", fmt.Errorf("invalid IP address %q", value)
+		}
+		value = ip.String()
+	case ListEntryTypeCustomer:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid customer ID %q", value)
+		}
+		value = id.String()
+	}
+	if value == "" {
+		return "", fmt.Errorf("empty %s value", strings.ToLower(string(entryType)))
+	}
+	return value, nil
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MerchantFraudRate is the share of a merchant's payments screened in the
+// last 90 days that are labeled fraudulent
+type MerchantFraudRate struct {
+	MerchantID    uuid.UUID `json:"merchant_id"`
+	Payments      int       `json:"payments"`
+	FraudPayments int       `json:"fraud_payments"`
+	FraudRate     float64   `json:"fraud_rate"`
+}
+
+// LabeledCheck is a past fraud decision and what the payment turned out to be
+type LabeledCheck struct {
+	RiskScore float64
+	Decision  string
+	// Rules holds the rules that matched, as check.rule
+	Rules []string
+	Label FraudLabel
+}
+
+// DecisionReportFilter selects the labeled payments a decision report covers,
+// by when they were screened. Thresholds are the risk score thresholds to
+// report on, repeated in the query; by default 0.1 to 0.9 in steps of 0.1.
+type DecisionReportFilter struct {
+	MerchantID string    `form:"merchant_id" binding:"omitempty,uuid"`
+	Since      time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until      time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Thresholds []float64 `form:"thresholds" binding:"omitempty,max=50,dive,min=0,max=1"`
+}
+
+// Performance is how well flagging payments singled out the fraudulent ones.
+// Precision is the share of flagged payments that were fraudulent and recall
+// the share of fraudulent payments that were flagged; either is 0 when there
+// is nothing to divide by.
+type Performance struct {
+	Flagged        int     `json:"flagged"`
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+}
+
+// DecisionPerformance is the performance of flagging the payments screening
+// decided as Decision: REVIEW, BLOCK, or REVIEW_OR_BLOCK for both
+type DecisionPerformance struct {
+	Decision string `json:"decision"`
+	Performance
+}
+
+// ThresholdPerformance is the performance of flagging the payments with a
+// risk score above Threshold
+type ThresholdPerformance struct {
+	Threshold float64 `json:"threshold"`
+	Performance
+}
+
+// RulePerformance is the performance of flagging the payments a rule matched
+type RulePerformance struct {
+	Rule string `json:"rule"`
+	Performance
+}
+
+// DecisionReport compares past fraud decisions with what the payments turned
+// out to be, per decision, risk score threshold and rule. Rules are ordered
+// by how many payments they flagged.
+type DecisionReport struct {
+	Payments      int                    `json:"payments"`
+	FraudPayments int                    `json:"fraud_payments"`
+	Decisions     []DecisionPerformance  `json:"decisions"`
+	Thresholds    []ThresholdPerformance `json:"thresholds"`
+	Rules         []RulePerformance      `json:"rules"`
+}
+
+// DefaultReportThresholds are the risk score thresholds reported on when
+// none are asked for
+var DefaultReportThresholds = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// NewDecisionReport reports on labeled fraud checks
+func NewDecisionReport(checks []LabeledCheck, thresholds []float64) DecisionReport {
+	report := DecisionReport{Payments: len(checks)}
+	for _, check := range checks {
+		if check.Label == FraudLabelFraud {
+			report.FraudPayments++
+		}
+	}
+
+	// flag tallies the payments a predicate flags
+	flag := func(flagged func(LabeledCheck) bool) Performance {
+		var p Performance
+		for _, check := range checks {
+			if !flagged(check) {
+				continue
+			}
+			p.Flagged++
+			if check.Label == FraudLabelFraud {
+				p.TruePositives++
+			} else {
+				p.FalsePositives++
+			}
+		}
+		return p.withRates(report.FraudPayments)
+	}
+
+	report.Decisions = []DecisionPerformance{
+		{Decision: "REVIEW", Performance: flag(func(c LabeledCheck) bool { return c.Decision == "REVIEW" })},
+		{Decision: "BLOCK", Performance: flag(func(c LabeledCheck) bool { return c.Decision == "BLOCK" })},
+		{Decision: "REVIEW_OR_BLOCK", Performance: flag(func(c LabeledCheck) bool { return c.Decision != "ALLOW" })},
+	}
+
+	sorted := append([]float64(nil), thresholds...)
+	sort.Float64s(sorted)
+	report.Thresholds = make([]ThresholdPerformance, 0, len(sorted))
+	for _, threshold := range sorted {
+		threshold := threshold
+		report.Thresholds = append(report.Thresholds, ThresholdPerformance{
+			Threshold:   threshold,
+			Performance: flag(func(c LabeledCheck) bool { return c.RiskScore > threshold }),
+		})
+	}
+
+	rules := make(map[string]*Performance)
+	for _, check := range checks {
+		for _, rule := range check.Rules {
+			p, ok := rules[rule]
+			if !ok {
+				p = &Performance{}
+				rules[rule] = p
+			}
+			p.Flagged++
+			if check.Label == FraudLabelFraud {
+				p.TruePositives++
+			} else {
+				p.FalsePositives++
+			}
+		}
+	}
+	report.Rules = make([]RulePerformance, 0, len(rules))
+	for rule, p := range rules {
+		report.Rules = append(report.Rules, RulePerformance{Rule: rule, Performance: p.withRates(report.FraudPayments)})
+	}
+	sort.Slice(report.Rules, func(i, j int) bool {
+		if report.Rules[i].Flagged != report.Rules[j].Flagged {
+			return report.Rules[i].Flagged > report.Rules[j].Flagged
+		}
+		return report.Rules[i].Rule < report.Rules[j].Rule
+	})
+	return report
+}
+
+// withRates returns the performance with its precision and recall out of
+// fraudPayments fraudulent payments
+func (p Performance) withRates(fraudPayments int) Performance {
+	if p.Flagged > 0 {
+		p.Precision = float64(p.TruePositives) / float64(p.Flagged)
+	}
+	if fraudPayments > 0 {
+		p.Recall = float64(p.TruePositives) / float64(fraudPayments)
+	}
+	return p
+}
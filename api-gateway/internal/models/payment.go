@@ -1,9 +1,12 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/money"
 )
 
 // PaymentStatus represents the status of a payment
@@ -12,6 +15,7 @@ type PaymentStatus string
 // Payment statuses
 const (
 	PaymentStatusInitiated  PaymentStatus = "INITIATED"
+	PaymentStatusReview     PaymentStatus = "REVIEW"
 	PaymentStatusAuthorized PaymentStatus = "AUTHORIZED"
 	PaymentStatusCaptured   PaymentStatus = "CAPTURED"
 	PaymentStatusSettled    PaymentStatus = "SETTLED"
@@ -39,7 +43,7 @@ type Payment struct {
 	ID               uuid.UUID      `json:"id"`
 	MerchantID       uuid.UUID      `json:"merchant_id"`
 	CustomerID       uuid.UUID      `json:"customer_id,omitempty"`
-	Amount           float64        `json:"amount"`
+	Amount           money.Decimal  `json:"amount"`
 	Currency         string         `json:"currency"`
 	Status           PaymentStatus  `json:"status"`
 	PaymentMethodID  *uuid.UUID     `json:"payment_method_id,omitempty"`
@@ -48,15 +52,41 @@ type Payment struct {
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 	IdempotencyKey   string         `json:"idempotency_key,omitempty"`
 	ReferenceID      string         `json:"reference_id,omitempty"`
+	DeclineCode      string         `json:"decline_code,omitempty"`
+	ClientIP         string         `json:"client_ip,omitempty"`
+	BillingCountry   string         `json:"billing_country,omitempty"`
+	CardIssuingCountry string       `json:"card_issuing_country,omitempty"`
+	Device           *Device        `json:"device,omitempty"`
+	Session          *Session       `json:"session,omitempty"`
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
 }
 
+// MaxAmount is the largest amount a payment can be for, which the amount
+// columns hold in every currency
+var MaxAmount = money.MustParse("999999999999999")
+
+// ValidateAmount checks that an amount is positive, at most MaxAmount and a
+// whole number of minor units of its currency, so 10.5 is a valid USD amount
+// but not a valid JPY one
+func ValidateAmount(amount money.Decimal, currency string) error {
+	switch {
+	case amount.Sign() <= 0:
+		return errors.New("amount must be greater than zero")
+	case amount.Cmp(MaxAmount) > 0:
+		return fmt.Errorf("amount must be at most %s", MaxAmount)
+	case !amount.Fits(currency):
+		return fmt.Errorf("amount must have at most %d decimals in %s", money.Exponent(currency), currency)
+	}
+	return nil
+}
+
 // PaymentRequest represents a request to create a new payment
 type PaymentRequest struct {
 	MerchantID       uuid.UUID      `json:"merchant_id" binding:"required"`
 	CustomerID       *uuid.UUID     `json:"customer_id"`
-	Amount           float64        `json:"amount" binding:"required,gt=0"`
+	// Amount is validated with ValidateAmount against the currency
+	Amount           money.Decimal  `json:"amount"`
 	Currency         string         `json:"currency" binding:"required,len=3"`
 	PaymentMethodID  *uuid.UUID     `json:"payment_method_id"`
 	PaymentMethodType PaymentMethod `json:"payment_method_type" binding:"required"`
@@ -64,6 +94,27 @@ type PaymentRequest struct {
 	Metadata         map[string]interface{} `json:"metadata"`
 	IdempotencyKey   string         `json:"idempotency_key"`
 	ReferenceID      string         `json:"reference_id"`
+	// BillingCountry and CardIssuingCountry are ISO 3166-1 alpha-2 codes that
+	// fraud screening compares with the country of the client IP
+	BillingCountry   string         `json:"billing_country" binding:"omitempty,iso3166_1_alpha2"`
+	CardIssuingCountry string       `json:"card_issuing_country" binding:"omitempty,iso3166_1_alpha2"`
+	// Device and Session are optional signals for fraud screening
+	Device           *Device        `json:"device"`
+	Session          *Session       `json:"session"`
+}
+
+// Device describes the device a payment was made from
+type Device struct {
+	ID        string `json:"id" binding:"required,max=128"`
+	UserAgent string `json:"user_agent" binding:"max=512"`
+	Screen    string `json:"screen" binding:"max=32"`
+	Timezone  string `json:"timezone" binding:"omitempty,timezone"`
+}
+
+// Session describes the customer session a payment was made in
+type Session struct {
+	ID             string `json:"id" binding:"max=128"`
+	AccountAgeDays *int   `json:"account_age_days" binding:"omitempty,min=0"`
 }
 
 // PaymentResponse represents a response with payment details
@@ -71,36 +122,98 @@ type PaymentResponse struct {
 	ID               uuid.UUID      `json:"id"`
 	MerchantID       uuid.UUID      `json:"merchant_id"`
 	CustomerID       *uuid.UUID     `json:"customer_id,omitempty"`
-	Amount           float64        `json:"amount"`
+	Amount           money.Decimal  `json:"amount"`
 	Currency         string         `json:"currency"`
 	Status           PaymentStatus  `json:"status"`
 	PaymentMethodType PaymentMethod `json:"payment_method_type"`
 	Description      string         `json:"description,omitempty"`
 	ReferenceID      string         `json:"reference_id,omitempty"`
+	DeclineCode      string         `json:"decline_code,omitempty"`
 	CreatedAt        time.Time      `json:"created_at"`
 }
 
-// PaymentEvent represents a payment event to be published to Kafka
-type PaymentEvent struct {
-	ID        uuid.UUID     `json:"id"`
-	Type      string        `json:"type"`
-	Payment   Payment       `json:"payment"`
-	Timestamp time.Time     `json:"timestamp"`
+// Response converts the payment to its API representation
+func (p Payment) Response() PaymentResponse {
+	response := PaymentResponse{
+		ID:                p.ID,
+		MerchantID:        p.MerchantID,
+		Amount:            p.Amount,
+		Currency:          p.Currency,
+		Status:            p.Status,
+		PaymentMethodType: p.PaymentMethodType,
+		Description:       p.Description,
+		ReferenceID:       p.ReferenceID,
+		DeclineCode:       p.DeclineCode,
+		CreatedAt:         p.CreatedAt,
+	}
+	if p.CustomerID != uuid.Nil {
+		customerID := p.CustomerID
+		response.CustomerID = &customerID
+	}
+	return response
+}
+
+// Review decisions
+const (
+	ReviewDecisionApprove = "approve"
+	ReviewDecisionReject  = "reject"
+)
+
+// ReviewRequest represents an analyst's decision on a payment held for fraud review
+type ReviewRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Reviewer string `json:"reviewer" binding:"required"`
+	Note     string `json:"note"`
 }
 
-// RefundRequest represents a request to refund a payment
+// Chargeback categories
+const (
+	ChargebackCategoryFraud   = "FRAUD"
+	ChargebackCategoryDispute = "DISPUTE"
+)
+
+// ChargebackRequest represents a chargeback confirmed on a captured payment.
+// Fraud chargebacks, the default, are for payments the cardholder did not
+// make; disputes are about goods or services the cardholder did pay for.
+type ChargebackRequest struct {
+	ReasonCode string `json:"reason_code" binding:"required,max=20"`
+	Reason     string `json:"reason" binding:"max=500"`
+	Category   string `json:"category" binding:"omitempty,oneof=FRAUD DISPUTE"`
+	Actor      string `json:"actor" binding:"required,max=100"`
+}
+
+// FraudReportRequest represents a merchant's report that a payment was
+// fraudulent or, for a payment fraud screening stopped, legitimate
+type FraudReportRequest struct {
+	Label FraudLabel `json:"label" binding:"required,oneof=FRAUD LEGITIMATE"`
+	Note  string     `json:"note" binding:"max=500"`
+	Actor string     `json:"actor" binding:"required,max=100"`
+}
+
+// RefundRequest represents a request to refund a payment. Fraudulent marks a
+// refund of a payment the merchant found to be fraudulent.
 type RefundRequest struct {
-	PaymentID      uuid.UUID `json:"payment_id" binding:"required"`
-	Amount         float64   `json:"amount" binding:"required,gt=0"`
-	Reason         string    `json:"reason"`
-	IdempotencyKey string    `json:"idempotency_key"`
+	PaymentID      uuid.UUID     `json:"payment_id" binding:"required"`
+	Amount         money.Decimal `json:"amount"`
+	Reason         string        `json:"reason" binding:"max=500"`
+	Fraudulent     bool          `json:"fraudulent"`
+	Actor          string        `json:"actor" binding:"max=100"`
+	IdempotencyKey string        `json:"idempotency_key"`
 }
 
+// RefundStatus is the state of a refund
+type RefundStatus string
+
+// Refund statuses
+const (
+	RefundStatusPending RefundStatus = "PENDING"
+)
+
 // RefundResponse represents a response with refund details
 type RefundResponse struct {
-	ID        uuid.UUID    `json:"id"`
-	PaymentID uuid.UUID    `json:"payment_id"`
-	Amount    float64      `json:"amount"`
-	Status    PaymentStatus `json:"status"`
-	CreatedAt time.Time    `json:"created_at"`
+	ID        uuid.UUID     `json:"id"`
+	PaymentID uuid.UUID     `json:"payment_id"`
+	Amount    money.Decimal `json:"amount"`
+	Status    RefundStatus  `json:"status"`
+	CreatedAt time.Time     `json:"created_at"`
 } 
\ No newline at end of file
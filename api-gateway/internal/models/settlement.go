@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// SettlementItem is a payment paid out by a settlement, with the fee and tax
+// charged on it and the net amount paid to the merchant for it. The fee is
+// FeePercent of the amount plus FixedFee, as priced by the merchant's
+// PricingPlan, and the tax TaxPercent of the fee in TaxJurisdiction. The
+// amounts of a settlement are the sums of its items'.
+type SettlementItem struct {
+	ID              uuid.UUID     `json:"id"`
+	SettlementID    uuid.UUID     `json:"settlement_id"`
+	PaymentID       uuid.UUID     `json:"payment_id"`
+	Amount          money.Decimal `json:"amount"`
+	Currency        string        `json:"currency"`
+	PaymentMethod   string        `json:"payment_method,omitempty"`
+	PricingPlan     string        `json:"pricing_plan,omitempty"`
+	FeePercent      money.Decimal `json:"fee_percent"`
+	FixedFee        money.Decimal `json:"fixed_fee"`
+	FeeAmount       money.Decimal `json:"fee_amount"`
+	TaxJurisdiction string        `json:"tax_jurisdiction,omitempty"`
+	TaxPercent      money.Decimal `json:"tax_percent"`
+	TaxAmount       money.Decimal `json:"tax_amount"`
+	NetAmount       money.Decimal `json:"net_amount"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
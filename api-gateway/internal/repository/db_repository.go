@@ -0,0 +1,1183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yourusername/fortexa/api-gateway/internal/models"
+	"github.com/yourusername/fortexa/pkg/money"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// DBRepository handles database operations
+type DBRepository struct {
+	db     *sql.DB
+	outbox *outbox.PostgresStore
+}
+
+// NewDBRepository creates a new database repository
+func NewDBRepository(connectionString string) (*DBRepository, error) {
+	db, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	// Test the connection
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Set connection pool settings
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	return &DBRepository{db: db, outbox: outbox.NewPostgresStore(db)}, nil
+}
+
+// Close closes the database connection
+func (r *DBRepository) Close() error {
+	return r.db.Close()
+}
+
+// Outbox returns the store the outbox relay reads from
+func (r *DBRepository) Outbox() outbox.Store {
+	return r.outbox
+}
+
+// CreatePayment stores a new payment and enqueues its events in the same transaction
+func (r *DBRepository) CreatePayment(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	metadata, err := json.Marshal(payment.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment metadata: %w", err)
+	}
+	var device, session sql.NullString
+	if payment.Device != nil {
+		encoded, err := json.Marshal(payment.Device)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payment device: %w", err)
+		}
+		device = sql.NullString{String: string(encoded), Valid: true}
+	}
+	if payment.Session != nil {
+		encoded, err := json.Marshal(payment.Session)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payment session: %w", err)
+		}
+		session = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO payments (
+            id, merchant_id, customer_id, amount, currency, status,
+            payment_method_id, payment_method_type, description, metadata,
+            idempotency_key, reference_id, client_ip, billing_country,
+            card_issuing_country, device, session, created_at, updated_at
+        ) VALUES (
+            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
+        )
+    `
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		payment.ID,
+		payment.MerchantID,
+		nullableUUID(payment.CustomerID),
+		payment.Amount,
+		payment.Currency,
+		payment.Status,
+		payment.PaymentMethodID,
+		payment.PaymentMethodType,
+		payment.Description,
+		string(metadata),
+		sql.NullString{String: payment.IdempotencyKey, Valid: payment.IdempotencyKey != ""},
+		payment.ReferenceID,
+		sql.NullString{String: payment.ClientIP, Valid: payment.ClientIP != ""},
+		sql.NullString{String: payment.BillingCountry, Valid: payment.BillingCountry != ""},
+		sql.NullString{String: payment.CardIssuingCountry, Valid: payment.CardIssuingCountry != ""},
+		device,
+		session,
+		payment.CreatedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment: %w", err)
+	}
+
+	return nil
+}
+
+// GetPayment returns a payment by ID, or ErrPaymentNotFound
+func (r *DBRepository) GetPayment(ctx context.Context, id uuid.UUID) (models.Payment, error) {
+	query := `
+        SELECT id, merchant_id, customer_id, amount, currency, status,
+            payment_method_id, payment_method_type, description, metadata,
+            idempotency_key, reference_id, decline_code, client_ip, billing_country,
+            card_issuing_country, device, session, created_at, updated_at
+        FROM payments
+        WHERE id = $1
+    `
+	var (
+		payment                                               models.Payment
+		customerID, paymentMethodID                           uuid.NullUUID
+		description, idempotencyKey, referenceID, declineCode sql.NullString
+		clientIP, billingCountry, cardIssuingCountry          sql.NullString
+		metadata, device, session                             []byte
+	)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&payment.ID,
+		&payment.MerchantID,
+		&customerID,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.Status,
+		&paymentMethodID,
+		&payment.PaymentMethodType,
+		&description,
+		&metadata,
+		&idempotencyKey,
+		&referenceID,
+		&declineCode,
+		&clientIP,
+		&billingCountry,
+		&cardIssuingCountry,
+		&device,
+		&session,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Payment{}, ErrPaymentNotFound
+	}
+	if err != nil {
+		return models.Payment{}, fmt.Errorf("failed to get payment: %w", err)
+	}
+	// Amount columns have three decimals; show the currency's
+	payment.Amount = payment.Amount.RoundTo(payment.Currency, money.RoundHalfEven)
+
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &payment.Metadata); err != nil {
+			return models.Payment{}, fmt.Errorf("failed to unmarshal payment metadata: %w", err)
+		}
+	}
+	if len(device) > 0 {
+		if err := json.Unmarshal(device, &payment.Device); err != nil {
+			return models.Payment{}, fmt.Errorf("failed to unmarshal payment device: %w", err)
+		}
+	}
+	if len(session) > 0 {
+		if err := json.Unmarshal(session, &payment.Session); err != nil {
+			return models.Payment{}, fmt.Errorf("failed to unmarshal payment session: %w", err)
+		}
+	}
+	if customerID.Valid {
+		payment.CustomerID = customerID.UUID
+	}
+	if paymentMethodID.Valid {
+		payment.PaymentMethodID = &paymentMethodID.UUID
+	}
+	payment.Description = description.String
+	payment.IdempotencyKey = idempotencyKey.String
+	payment.ReferenceID = referenceID.String
+	payment.DeclineCode = declineCode.String
+	payment.ClientIP = clientIP.String
+	payment.BillingCountry = billingCountry.String
+	payment.CardIssuingCountry = cardIssuingCountry.String
+
+	return payment, nil
+}
+
+// ResolveReview moves a payment held for fraud review to payment.Status,
+// closes its fraud case, labels the payment and enqueues the messages
+// resolving the review in the same transaction
+func (r *DBRepository) ResolveReview(ctx context.Context, caseID uuid.UUID, payment models.Payment, resolution models.FraudCaseResolution, messages ...outbox.Message) error {
+	metadata, err := json.Marshal(payment.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Only the request that moves the payment out of REVIEW resolves it
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE payments SET status = $2, metadata = $3, updated_at = $4 WHERE id = $1 AND status = $5`,
+		payment.ID,
+		payment.Status,
+		string(metadata),
+		payment.UpdatedAt,
+		models.PaymentStatusReview,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve review: %w", err)
+	}
+	resolved, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to resolve review: %w", err)
+	}
+	if resolved == 0 {
+		return ErrPaymentNotInReview
+	}
+
+	result, err = tx.ExecContext(
+		ctx,
+		`UPDATE fraud_cases SET status = $3, reviewer = $4, note = $5, resolved_at = $6
+        WHERE id = $1 AND payment_id = $2 AND status = $7`,
+		caseID,
+		payment.ID,
+		resolution.Status,
+		resolution.Reviewer,
+		sql.NullString{String: resolution.Note, Valid: resolution.Note != ""},
+		resolution.ResolvedAt,
+		models.FraudCaseStatusOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve fraud case: %w", err)
+	}
+	if resolved, err = result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to resolve fraud case: %w", err)
+	}
+	if resolved == 0 {
+		return ErrPaymentNotInReview
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO fraud_labels (payment_id, label, source, actor, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		payment.ID,
+		resolution.Label,
+		models.LabelSourceReview,
+		resolution.Reviewer,
+		resolution.ResolvedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to label payment: %w", err)
+	}
+
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	return nil
+}
+
+// RecordChargeback moves a captured or settled payment to CHARGEBACK and
+// enqueues the messages announcing it in the same transaction
+func (r *DBRepository) RecordChargeback(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	metadata, err := json.Marshal(payment.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Only the request that moves the payment out of CAPTURED or SETTLED records the chargeback
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE payments SET status = $2, metadata = $3, updated_at = $4 WHERE id = $1 AND status IN ($5, $6)`,
+		payment.ID,
+		models.PaymentStatusChargeback,
+		string(metadata),
+		payment.UpdatedAt,
+		models.PaymentStatusCaptured,
+		models.PaymentStatusSettled,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record chargeback: %w", err)
+	}
+	recorded, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to record chargeback: %w", err)
+	}
+	if recorded == 0 {
+		return ErrPaymentNotCaptured
+	}
+
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chargeback: %w", err)
+	}
+
+	return nil
+}
+
+// RequestRefund records a refund requested on a captured, settled or
+// partially refunded payment and enqueues the refund command in the same transaction
+func (r *DBRepository) RequestRefund(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	metadata, err := json.Marshal(payment.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The payment engine moves the payment to REFUNDED once the refund goes through
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE payments SET metadata = $2, updated_at = $3 WHERE id = $1 AND status IN ($4, $5, $6)`,
+		payment.ID,
+		string(metadata),
+		payment.UpdatedAt,
+		models.PaymentStatusCaptured,
+		models.PaymentStatusSettled,
+		models.PaymentStatusRefunded,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to request refund: %w", err)
+	}
+	requested, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to request refund: %w", err)
+	}
+	if requested == 0 {
+		return ErrPaymentNotCaptured
+	}
+
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refund request: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFraudReport records a merchant's fraud report in a payment's metadata
+// and enqueues the messages announcing it in the same transaction
+func (r *DBRepository) RecordFraudReport(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	metadata, err := json.Marshal(payment.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE payments SET metadata = $2, updated_at = $3 WHERE id = $1`,
+		payment.ID,
+		string(metadata),
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record fraud report: %w", err)
+	}
+	recorded, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to record fraud report: %w", err)
+	}
+	if recorded == 0 {
+		return ErrPaymentNotFound
+	}
+
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fraud report: %w", err)
+	}
+
+	return nil
+}
+
+// listEntryColumns are the columns scanned by scanListEntry
+const listEntryColumns = `id, list, entry_type, value, merchant_id, reason, source, created_by, expires_at, created_at, updated_at`
+
+// AddListEntry adds an entry to a fraud list, or renews the live entry with
+// the same value and scope, and records it in the audit trail
+func (r *DBRepository) AddListEntry(ctx context.Context, entry models.ListEntry) (models.ListEntry, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ListEntry{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO fraud_list_entries (
+            list, entry_type, value, merchant_id, reason, source, created_by, expires_at
+        ) VALUES (
+            $1, $2, $3, $4, $5, $6, $7, $8
+        )
+        ON CONFLICT (list, entry_type, value, (COALESCE(merchant_id, '00000000-0000-0000-0000-000000000000'::uuid)))
+            WHERE removed_at IS NULL
+        DO UPDATE SET reason = COALESCE(EXCLUDED.reason, fraud_list_entries.reason),
+            expires_at = EXCLUDED.expires_at, updated_at = CURRENT_TIMESTAMP
+        RETURNING ` + listEntryColumns
+	stored, err := scanListEntry(tx.QueryRowContext(
+		ctx,
+		query,
+		entry.List,
+		entry.Type,
+		entry.Value,
+		entry.MerchantID,
+		sql.NullString{String: entry.Reason, Valid: entry.Reason != ""},
+		entry.Source,
+		entry.CreatedBy,
+		entry.ExpiresAt,
+	))
+	if err != nil {
+		return models.ListEntry{}, fmt.Errorf("failed to add list entry: %w", err)
+	}
+
+	if err := insertListAudit(ctx, tx, stored.ID, models.ListAuditActionAdded, entry.CreatedBy, entry.Reason, entry.ExpiresAt); err != nil {
+		return models.ListEntry{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ListEntry{}, fmt.Errorf("failed to commit list entry: %w", err)
+	}
+
+	return stored, nil
+}
+
+// RemoveListEntry removes a list entry and records it in the audit trail
+func (r *DBRepository) RemoveListEntry(ctx context.Context, id uuid.UUID, removal models.ListEntryRemoval) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE fraud_list_entries SET removed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND removed_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove list entry: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to remove list entry: %w", err)
+	}
+	if removed == 0 {
+		return ErrListEntryNotFound
+	}
+
+	if err := insertListAudit(ctx, tx, id, models.ListAuditActionRemoved, removal.Actor, removal.Reason, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit list entry removal: %w", err)
+	}
+
+	return nil
+}
+
+// ListEntries returns the live entries of the fraud lists matching a filter, newest first
+func (r *DBRepository) ListEntries(ctx context.Context, filter models.ListEntryFilter) ([]models.ListEntry, error) {
+	conditions := []string{"removed_at IS NULL", "(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)"}
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.List != "" {
+		where("list = $%d", filter.List)
+	}
+	if filter.Type != "" {
+		where("entry_type = $%d", filter.Type)
+	}
+	if filter.Value != "" {
+		where("value = $%d", filter.Value)
+	}
+	if filter.Global {
+		conditions = append(conditions, "merchant_id IS NULL")
+	} else if filter.MerchantID != "" {
+		where("merchant_id = $%d", filter.MerchantID)
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	query := `SELECT ` + listEntryColumns + ` FROM fraud_list_entries WHERE ` +
+		strings.Join(conditions, " AND ") + fmt.Sprintf(` ORDER BY created_at DESC LIMIT $%d`, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.ListEntry{}
+	for rows.Next() {
+		entry, err := scanListEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan list entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	return entries, nil
+}
+
+// ListEntryAudit returns the audit trail of a list entry, oldest first
+func (r *DBRepository) ListEntryAudit(ctx context.Context, id uuid.UUID) ([]models.ListAuditRecord, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM fraud_list_entries WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list entry: %w", err)
+	}
+	if !exists {
+		return nil, ErrListEntryNotFound
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, entry_id, action, actor, reason, expires_at, created_at FROM fraud_list_audit WHERE entry_id = $1 ORDER BY created_at`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list entry audit: %w", err)
+	}
+	defer rows.Close()
+
+	records := []models.ListAuditRecord{}
+	for rows.Next() {
+		var (
+			record    models.ListAuditRecord
+			reason    sql.NullString
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&record.ID, &record.EntryID, &record.Action, &record.Actor, &reason, &expiresAt, &record.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan list entry audit: %w", err)
+		}
+		record.Reason = reason.String
+		if expiresAt.Valid {
+			record.ExpiresAt = &expiresAt.Time
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get list entry audit: %w", err)
+	}
+	return records, nil
+}
+
+// fraudCaseColumns are the columns scanned by scanFraudCase, from fraud_cases
+// joined with fraud_checks
+const fraudCaseColumns = `fc.id, fc.payment_id, fc.merchant_id, fc.status, ch.risk_score, ch.reason, ch.reason_codes,
+    fc.reviewer, fc.note, fc.created_at, fc.resolved_at`
+
+// ListFraudCases returns the fraud cases matching a filter, oldest first
+func (r *DBRepository) ListFraudCases(ctx context.Context, filter models.FraudCaseFilter) ([]models.FraudCase, error) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Status != "" {
+		where("fc.status = $%d", filter.Status)
+	}
+	if filter.MerchantID != "" {
+		where("fc.merchant_id = $%d", filter.MerchantID)
+	}
+	if filter.PaymentID != "" {
+		where("fc.payment_id = $%d", filter.PaymentID)
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = 100
+	}
+	args = append(args, limit, filter.Offset)
+
+	query := `SELECT ` + fraudCaseColumns + `
+        FROM fraud_cases fc JOIN fraud_checks ch ON ch.id = fc.fraud_check_id
+        WHERE ` + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(` ORDER BY fc.created_at LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fraud cases: %w", err)
+	}
+	defer rows.Close()
+
+	cases := []models.FraudCase{}
+	for rows.Next() {
+		fraudCase, err := scanFraudCase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fraud case: %w", err)
+		}
+		cases = append(cases, fraudCase)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list fraud cases: %w", err)
+	}
+	return cases, nil
+}
+
+// GetFraudCase returns a fraud case with its fraud check
+func (r *DBRepository) GetFraudCase(ctx context.Context, id uuid.UUID) (models.FraudCaseDetail, error) {
+	var (
+		detail              models.FraudCaseDetail
+		checks, modelScores []byte
+	)
+	query := `SELECT ` + fraudCaseColumns + `, ch.id, ch.decision, ch.checks, ch.model_scores, ch.created_at
+        FROM fraud_cases fc JOIN fraud_checks ch ON ch.id = fc.fraud_check_id
+        WHERE fc.id = $1`
+	fraudCase, err := scanFraudCase(r.db.QueryRowContext(ctx, query, id),
+		&detail.Check.ID, &detail.Check.Decision, &checks, &modelScores, &detail.Check.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.FraudCaseDetail{}, ErrFraudCaseNotFound
+	}
+	if err != nil {
+		return models.FraudCaseDetail{}, fmt.Errorf("failed to get fraud case: %w", err)
+	}
+	if err := json.Unmarshal(checks, &detail.Check.Checks); err != nil {
+		return models.FraudCaseDetail{}, fmt.Errorf("failed to unmarshal fraud check items: %w", err)
+	}
+	if err := json.Unmarshal(modelScores, &detail.Check.ModelScores); err != nil {
+		return models.FraudCaseDetail{}, fmt.Errorf("failed to unmarshal model scores: %w", err)
+	}
+
+	detail.FraudCase = fraudCase
+	detail.Check.PaymentID = fraudCase.PaymentID
+	detail.Check.RiskScore = fraudCase.RiskScore
+	detail.Check.Reason = fraudCase.Reason
+	detail.Check.ReasonCodes = fraudCase.ReasonCodes
+	return detail, nil
+}
+
+// TrainingExamples returns the labeled fraud checks matching a filter, oldest label first
+func (r *DBRepository) TrainingExamples(ctx context.Context, filter models.TrainingDataFilter) ([]models.TrainingExample, error) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.MerchantID != "" {
+		where("merchant_id = $%d", filter.MerchantID)
+	}
+	if !filter.Since.IsZero() {
+		where("labeled_at >= $%d", filter.Since)
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = 1000
+	}
+	args = append(args, limit, filter.Offset)
+
+	query := `SELECT payment_id, merchant_id, features, risk_score, decision, label, source, labeled_at
+        FROM fraud_training_examples
+        WHERE ` + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(` ORDER BY labeled_at, payment_id LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get training examples: %w", err)
+	}
+	defer rows.Close()
+
+	examples := []models.TrainingExample{}
+	for rows.Next() {
+		var (
+			example  models.TrainingExample
+			features []byte
+		)
+		err := rows.Scan(&example.PaymentID, &example.MerchantID, &features, &example.RiskScore, &example.Decision, &example.Label, &example.Source, &example.LabeledAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan training example: %w", err)
+		}
+		if err := json.Unmarshal(features, &example.Features); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal features: %w", err)
+		}
+		examples = append(examples, example)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get training examples: %w", err)
+	}
+	return examples, nil
+}
+
+// MerchantFraudRates returns the fraud rates of merchants over the last 90 days
+func (r *DBRepository) MerchantFraudRates(ctx context.Context, merchantID string) ([]models.MerchantFraudRate, error) {
+	query := `SELECT merchant_id, payments, fraud_payments, fraud_rate FROM merchant_fraud_rates`
+	var args []interface{}
+	if merchantID != "" {
+		query += ` WHERE merchant_id = $1`
+		args = append(args, merchantID)
+	}
+	query += ` ORDER BY fraud_rate DESC, merchant_id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant fraud rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []models.MerchantFraudRate{}
+	for rows.Next() {
+		var rate models.MerchantFraudRate
+		if err := rows.Scan(&rate.MerchantID, &rate.Payments, &rate.FraudPayments, &rate.FraudRate); err != nil {
+			return nil, fmt.Errorf("failed to scan merchant fraud rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get merchant fraud rates: %w", err)
+	}
+	return rates, nil
+}
+
+// LabeledChecks returns the labeled fraud checks of the payments screened in
+// a filter's time range
+func (r *DBRepository) LabeledChecks(ctx context.Context, filter models.DecisionReportFilter) ([]models.LabeledCheck, error) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.MerchantID != "" {
+		where("c.merchant_id = $%d", filter.MerchantID)
+	}
+	if !filter.Since.IsZero() {
+		where("c.created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("c.created_at < $%d", filter.Until)
+	}
+
+	query := `SELECT c.risk_score, c.decision, c.checks, t.label
+        FROM fraud_training_examples t
+        JOIN fraud_checks c ON c.payment_id = t.payment_id
+        WHERE ` + strings.Join(conditions, " AND ")
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labeled checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []models.LabeledCheck{}
+	for rows.Next() {
+		var (
+			check models.LabeledCheck
+			items []byte
+		)
+		if err := rows.Scan(&check.RiskScore, &check.Decision, &items, &check.Label); err != nil {
+			return nil, fmt.Errorf("failed to scan labeled check: %w", err)
+		}
+		var fired []models.FraudCheckItem
+		if err := json.Unmarshal(items, &fired); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checks: %w", err)
+		}
+		for _, item := range fired {
+			for _, rule := range item.Rules {
+				check.Rules = append(check.Rules, item.Type+"."+rule)
+			}
+		}
+		checks = append(checks, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get labeled checks: %w", err)
+	}
+	return checks, nil
+}
+
+// scanner is a row that can be scanned, either *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanListEntry scans the listEntryColumns of a row
+func scanListEntry(row scanner) (models.ListEntry, error) {
+	var (
+		entry      models.ListEntry
+		merchantID uuid.NullUUID
+		reason     sql.NullString
+		expiresAt  sql.NullTime
+	)
+	err := row.Scan(
+		&entry.ID,
+		&entry.List,
+		&entry.Type,
+		&entry.Value,
+		&merchantID,
+		&reason,
+		&entry.Source,
+		&entry.CreatedBy,
+		&expiresAt,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+	if err != nil {
+		return models.ListEntry{}, err
+	}
+	if merchantID.Valid {
+		entry.MerchantID = &merchantID.UUID
+	}
+	entry.Reason = reason.String
+	if expiresAt.Valid {
+		entry.ExpiresAt = &expiresAt.Time
+	}
+	return entry, nil
+}
+
+// scanFraudCase scans the fraudCaseColumns of a row, followed by any extra
+// columns into extra
+func scanFraudCase(row scanner, extra ...interface{}) (models.FraudCase, error) {
+	var (
+		fraudCase        models.FraudCase
+		reason, reviewer sql.NullString
+		note             sql.NullString
+		reasonCodes      pq.StringArray
+		resolvedAt       sql.NullTime
+	)
+	dest := []interface{}{
+		&fraudCase.ID,
+		&fraudCase.PaymentID,
+		&fraudCase.MerchantID,
+		&fraudCase.Status,
+		&fraudCase.RiskScore,
+		&reason,
+		&reasonCodes,
+		&reviewer,
+		&note,
+		&fraudCase.CreatedAt,
+		&resolvedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.FraudCase{}, err
+	}
+	fraudCase.Reason = reason.String
+	fraudCase.ReasonCodes = reasonCodes
+	fraudCase.Reviewer = reviewer.String
+	fraudCase.Note = note.String
+	if resolvedAt.Valid {
+		fraudCase.ResolvedAt = &resolvedAt.Time
+	}
+	return fraudCase, nil
+}
+
+// insertListAudit records a change to a list entry
+func insertListAudit(ctx context.Context, tx *sql.Tx, entryID uuid.UUID, action models.ListAuditAction, actor, reason string, expiresAt *time.Time) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO fraud_list_audit (entry_id, action, actor, reason, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		entryID,
+		action,
+		actor,
+		sql.NullString{String: reason, Valid: reason != ""},
+		expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record list entry audit: %w", err)
+	}
+	return nil
+}
+
+// nullableUUID maps the zero UUID to NULL
+func nullableUUID(id uuid.UUID) interface{} {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id
+}
+
+// SettlementItems returns the payments a settlement paid out, oldest payment
+// first, or ErrSettlementNotFound
+func (r *DBRepository) SettlementItems(ctx context.Context, settlementID uuid.UUID) ([]models.SettlementItem, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM settlements WHERE id = $1)`, settlementID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settlement: %w", err)
+	}
+	if !exists {
+		return nil, ErrSettlementNotFound
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT si.id, si.settlement_id, si.payment_id, si.amount, si.currency, si.payment_method,
+            si.pricing_plan, si.fee_percent, si.fixed_fee, si.fee_amount,
+            si.tax_jurisdiction, si.tax_percent, si.tax_amount, si.net_amount, si.created_at
+        FROM settlement_items si
+        JOIN payments p ON p.id = si.payment_id
+        WHERE si.settlement_id = $1
+        ORDER BY p.created_at, si.id`,
+		settlementID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settlement items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.SettlementItem{}
+	for rows.Next() {
+		var (
+			item            models.SettlementItem
+			paymentMethod   sql.NullString
+			pricingPlan     sql.NullString
+			taxJurisdiction sql.NullString
+		)
+		err := rows.Scan(&item.ID, &item.SettlementID, &item.PaymentID, &item.Amount, &item.Currency, &paymentMethod,
+			&pricingPlan, &item.FeePercent, &item.FixedFee, &item.FeeAmount,
+			&taxJurisdiction, &item.TaxPercent, &item.TaxAmount, &item.NetAmount, &item.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan settlement item: %w", err)
+		}
+		item.PaymentMethod = paymentMethod.String
+		item.PricingPlan = pricingPlan.String
+		item.TaxJurisdiction = taxJurisdiction.String
+		// Amount columns have three decimals; show the currency's
+		item.Amount = item.Amount.RoundTo(item.Currency, money.RoundHalfEven)
+		item.FixedFee = item.FixedFee.RoundTo(item.Currency, money.RoundHalfEven)
+		item.FeeAmount = item.FeeAmount.RoundTo(item.Currency, money.RoundHalfEven)
+		item.TaxAmount = item.TaxAmount.RoundTo(item.Currency, money.RoundHalfEven)
+		item.NetAmount = item.NetAmount.RoundTo(item.Currency, money.RoundHalfEven)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get settlement items: %w", err)
+	}
+	return items, nil
+}
+
+// foreignKeyViolation is the PostgreSQL error code of a foreign key violation
+const foreignKeyViolation = "23503"
+
+// bankAccountColumns are the columns of merchant_bank_accounts scanned by
+// scanBankAccount
+const bankAccountColumns = `id, merchant_id, holder_name, scheme, account_number_encrypted,
+            account_number_last4, ifsc, bic, status, verification_reference, registered_name,
+            failure_reason, verified_at, replaces_account_id, replaced_at, created_at, updated_at`
+
+// CreateBankAccount registers a merchant's bank account, pending verification
+func (r *DBRepository) CreateBankAccount(ctx context.Context, account models.BankAccount) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO merchant_bank_accounts (
+            id, merchant_id, holder_name, scheme, account_number_encrypted,
+            account_number_last4, ifsc, bic, status, created_at, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		account.ID,
+		account.MerchantID,
+		account.HolderName,
+		account.Scheme,
+		account.AccountNumberEncrypted,
+		account.AccountNumberLast4,
+		sql.NullString{String: account.IFSC, Valid: account.IFSC != ""},
+		sql.NullString{String: account.BIC, Valid: account.BIC != ""},
+		account.Status,
+		account.CreatedAt,
+		account.UpdatedAt,
+	)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return ErrMerchantNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create bank account: %w", err)
+	}
+	return nil
+}
+
+// BankAccounts returns a merchant's bank accounts, newest first
+func (r *DBRepository) BankAccounts(ctx context.Context, merchantID uuid.UUID) ([]models.BankAccount, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+bankAccountColumns+`
+        FROM merchant_bank_accounts
+        WHERE merchant_id = $1
+        ORDER BY created_at DESC, id`,
+		merchantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bank accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.BankAccount{}
+	for rows.Next() {
+		account, err := scanBankAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get bank accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetBankAccount returns a merchant's bank account
+func (r *DBRepository) GetBankAccount(ctx context.Context, merchantID, id uuid.UUID) (models.BankAccount, error) {
+	account, err := scanBankAccount(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+bankAccountColumns+` FROM merchant_bank_accounts WHERE id = $1 AND merchant_id = $2`,
+		id,
+		merchantID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.BankAccount{}, ErrBankAccountNotFound
+	}
+	return account, err
+}
+
+// RecordBankAccountVerification records the penny drop of a pending bank
+// account. A verified account replaces the merchant's payout account.
+func (r *DBRepository) RecordBankAccountVerification(ctx context.Context, id uuid.UUID, verification models.BankAccountVerification) (models.BankAccount, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.BankAccount{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the account, so it is verified once
+	var merchantID uuid.UUID
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT merchant_id FROM merchant_bank_accounts WHERE id = $1 AND status = $2 FOR UPDATE`,
+		id,
+		models.BankAccountStatusPending,
+	).Scan(&merchantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.BankAccount{}, ErrBankAccountNotPending
+	}
+	if err != nil {
+		return models.BankAccount{}, fmt.Errorf("failed to get bank account: %w", err)
+	}
+
+	status := models.BankAccountStatusFailed
+	var (
+		verifiedAt sql.NullTime
+		replaces   uuid.NullUUID
+	)
+	if verification.Verified {
+		status = models.BankAccountStatusVerified
+		verifiedAt = sql.NullTime{Time: verification.CompletedAt, Valid: true}
+		// Replace the merchant's payout account, if it has one
+		err = tx.QueryRowContext(
+			ctx,
+			`UPDATE merchant_bank_accounts SET status = $1, replaced_at = $2, updated_at = $2
+            WHERE merchant_id = $3 AND status = $4
+            RETURNING id`,
+			models.BankAccountStatusReplaced,
+			verification.CompletedAt,
+			merchantID,
+			models.BankAccountStatusVerified,
+		).Scan(&replaces)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return models.BankAccount{}, fmt.Errorf("failed to replace payout account: %w", err)
+		}
+	}
+
+	account, err := scanBankAccount(tx.QueryRowContext(
+		ctx,
+		`UPDATE merchant_bank_accounts SET status = $1, verification_reference = $2,
+            registered_name = $3, failure_reason = $4, verified_at = $5,
+            replaces_account_id = $6, updated_at = $7
+        WHERE id = $8
+        RETURNING `+bankAccountColumns,
+		status,
+		sql.NullString{String: verification.Reference, Valid: verification.Reference != ""},
+		sql.NullString{String: verification.RegisteredName, Valid: verification.RegisteredName != ""},
+		sql.NullString{String: verification.Reason, Valid: verification.Reason != ""},
+		verifiedAt,
+		replaces,
+		verification.CompletedAt,
+		id,
+	))
+	if err != nil {
+		return models.BankAccount{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.BankAccount{}, fmt.Errorf("failed to commit bank account verification: %w", err)
+	}
+	return account, nil
+}
+
+// scanBankAccount scans the bankAccountColumns of a row
+func scanBankAccount(row scanner) (models.BankAccount, error) {
+	var (
+		account               models.BankAccount
+		ifsc                  sql.NullString
+		bic                   sql.NullString
+		verificationReference sql.NullString
+		registeredName        sql.NullString
+		failureReason         sql.NullString
+		verifiedAt            sql.NullTime
+		replacesAccountID     uuid.NullUUID
+		replacedAt            sql.NullTime
+	)
+	err := row.Scan(&account.ID, &account.MerchantID, &account.HolderName, &account.Scheme,
+		&account.AccountNumberEncrypted, &account.AccountNumberLast4, &ifsc, &bic, &account.Status,
+		&verificationReference, &registeredName, &failureReason, &verifiedAt,
+		&replacesAccountID, &replacedAt, &account.CreatedAt, &account.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.BankAccount{}, err
+	}
+	if err != nil {
+		return models.BankAccount{}, fmt.Errorf("failed to scan bank account: %w", err)
+	}
+	account.IFSC = ifsc.String
+	account.BIC = bic.String
+	account.VerificationReference = verificationReference.String
+	account.RegisteredName = registeredName.String
+	account.FailureReason = failureReason.String
+	if verifiedAt.Valid {
+		account.VerifiedAt = &verifiedAt.Time
+	}
+	if replacesAccountID.Valid {
+		account.ReplacesAccountID = &replacesAccountID.UUID
+	}
+	if replacedAt.Valid {
+		account.ReplacedAt = &replacedAt.Time
+	}
+	return account, nil
+}
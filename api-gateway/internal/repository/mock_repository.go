@@ -0,0 +1,458 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/api-gateway/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	mutex    sync.Mutex
+	payments map[uuid.UUID]models.Payment
+	entries  map[uuid.UUID]*mockListEntry
+	audit    []models.ListAuditRecord
+	cases    map[uuid.UUID]*models.FraudCaseDetail
+	examples []models.TrainingExample
+	accounts map[uuid.UUID]models.BankAccount
+	outbox   *outbox.MemoryStore
+}
+
+// mockListEntry is a list entry and when it was removed
+type mockListEntry struct {
+	entry   models.ListEntry
+	removed bool
+}
+
+// NewMockRepository creates a new mock repository for demonstration
+func NewMockRepository() Repository {
+	log.Println("Using mock repository for database operations")
+	return &MockRepository{
+		payments: make(map[uuid.UUID]models.Payment),
+		entries:  make(map[uuid.UUID]*mockListEntry),
+		cases:    make(map[uuid.UUID]*models.FraudCaseDetail),
+		accounts: make(map[uuid.UUID]models.BankAccount),
+		outbox:   outbox.NewMemoryStore(),
+	}
+}
+
+// CreatePayment mocks storing a payment and enqueues its messages in memory
+func (r *MockRepository) CreatePayment(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	log.Printf("[MOCK] Created payment %s for merchant %s", payment.ID, payment.MerchantID)
+	r.payments[payment.ID] = payment
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// GetPayment returns a payment created through this repository. Status
+// changes made by the payment engine are not visible in mock mode.
+func (r *MockRepository) GetPayment(ctx context.Context, id uuid.UUID) (models.Payment, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	payment, ok := r.payments[id]
+	if !ok {
+		return models.Payment{}, ErrPaymentNotFound
+	}
+	return payment, nil
+}
+
+// ResolveReview mocks resolving a review, closes its case and labels the
+// payment in memory, and enqueues its messages in memory
+func (r *MockRepository) ResolveReview(ctx context.Context, caseID uuid.UUID, payment models.Payment, resolution models.FraudCaseResolution, messages ...outbox.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if stored, ok := r.payments[payment.ID]; !ok || stored.Status != models.PaymentStatusReview {
+		return ErrPaymentNotInReview
+	}
+	fraudCase, ok := r.cases[caseID]
+	if !ok || fraudCase.PaymentID != payment.ID || fraudCase.Status != models.FraudCaseStatusOpen {
+		return ErrPaymentNotInReview
+	}
+	log.Printf("[MOCK] Resolved review of payment %s as %s", payment.ID, payment.Status)
+	r.payments[payment.ID] = payment
+	fraudCase.Status = resolution.Status
+	fraudCase.Reviewer = resolution.Reviewer
+	fraudCase.Note = resolution.Note
+	resolvedAt := resolution.ResolvedAt
+	fraudCase.ResolvedAt = &resolvedAt
+	r.examples = append(r.examples, models.TrainingExample{
+		PaymentID:  payment.ID,
+		MerchantID: payment.MerchantID,
+		Features:   map[string]float64{},
+		RiskScore:  fraudCase.Check.RiskScore,
+		Decision:   fraudCase.Check.Decision,
+		Label:      resolution.Label,
+		Source:     models.LabelSourceReview,
+		LabeledAt:  resolution.ResolvedAt,
+	})
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// RecordChargeback mocks recording a chargeback and enqueues its messages in memory
+func (r *MockRepository) RecordChargeback(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ok := r.payments[payment.ID]
+	if !ok || stored.Status != models.PaymentStatusCaptured && stored.Status != models.PaymentStatusSettled {
+		return ErrPaymentNotCaptured
+	}
+	log.Printf("[MOCK] Recorded chargeback of payment %s", payment.ID)
+	payment.Status = models.PaymentStatusChargeback
+	r.payments[payment.ID] = payment
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// RequestRefund mocks requesting a refund and enqueues its messages in memory
+func (r *MockRepository) RequestRefund(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ok := r.payments[payment.ID]
+	if !ok || stored.Status != models.PaymentStatusCaptured && stored.Status != models.PaymentStatusSettled &&
+		stored.Status != models.PaymentStatusRefunded {
+		return ErrPaymentNotCaptured
+	}
+	log.Printf("[MOCK] Requested refund of payment %s", payment.ID)
+	r.payments[payment.ID] = payment
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// RecordFraudReport mocks recording a fraud report and enqueues its messages in memory
+func (r *MockRepository) RecordFraudReport(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.payments[payment.ID]; !ok {
+		return ErrPaymentNotFound
+	}
+	log.Printf("[MOCK] Recorded fraud report on payment %s", payment.ID)
+	r.payments[payment.ID] = payment
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// AddListEntry adds or renews a list entry in memory
+func (r *MockRepository) AddListEntry(ctx context.Context, entry models.ListEntry) (models.ListEntry, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	stored := r.findListEntry(entry)
+	if stored == nil {
+		entry.ID = uuid.New()
+		entry.CreatedAt = now
+		stored = &mockListEntry{entry: entry}
+		r.entries[entry.ID] = stored
+	} else if entry.Reason != "" {
+		stored.entry.Reason = entry.Reason
+	}
+	stored.entry.ExpiresAt = entry.ExpiresAt
+	stored.entry.UpdatedAt = now
+	r.addAudit(stored.entry.ID, models.ListAuditActionAdded, entry.CreatedBy, entry.Reason, entry.ExpiresAt)
+	return stored.entry, nil
+}
+
+// RemoveListEntry removes a list entry in memory
+func (r *MockRepository) RemoveListEntry(ctx context.Context, id uuid.UUID, removal models.ListEntryRemoval) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ok := r.entries[id]
+	if !ok || stored.removed {
+		return ErrListEntryNotFound
+	}
+	stored.removed = true
+	stored.entry.UpdatedAt = time.Now()
+	r.addAudit(id, models.ListAuditActionRemoved, removal.Actor, removal.Reason, nil)
+	return nil
+}
+
+// ListEntries returns the live list entries in memory matching a filter, newest first
+func (r *MockRepository) ListEntries(ctx context.Context, filter models.ListEntryFilter) ([]models.ListEntry, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	entries := []models.ListEntry{}
+	for _, stored := range r.entries {
+		entry := stored.entry
+		switch {
+		case stored.removed, entry.ExpiresAt != nil && !entry.ExpiresAt.After(now):
+		case filter.List != "" && entry.List != filter.List:
+		case filter.Type != "" && entry.Type != filter.Type:
+		case filter.Value != "" && entry.Value != filter.Value:
+		case filter.Global && entry.MerchantID != nil:
+		case !filter.Global && filter.MerchantID != "" && (entry.MerchantID == nil || entry.MerchantID.String() != filter.MerchantID):
+		default:
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	limit := filter.Limit
+	if limit == 0 {
+		limit = 100
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// ListEntryAudit returns the audit trail of a list entry in memory
+func (r *MockRepository) ListEntryAudit(ctx context.Context, id uuid.UUID) ([]models.ListAuditRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.entries[id]; !ok {
+		return nil, ErrListEntryNotFound
+	}
+	records := []models.ListAuditRecord{}
+	for _, record := range r.audit {
+		if record.EntryID == id {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// ListFraudCases returns the fraud cases in memory matching a filter, oldest
+// first. Cases are opened by fraud detection, so there are none in memory.
+func (r *MockRepository) ListFraudCases(ctx context.Context, filter models.FraudCaseFilter) ([]models.FraudCase, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cases := []models.FraudCase{}
+	for _, detail := range r.cases {
+		fraudCase := detail.FraudCase
+		switch {
+		case filter.Status != "" && fraudCase.Status != filter.Status:
+		case filter.MerchantID != "" && fraudCase.MerchantID.String() != filter.MerchantID:
+		case filter.PaymentID != "" && fraudCase.PaymentID.String() != filter.PaymentID:
+		default:
+			cases = append(cases, fraudCase)
+		}
+	}
+	sort.Slice(cases, func(i, j int) bool {
+		return cases[i].CreatedAt.Before(cases[j].CreatedAt)
+	})
+	start, end := pageBounds(len(cases), filter.Offset, filter.Limit, 100)
+	return cases[start:end], nil
+}
+
+// GetFraudCase returns a fraud case in memory
+func (r *MockRepository) GetFraudCase(ctx context.Context, id uuid.UUID) (models.FraudCaseDetail, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	detail, ok := r.cases[id]
+	if !ok {
+		return models.FraudCaseDetail{}, ErrFraudCaseNotFound
+	}
+	return *detail, nil
+}
+
+// TrainingExamples returns the payments labeled in memory, with the latest
+// label of each payment, oldest label first
+func (r *MockRepository) TrainingExamples(ctx context.Context, filter models.TrainingDataFilter) ([]models.TrainingExample, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	latest := make(map[uuid.UUID]int)
+	for i, example := range r.examples {
+		latest[example.PaymentID] = i
+	}
+	examples := []models.TrainingExample{}
+	for i, example := range r.examples {
+		switch {
+		case latest[example.PaymentID] != i:
+		case filter.MerchantID != "" && example.MerchantID.String() != filter.MerchantID:
+		case example.LabeledAt.Before(filter.Since):
+		default:
+			examples = append(examples, example)
+		}
+	}
+	start, end := pageBounds(len(examples), filter.Offset, filter.Limit, 1000)
+	return examples[start:end], nil
+}
+
+// MerchantFraudRates returns no rates: fraud checks are recorded by fraud
+// detection and are not visible in mock mode
+func (r *MockRepository) MerchantFraudRates(ctx context.Context, merchantID string) ([]models.MerchantFraudRate, error) {
+	return []models.MerchantFraudRate{}, nil
+}
+
+// LabeledChecks returns the fraud checks of the cases reviewed in memory, with
+// the latest label of each payment
+func (r *MockRepository) LabeledChecks(ctx context.Context, filter models.DecisionReportFilter) ([]models.LabeledCheck, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	labels := make(map[uuid.UUID]models.FraudLabel)
+	for _, example := range r.examples {
+		labels[example.PaymentID] = example.Label
+	}
+	checks := []models.LabeledCheck{}
+	for _, detail := range r.cases {
+		label, ok := labels[detail.PaymentID]
+		switch {
+		case !ok:
+		case filter.MerchantID != "" && detail.MerchantID.String() != filter.MerchantID:
+		case detail.Check.CreatedAt.Before(filter.Since):
+		case !filter.Until.IsZero() && !detail.Check.CreatedAt.Before(filter.Until):
+		default:
+			check := models.LabeledCheck{
+				RiskScore: detail.Check.RiskScore,
+				Decision:  detail.Check.Decision,
+				Label:     label,
+			}
+			for _, item := range detail.Check.Checks {
+				for _, rule := range item.Rules {
+					check.Rules = append(check.Rules, item.Type+"."+rule)
+				}
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// pageBounds returns the bounds of the page of n items starting at offset, of
+// at most limit items, or defaultLimit when limit is zero
+func pageBounds(n, offset, limit, defaultLimit int) (int, int) {
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if end > n {
+		end = n
+	}
+	return offset, end
+}
+
+// findListEntry returns the live entry with the value and scope of entry
+func (r *MockRepository) findListEntry(entry models.ListEntry) *mockListEntry {
+	for _, stored := range r.entries {
+		e := stored.entry
+		sameScope := e.MerchantID == nil && entry.MerchantID == nil ||
+			e.MerchantID != nil && entry.MerchantID != nil && *e.MerchantID == *entry.MerchantID
+		if !stored.removed && sameScope && e.List == entry.List && e.Type == entry.Type && e.Value == entry.Value {
+			return stored
+		}
+	}
+	return nil
+}
+
+// addAudit records a change to a list entry in memory
+func (r *MockRepository) addAudit(entryID uuid.UUID, action models.ListAuditAction, actor, reason string, expiresAt *time.Time) {
+	r.audit = append(r.audit, models.ListAuditRecord{
+		ID:        uuid.New(),
+		EntryID:   entryID,
+		Action:    action,
+		Actor:     actor,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Outbox returns the in-memory outbox store
+func (r *MockRepository) Outbox() outbox.Store {
+	return r.outbox
+}
+
+// SettlementItems returns ErrSettlementNotFound, since settlements are
+// created by the settlement engine and not visible in mock mode
+func (r *MockRepository) SettlementItems(ctx context.Context, settlementID uuid.UUID) ([]models.SettlementItem, error) {
+	return nil, ErrSettlementNotFound
+}
+
+// CreateBankAccount mocks registering a bank account in memory
+func (r *MockRepository) CreateBankAccount(ctx context.Context, account models.BankAccount) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	log.Printf("[MOCK] Registered bank account %s for merchant %s", account.ID, account.MerchantID)
+	r.accounts[account.ID] = account
+	return nil
+}
+
+// BankAccounts returns a merchant's bank accounts in memory, newest first
+func (r *MockRepository) BankAccounts(ctx context.Context, merchantID uuid.UUID) ([]models.BankAccount, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	accounts := []models.BankAccount{}
+	for _, account := range r.accounts {
+		if account.MerchantID == merchantID {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].CreatedAt.After(accounts[j].CreatedAt) })
+	return accounts, nil
+}
+
+// GetBankAccount returns a merchant's bank account in memory
+func (r *MockRepository) GetBankAccount(ctx context.Context, merchantID, id uuid.UUID) (models.BankAccount, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok || account.MerchantID != merchantID {
+		return models.BankAccount{}, ErrBankAccountNotFound
+	}
+	return account, nil
+}
+
+// RecordBankAccountVerification mocks recording a penny drop in memory
+func (r *MockRepository) RecordBankAccountVerification(ctx context.Context, id uuid.UUID, verification models.BankAccountVerification) (models.BankAccount, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok || account.Status != models.BankAccountStatusPending {
+		return models.BankAccount{}, ErrBankAccountNotPending
+	}
+	at := verification.CompletedAt
+	account.Status = models.BankAccountStatusFailed
+	account.VerificationReference = verification.Reference
+	account.RegisteredName = verification.RegisteredName
+	account.FailureReason = verification.Reason
+	account.UpdatedAt = at
+	if verification.Verified {
+		for _, payout := range r.accounts {
+			if payout.MerchantID == account.MerchantID && payout.Status == models.BankAccountStatusVerified {
+				payout.Status = models.BankAccountStatusReplaced
+				payout.ReplacedAt = &at
+				payout.UpdatedAt = at
+				r.accounts[payout.ID] = payout
+				replaced := payout.ID
+				account.ReplacesAccountID = &replaced
+			}
+		}
+		account.Status = models.BankAccountStatusVerified
+		account.VerifiedAt = &at
+	}
+	r.accounts[id] = account
+	log.Printf("[MOCK] Bank account %s of merchant %s %s", id, account.MerchantID, account.Status)
+	return account, nil
+}
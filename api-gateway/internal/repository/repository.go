@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/api-gateway/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// Repository errors
+var (
+	ErrPaymentNotFound    = errors.New("payment not found")
+	ErrPaymentNotInReview = errors.New("payment is not held for review")
+	ErrPaymentNotCaptured = errors.New("payment has not been captured")
+	ErrListEntryNotFound  = errors.New("list entry not found")
+	ErrFraudCaseNotFound  = errors.New("fraud case not found")
+	ErrSettlementNotFound = errors.New("settlement not found")
+	ErrMerchantNotFound   = errors.New("merchant not found")
+
+	ErrBankAccountNotFound   = errors.New("bank account not found")
+	ErrBankAccountNotPending = errors.New("bank account is not pending verification")
+)
+
+// Repository defines the interface for database operations
+type Repository interface {
+	// CreatePayment stores a new payment and enqueues its events in the same transaction
+	CreatePayment(ctx context.Context, payment models.Payment, messages ...outbox.Message) error
+
+	// GetPayment returns a payment by ID, or ErrPaymentNotFound
+	GetPayment(ctx context.Context, id uuid.UUID) (models.Payment, error)
+
+	// ResolveReview moves a payment held for fraud review to payment.Status,
+	// closes its fraud case, labels the payment and enqueues the messages
+	// resolving the review in the same transaction. It returns
+	// ErrPaymentNotInReview if the payment is no longer held or the case is
+	// no longer open, so each review is resolved only once.
+	ResolveReview(ctx context.Context, caseID uuid.UUID, payment models.Payment, resolution models.FraudCaseResolution, messages ...outbox.Message) error
+
+	// RecordChargeback moves a captured or settled payment to CHARGEBACK and
+	// enqueues the messages announcing it in the same transaction. It returns
+	// ErrPaymentNotCaptured if the payment was never captured or is already
+	// charged back.
+	RecordChargeback(ctx context.Context, payment models.Payment, messages ...outbox.Message) error
+
+	// RequestRefund records a refund requested on a captured, settled or
+	// partially refunded payment in its metadata and enqueues the refund
+	// command in the same transaction. It returns ErrPaymentNotCaptured if
+	// the payment was never captured or has since been charged back.
+	RequestRefund(ctx context.Context, payment models.Payment, messages ...outbox.Message) error
+
+	// RecordFraudReport records a merchant's fraud report in a payment's
+	// metadata and enqueues the messages announcing it in the same
+	// transaction, or returns ErrPaymentNotFound
+	RecordFraudReport(ctx context.Context, payment models.Payment, messages ...outbox.Message) error
+
+	// AddListEntry adds an entry to a fraud list and records it in the audit
+	// trail. Adding an entry that is already listed in the same scope renews
+	// it; the stored entry is returned.
+	AddListEntry(ctx context.Context, entry models.ListEntry) (models.ListEntry, error)
+
+	// RemoveListEntry removes a list entry and records it in the audit trail,
+	// or returns ErrListEntryNotFound
+	RemoveListEntry(ctx context.Context, id uuid.UUID, removal models.ListEntryRemoval) error
+
+	// ListEntries returns the entries of the fraud lists that have not been
+	// removed or expired, newest first
+	ListEntries(ctx context.Context, filter models.ListEntryFilter) ([]models.ListEntry, error)
+
+	// ListEntryAudit returns the audit trail of a list entry, oldest first, or
+	// ErrListEntryNotFound
+	ListEntryAudit(ctx context.Context, id uuid.UUID) ([]models.ListAuditRecord, error)
+
+	// ListFraudCases returns the fraud cases matching a filter, oldest first,
+	// so open cases come in the order they should be reviewed
+	ListFraudCases(ctx context.Context, filter models.FraudCaseFilter) ([]models.FraudCase, error)
+
+	// GetFraudCase returns a fraud case with its fraud check, or
+	// ErrFraudCaseNotFound
+	GetFraudCase(ctx context.Context, id uuid.UUID) (models.FraudCaseDetail, error)
+
+	// TrainingExamples returns the labeled fraud checks matching a filter,
+	// with the latest label of each payment, oldest label first
+	TrainingExamples(ctx context.Context, filter models.TrainingDataFilter) ([]models.TrainingExample, error)
+
+	// MerchantFraudRates returns the fraud rates of merchants over the last
+	// 90 days, of one merchant if merchantID is set
+	MerchantFraudRates(ctx context.Context, merchantID string) ([]models.MerchantFraudRate, error)
+
+	// LabeledChecks returns the labeled fraud checks of the payments screened
+	// in a filter's time range, with the latest label of each payment
+	LabeledChecks(ctx context.Context, filter models.DecisionReportFilter) ([]models.LabeledCheck, error)
+
+	// SettlementItems returns the payments a settlement paid out, oldest
+	// payment first, or ErrSettlementNotFound
+	SettlementItems(ctx context.Context, settlementID uuid.UUID) ([]models.SettlementItem, error)
+
+	// CreateBankAccount registers a merchant's bank account, pending
+	// verification, or returns ErrMerchantNotFound
+	CreateBankAccount(ctx context.Context, account models.BankAccount) error
+
+	// BankAccounts returns a merchant's bank accounts, newest first
+	BankAccounts(ctx context.Context, merchantID uuid.UUID) ([]models.BankAccount, error)
+
+	// GetBankAccount returns a merchant's bank account, or
+	// ErrBankAccountNotFound
+	GetBankAccount(ctx context.Context, merchantID, id uuid.UUID) (models.BankAccount, error)
+
+	// RecordBankAccountVerification records the penny drop of a pending bank
+	// account and returns the account. A verified account becomes the
+	// merchant's payout account, replacing its previous one in the same
+	// transaction. It returns ErrBankAccountNotPending if the account is no
+	// longer pending, so each account is verified only once.
+	RecordBankAccountVerification(ctx context.Context, id uuid.UUID, verification models.BankAccountVerification) (models.BankAccount, error)
+
+	// Outbox returns the store the outbox relay reads from
+	Outbox() outbox.Store
+}
+
+// Ensure DBRepository implements Repository interface
+var _ Repository = (*DBRepository)(nil)
@@ -0,0 +1,346 @@
+// Command backtest replays historical payments through fraud screening with
+// the current configuration and a candidate one, and reports how the allow,
+// review and block rates and the fraud detected would have changed.
+//
+// Usage:
+//
+//	backtest -candidate candidate.yaml -kafka [-topic payments.events] [-since 2024-05-01T00:00:00Z] [-until 2024-05-08T00:00:00Z]
+//	backtest -candidate candidate.yaml -jsonl payments.jsonl
+//	backtest -candidate candidate.yaml -csv payments.csv [-changes 50] [-json]
+//
+// The current configuration is loaded like the service's, from defaults, the
+// -config file (default $CONFIG_FILE) and the environment. The candidate is
+// the current configuration with the settings of the candidate file, in the
+// same YAML format, on top, such as:
+//
+//	app:
+//	  fraud_threshold: 0.65
+//	rules:
+//	  file: candidate-rules.yaml
+//
+// Payments are replayed from the payment events of a Kafka topic, whose
+// chargebacks, fraud refunds and fraud reports label them, or from an export
+// with one payment per JSON line or CSV row and an optional label column;
+// see the backtest package. Fraud lists, velocity and device history start
+// empty and are built from the replayed payments.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yourusername/fortexa/fraud-detection/internal/analyzer"
+	"github.com/yourusername/fortexa/fraud-detection/internal/backtest"
+	"github.com/yourusername/fortexa/fraud-detection/internal/config"
+	"github.com/yourusername/fortexa/fraud-detection/internal/device"
+	"github.com/yourusername/fortexa/fraud-detection/internal/fx"
+	"github.com/yourusername/fortexa/fraud-detection/internal/geoip"
+	"github.com/yourusername/fortexa/fraud-detection/internal/lists"
+	"github.com/yourusername/fortexa/fraud-detection/internal/ml"
+	"github.com/yourusername/fortexa/fraud-detection/internal/rules"
+	"github.com/yourusername/fortexa/fraud-detection/internal/velocity"
+	sharedconfig "github.com/yourusername/fortexa/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML config file of the current configuration")
+	candidateFile := flag.String("candidate", "", "YAML config file with the candidate settings")
+	fromKafka := flag.Bool("kafka", false, "replay the payment events of a Kafka topic")
+	topic := flag.String("topic", "", "Kafka topic to replay (default the payment events topic)")
+	since := flag.String("since", "", "replay Kafka events from this time, in RFC 3339 format")
+	until := flag.String("until", "", "replay Kafka events before this time, in RFC 3339 format")
+	jsonlFile := flag.String("jsonl", "", "replay a JSONL export of payments")
+	csvFile := flag.String("csv", "", "replay a CSV export of payments")
+	changes := flag.Int("changes", 20, "number of payments decided differently to list")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	verbose := flag.Bool("verbose", false, "log the screening of every payment")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, options{
+		configFile:    *configFile,
+		candidateFile: *candidateFile,
+		fromKafka:     *fromKafka,
+		topic:         *topic,
+		since:         *since,
+		until:         *until,
+		jsonlFile:     *jsonlFile,
+		csvFile:       *csvFile,
+		changes:       *changes,
+		asJSON:        *asJSON,
+		verbose:       *verbose,
+	}); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// options holds the command-line flags
+type options struct {
+	configFile    string
+	candidateFile string
+	fromKafka     bool
+	topic         string
+	since         string
+	until         string
+	jsonlFile     string
+	csvFile       string
+	changes       int
+	asJSON        bool
+	verbose       bool
+}
+
+// run loads both configurations, replays the payments and prints the report
+func run(ctx context.Context, opts options) error {
+	sources := 0
+	for _, set := range []bool{opts.fromKafka, opts.jsonlFile != "", opts.csvFile != ""} {
+		if set {
+			sources++
+		}
+	}
+	if opts.candidateFile == "" || sources != 1 {
+		return errors.New("-candidate and one of -kafka, -jsonl or -csv are required")
+	}
+
+	current := &config.Config{}
+	if err := sharedconfig.Load(current, opts.configFile); err != nil {
+		return fmt.Errorf("invalid current configuration: %w", err)
+	}
+	candidate, err := loadCandidate(opts.configFile, opts.candidateFile)
+	if err != nil {
+		return err
+	}
+
+	replayed, err := readEvents(ctx, current, opts)
+	if err != nil {
+		return err
+	}
+
+	// Screening logs every payment; keep the report readable unless asked for
+	if !opts.verbose {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+
+	currentAnalyzer, closeCurrent, err := newAnalyzer(current)
+	if err != nil {
+		return fmt.Errorf("current configuration: %w", err)
+	}
+	defer closeCurrent()
+	candidateAnalyzer, closeCandidate, err := newAnalyzer(candidate)
+	if err != nil {
+		return fmt.Errorf("candidate configuration: %w", err)
+	}
+	defer closeCandidate()
+
+	report, err := backtest.New(currentAnalyzer, candidateAnalyzer).Run(ctx, replayed)
+	if err != nil {
+		return err
+	}
+
+	if opts.asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printReport(os.Stdout, report, opts.changes)
+	return nil
+}
+
+// loadCandidate loads the current configuration with the settings of the
+// candidate file on top, so the candidate only has to name what it changes
+func loadCandidate(configFile, candidateFile string) (*config.Config, error) {
+	candidate := &config.Config{}
+	if err := sharedconfig.Load(candidate, configFile); err != nil {
+		return nil, fmt.Errorf("invalid current configuration: %w", err)
+	}
+	data, err := os.ReadFile(candidateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read candidate configuration: %w", err)
+	}
+	// A misspelt setting would leave the candidate identical to the current configuration
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(candidate); err != nil {
+		return nil, fmt.Errorf("failed to parse candidate configuration %s: %w", candidateFile, err)
+	}
+	if err := sharedconfig.Validate(candidate); err != nil {
+		return nil, fmt.Errorf("invalid candidate configuration: %w", err)
+	}
+	return candidate, nil
+}
+
+// readEvents reads the payment events to replay from the chosen source
+func readEvents(ctx context.Context, cfg *config.Config, opts options) ([]backtest.Event, error) {
+	if opts.fromKafka {
+		since, err := parseTime("-since", opts.since)
+		if err != nil {
+			return nil, err
+		}
+		until, err := parseTime("-until", opts.until)
+		if err != nil {
+			return nil, err
+		}
+		topic := opts.topic
+		if topic == "" {
+			topic = cfg.Kafka.PaymentEventsTopic
+		}
+		return backtest.ReadKafka(ctx, cfg.Kafka.Brokers, topic, since, until)
+	}
+
+	path, read := opts.jsonlFile, backtest.ReadJSONL
+	if opts.csvFile != "" {
+		path, read = opts.csvFile, backtest.ReadCSV
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer file.Close()
+	replayed, err := read(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return replayed, nil
+}
+
+// parseTime parses an optional RFC 3339 flag value
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// newAnalyzer creates an analyzer screening payments as a configuration says,
+// with empty in-memory stores of its own. The returned function closes the
+// GeoIP databases it opened.
+func newAnalyzer(cfg *config.Config) (*analyzer.FraudAnalyzer, func() error, error) {
+	velocityLimits, err := velocity.ParseLimits(cfg.Velocity.Limits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid velocity limits: %w", err)
+	}
+	ruleSet := rules.Default()
+	if cfg.Rules.File != "" {
+		if ruleSet, err = rules.LoadFile(cfg.Rules.File); err != nil {
+			return nil, nil, fmt.Errorf("failed to load fraud rules: %w", err)
+		}
+	}
+	chargebackTypes, err := lists.ParseTypes(cfg.Lists.ChargebackTypes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid chargeback list types: %w", err)
+	}
+	chargebacks := lists.ChargebackPolicy{
+		Types:  chargebackTypes,
+		TTL:    time.Duration(cfg.Lists.ChargebackTTLDays) * 24 * time.Hour,
+		Global: cfg.Lists.ChargebackScope == "global",
+	}
+	var model ml.Model
+	if cfg.Model.File != "" {
+		if model, err = ml.Load(cfg.Model.File); err != nil {
+			return nil, nil, fmt.Errorf("failed to load model: %w", err)
+		}
+	}
+	exchangeRates := fx.Default()
+	if cfg.FX.RatesFile != "" {
+		if exchangeRates, err = fx.LoadFile(cfg.FX.RatesFile); err != nil {
+			return nil, nil, fmt.Errorf("failed to load exchange rates: %w", err)
+		}
+	}
+
+	deviceRetention := time.Duration(cfg.Device.RetentionDays) * 24 * time.Hour
+	fraudAnalyzer := analyzer.NewFraudAnalyzer(cfg.App.ReviewThreshold, cfg.App.FraudThreshold, velocity.NewMemoryStore(), velocityLimits, ruleSet).
+		WithDevices(device.NewMemoryStore(deviceRetention)).
+		WithLists(lists.NewMemoryStore(), chargebacks).
+		WithModels(model, nil).
+		WithExchangeRates(exchangeRates)
+
+	closeGeoIP := func() error { return nil }
+	if cfg.GeoIP.CountryDB != "" || cfg.GeoIP.ASNDB != "" || cfg.GeoIP.AnonymousDB != "" {
+		geoDB, err := geoip.Open(cfg.GeoIP.CountryDB, cfg.GeoIP.ASNDB, cfg.GeoIP.AnonymousDB)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open GeoIP databases: %w", err)
+		}
+		fraudAnalyzer.WithGeoIP(geoDB)
+		closeGeoIP = geoDB.Close
+	}
+	return fraudAnalyzer, closeGeoIP, nil
+}
+
+// printReport prints the decisions of both configurations side by side,
+// then the payments decided differently, at most limit of them
+func printReport(w io.Writer, report backtest.Report, limit int) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Payments replayed: %d (%d labeled fraud, %d labeled legitimate)\n\n",
+		report.Payments, report.FraudPayments, report.LegitimatePayments)
+
+	fmt.Fprintln(tw, "\tCURRENT\tCANDIDATE\tCHANGE")
+	rows := []struct {
+		name               string
+		current, candidate int
+		of                 int
+	}{
+		{"Allowed", report.Current.Allowed, report.Candidate.Allowed, report.Payments},
+		{"Reviewed", report.Current.Reviewed, report.Candidate.Reviewed, report.Payments},
+		{"Blocked", report.Current.Blocked, report.Candidate.Blocked, report.Payments},
+		{"Fraud detected", report.Current.Detected(), report.Candidate.Detected(), report.FraudPayments},
+		{"  reviewed", report.Current.FraudReviewed, report.Candidate.FraudReviewed, report.FraudPayments},
+		{"  blocked", report.Current.FraudBlocked, report.Candidate.FraudBlocked, report.FraudPayments},
+		{"Fraud missed", report.Current.FraudMissed, report.Candidate.FraudMissed, report.FraudPayments},
+		{"Legitimate reviewed", report.Current.LegitimateReviewed, report.Candidate.LegitimateReviewed, report.LegitimatePayments},
+		{"Legitimate blocked", report.Current.LegitimateBlocked, report.Candidate.LegitimateBlocked, report.LegitimatePayments},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%+d\n", row.name, share(row.current, row.of), share(row.candidate, row.of), row.candidate-row.current)
+	}
+
+	if len(report.Changes) > 0 {
+		fmt.Fprintln(tw, "\nDECISION CHANGE\tPAYMENTS")
+		for _, change := range report.Changes {
+			fmt.Fprintf(tw, "%s -> %s\t%d\n", change.From, change.To, change.Count)
+		}
+	}
+
+	if len(report.Changed) > 0 && limit > 0 {
+		fmt.Fprintln(tw, "\nPAYMENT\tMERCHANT\tCURRENT\tCANDIDATE\tLABEL")
+		for i, decision := range report.Changed {
+			if i == limit {
+				fmt.Fprintf(tw, "... %d more\n", len(report.Changed)-limit)
+				break
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s (%.2f)\t%s (%.2f)\t%s\n",
+				decision.PaymentID, decision.MerchantID,
+				decision.Current, decision.CurrentRiskScore,
+				decision.Candidate, decision.CandidateRiskScore,
+				decision.Label)
+		}
+	}
+	tw.Flush()
+}
+
+// share formats a count with its percentage of a total
+func share(count, total int) string {
+	if total == 0 {
+		return fmt.Sprintf("%d", count)
+	}
+	return fmt.Sprintf("%d (%.1f%%)", count, 100*float64(count)/float64(total))
+}
@@ -2,18 +2,33 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
 	"github.com/yourusername/fortexa/fraud-detection/internal/analyzer"
 	"github.com/yourusername/fortexa/fraud-detection/internal/config"
+	"github.com/yourusername/fortexa/fraud-detection/internal/device"
+	"github.com/yourusername/fortexa/fraud-detection/internal/feedback"
+	"github.com/yourusername/fortexa/fraud-detection/internal/fx"
+	"github.com/yourusername/fortexa/fraud-detection/internal/geoip"
+	"github.com/yourusername/fortexa/fraud-detection/internal/lists"
+	"github.com/yourusername/fortexa/fraud-detection/internal/ml"
 	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+	"github.com/yourusername/fortexa/fraud-detection/internal/repository"
+	"github.com/yourusername/fortexa/fraud-detection/internal/rules"
+	"github.com/yourusername/fortexa/fraud-detection/internal/velocity"
+	"github.com/yourusername/fortexa/pkg/consumer"
+	"github.com/yourusername/fortexa/pkg/dlq"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
 func main() {
@@ -34,10 +49,25 @@ func main() {
 		cancel()
 	}()
 
+	var repo repository.Repository
+
+	// Check if running in mock mode
+	if cfg.App.MockMode {
+		log.Println("Running in MOCK MODE - No database connection required")
+		repo = repository.NewMockRepository()
+	} else {
+		dbRepo, err := repository.NewDBRepository(cfg.Database.ConnectionString())
+		if err != nil {
+			log.Fatalf("Failed to initialize database repository: %v", err)
+		}
+		defer dbRepo.Close()
+		repo = dbRepo
+	}
+
 	// Create Kafka reader for consuming payment events
 	kafkaReader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:     cfg.Kafka.Brokers,
-		Topic:       cfg.Kafka.PaymentsTopic,
+		Topic:       cfg.Kafka.PaymentEventsTopic,
 		GroupID:     cfg.Kafka.ConsumerGroup,
 		MinBytes:    10e3, // 10KB
 		MaxBytes:    10e6, // 10MB
@@ -46,22 +76,115 @@ func main() {
 	})
 	defer kafkaReader.Close()
 
-	// Create Kafka writer for publishing fraud events
-	kafkaWriter := &kafka.Writer{
-		Addr:         kafka.TCP(cfg.Kafka.Brokers...),
-		Topic:        cfg.Kafka.PaymentsTopic, // We'll publish back to the same topic
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireAll,
-		Async:        false,
-	}
+	// Create Kafka writer for relaying fraud events from the outbox and dead-lettering messages
+	kafkaWriter := outbox.NewWriter(cfg.Kafka.Brokers...)
 	defer kafkaWriter.Close()
 
+	// Start the outbox relay
+	relay := outbox.NewRelay(
+		repo.Outbox(),
+		kafkaWriter,
+		time.Duration(cfg.Outbox.PollIntervalMs)*time.Millisecond,
+		cfg.Outbox.BatchSize,
+	)
+	go relay.Run(ctx)
+
+	// Create the velocity and device stores shared by all workers
+	var redisClient *redis.Client
+	if cfg.Velocity.Store == "redis" || cfg.Device.Store == "redis" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port),
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		defer redisClient.Close()
+	}
+	var velocityStore velocity.Store = velocity.NewMemoryStore()
+	if cfg.Velocity.Store == "redis" {
+		velocityStore = velocity.NewRedisStore(redisClient, cfg.Redis.KeyPrefix)
+	}
+	deviceRetention := time.Duration(cfg.Device.RetentionDays) * 24 * time.Hour
+	var deviceStore device.Store = device.NewMemoryStore(deviceRetention)
+	if cfg.Device.Store == "redis" {
+		deviceStore = device.NewRedisStore(redisClient, cfg.Device.KeyPrefix, deviceRetention)
+	}
+	velocityLimits, err := velocity.ParseLimits(cfg.Velocity.Limits)
+	if err != nil {
+		log.Fatalf("Invalid velocity limits: %v", err)
+	}
+
+	// Load the fraud rules, reloading them when the rule file changes
+	var ruleProvider rules.Provider = rules.Default()
+	if cfg.Rules.File != "" {
+		loader, err := rules.NewLoader(cfg.Rules.File)
+		if err != nil {
+			log.Fatalf("Failed to load fraud rules: %v", err)
+		}
+		go loader.Run(ctx, time.Duration(cfg.Rules.ReloadIntervalSeconds)*time.Second)
+		ruleProvider = loader
+		log.Printf("Loaded fraud rules from %s", cfg.Rules.File)
+	}
+
+	// Decide what confirmed fraud adds to the blocklist
+	chargebackTypes, err := lists.ParseTypes(cfg.Lists.ChargebackTypes)
+	if err != nil {
+		log.Fatalf("Invalid chargeback list types: %v", err)
+	}
+	chargebacks := lists.ChargebackPolicy{
+		Types:  chargebackTypes,
+		TTL:    time.Duration(cfg.Lists.ChargebackTTLDays) * 24 * time.Hour,
+		Global: cfg.Lists.ChargebackScope == "global",
+	}
+
+	// Load the machine-learning models, if any
+	var liveModel, shadowModel ml.Model
+	if cfg.Model.File != "" {
+		if liveModel, err = ml.Load(cfg.Model.File); err != nil {
+			log.Fatalf("Failed to load model: %v", err)
+		}
+		log.Printf("Loaded model %s version %s", liveModel.Name(), liveModel.Version())
+	}
+	if cfg.Model.ShadowFile != "" {
+		if shadowModel, err = ml.Load(cfg.Model.ShadowFile); err != nil {
+			log.Fatalf("Failed to load shadow model: %v", err)
+		}
+		log.Printf("Loaded shadow model %s version %s", shadowModel.Name(), shadowModel.Version())
+	}
+
+	// Load the exchange rates amounts are compared in
+	exchangeRates := fx.Default()
+	if cfg.FX.RatesFile != "" {
+		if exchangeRates, err = fx.LoadFile(cfg.FX.RatesFile); err != nil {
+			log.Fatalf("Failed to load exchange rates: %v", err)
+		}
+	}
+	log.Printf("Comparing amounts in %s", exchangeRates.Reference())
+
+	// Keep the merchants' fraud rates up to date with the labels of their payments
+	merchantRates := feedback.NewRates(repo.Feedback())
+	go merchantRates.Run(ctx, time.Duration(cfg.Feedback.RatesRefreshSeconds)*time.Second)
+
 	// Create fraud analyzer
-	fraudAnalyzer := analyzer.NewFraudAnalyzer(cfg.App.FraudThreshold)
+	fraudAnalyzer := analyzer.NewFraudAnalyzer(cfg.App.ReviewThreshold, cfg.App.FraudThreshold, velocityStore, velocityLimits, ruleProvider).
+		WithDevices(deviceStore).
+		WithLists(repo.Lists(), chargebacks).
+		WithModels(liveModel, shadowModel).
+		WithMerchantRates(merchantRates).
+		WithExchangeRates(exchangeRates)
+	if cfg.GeoIP.CountryDB != "" || cfg.GeoIP.ASNDB != "" || cfg.GeoIP.AnonymousDB != "" {
+		geoDB, err := geoip.Open(cfg.GeoIP.CountryDB, cfg.GeoIP.ASNDB, cfg.GeoIP.AnonymousDB)
+		if err != nil {
+			log.Fatalf("Failed to open GeoIP databases: %v", err)
+		}
+		defer geoDB.Close()
+		fraudAnalyzer.WithGeoIP(geoDB)
+	}
 
 	// Start processing payments
 	log.Println("Starting fraud detection service")
-	err := processPayments(ctx, kafkaReader, kafkaWriter, fraudAnalyzer)
+	producer := events.NewProducer(cfg.App.Name)
+	deadLetter := dlq.NewPublisher(kafkaWriter, cfg.Kafka.DeadLetterTopic, cfg.App.Name)
+	err = processPayments(ctx, kafkaReader, repo, producer, fraudAnalyzer, deadLetter, cfg.Kafka)
 	if err != nil {
 		log.Fatalf("Error processing payments: %v", err)
 	}
@@ -69,84 +192,131 @@ func main() {
 	log.Println("Fraud detection service shutdown complete")
 }
 
-// processPayments continuously reads payment events from Kafka and analyzes them for fraud
-func processPayments(ctx context.Context, reader *kafka.Reader, writer *kafka.Writer, analyzer *analyzer.FraudAnalyzer) error {
+// processPayments continuously reads payment events from Kafka and screens new payments for fraud.
+// Events for the same payment are analyzed in order on a bounded pool of workers,
+// and offsets are committed only after an event has been fully processed or dead-lettered.
+// Fraud decisions are published to the fraud topic and the commands applying them to the payment commands topic.
+func processPayments(ctx context.Context, reader *kafka.Reader, repo repository.Repository, producer events.Producer, analyzer *analyzer.FraudAnalyzer, deadLetter consumer.DeadLetterer, kafkaCfg config.KafkaConfig) error {
 	log.Println("Processing payments for fraud detection")
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Payment processing shutting down")
-			return nil
-		default:
-			message, err := reader.ReadMessage(ctx)
-			if err != nil {
-				log.Printf("Error reading message: %v", err)
-				continue
-			}
-
-			// Process the message
-			go processMessage(ctx, message, writer, analyzer)
-		}
+	handler := func(ctx context.Context, message kafka.Message) error {
+		return processMessage(ctx, message, repo, producer, kafkaCfg, analyzer)
 	}
+	err := consumer.New(reader, handler, kafkaCfg.Concurrency, kafkaCfg.QueueSize).
+		WithDeadLetter(deadLetter, kafkaCfg.MaxAttempts).
+		Run(ctx)
+
+	log.Println("Payment processing shutting down")
+	return err
+}
+
+// commandTypes maps each fraud decision to the payment command that applies it
+var commandTypes = map[models.FraudDecision]string{
+	models.FraudDecisionAllow:  events.TypePaymentAuthorizationRequested,
+	models.FraudDecisionReview: events.TypePaymentReviewRequested,
+	models.FraudDecisionBlock:  events.TypePaymentDeclineRequested,
 }
 
-// processMessage processes a Kafka message containing a payment event
-func processMessage(ctx context.Context, message kafka.Message, writer *kafka.Writer, analyzer *analyzer.FraudAnalyzer) {
+// processMessage screens a payment.initiated event for fraud. The payment engine
+// only authorizes a payment when told to by the resulting command, so no payment
+// is authorized or captured before it has been screened. Chargebacks, refunds
+// and fraud reports label the payment instead, and blocklist it if they
+// confirm it fraudulent.
+func processMessage(ctx context.Context, message kafka.Message, repo repository.Repository, producer events.Producer, kafkaCfg config.KafkaConfig, analyzer *analyzer.FraudAnalyzer) error {
 	log.Printf("Processing message with key: %s", string(message.Key))
 
-	var event models.PaymentEvent
-	if err := json.Unmarshal(message.Value, &event); err != nil {
-		log.Printf("Error unmarshaling event: %v", err)
-		return
+	event, err := events.Decode(message.Value)
+	if err != nil {
+		return consumer.Permanent(err)
+	}
+
+	// Chargebacks, fraud refunds and merchants' reports label the payment
+	switch event.Type {
+	case events.TypePaymentChargedBack, events.TypePaymentRefunded, events.TypePaymentFraudReported:
+		var payload events.Payment
+		if err := event.DecodePayload(&payload); err != nil {
+			return consumer.Permanent(err)
+		}
+		return recordOutcome(ctx, event, models.PaymentFromEvent(payload), repo, analyzer)
+	}
+
+	// Only payments entering the flow are screened
+	if event.Type != events.TypePaymentInitiated {
+		return nil
 	}
 
-	// Only process payment initiated, authorized, or captured events
-	if event.Type != "payment.initiated" && 
-	   event.Type != "payment.authorized" && 
-	   event.Type != "payment.captured" {
-		return
+	var payload events.Payment
+	if err := event.DecodePayload(&payload); err != nil {
+		return consumer.Permanent(err)
 	}
+	payment := models.PaymentFromEvent(payload)
 
-	log.Printf("Analyzing payment for fraud: %s, Event: %s", event.Payment.ID, event.Type)
+	// A redelivered payment event is not analyzed again: that would observe
+	// its device and count it in velocity twice, and could reach a different
+	// decision. The decision recorded was published with the check.
+	checked, err := repo.HasFraudCheck(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+	if checked {
+		log.Printf("Payment %s was already screened, skipping event %s", payment.ID, event.ID)
+		return nil
+	}
+
+	log.Printf("Analyzing payment for fraud: %s, Event: %s", payment.ID, event.Type)
 
 	// Analyze the payment for fraud
-	fraudCheck := analyzer.AnalyzePayment(event.Payment)
-
-	// If fraudulent, publish a fraud event
-	if fraudCheck.IsFraudulent {
-		log.Printf("FRAUD DETECTED: Payment ID: %s, Risk Score: %.2f, Reason: %s", 
-			fraudCheck.PaymentID, fraudCheck.RiskScore, fraudCheck.Reason)
-		
-		// Create a fraud event
-		fraudEvent := models.FraudEvent{
-			ID:         uuid.New(),
-			Type:       "fraud.detected",
-			FraudCheck: fraudCheck,
-			Timestamp:  time.Now(),
-		}
+	fraudCheck := analyzer.AnalyzePayment(ctx, payment)
+	log.Printf("Fraud decision for payment %s: %s, Risk Score: %.2f, Reason: %s",
+		fraudCheck.PaymentID, fraudCheck.Decision, fraudCheck.RiskScore, fraudCheck.Reason)
 
-		// Serialize the event
-		eventJSON, err := json.Marshal(fraudEvent)
-		if err != nil {
-			log.Printf("Error marshaling fraud event: %v", err)
-			return
-		}
+	// Publish the decision, caused by the payment event
+	checkEvent, err := producer.Caused(event, events.TypeFraudCheckCompleted, fraudCheck.Event())
+	if err != nil {
+		return err
+	}
+	checkMessage, err := checkEvent.Message(kafkaCfg.FraudTopic, string(message.Key))
+	if err != nil {
+		return err
+	}
 
-		// Publish the fraud event
-		err = writer.WriteMessages(ctx, kafka.Message{
-			Key:   message.Key,
-			Value: eventJSON,
-		})
+	// Tell the payment engine how to proceed, with the screening result in the payment metadata
+	if payload.Metadata == nil {
+		payload.Metadata = make(map[string]string)
+	}
+	payload.Metadata["fraud_decision"] = string(fraudCheck.Decision)
+	payload.Metadata["fraud_risk_score"] = strconv.FormatFloat(fraudCheck.RiskScore, 'f', 4, 64)
+	if fraudCheck.Reason != "" {
+		payload.Metadata["fraud_reason"] = fraudCheck.Reason
+	}
+	command, err := producer.Caused(event, commandTypes[fraudCheck.Decision], payload)
+	if err != nil {
+		return err
+	}
+	commandMessage, err := command.Message(kafkaCfg.PaymentCommandsTopic, string(message.Key))
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			log.Printf("Error publishing fraud event: %v", err)
-			return
-		}
+	// Record the decision and the command for publishing
+	if err := repo.RecordFraudCheck(ctx, fraudCheck, checkMessage, commandMessage); err != nil {
+		return fmt.Errorf("failed to record fraud check for payment %s: %w", fraudCheck.PaymentID, err)
+	}
 
-		log.Printf("Published fraud event for payment: %s", fraudCheck.PaymentID)
-	} else {
-		log.Printf("Payment passed fraud checks: %s, Risk Score: %.2f", 
-			fraudCheck.PaymentID, fraudCheck.RiskScore)
+	log.Printf("Recorded fraud decision for payment: %s", fraudCheck.PaymentID)
+	return nil
+}
+
+// recordOutcome labels a payment with the outcome an event announces, if any,
+// and blocklists the payment if the outcome confirms it fraudulent
+func recordOutcome(ctx context.Context, event events.Envelope, payment models.Payment, repo repository.Repository, analyzer *analyzer.FraudAnalyzer) error {
+	outcome, ok := feedback.FromEvent(event, payment)
+	if !ok {
+		return nil
 	}
-} 
\ No newline at end of file
+	if err := repo.Feedback().Record(ctx, outcome); err != nil {
+		return fmt.Errorf("failed to label payment %s: %w", payment.ID, err)
+	}
+	log.Printf("Labeled payment %s as %s from %s", payment.ID, outcome.Label, outcome.Source)
+	return analyzer.BlockFraud(ctx, payment, outcome)
+}
@@ -1,54 +1,295 @@
 package analyzer
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"math/rand"
+	"net"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/fraud-detection/internal/device"
+	"github.com/yourusername/fortexa/fraud-detection/internal/feedback"
+	"github.com/yourusername/fortexa/fraud-detection/internal/fx"
+	"github.com/yourusername/fortexa/fraud-detection/internal/geoip"
+	"github.com/yourusername/fortexa/fraud-detection/internal/lists"
+	"github.com/yourusername/fortexa/fraud-detection/internal/ml"
 	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+	"github.com/yourusername/fortexa/fraud-detection/internal/rules"
+	"github.com/yourusername/fortexa/fraud-detection/internal/velocity"
 )
 
+const (
+	// riskyScore is the check score from which a check counts as a risk factor
+	riskyScore = 0.5
+	// maxReasonCodes is the number of risk factors named in a decision
+	maxReasonCodes = 3
+	// modelCheck is the check the live model's score counts as
+	modelCheck = "model_check"
+	// blocklistActor is recorded as the creator of blocklist entries added for confirmed fraud
+	blocklistActor = "fraud-detection"
+)
+
+// listTypeNames names list entry types in decision reasons
+var listTypeNames = map[lists.Type]string{
+	lists.TypeCard:     "Card",
+	lists.TypeEmail:    "Email",
+	lists.TypeVPA:      "VPA",
+	lists.TypeIP:       "IP address",
+	lists.TypeDevice:   "Device",
+	lists.TypeCustomer: "Customer",
+}
+
+// blocklistSources are the sources of blocklist entries added for the
+// outcomes confirming fraud
+var blocklistSources = map[feedback.Source]lists.Source{
+	feedback.SourceChargeback:     lists.SourceChargeback,
+	feedback.SourceRefund:         lists.SourceRefund,
+	feedback.SourceMerchantReport: lists.SourceMerchantReport,
+}
+
+// factor is a check's share of the risk score
+type factor struct {
+	code         string
+	info         string
+	contribution float64
+}
+
 // FraudAnalyzer analyzes payments for potential fraud
 type FraudAnalyzer struct {
-	// In a real implementation, this would contain sophisticated fraud detection algorithms,
-	// machine learning models, and connections to fraud databases
-	fraudThreshold float64
+	reviewThreshold float64
+	fraudThreshold  float64
+	velocity        velocity.Store
+	velocityLimits  []velocity.Limit
+	rules           rules.Provider
+	geoip           geoip.Resolver
+	devices         device.Store
+	lists           lists.Store
+	chargebacks     lists.ChargebackPolicy
+	model           ml.Model
+	shadowModel     ml.Model
+	rates           *feedback.Rates
+	exchange        *fx.Rates
 }
 
-// NewFraudAnalyzer creates a new FraudAnalyzer
-func NewFraudAnalyzer(fraudThreshold float64) *FraudAnalyzer {
+// NewFraudAnalyzer creates a new FraudAnalyzer. Payments with a risk score
+// above fraudThreshold are blocked, and those above reviewThreshold are held
+// for manual review. Every analyzed payment is recorded in velocityStore and
+// checked against velocityLimits, and then runs through the checks of the
+// current rule set. Amounts are converted into USD with approximate rates
+// until WithExchangeRates sets others.
+func NewFraudAnalyzer(reviewThreshold, fraudThreshold float64, velocityStore velocity.Store, velocityLimits []velocity.Limit, ruleProvider rules.Provider) *FraudAnalyzer {
 	return &FraudAnalyzer{
-		fraudThreshold: fraudThreshold,
+		reviewThreshold: reviewThreshold,
+		fraudThreshold:  fraudThreshold,
+		velocity:        velocityStore,
+		velocityLimits:  velocityLimits,
+		rules:           ruleProvider,
+		exchange:        fx.Default(),
 	}
 }
 
-// AnalyzePayment checks a payment for potential fraud
-func (a *FraudAnalyzer) AnalyzePayment(payment models.Payment) models.FraudCheck {
+// WithGeoIP resolves the country, network and anonymity of client IPs, so
+// rules can compare the IP country with the billing and card issuing countries
+func (a *FraudAnalyzer) WithGeoIP(resolver geoip.Resolver) *FraudAnalyzer {
+	a.geoip = resolver
+	return a
+}
+
+// WithDevices records which customers pay from which device, so rules can
+// spot devices shared by many customers and customers on a new device
+func (a *FraudAnalyzer) WithDevices(store device.Store) *FraudAnalyzer {
+	a.devices = store
+	return a
+}
+
+// WithLists consults the blocklists and allowlists in store before scoring
+// payments, and adds the values of payments confirmed fraudulent to the
+// blocklist as the chargeback policy says
+func (a *FraudAnalyzer) WithLists(store lists.Store, chargebacks lists.ChargebackPolicy) *FraudAnalyzer {
+	a.lists = store
+	a.chargebacks = chargebacks
+	return a
+}
+
+// WithModels scores payments with a machine-learning model, whose fraud
+// probability counts as the model_check check. A shadow model, if not nil,
+// scores every payment too, but only to record the decision it would have
+// led to in place of the live model. Either model may be nil.
+func (a *FraudAnalyzer) WithModels(live, shadow ml.Model) *FraudAnalyzer {
+	a.model = live
+	a.shadowModel = shadow
+	return a
+}
+
+// WithMerchantRates makes the merchants' fraud rates available to rules
+func (a *FraudAnalyzer) WithMerchantRates(rates *feedback.Rates) *FraudAnalyzer {
+	a.rates = rates
+	return a
+}
+
+// WithExchangeRates converts amounts into the reference currency of rates
+// for the amount attribute of rules and for velocity sums, so limits set in
+// the reference currency hold for payments in every currency
+func (a *FraudAnalyzer) WithExchangeRates(rates *fx.Rates) *FraudAnalyzer {
+	a.exchange = rates
+	return a
+}
+
+// AnalyzePayment checks a payment for potential fraud. Payments with a listed
+// value are blocked or allowed without being scored. Otherwise the risk score
+// is the weighted average of the check scores, compared with the merchant's
+// review and block thresholds; rules that force a review or block override it.
+func (a *FraudAnalyzer) AnalyzePayment(ctx context.Context, payment models.Payment) models.FraudCheck {
 	log.Printf("Analyzing payment for fraud: %s", payment.ID)
 
-	// In a real implementation, this would run multiple sophisticated checks
-	// For the MVP, we'll implement some basic checks
-	checks := []models.FraudCheckItem{
-		a.checkAmount(payment),
-		a.checkVelocity(payment),
-		a.checkGeolocation(payment),
-		a.checkPaymentMethod(payment),
+	if check, ok := a.checkLists(ctx, payment); ok {
+		return check
 	}
 
-	// Calculate overall risk score (average of all checks)
-	var totalScore float64
-	for _, check := range checks {
-		totalScore += check.Score
+	ruleSet := a.rules.Rules()
+	merchantID := payment.MerchantID.String()
+
+	// Velocity is checked first, so rules can refer to the velocity features
+	velocities, velocityErr := a.recordVelocity(ctx, payment)
+	velocityCheck := a.checkVelocity(payment, velocities, velocityErr)
+	checks := []models.FraudCheckItem{velocityCheck}
+	codes := []string{velocityCheck.Type}
+
+	attributes := a.paymentAttributes(ctx, payment, velocities)
+	results, err := ruleSet.Evaluate(merchantID, attributes)
+	if err != nil {
+		// Rules that cannot be evaluated do not fire; the rest still count
+		log.Printf("Failed to evaluate rules for payment %s: %v", payment.ID, err)
+	}
+	var forced rules.Result
+	for _, result := range results {
+		checks = append(checks, models.FraudCheckItem{
+			Type:  result.Check,
+			Score: result.Score,
+			Info:  result.Info,
+			Rules: result.Rules,
+		})
+		code := result.Check
+		if result.Rule != "" {
+			code += "." + result.Rule
+		}
+		codes = append(codes, code)
+
+		if result.Action == rules.ActionBlock || result.Action == rules.ActionReview && forced.Action == rules.ActionNone {
+			forced = result
+		}
+	}
+
+	// The live model's score is one more check
+	features := ml.Extract(attributes)
+	liveScore, live := a.scoreModel(a.model, features, payment)
+	if live {
+		checks = append(checks, models.FraudCheckItem{
+			Type:  modelCheck,
+			Score: liveScore,
+			Info:  fmt.Sprintf("Model %s fraud probability %.2f", a.model.Name(), liveScore),
+		})
+		codes = append(codes, modelCheck)
+	}
+
+	// Weigh the checks into the risk score
+	var totalScore, totalWeight float64
+	for i := range checks {
+		checks[i].Weight = ruleSet.Weight(merchantID, checks[i].Type)
+		totalScore += checks[i].Score * checks[i].Weight
+		totalWeight += checks[i].Weight
+	}
+	var riskScore float64
+	if totalWeight > 0 {
+		riskScore = totalScore / totalWeight
+	}
+
+	thresholds, ok := ruleSet.Thresholds(merchantID)
+	if !ok {
+		thresholds = rules.Thresholds{Review: a.reviewThreshold, Block: a.fraudThreshold}
+	}
+
+	// Name the checks that added most to the risk score
+	factors := make([]factor, 0, len(checks))
+	for i, check := range checks {
+		if check.Score >= riskyScore && totalWeight > 0 {
+			factors = append(factors, factor{code: codes[i], info: check.Info, contribution: check.Score * check.Weight / totalWeight})
+		}
+	}
+	sort.SliceStable(factors, func(i, j int) bool {
+		return factors[i].contribution > factors[j].contribution
+	})
+	if len(factors) > maxReasonCodes {
+		factors = factors[:maxReasonCodes]
+	}
+	var reasonCodes, infos []string
+	if forced.Action != rules.ActionNone {
+		reasonCodes = append(reasonCodes, forced.Check+"."+forced.ActionRule)
+		if forced.Rule == forced.ActionRule {
+			infos = append(infos, forced.Info)
+		}
+	}
+	for _, f := range factors {
+		if len(reasonCodes) > 0 && f.code == reasonCodes[0] {
+			continue
+		}
+		reasonCodes = append(reasonCodes, f.code)
+		infos = append(infos, f.info)
 	}
-	riskScore := totalScore / float64(len(checks))
 
-	// Determine if payment is fraudulent based on threshold
-	isFraudulent := riskScore > a.fraudThreshold
+	// Decide whether the payment may proceed
+	decision := decide(riskScore, thresholds, forced.Action)
 	reason := ""
-	if isFraudulent {
-		reason = "Multiple risk factors detected"
+	switch {
+	case decision == models.FraudDecisionBlock && forced.Action == rules.ActionBlock:
+		reason = fmt.Sprintf("Blocked by rule %s.%s", forced.Check, forced.ActionRule)
+	case decision == models.FraudDecisionBlock:
+		reason = fmt.Sprintf("Risk score %.2f above block threshold %.2f", riskScore, thresholds.Block)
+	case decision == models.FraudDecisionReview && forced.Action == rules.ActionReview:
+		reason = fmt.Sprintf("Review required by rule %s.%s", forced.Check, forced.ActionRule)
+	case decision == models.FraudDecisionReview:
+		reason = fmt.Sprintf("Risk score %.2f above review threshold %.2f", riskScore, thresholds.Review)
+	}
+	if reason != "" && len(infos) > 0 {
+		reason += ": " + strings.Join(infos, "; ")
+	}
+
+	// Record what the shadow model would have decided in place of the live one
+	var modelScores []models.ModelScore
+	if live {
+		modelScores = append(modelScores, models.ModelScore{
+			Model:    a.model.Name(),
+			Version:  a.model.Version(),
+			Score:    liveScore,
+			Decision: decision,
+		})
+	}
+	if shadowScore, ok := a.scoreModel(a.shadowModel, features, payment); ok {
+		weight := ruleSet.Weight(merchantID, modelCheck)
+		shadowTotal, shadowWeight := totalScore+shadowScore*weight, totalWeight+weight
+		if live {
+			shadowTotal -= liveScore * weight
+			shadowWeight -= weight
+		}
+		var shadowRisk float64
+		if shadowWeight > 0 {
+			shadowRisk = shadowTotal / shadowWeight
+		}
+		shadowDecision := decide(shadowRisk, thresholds, forced.Action)
+		if shadowDecision != decision {
+			log.Printf("Shadow model %s would have decided %s instead of %s for payment %s",
+				a.shadowModel.Name(), shadowDecision, decision, payment.ID)
+		}
+		modelScores = append(modelScores, models.ModelScore{
+			Model:    a.shadowModel.Name(),
+			Version:  a.shadowModel.Version(),
+			Score:    shadowScore,
+			Shadow:   true,
+			Decision: shadowDecision,
+		})
 	}
 
 	return models.FraudCheck{
@@ -56,130 +297,451 @@ func (a *FraudAnalyzer) AnalyzePayment(payment models.Payment) models.FraudCheck
 		MerchantID:   payment.MerchantID,
 		CustomerID:   payment.CustomerID,
 		RiskScore:    riskScore,
-		IsFraudulent: isFraudulent,
+		IsFraudulent: decision == models.FraudDecisionBlock,
+		Decision:     decision,
 		Reason:       reason,
+		ReasonCodes:  reasonCodes,
 		Checks:       checks,
+		ModelScores:  modelScores,
+		Features:     features,
 		CreatedAt:    time.Now(),
 	}
 }
 
-// checkAmount analyzes the payment amount for unusual patterns
-func (a *FraudAnalyzer) checkAmount(payment models.Payment) models.FraudCheckItem {
-	// In a real implementation, this would check if the amount is typical for the customer,
-	// merchant, and payment method
-	
-	var score float64
-	var info string
-	
-	// Simplified check: Transactions > 10000 are considered higher risk
-	if payment.Amount > 10000 {
-		score = 0.8
-		info = "Unusually large transaction amount"
-	} else if payment.Amount > 5000 {
-		score = 0.5
-		info = "Larger than average transaction amount"
-	} else {
-		score = 0.1
-		info = "Normal transaction amount"
-	}
-	
-	return models.FraudCheckItem{
-		Type:  "amount_check",
-		Score: score,
-		Info:  info,
+// decide returns the decision for a risk score. Rules that force a block or
+// review take precedence over a lower score.
+func decide(riskScore float64, thresholds rules.Thresholds, forced rules.Action) models.FraudDecision {
+	switch {
+	case forced == rules.ActionBlock || riskScore > thresholds.Block:
+		return models.FraudDecisionBlock
+	case forced == rules.ActionReview || riskScore > thresholds.Review:
+		return models.FraudDecisionReview
 	}
+	return models.FraudDecisionAllow
 }
 
-// checkVelocity checks for unusual transaction frequency
-func (a *FraudAnalyzer) checkVelocity(payment models.Payment) models.FraudCheckItem {
-	// In a real implementation, this would check the number of transactions
-	// from this customer in the last hour/day
-	
-	// For the MVP, we'll simulate with random data
-	score := rand.Float64() * 0.5
-	
-	var info string
-	if score > 0.3 {
-		info = "Multiple transactions detected in a short period"
-	} else {
-		info = "Normal transaction frequency"
+// scoreModel scores the features with a model. It returns false when there
+// is no model or it fails, in which case the payment is screened without it.
+func (a *FraudAnalyzer) scoreModel(model ml.Model, features ml.Features, payment models.Payment) (float64, bool) {
+	if model == nil {
+		return 0, false
 	}
-	
-	return models.FraudCheckItem{
-		Type:  "velocity_check",
-		Score: score,
-		Info:  info,
+	score, err := model.Score(features)
+	if err != nil {
+		log.Printf("Model %s failed to score payment %s: %v", model.Name(), payment.ID, err)
+		return 0, false
+	}
+	return score, true
+}
+
+// checkLists decides a payment with a listed value. A blocklisted value blocks
+// the payment and, failing that, an allowlisted one allows it, whatever the
+// scope of the entries. When the lists cannot be read, the payment is scored.
+func (a *FraudAnalyzer) checkLists(ctx context.Context, payment models.Payment) (models.FraudCheck, bool) {
+	if a.lists == nil {
+		return models.FraudCheck{}, false
+	}
+	values := listValues(payment)
+	if len(values) == 0 {
+		return models.FraudCheck{}, false
+	}
+	entries, err := a.lists.Match(ctx, payment.MerchantID.String(), values)
+	if err != nil {
+		log.Printf("List check failed for payment %s: %v", payment.ID, err)
+		return models.FraudCheck{}, false
+	}
+
+	var matched *lists.Entry
+	for i := range entries {
+		if entries[i].List == lists.ListBlock {
+			matched = &entries[i]
+			break
+		}
+		if matched == nil {
+			matched = &entries[i]
+		}
+	}
+	if matched == nil {
+		return models.FraudCheck{}, false
+	}
+
+	list, decision, score, verb := "blocklist", models.FraudDecisionBlock, 1.0, "Blocked"
+	if matched.List == lists.ListAllow {
+		list, decision, score, verb = "allowlist", models.FraudDecisionAllow, 0.0, "Allowed"
+	}
+	code := list + "." + strings.ToLower(string(matched.Type))
+	info := fmt.Sprintf("%s on %s %s", listTypeNames[matched.Type], matched.Scope(), list)
+	if matched.Reason != "" {
+		info += " (" + matched.Reason + ")"
+	}
+
+	return models.FraudCheck{
+		PaymentID:    payment.ID,
+		MerchantID:   payment.MerchantID,
+		CustomerID:   payment.CustomerID,
+		RiskScore:    score,
+		IsFraudulent: decision == models.FraudDecisionBlock,
+		Decision:     decision,
+		Reason:       verb + " by list: " + info,
+		ReasonCodes:  []string{code},
+		Checks: []models.FraudCheckItem{{
+			Type:   "list_check",
+			Score:  score,
+			Weight: 1,
+			Info:   info,
+			Rules:  []string{code},
+		}},
+		CreatedAt: time.Now(),
+	}, true
+}
+
+// BlockFraud adds the values of a payment confirmed fraudulent to the
+// blocklist, as far as the chargeback policy covers their types. Outcomes
+// that do not label the payment fraudulent, or that come from a review, add
+// nothing.
+func (a *FraudAnalyzer) BlockFraud(ctx context.Context, payment models.Payment, outcome feedback.Outcome) error {
+	source, ok := blocklistSources[outcome.Source]
+	if a.lists == nil || outcome.Label != feedback.LabelFraud || !ok {
+		return nil
+	}
+
+	covered := make(map[lists.Type]bool, len(a.chargebacks.Types))
+	for _, t := range a.chargebacks.Types {
+		covered[t] = true
+	}
+	merchantID := payment.MerchantID.String()
+	if a.chargebacks.Global {
+		merchantID = ""
+	}
+	var expiresAt *time.Time
+	if a.chargebacks.TTL > 0 {
+		expiry := time.Now().Add(a.chargebacks.TTL)
+		expiresAt = &expiry
+	}
+	var reason string
+	switch outcome.Source {
+	case feedback.SourceChargeback:
+		reason = "Chargeback on payment " + payment.ID.String()
+		if code, ok := payment.Metadata["chargeback_reason_code"].(string); ok && code != "" {
+			reason += ", reason code " + code
+		}
+	case feedback.SourceRefund:
+		reason = "Payment " + payment.ID.String() + " refunded as fraudulent"
+	case feedback.SourceMerchantReport:
+		reason = "Payment " + payment.ID.String() + " reported as fraudulent by the merchant"
 	}
+
+	var entries []lists.Entry
+	for _, v := range listValues(payment) {
+		if !covered[v.Type] {
+			continue
+		}
+		entries = append(entries, lists.Entry{
+			List:       lists.ListBlock,
+			Type:       v.Type,
+			Value:      v.Value,
+			MerchantID: merchantID,
+			Reason:     reason,
+			Source:     source,
+			CreatedBy:  blocklistActor,
+			ExpiresAt:  expiresAt,
+		})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := a.lists.Add(ctx, entries...); err != nil {
+		return fmt.Errorf("failed to blocklist fraudulent payment %s: %w", payment.ID, err)
+	}
+	log.Printf("Blocklisted %d values of fraudulent payment %s", len(entries), payment.ID)
+	return nil
 }
 
-// checkGeolocation checks for unusual location patterns
-func (a *FraudAnalyzer) checkGeolocation(payment models.Payment) models.FraudCheckItem {
-	// In a real implementation, this would check if the transaction location
-	// is consistent with the customer's typical locations
-	
-	// For the MVP, we'll check if the payment has location data in metadata
-	var score float64
-	var info string
-	
-	if payment.Metadata != nil {
-		if location, ok := payment.Metadata["location"].(string); ok {
-			if strings.Contains(strings.ToLower(location), "nigeria") || 
-			   strings.Contains(strings.ToLower(location), "ukraine") {
-				// Some regions have higher fraud rates (this is just for demo purposes)
-				score = 0.9
-				info = "Transaction from high-risk region"
-			} else {
-				score = 0.2
-				info = "Transaction from normal region"
+// listValues returns the values of a payment list entries can match: the
+// card fingerprint, email and VPA from the payment metadata, the client IP,
+// the device and the customer
+func listValues(payment models.Payment) []lists.Value {
+	card, _ := payment.Metadata["card_fingerprint"].(string)
+	email, _ := payment.Metadata["email"].(string)
+	vpa, _ := payment.Metadata["vpa"].(string)
+	candidates := []lists.Value{
+		{Type: lists.TypeCard, Value: card},
+		{Type: lists.TypeEmail, Value: email},
+		{Type: lists.TypeVPA, Value: vpa},
+		{Type: lists.TypeIP, Value: clientIP(payment)},
+		{Type: lists.TypeDevice, Value: deviceID(payment)},
+	}
+	if payment.CustomerID != uuid.Nil {
+		candidates = append(candidates, lists.Value{Type: lists.TypeCustomer, Value: payment.CustomerID.String()})
+	}
+
+	values := make([]lists.Value, 0, len(candidates))
+	for _, candidate := range candidates {
+		if value, ok := lists.Normalize(candidate.Type, candidate.Value); ok {
+			values = append(values, lists.Value{Type: candidate.Type, Value: value})
+		}
+	}
+	return values
+}
+
+// recordVelocity records the payment against the customer, card, IP address,
+// device and merchant it came from, and returns their recent velocity
+func (a *FraudAnalyzer) recordVelocity(ctx context.Context, payment models.Payment) ([]velocity.Velocity, error) {
+	at := payment.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return a.velocity.Record(ctx, payment.ID.String(), velocityKeys(payment), a.referenceAmount(payment), at)
+}
+
+// referenceAmount returns the payment's amount in the reference currency. An
+// amount in a currency without a rate is taken as it is, so large payments in
+// it are still caught, if too often.
+func (a *FraudAnalyzer) referenceAmount(payment models.Payment) float64 {
+	amount, ok := a.exchange.Convert(payment.Amount.Float64(), payment.Currency)
+	if !ok {
+		log.Printf("No exchange rate from %s to %s for payment %s, using its amount unconverted",
+			payment.Currency, a.exchange.Reference(), payment.ID)
+		return payment.Amount.Float64()
+	}
+	return amount
+}
+
+// checkVelocity scores how close the recent number and total amount of
+// payments are to the configured limits
+func (a *FraudAnalyzer) checkVelocity(payment models.Payment, velocities []velocity.Velocity, err error) models.FraudCheckItem {
+	if err != nil {
+		// Screening goes on without velocity rather than holding up every payment
+		log.Printf("Velocity check failed for payment %s: %v", payment.ID, err)
+		return models.FraudCheckItem{
+			Type:  "velocity_check",
+			Score: 0.5,
+			Info:  "Velocity data unavailable",
+		}
+	}
+
+	// Find the limit the payment comes closest to, and every limit it exceeds
+	var highest float64
+	var exceeded, fired []string
+	for _, v := range velocities {
+		for _, limit := range a.velocityLimits {
+			if !limit.Applies(v) {
+				continue
+			}
+			observed := limit.Observed(v)
+			ratio := observed / limit.Value
+			if ratio > highest {
+				highest = ratio
+			}
+			if ratio > 1 {
+				exceeded = append(exceeded, fmt.Sprintf("%s %s %s %g above %g",
+					limit.Dimension, limit.Window, limit.Measure, observed, limit.Value))
+				fired = append(fired, limit.String())
 			}
-		} else {
-			score = 0.5
-			info = "No location data provided"
 		}
-	} else {
-		score = 0.5
-		info = "No location data provided"
 	}
-	
+
+	score := 0.1 + 0.6*highest
+	info := "Normal transaction frequency"
+	switch {
+	case len(exceeded) > 0:
+		score = 0.9
+		info = "Velocity limits exceeded: " + strings.Join(exceeded, "; ")
+	case highest >= 0.5:
+		info = "Transaction frequency approaching velocity limits"
+	}
+
 	return models.FraudCheckItem{
-		Type:  "geolocation_check",
+		Type:  "velocity_check",
 		Score: score,
 		Info:  info,
+		Rules: fired,
 	}
 }
 
-// checkPaymentMethod analyzes the payment method for risk
-func (a *FraudAnalyzer) checkPaymentMethod(payment models.Payment) models.FraudCheckItem {
-	// Different payment methods have different risk profiles
-	
-	var score float64
-	var info string
-	
-	switch payment.PaymentMethodType {
-	case models.PaymentMethodCreditCard, models.PaymentMethodDebitCard:
-		// Card payments have moderate risk
-		score = 0.4
-		info = "Card payment - moderate risk"
-	case models.PaymentMethodUPI:
-		// UPI is generally linked to bank accounts and has lower risk
-		score = 0.2
-		info = "UPI payment - lower risk"
-	case models.PaymentMethodBankTransfer:
-		// Bank transfers have lower risk
-		score = 0.1
-		info = "Bank transfer - lower risk"
-	case models.PaymentMethodCrypto:
-		// Crypto has higher risk due to anonymity
-		score = 0.7
-		info = "Crypto payment - higher risk"
-	default:
-		score = 0.5
-		info = "Unknown payment method"
-	}
-	
-	return models.FraudCheckItem{
-		Type:  "payment_method_check",
-		Score: score,
-		Info:  info,
+// velocityKeys returns the keys the payment's velocity is tracked under. The
+// card fingerprint is taken from the payment metadata when the client
+// provided it.
+func velocityKeys(payment models.Payment) []velocity.Key {
+	keys := []velocity.Key{{Dimension: velocity.DimensionMerchant, Value: payment.MerchantID.String()}}
+	if payment.CustomerID != uuid.Nil {
+		keys = append(keys, velocity.Key{Dimension: velocity.DimensionCustomer, Value: payment.CustomerID.String()})
+	}
+
+	metadataKeys := []struct {
+		dimension velocity.Dimension
+		field     string
+	}{
+		{velocity.DimensionCard, "card_fingerprint"},
+	}
+	if ip := clientIP(payment); ip != "" {
+		keys = append(keys, velocity.Key{Dimension: velocity.DimensionIP, Value: ip})
+	}
+	if id := deviceID(payment); id != "" {
+		keys = append(keys, velocity.Key{Dimension: velocity.DimensionDevice, Value: id})
+	}
+	for _, mk := range metadataKeys {
+		if value, ok := payment.Metadata[mk.field].(string); ok && value != "" {
+			keys = append(keys, velocity.Key{Dimension: mk.dimension, Value: value})
+		}
+	}
+	return keys
+}
+
+// clientIP returns the IP address the payment came from: the one seen by the
+// gateway, or else the one the client put in the metadata
+func clientIP(payment models.Payment) string {
+	if payment.ClientIP != "" {
+		return payment.ClientIP
+	}
+	ip, _ := payment.Metadata["ip_address"].(string)
+	return ip
+}
+
+// deviceID returns the device the payment was made from: the one in the
+// payment's device signals, or else the one the client put in the metadata
+func deviceID(payment models.Payment) string {
+	if payment.Device != nil && payment.Device.ID != "" {
+		return payment.Device.ID
+	}
+	id, _ := payment.Metadata["device_id"].(string)
+	return id
+}
+
+// paymentAttributes returns the payment attributes rule conditions can refer to
+func (a *FraudAnalyzer) paymentAttributes(ctx context.Context, payment models.Payment, velocities []velocity.Velocity) rules.Attributes {
+	attributes := rules.Attributes{
+		"amount":          a.referenceAmount(payment),
+		"amount_original": payment.Amount.Float64(),
+		"currency":        payment.Currency,
+		"payment_method":  string(payment.PaymentMethodType),
+		"merchant_id":     payment.MerchantID.String(),
+		"description":     payment.Description,
+	}
+	if payment.CustomerID != uuid.Nil {
+		attributes["customer_id"] = payment.CustomerID.String()
+	}
+	if payment.BillingCountry != "" {
+		attributes["billing_country"] = payment.BillingCountry
+	}
+	if payment.CardIssuingCountry != "" {
+		attributes["card_issuing_country"] = payment.CardIssuingCountry
+	}
+	if ip := clientIP(payment); ip != "" {
+		attributes["client_ip"] = ip
+		a.addIPAttributes(attributes, payment, ip)
+	}
+	a.addDeviceAttributes(ctx, attributes, payment)
+	if a.rates != nil {
+		if rate, ok := a.rates.Get(payment.MerchantID.String()); ok {
+			attributes["merchant.payments"] = float64(rate.Payments)
+			attributes["merchant.fraud_payments"] = float64(rate.FraudPayments)
+			attributes["merchant.fraud_rate"] = rate.Rate
+		}
+	}
+	if payment.Session != nil {
+		if payment.Session.ID != "" {
+			attributes["session.id"] = payment.Session.ID
+		}
+		if payment.Session.AccountAgeDays != nil {
+			attributes["session.account_age_days"] = float64(*payment.Session.AccountAgeDays)
+		}
+	}
+	for key, value := range payment.Metadata {
+		switch value.(type) {
+		case string, float64, bool:
+			attributes["metadata."+key] = value
+		}
+	}
+	for _, v := range velocities {
+		prefix := "velocity." + string(v.Key.Dimension) + "." + v.Window.Name
+		attributes[prefix+".count"] = float64(v.Count)
+		attributes[prefix+".sum"] = v.Sum
+	}
+	return attributes
+}
+
+// addIPAttributes adds what the GeoIP databases know about the client IP.
+// Attributes the databases cannot tell are left out, so they are null.
+func (a *FraudAnalyzer) addIPAttributes(attributes rules.Attributes, payment models.Payment, address string) {
+	if a.geoip == nil {
+		return
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		log.Printf("Ignoring invalid client IP %q of payment %s", address, payment.ID)
+		return
+	}
+	location, err := a.geoip.Lookup(ip)
+	if err != nil {
+		log.Printf("GeoIP lookup failed for payment %s: %v", payment.ID, err)
+		return
 	}
-} 
\ No newline at end of file
+
+	attributes["ip.private"] = location.Private
+	if location.Country != "" {
+		attributes["ip.country"] = location.Country
+	}
+	if location.ASN != 0 {
+		attributes["ip.asn"] = float64(location.ASN)
+		attributes["ip.organization"] = location.Organization
+	}
+	if location.AnonymityKnown {
+		attributes["ip.anonymous"] = location.Anonymous
+		attributes["ip.anonymous_vpn"] = location.AnonymousVPN
+		attributes["ip.hosting"] = location.Hosting
+		attributes["ip.public_proxy"] = location.PublicProxy
+		attributes["ip.residential_proxy"] = location.ResidentialProxy
+		attributes["ip.tor_exit_node"] = location.TorExitNode
+	}
+}
+
+// addDeviceAttributes adds the device signals of the payment and how the
+// device has been used. Whether the device's time zone matches the client IP
+// is only known when the IP country is.
+func (a *FraudAnalyzer) addDeviceAttributes(ctx context.Context, attributes rules.Attributes, payment models.Payment) {
+	id := deviceID(payment)
+	if id == "" {
+		return
+	}
+	attributes["device.id"] = id
+
+	if d := payment.Device; d != nil {
+		if d.UserAgent != "" {
+			attributes["device.user_agent"] = d.UserAgent
+		}
+		if d.Screen != "" {
+			attributes["device.screen"] = d.Screen
+		}
+		if d.Timezone != "" {
+			attributes["device.timezone"] = d.Timezone
+			if country, ok := attributes["ip.country"].(string); ok {
+				if in, known := geoip.TimezoneInCountry(d.Timezone, country); known {
+					attributes["device.timezone_matches_ip"] = in
+				}
+			}
+		}
+	}
+
+	if a.devices == nil {
+		return
+	}
+	at := payment.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	customerID := ""
+	if payment.CustomerID != uuid.Nil {
+		customerID = payment.CustomerID.String()
+	}
+	usage, err := a.devices.Observe(ctx, id, customerID, at)
+	if err != nil {
+		log.Printf("Device lookup failed for payment %s: %v", payment.ID, err)
+		return
+	}
+	attributes["device.customers"] = float64(usage.Customers)
+	if customerID != "" {
+		attributes["device.new"] = usage.New
+		attributes["device.age_days"] = at.Sub(usage.FirstSeen).Hours() / 24
+	}
+}
@@ -0,0 +1,220 @@
+// Package backtest replays historical payments through fraud screening with
+// two configurations, the current one and a candidate, and compares the
+// decisions each would have made.
+//
+// Each configuration is screened by its own analyzer with in-memory velocity,
+// device and list stores, so every payment is screened against the replayed
+// history before it, as it was in production. Events are replayed in the
+// order they happened. Chargebacks, fraud refunds and fraud reports in the
+// replayed history label their payment and blocklist confirmed fraud as they
+// do in production; exports may carry each payment's label instead. Merchants'
+// fraud rates are not replayed, so rules on merchant.* attributes do not
+// fire in backtests.
+package backtest
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/fraud-detection/internal/analyzer"
+	"github.com/yourusername/fortexa/fraud-detection/internal/feedback"
+	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+)
+
+// Event is a historical event to replay
+type Event struct {
+	// At is when the event happened
+	At time.Time
+	// Payment is the payment the event is about
+	Payment models.Payment
+	// Screen is set for payments entering the flow, which are screened
+	Screen bool
+	// Outcome, if set, labels the payment and blocklists confirmed fraud
+	Outcome *feedback.Outcome
+	// Label, if set, is the label an export gives the payment. Unlike an
+	// outcome it blocklists nothing, since it is not known when it was given.
+	Label feedback.Label
+}
+
+// Decision is how the current and the candidate configuration decided a payment
+type Decision struct {
+	PaymentID          uuid.UUID            `json:"payment_id"`
+	MerchantID         uuid.UUID            `json:"merchant_id"`
+	Current            models.FraudDecision `json:"current"`
+	CurrentRiskScore   float64              `json:"current_risk_score"`
+	Candidate          models.FraudDecision `json:"candidate"`
+	CandidateRiskScore float64              `json:"candidate_risk_score"`
+	Label              feedback.Label       `json:"label,omitempty"`
+}
+
+// Tally counts the decisions of one configuration. Fraud payments that were
+// reviewed or blocked count as detected; the rest were missed.
+type Tally struct {
+	Allowed            int `json:"allowed"`
+	Reviewed           int `json:"reviewed"`
+	Blocked            int `json:"blocked"`
+	FraudReviewed      int `json:"fraud_reviewed"`
+	FraudBlocked       int `json:"fraud_blocked"`
+	FraudMissed        int `json:"fraud_missed"`
+	LegitimateReviewed int `json:"legitimate_reviewed"`
+	LegitimateBlocked  int `json:"legitimate_blocked"`
+}
+
+// Detected returns the number of fraud payments reviewed or blocked
+func (t Tally) Detected() int {
+	return t.FraudReviewed + t.FraudBlocked
+}
+
+// add counts a decision on a payment with a label, if it has one
+func (t *Tally) add(decision models.FraudDecision, label feedback.Label) {
+	switch decision {
+	case models.FraudDecisionAllow:
+		t.Allowed++
+		if label == feedback.LabelFraud {
+			t.FraudMissed++
+		}
+	case models.FraudDecisionReview:
+		t.Reviewed++
+		switch label {
+		case feedback.LabelFraud:
+			t.FraudReviewed++
+		case feedback.LabelLegitimate:
+			t.LegitimateReviewed++
+		}
+	case models.FraudDecisionBlock:
+		t.Blocked++
+		switch label {
+		case feedback.LabelFraud:
+			t.FraudBlocked++
+		case feedback.LabelLegitimate:
+			t.LegitimateBlocked++
+		}
+	}
+}
+
+// Change is the number of payments the candidate configuration decides
+// differently, from one decision to another
+type Change struct {
+	From  models.FraudDecision `json:"from"`
+	To    models.FraudDecision `json:"to"`
+	Count int                  `json:"count"`
+}
+
+// Report compares the decisions of the current and the candidate
+// configuration on the replayed payments. Changed lists the payments decided
+// differently, labeled fraud first.
+type Report struct {
+	Payments           int        `json:"payments"`
+	FraudPayments      int        `json:"fraud_payments"`
+	LegitimatePayments int        `json:"legitimate_payments"`
+	Current            Tally      `json:"current"`
+	Candidate          Tally      `json:"candidate"`
+	Changes            []Change   `json:"changes"`
+	Changed            []Decision `json:"changed"`
+}
+
+// Replay screens payments with the current and the candidate configuration
+type Replay struct {
+	current   *analyzer.FraudAnalyzer
+	candidate *analyzer.FraudAnalyzer
+}
+
+// New creates a Replay comparing two analyzers. Each must have stores of its
+// own, since screening a payment records it in the analyzer's velocity and
+// device history.
+func New(current, candidate *analyzer.FraudAnalyzer) *Replay {
+	return &Replay{current: current, candidate: candidate}
+}
+
+// Run replays events in the order they happened and reports how the two
+// configurations decided the payments screened. A payment is screened once,
+// however often it appears, and counts with the last label it was given.
+func (r *Replay) Run(ctx context.Context, replayed []Event) (Report, error) {
+	sort.SliceStable(replayed, func(i, j int) bool {
+		return replayed[i].At.Before(replayed[j].At)
+	})
+
+	labels := make(map[uuid.UUID]feedback.Label)
+	screened := make(map[uuid.UUID]bool)
+	var decisions []Decision
+	for _, event := range replayed {
+		if err := ctx.Err(); err != nil {
+			return Report{}, err
+		}
+		id := event.Payment.ID
+		if event.Label != "" {
+			labels[id] = event.Label
+		}
+		if event.Outcome != nil {
+			labels[id] = event.Outcome.Label
+			if err := r.current.BlockFraud(ctx, event.Payment, *event.Outcome); err != nil {
+				return Report{}, err
+			}
+			if err := r.candidate.BlockFraud(ctx, event.Payment, *event.Outcome); err != nil {
+				return Report{}, err
+			}
+		}
+		if !event.Screen || screened[id] {
+			continue
+		}
+		screened[id] = true
+
+		current := r.current.AnalyzePayment(ctx, event.Payment)
+		candidate := r.candidate.AnalyzePayment(ctx, event.Payment)
+		decisions = append(decisions, Decision{
+			PaymentID:          id,
+			MerchantID:         event.Payment.MerchantID,
+			Current:            current.Decision,
+			CurrentRiskScore:   current.RiskScore,
+			Candidate:          candidate.Decision,
+			CandidateRiskScore: candidate.RiskScore,
+		})
+	}
+
+	return newReport(decisions, labels), nil
+}
+
+// decisionOrder orders decisions from the most to the least permissive
+var decisionOrder = map[models.FraudDecision]int{
+	models.FraudDecisionAllow:  0,
+	models.FraudDecisionReview: 1,
+	models.FraudDecisionBlock:  2,
+}
+
+// newReport tallies the decisions on screened payments with their labels
+func newReport(decisions []Decision, labels map[uuid.UUID]feedback.Label) Report {
+	report := Report{Payments: len(decisions), Changes: []Change{}, Changed: []Decision{}}
+	changes := make(map[[2]models.FraudDecision]int)
+	for _, decision := range decisions {
+		decision.Label = labels[decision.PaymentID]
+		switch decision.Label {
+		case feedback.LabelFraud:
+			report.FraudPayments++
+		case feedback.LabelLegitimate:
+			report.LegitimatePayments++
+		}
+		report.Current.add(decision.Current, decision.Label)
+		report.Candidate.add(decision.Candidate, decision.Label)
+		if decision.Current != decision.Candidate {
+			changes[[2]models.FraudDecision{decision.Current, decision.Candidate}]++
+			report.Changed = append(report.Changed, decision)
+		}
+	}
+
+	for change, count := range changes {
+		report.Changes = append(report.Changes, Change{From: change[0], To: change[1], Count: count})
+	}
+	sort.Slice(report.Changes, func(i, j int) bool {
+		a, b := report.Changes[i], report.Changes[j]
+		if a.From != b.From {
+			return decisionOrder[a.From] < decisionOrder[b.From]
+		}
+		return decisionOrder[a.To] < decisionOrder[b.To]
+	})
+	sort.SliceStable(report.Changed, func(i, j int) bool {
+		return report.Changed[i].Label == feedback.LabelFraud && report.Changed[j].Label != feedback.LabelFraud
+	})
+	return report
+}
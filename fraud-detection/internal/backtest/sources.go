@@ -0,0 +1,326 @@
+package backtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/fortexa/fraud-detection/internal/feedback"
+	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+	"github.com/yourusername/fortexa/pkg/events"
+)
+
+// maxLineSize is the longest JSONL line read
+const maxLineSize = 1 << 20
+
+// FromEnvelope returns the event to replay for a payment event. Initiated
+// payments are screened, and chargebacks, fraud refunds and fraud reports
+// label their payment; other events are not replayed.
+func FromEnvelope(envelope events.Envelope) (Event, bool, error) {
+	switch envelope.Type {
+	case events.TypePaymentInitiated, events.TypePaymentChargedBack, events.TypePaymentRefunded, events.TypePaymentFraudReported:
+	default:
+		return Event{}, false, nil
+	}
+
+	var payload events.Payment
+	if err := envelope.DecodePayload(&payload); err != nil {
+		return Event{}, false, err
+	}
+	event := Event{
+		At:      envelope.OccurredAt,
+		Payment: models.PaymentFromEvent(payload),
+		Screen:  envelope.Type == events.TypePaymentInitiated,
+	}
+	if outcome, ok := feedback.FromEvent(envelope, event.Payment); ok {
+		event.Outcome = &outcome
+	}
+	if !event.Screen && event.Outcome == nil {
+		return Event{}, false, nil
+	}
+	return event, true, nil
+}
+
+// ReadKafka reads the payment events a topic received between since and until
+// from every partition. A zero since reads from the start of the topic and a
+// zero until up to its current end. Messages that are not payment events
+// are skipped.
+func ReadKafka(ctx context.Context, brokers []string, topic string, since, until time.Time) ([]Event, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("no Kafka brokers")
+	}
+	conn, err := kafka.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partitions of %s: %w", topic, err)
+	}
+
+	var replayed []Event
+	for _, partition := range partitions {
+		err := readPartition(ctx, brokers[0], topic, partition.ID, since, until, func(message kafka.Message) {
+			envelope, err := events.Decode(message.Value)
+			if err != nil {
+				log.Printf("Skipping %s/%d@%d: %v", topic, message.Partition, message.Offset, err)
+				return
+			}
+			if envelope.OccurredAt.Before(since) || !until.IsZero() && !envelope.OccurredAt.Before(until) {
+				return
+			}
+			event, ok, err := FromEnvelope(envelope)
+			if err != nil {
+				log.Printf("Skipping %s/%d@%d: %v", topic, message.Partition, message.Offset, err)
+				return
+			}
+			if ok {
+				replayed = append(replayed, event)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return replayed, nil
+}
+
+// readPartition calls fn for each message of a partition written from since
+// until until, or up to the end of the partition when reading started
+func readPartition(ctx context.Context, broker, topic string, partition int, since, until time.Time, fn func(kafka.Message)) error {
+	conn, err := kafka.DialLeader(ctx, "tcp", broker, topic, partition)
+	if err != nil {
+		return fmt.Errorf("failed to connect to leader of %s/%d: %w", topic, partition, err)
+	}
+	defer conn.Close()
+
+	offset, last, err := conn.ReadOffsets()
+	if err != nil {
+		return fmt.Errorf("failed to read offsets of %s/%d: %w", topic, partition, err)
+	}
+	if !since.IsZero() {
+		if offset, err = conn.ReadOffset(since); err != nil {
+			return fmt.Errorf("failed to find offset of %s in %s/%d: %w", since.Format(time.RFC3339), topic, partition, err)
+		}
+	}
+	if offset >= last {
+		return nil
+	}
+	if _, err := conn.Seek(offset, kafka.SeekAbsolute); err != nil {
+		return fmt.Errorf("failed to seek %s/%d to %d: %w", topic, partition, offset, err)
+	}
+
+	for offset < last {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		batch := conn.ReadBatch(1, 10e6)
+		for offset < last {
+			message, err := batch.ReadMessage()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				batch.Close()
+				return fmt.Errorf("failed to read %s/%d: %w", topic, partition, err)
+			}
+			offset = message.Offset + 1
+			if !until.IsZero() && !message.Time.Before(until) {
+				batch.Close()
+				return nil
+			}
+			fn(message)
+		}
+		if err := batch.Close(); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read %s/%d: %w", topic, partition, err)
+		}
+	}
+	return nil
+}
+
+// exportRecord is a payment in an export: the payment as in payment events,
+// with the label it was given, if any
+type exportRecord struct {
+	events.Payment
+	Label feedback.Label `json:"label"`
+}
+
+// event returns the event screening the exported payment when it was created
+func (r exportRecord) event() (Event, error) {
+	switch {
+	case r.ID == uuid.Nil:
+		return Event{}, errors.New("payment has no id")
+	case r.MerchantID == uuid.Nil:
+		return Event{}, fmt.Errorf("payment %s has no merchant_id", r.ID)
+	case r.CreatedAt.IsZero():
+		return Event{}, fmt.Errorf("payment %s has no created_at", r.ID)
+	}
+	label := feedback.Label(strings.ToUpper(string(r.Label)))
+	if label != "" && label != feedback.LabelFraud && label != feedback.LabelLegitimate {
+		return Event{}, fmt.Errorf("payment %s has unknown label %q", r.ID, r.Label)
+	}
+	if r.Status == "" {
+		r.Status = string(models.PaymentStatusInitiated)
+	}
+	return Event{
+		At:      r.CreatedAt,
+		Payment: models.PaymentFromEvent(r.Payment),
+		Screen:  true,
+		Label:   label,
+	}, nil
+}
+
+// ReadJSONL reads an export of payments with one JSON object per line, with
+// the fields of payment events and optionally a label, FRAUD or LEGITIMATE.
+// Blank lines are skipped.
+func ReadJSONL(r io.Reader) ([]Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	var replayed []Event
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var record exportRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		event, err := record.event()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		replayed = append(replayed, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	return replayed, nil
+}
+
+// csvColumns set the payment fields of the CSV columns. Columns named
+// metadata.<key> set payment metadata.
+var csvColumns = map[string]func(r *exportRecord, value string) error{
+	"id": func(r *exportRecord, value string) (err error) {
+		r.ID, err = uuid.Parse(value)
+		return err
+	},
+	"merchant_id": func(r *exportRecord, value string) (err error) {
+		r.MerchantID, err = uuid.Parse(value)
+		return err
+	},
+	"customer_id": func(r *exportRecord, value string) error {
+		id, err := uuid.Parse(value)
+		r.CustomerID = &id
+		return err
+	},
+	"amount": func(r *exportRecord, value string) (err error) {
+		r.Amount, err = strconv.ParseFloat(value, 64)
+		return err
+	},
+	"currency":             func(r *exportRecord, value string) error { r.Currency = value; return nil },
+	"status":               func(r *exportRecord, value string) error { r.Status = value; return nil },
+	"payment_method_type":  func(r *exportRecord, value string) error { r.PaymentMethodType = value; return nil },
+	"description":          func(r *exportRecord, value string) error { r.Description = value; return nil },
+	"client_ip":            func(r *exportRecord, value string) error { r.ClientIP = value; return nil },
+	"billing_country":      func(r *exportRecord, value string) error { r.BillingCountry = value; return nil },
+	"card_issuing_country": func(r *exportRecord, value string) error { r.CardIssuingCountry = value; return nil },
+	"device_id":            func(r *exportRecord, value string) error { device(r).ID = value; return nil },
+	"device_user_agent":    func(r *exportRecord, value string) error { device(r).UserAgent = value; return nil },
+	"device_screen":        func(r *exportRecord, value string) error { device(r).Screen = value; return nil },
+	"device_timezone":      func(r *exportRecord, value string) error { device(r).Timezone = value; return nil },
+	"session_id":           func(r *exportRecord, value string) error { session(r).ID = value; return nil },
+	"account_age_days": func(r *exportRecord, value string) error {
+		days, err := strconv.Atoi(value)
+		session(r).AccountAgeDays = &days
+		return err
+	},
+	"created_at": func(r *exportRecord, value string) (err error) {
+		r.CreatedAt, err = time.Parse(time.RFC3339, value)
+		return err
+	},
+	"label": func(r *exportRecord, value string) error { r.Label = feedback.Label(value); return nil },
+}
+
+// device returns the record's device signals, adding them if missing
+func device(r *exportRecord) *events.Device {
+	if r.Device == nil {
+		r.Device = &events.Device{}
+	}
+	return r.Device
+}
+
+// session returns the record's session signals, adding them if missing
+func session(r *exportRecord) *events.Session {
+	if r.Session == nil {
+		r.Session = &events.Session{}
+	}
+	return r.Session
+}
+
+// ReadCSV reads an export of payments as CSV with a header row. Columns are
+// named after the fields of payment events, with the device and session
+// fields prefixed by device_ and session_ and account_age_days unprefixed;
+// columns named metadata.<key> hold payment metadata, and a label column
+// FRAUD or LEGITIMATE. Times are in RFC 3339 format. Empty cells are skipped.
+func ReadCSV(r io.Reader) ([]Event, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for _, column := range header {
+		if _, ok := csvColumns[column]; !ok && !strings.HasPrefix(column, "metadata.") {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+	}
+
+	var replayed []Event
+	for row := 2; ; row++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		var record exportRecord
+		for i, value := range values {
+			if value == "" {
+				continue
+			}
+			column := header[i]
+			if key := strings.TrimPrefix(column, "metadata."); key != column {
+				if record.Metadata == nil {
+					record.Metadata = make(map[string]string)
+				}
+				record.Metadata[key] = value
+				continue
+			}
+			if err := csvColumns[column](&record, value); err != nil {
+				return nil, fmt.Errorf("row %d, column %s: %w", row, column, err)
+			}
+		}
+		event, err := record.event()
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		replayed = append(replayed, event)
+	}
+	return replayed, nil
+}
@@ -1,88 +1,151 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
+	"errors"
 
-	"github.com/joho/godotenv"
+	"github.com/yourusername/fortexa/fraud-detection/internal/lists"
+	"github.com/yourusername/fortexa/fraud-detection/internal/velocity"
+	sharedconfig "github.com/yourusername/fortexa/pkg/config"
 )
 
 // Config holds all configuration for the service
 type Config struct {
-	App   AppConfig
-	Kafka KafkaConfig
+	App      AppConfig             `yaml:"app"`
+	Database sharedconfig.Database `yaml:"database"`
+	Kafka    KafkaConfig           `yaml:"kafka"`
+	Outbox   sharedconfig.Outbox   `yaml:"outbox"`
+	Rules    RulesConfig           `yaml:"rules"`
+	Velocity VelocityConfig        `yaml:"velocity"`
+	GeoIP    GeoIPConfig           `yaml:"geoip"`
+	Device   DeviceConfig          `yaml:"device"`
+	Lists    ListsConfig           `yaml:"lists"`
+	Model    ModelConfig           `yaml:"model"`
+	Feedback FeedbackConfig        `yaml:"feedback"`
+	FX       FXConfig              `yaml:"fx"`
+	Redis    RedisConfig           `yaml:"redis"`
 }
 
 // AppConfig holds the configuration for the application
 type AppConfig struct {
-	Name            string
-	Environment     string
-	ShutdownTimeout int
-	FraudThreshold  float64
+	Name            string  `yaml:"name" env:"APP_NAME" default:"fraud-detection" required:"true"`
+	Environment     string  `yaml:"environment" env:"APP_ENV" default:"development"`
+	ShutdownTimeout int     `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"5" min:"1"`
+	ReviewThreshold float64 `yaml:"review_threshold" env:"FRAUD_REVIEW_THRESHOLD" default:"0.45" min:"0" max:"1"`
+	FraudThreshold  float64 `yaml:"fraud_threshold" env:"FRAUD_THRESHOLD" default:"0.7" min:"0" max:"1"`
+	MockMode        bool    `yaml:"mock_mode" env:"MOCK_MODE"`
+}
+
+// Validate checks that payments are held for review before they are blocked
+func (a AppConfig) Validate() error {
+	if a.ReviewThreshold > a.FraudThreshold {
+		return errors.New("app: FRAUD_REVIEW_THRESHOLD must not be above FRAUD_THRESHOLD")
+	}
+	return nil
 }
 
 // KafkaConfig holds the configuration for Kafka
 type KafkaConfig struct {
-	Brokers       []string
-	PaymentsTopic string
-	FraudTopic    string
-	ConsumerGroup string
+	Brokers              []string `yaml:"brokers" env:"KAFKA_BROKERS" default:"localhost:9092" required:"true"`
+	PaymentCommandsTopic string   `yaml:"payment_commands_topic" env:"KAFKA_PAYMENT_COMMANDS_TOPIC" default:"payments.commands" required:"true"`
+	PaymentEventsTopic   string   `yaml:"payment_events_topic" env:"KAFKA_PAYMENT_EVENTS_TOPIC" default:"payments.events" required:"true"`
+	FraudTopic           string   `yaml:"fraud_topic" env:"KAFKA_FRAUD_TOPIC" default:"fraud.events" required:"true"`
+	ConsumerGroup        string   `yaml:"consumer_group" env:"KAFKA_CONSUMER_GROUP" default:"fraud-detection" required:"true"`
+	Concurrency          int      `yaml:"concurrency" env:"KAFKA_CONSUMER_CONCURRENCY" default:"8" min:"1"`
+	QueueSize            int      `yaml:"queue_size" env:"KAFKA_CONSUMER_QUEUE_SIZE" default:"100" min:"1"`
+	MaxAttempts          int      `yaml:"max_attempts" env:"KAFKA_CONSUMER_MAX_ATTEMPTS" default:"5" min:"1"`
+	DeadLetterTopic      string   `yaml:"dead_letter_topic" env:"KAFKA_DLQ_TOPIC" default:"fraud-detection.dlq" required:"true"`
 }
 
-// New returns a new Config struct
-func New() *Config {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
-	}
+// RulesConfig holds the configuration of the fraud rules. Without a file the
+// built-in rules are used.
+type RulesConfig struct {
+	File                  string `yaml:"file" env:"RULES_FILE"`
+	ReloadIntervalSeconds int    `yaml:"reload_interval_seconds" env:"RULES_RELOAD_INTERVAL_SECONDS" default:"10" min:"1"`
+}
 
-	return &Config{
-		App: AppConfig{
-			Name:            getEnv("APP_NAME", "fraud-detection"),
-			Environment:     getEnv("APP_ENV", "development"),
-			ShutdownTimeout: getEnvAsInt("SHUTDOWN_TIMEOUT", 5),
-			FraudThreshold:  getEnvAsFloat("FRAUD_THRESHOLD", 0.7),
-		},
-		Kafka: KafkaConfig{
-			Brokers:       getEnvAsSlice("KAFKA_BROKERS", []string{"localhost:9092"}),
-			PaymentsTopic: getEnv("KAFKA_PAYMENTS_TOPIC", "payments"),
-			FraudTopic:    getEnv("KAFKA_FRAUD_TOPIC", "fraud"),
-			ConsumerGroup: getEnv("KAFKA_CONSUMER_GROUP", "fraud-detection"),
-		},
-	}
+// VelocityConfig holds the configuration of velocity checks. Limits are
+// written as dimension.window.measure=value, where the dimension is customer,
+// card, ip, device or merchant, the window is 1m, 1h or 24h and the measure is
+// the count or sum of payments. Sums are in the reference currency of FX.
+type VelocityConfig struct {
+	Store  string   `yaml:"store" env:"VELOCITY_STORE" default:"memory" oneof:"memory|redis"`
+	Limits []string `yaml:"limits" env:"VELOCITY_LIMITS" default:"customer.1m.count=3,customer.1h.count=10,customer.24h.count=30,customer.24h.sum=50000,card.1m.count=3,card.1h.count=10,card.24h.count=30,card.24h.sum=50000,ip.1m.count=5,ip.1h.count=30,ip.24h.count=100,device.1m.count=3,device.1h.count=15,device.24h.count=50,merchant.1m.count=1000"`
 }
 
-// Simple helper function to read an environment or return a default value
-func getEnv(key string, defaultVal string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultVal
+// Validate checks that the velocity limits can be parsed
+func (v VelocityConfig) Validate() error {
+	_, err := velocity.ParseLimits(v.Limits)
+	return err
 }
 
-// Simple helper function to read an environment variable into a string slice or return a default value
-func getEnvAsSlice(key string, defaultVal []string) []string {
-	if value, exists := os.LookupEnv(key); exists {
-		return []string{value}
-	}
-	return defaultVal
+// GeoIPConfig holds the paths of MaxMind-format databases used to resolve
+// client IPs. Each is optional; without any, IP attributes are unknown.
+type GeoIPConfig struct {
+	CountryDB   string `yaml:"country_db" env:"GEOIP_COUNTRY_DB"`
+	ASNDB       string `yaml:"asn_db" env:"GEOIP_ASN_DB"`
+	AnonymousDB string `yaml:"anonymous_db" env:"GEOIP_ANONYMOUS_DB"`
 }
 
-// Simple helper function to read an environment variable into an integer or return a default value
-func getEnvAsInt(key string, defaultVal int) int {
-	valueStr := getEnv(key, "")
-	if value, err := strconv.Atoi(valueStr); err == nil {
-		return value
-	}
-	return defaultVal
+// DeviceConfig holds the configuration of device tracking. Customers are
+// forgotten on a device they have not used for RetentionDays.
+type DeviceConfig struct {
+	Store         string `yaml:"store" env:"DEVICE_STORE" default:"memory" oneof:"memory|redis"`
+	RetentionDays int    `yaml:"retention_days" env:"DEVICE_RETENTION_DAYS" default:"30" min:"1"`
+	KeyPrefix     string `yaml:"key_prefix" env:"DEVICE_KEY_PREFIX" default:"fraud:device:"`
 }
 
-// Simple helper function to read an environment variable into a float or return a default value
-func getEnvAsFloat(key string, defaultVal float64) float64 {
-	valueStr := getEnv(key, "")
-	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
-		return value
-	}
-	return defaultVal
-} 
\ No newline at end of file
+// ListsConfig holds what confirmed fraud, from fraud chargebacks, refunds of
+// fraudulent payments and merchants' fraud reports, adds to the blocklist: the
+// payment's values of ChargebackTypes, for the payment's merchant or, with a
+// global scope, for all merchants. Entries expire after ChargebackTTLDays, or
+// never when it is 0.
+type ListsConfig struct {
+	ChargebackTypes   []string `yaml:"chargeback_types" env:"LISTS_CHARGEBACK_TYPES" default:"CARD,EMAIL,VPA,DEVICE"`
+	ChargebackTTLDays int      `yaml:"chargeback_ttl_days" env:"LISTS_CHARGEBACK_TTL_DAYS" default:"180" min:"0"`
+	ChargebackScope   string   `yaml:"chargeback_scope" env:"LISTS_CHARGEBACK_SCOPE" default:"merchant" oneof:"merchant|global"`
+}
+
+// Validate checks that the chargeback entry types are known
+func (l ListsConfig) Validate() error {
+	_, err := lists.ParseTypes(l.ChargebackTypes)
+	return err
+}
+
+// FeedbackConfig holds how often the merchants' fraud rates, which follow the
+// labels of their payments, are reloaded
+type FeedbackConfig struct {
+	RatesRefreshSeconds int `yaml:"rates_refresh_seconds" env:"FEEDBACK_RATES_REFRESH_SECONDS" default:"300" min:"1"`
+}
+
+// ModelConfig holds the paths of the machine-learning models exported to
+// JSON. The live model's score is a check in the risk score; the shadow model
+// is only scored for comparison. Both are optional.
+type ModelConfig struct {
+	File       string `yaml:"file" env:"MODEL_FILE"`
+	ShadowFile string `yaml:"shadow_file" env:"MODEL_SHADOW_FILE"`
+}
+
+// FXConfig holds the path of the exchange rates that convert amounts into a
+// reference currency for amount rules and velocity sums. Without a file,
+// approximate rates into USD are used.
+type FXConfig struct {
+	RatesFile string `yaml:"rates_file" env:"FX_RATES_FILE"`
+}
+
+// RedisConfig holds the configuration for Redis, used when VELOCITY_STORE or DEVICE_STORE is redis
+type RedisConfig struct {
+	Host      string `yaml:"host" env:"REDIS_HOST" default:"localhost"`
+	Port      string `yaml:"port" env:"REDIS_PORT" default:"6379"`
+	Password  string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB        int    `yaml:"db" env:"REDIS_DB" default:"0" min:"0"`
+	KeyPrefix string `yaml:"key_prefix" env:"VELOCITY_KEY_PREFIX" default:"fraud:velocity:"`
+}
+
+// New loads the configuration from defaults, the optional config file and the
+// environment. It exits on invalid configuration, and prints the configuration
+// and exits when run with --print-config.
+func New() *Config {
+	cfg := &Config{}
+	sharedconfig.MustLoad(cfg)
+	return cfg
+}
@@ -0,0 +1,28 @@
+// Package device remembers which customers pay from which devices, so that
+// a device shared by many customers, or a customer paying from a device they
+// have not used before, can be told apart from a customer's usual device.
+package device
+
+import (
+	"context"
+	"time"
+)
+
+// Usage is what is known about a device when a customer pays from it.
+// Customers is the number of distinct customers that used the device within
+// the retention period, including this one. New reports whether this is the
+// first time the customer was seen on the device.
+type Usage struct {
+	Customers int
+	New       bool
+	FirstSeen time.Time
+}
+
+// Store records which customers use a device
+type Store interface {
+	// Observe records that a customer used a device at the given time and
+	// returns the device's usage. A customer's first use is identified by its
+	// time, so observing the same payment again still reports it as new. An
+	// empty customer ID only reads the usage.
+	Observe(ctx context.Context, deviceID, customerID string, at time.Time) (Usage, error)
+}
@@ -0,0 +1,101 @@
+package device
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often a MemoryStore drops devices that have been idle for the retention period
+const sweepInterval = time.Minute
+
+// MemoryStore is an in-process Store. Each fraud-detection instance only sees
+// the payments of the partitions it consumes; use RedisStore to share device
+// usage across instances.
+type MemoryStore struct {
+	retention time.Duration
+
+	mutex     sync.Mutex
+	devices   map[string]*deviceUsage
+	lastSweep time.Time
+}
+
+// deviceUsage holds when each customer first and last used a device
+type deviceUsage struct {
+	customers map[string]*seen
+	lastSeen  time.Time
+}
+
+// seen is when a customer first and last used a device
+type seen struct {
+	first time.Time
+	last  time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore that forgets customers who have
+// not used a device for retention
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		retention: retention,
+		devices:   make(map[string]*deviceUsage),
+	}
+}
+
+// Observe records that a customer used a device and returns the device's usage
+func (s *MemoryStore) Observe(ctx context.Context, deviceID, customerID string, at time.Time) (Usage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sweep(at)
+
+	d, ok := s.devices[deviceID]
+	if !ok {
+		if customerID == "" {
+			return Usage{}, nil
+		}
+		d = &deviceUsage{customers: make(map[string]*seen)}
+		s.devices[deviceID] = d
+	}
+	if at.After(d.lastSeen) {
+		d.lastSeen = at
+	}
+
+	cutoff := at.Add(-s.retention)
+	for id, customer := range d.customers {
+		if customer.last.Before(cutoff) {
+			delete(d.customers, id)
+		}
+	}
+
+	var usage Usage
+	if customerID != "" {
+		customer, ok := d.customers[customerID]
+		if !ok {
+			customer = &seen{first: at, last: at}
+			d.customers[customerID] = customer
+		}
+		if at.After(customer.last) {
+			customer.last = at
+		}
+		usage.FirstSeen = customer.first
+		usage.New = customer.first.Equal(at)
+	}
+	usage.Customers = len(d.customers)
+	return usage, nil
+}
+
+// sweep drops devices that have been idle for the retention period, at most
+// once per sweep interval
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	cutoff := now.Add(-s.retention)
+	for id, d := range s.devices {
+		if d.lastSeen.Before(cutoff) {
+			delete(s.devices, id)
+		}
+	}
+}
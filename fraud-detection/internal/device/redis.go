@@ -0,0 +1,79 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// observeScript records a customer's use of a device and reads back its usage
+// in one atomic step. KEYS[1] is a sorted set of the device's customers scored
+// by when they last used it, and KEYS[2] a hash of when they first did. ARGV
+// holds the customer, the time in milliseconds and the retention in
+// milliseconds. Customers idle for the retention period are removed.
+var observeScript = redis.NewScript(`
+local customer, at, retention = ARGV[1], tonumber(ARGV[2]), tonumber(ARGV[3])
+local stale = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', '(' .. (at - retention))
+if #stale > 0 then
+  redis.call('ZREM', KEYS[1], unpack(stale))
+  redis.call('HDEL', KEYS[2], unpack(stale))
+end
+local first = false
+if customer ~= '' then
+  redis.call('ZADD', KEYS[1], 'GT', at, customer)
+  redis.call('HSETNX', KEYS[2], customer, at)
+  first = redis.call('HGET', KEYS[2], customer)
+  redis.call('PEXPIRE', KEYS[1], retention)
+  redis.call('PEXPIRE', KEYS[2], retention)
+end
+return {redis.call('ZCARD', KEYS[1]), first}
+`)
+
+// RedisStore is a Store kept in Redis, so device usage is shared by every
+// fraud-detection instance. It requires Redis 6.2 or later.
+type RedisStore struct {
+	client    redis.UniversalClient
+	prefix    string
+	retention time.Duration
+}
+
+// NewRedisStore creates a new RedisStore whose keys start with prefix and
+// that forgets customers who have not used a device for retention
+func NewRedisStore(client redis.UniversalClient, prefix string, retention time.Duration) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		prefix:    prefix,
+		retention: retention,
+	}
+}
+
+// Observe records that a customer used a device and returns the device's usage
+func (s *RedisStore) Observe(ctx context.Context, deviceID, customerID string, at time.Time) (Usage, error) {
+	// Both keys share a hash tag so they land on the same cluster slot
+	keys := []string{
+		s.prefix + "{" + deviceID + "}:last",
+		s.prefix + "{" + deviceID + "}:first",
+	}
+	values, err := observeScript.Run(ctx, s.client, keys, customerID, at.UnixMilli(), s.retention.Milliseconds()).Slice()
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to record device usage: %w", err)
+	}
+	if len(values) == 0 {
+		return Usage{}, fmt.Errorf("failed to record device usage: empty reply")
+	}
+
+	customers, _ := values[0].(int64)
+	usage := Usage{Customers: int(customers)}
+	if len(values) > 1 && values[1] != nil {
+		first, err := strconv.ParseInt(fmt.Sprint(values[1]), 10, 64)
+		if err != nil {
+			return Usage{}, fmt.Errorf("failed to parse first use of device %s: %w", deviceID, err)
+		}
+		usage.FirstSeen = time.UnixMilli(first)
+		usage.New = first == at.UnixMilli()
+	}
+	return usage, nil
+}
@@ -0,0 +1,128 @@
+// Package feedback turns what happens to payments after screening into fraud
+// labels.
+//
+// A payment is labeled fraudulent or legitimate when an analyst reviews it,
+// which the gateway records, when it is charged back, when the merchant
+// refunds it as fraudulent, and when the merchant reports it. Fraud detection
+// records the labels of the payment events announcing the last three,
+// blocklists payments confirmed fraudulent, and keeps per-merchant fraud
+// rates that rules can refer to. Labeled fraud checks are the training data
+// of fraud models and the ground truth of decision reports.
+package feedback
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+	"github.com/yourusername/fortexa/pkg/events"
+)
+
+// Label says whether a payment turned out fraudulent
+type Label string
+
+// Labels
+const (
+	LabelFraud      Label = "FRAUD"
+	LabelLegitimate Label = "LEGITIMATE"
+)
+
+// Source is where a label came from
+type Source string
+
+// Sources
+const (
+	SourceReview         Source = "REVIEW"
+	SourceChargeback     Source = "CHARGEBACK"
+	SourceRefund         Source = "REFUND"
+	SourceMerchantReport Source = "MERCHANT_REPORT"
+)
+
+// Chargeback categories, in the chargeback_category payment metadata. Fraud
+// chargebacks are for payments the cardholder did not make; disputes are
+// about goods or services the cardholder did pay for.
+const (
+	ChargebackCategoryFraud   = "FRAUD"
+	ChargebackCategoryDispute = "DISPUTE"
+)
+
+// Outcome labels a payment
+type Outcome struct {
+	PaymentID  uuid.UUID
+	MerchantID uuid.UUID
+	Label      Label
+	Source     Source
+	Actor      string
+	At         time.Time
+}
+
+// Rate is a merchant's fraud rate: the share of its recently screened
+// payments labeled fraudulent
+type Rate struct {
+	Payments      int
+	FraudPayments int
+	Rate          float64
+}
+
+// Store holds the labels
+type Store interface {
+	// Record labels a payment. Recording the same outcome again, as when its
+	// event is redelivered, has no effect.
+	Record(ctx context.Context, outcome Outcome) error
+
+	// Rates returns the fraud rates of the merchants with screened payments
+	Rates(ctx context.Context) (map[string]Rate, error)
+}
+
+// FromEvent returns the outcome a payment event announces, if any:
+//
+//   - payment.charged_back labels the payment fraudulent, or legitimate for
+//     chargebacks in the DISPUTE category
+//   - payment.refunded labels the payment fraudulent if the merchant refunded
+//     it as fraudulent (refund_fraudulent metadata)
+//   - payment.fraud_reported labels the payment as the merchant reported it
+//     (fraud_report_label metadata)
+func FromEvent(event events.Envelope, payment models.Payment) (Outcome, bool) {
+	outcome := Outcome{
+		PaymentID:  payment.ID,
+		MerchantID: payment.MerchantID,
+		At:         event.OccurredAt,
+	}
+	switch event.Type {
+	case events.TypePaymentChargedBack:
+		outcome.Source = SourceChargeback
+		outcome.Label = LabelFraud
+		if strings.EqualFold(metadata(payment, "chargeback_category"), ChargebackCategoryDispute) {
+			outcome.Label = LabelLegitimate
+		}
+		outcome.Actor = metadata(payment, "chargeback_recorded_by")
+	case events.TypePaymentRefunded:
+		if metadata(payment, "refund_fraudulent") != "true" {
+			return Outcome{}, false
+		}
+		outcome.Source = SourceRefund
+		outcome.Label = LabelFraud
+		outcome.Actor = metadata(payment, "refund_requested_by")
+	case events.TypePaymentFraudReported:
+		outcome.Source = SourceMerchantReport
+		outcome.Label = Label(strings.ToUpper(metadata(payment, "fraud_report_label")))
+		if outcome.Label != LabelFraud && outcome.Label != LabelLegitimate {
+			return Outcome{}, false
+		}
+		outcome.Actor = metadata(payment, "fraud_reported_by")
+	default:
+		return Outcome{}, false
+	}
+	if outcome.Actor == "" {
+		outcome.Actor = event.Producer
+	}
+	return outcome, true
+}
+
+// metadata returns a string payment metadata value
+func metadata(payment models.Payment, key string) string {
+	value, _ := payment.Metadata[key].(string)
+	return value
+}
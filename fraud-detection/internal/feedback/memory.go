@@ -0,0 +1,61 @@
+package feedback
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore is an in-process Store, used in mock mode. It does not see
+// screened payments, so its rates are the share of labeled payments whose
+// latest label is fraudulent.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	outcomes []Outcome
+}
+
+// NewMemoryStore creates a new, empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Record labels a payment, unless the same outcome is already recorded
+func (s *MemoryStore) Record(ctx context.Context, outcome Outcome) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, recorded := range s.outcomes {
+		if recorded.PaymentID == outcome.PaymentID && recorded.Source == outcome.Source &&
+			recorded.Label == outcome.Label && recorded.At.Equal(outcome.At) {
+			return nil
+		}
+	}
+	s.outcomes = append(s.outcomes, outcome)
+	return nil
+}
+
+// Rates returns the fraud rates of the merchants with labeled payments
+func (s *MemoryStore) Rates(ctx context.Context) (map[string]Rate, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	latest := make(map[uuid.UUID]Outcome)
+	for _, outcome := range s.outcomes {
+		if previous, ok := latest[outcome.PaymentID]; !ok || !outcome.At.Before(previous.At) {
+			latest[outcome.PaymentID] = outcome
+		}
+	}
+	rates := make(map[string]Rate)
+	for _, outcome := range latest {
+		merchantID := outcome.MerchantID.String()
+		rate := rates[merchantID]
+		rate.Payments++
+		if outcome.Label == LabelFraud {
+			rate.FraudPayments++
+		}
+		rate.Rate = float64(rate.FraudPayments) / float64(rate.Payments)
+		rates[merchantID] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,62 @@
+package feedback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PostgresStore is a Store backed by the fraud_labels table, shared with the
+// gateway that records review labels
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgresStore
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Record labels a payment, unless the same outcome is already recorded
+func (s *PostgresStore) Record(ctx context.Context, outcome Outcome) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO fraud_labels (payment_id, label, source, actor, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (payment_id, source, label, created_at) DO NOTHING`,
+		outcome.PaymentID,
+		outcome.Label,
+		outcome.Source,
+		outcome.Actor,
+		outcome.At,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record fraud label: %w", err)
+	}
+	return nil
+}
+
+// Rates returns the fraud rates of the merchants with payments screened in the last 90 days
+func (s *PostgresStore) Rates(ctx context.Context) (map[string]Rate, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT merchant_id, payments, fraud_payments, fraud_rate FROM merchant_fraud_rates`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant fraud rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := make(map[string]Rate)
+	for rows.Next() {
+		var (
+			merchantID string
+			rate       Rate
+		)
+		if err := rows.Scan(&merchantID, &rate.Payments, &rate.FraudPayments, &rate.Rate); err != nil {
+			return nil, fmt.Errorf("failed to scan merchant fraud rate: %w", err)
+		}
+		rates[merchantID] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get merchant fraud rates: %w", err)
+	}
+	return rates, nil
+}
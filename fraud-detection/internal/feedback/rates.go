@@ -0,0 +1,59 @@
+package feedback
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Rates keeps the merchants' fraud rates of a store, refreshed periodically
+// so payments can be screened without querying them
+type Rates struct {
+	store   Store
+	current atomic.Pointer[map[string]Rate]
+}
+
+// NewRates creates Rates over a store. They are empty until refreshed.
+func NewRates(store Store) *Rates {
+	return &Rates{store: store}
+}
+
+// Get returns a merchant's fraud rate, or false if the merchant has no
+// screened payments
+func (r *Rates) Get(merchantID string) (Rate, bool) {
+	rates := r.current.Load()
+	if rates == nil {
+		return Rate{}, false
+	}
+	rate, ok := (*rates)[merchantID]
+	return rate, ok
+}
+
+// Refresh reloads the rates from the store
+func (r *Rates) Refresh(ctx context.Context) error {
+	rates, err := r.store.Rates(ctx)
+	if err != nil {
+		return err
+	}
+	r.current.Store(&rates)
+	return nil
+}
+
+// Run refreshes the rates every interval until the context is done. Failed
+// refreshes are logged and the previous rates stay in effect.
+func (r *Rates) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to refresh merchant fraud rates: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
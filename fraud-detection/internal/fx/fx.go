@@ -0,0 +1,125 @@
+// Package fx converts payment amounts into a reference currency, so amount
+// rules and velocity sums compare payments in different currencies like with
+// like.
+//
+// Rates are read from a YAML file giving the value of one unit of each
+// currency in the reference currency:
+//
+//	reference: USD
+//	rates:
+//	  EUR: 1.08
+//	  INR: 0.012
+//	  JPY: 0.0067
+//
+// The rates only need to be accurate enough for fraud screening, so they are
+// loaded once rather than tracked live.
+package fx
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rates converts amounts into a reference currency
+type Rates struct {
+	reference string
+	rates     map[string]float64
+}
+
+// defaultRates are approximate values of common currencies in USD
+var defaultRates = map[string]float64{
+	"AED": 0.27,
+	"AUD": 0.66,
+	"BHD": 2.65,
+	"BRL": 0.18,
+	"CAD": 0.73,
+	"CHF": 1.13,
+	"CNY": 0.14,
+	"EUR": 1.08,
+	"GBP": 1.27,
+	"HKD": 0.13,
+	"IDR": 0.000062,
+	"INR": 0.012,
+	"JPY": 0.0067,
+	"KRW": 0.00073,
+	"KWD": 3.25,
+	"MXN": 0.055,
+	"MYR": 0.22,
+	"NZD": 0.6,
+	"OMR": 2.6,
+	"SAR": 0.27,
+	"SGD": 0.74,
+	"THB": 0.028,
+	"ZAR": 0.054,
+}
+
+// New creates rates converting into reference, with the value of one unit of
+// each currency in it. The reference currency itself need not be listed.
+func New(reference string, rates map[string]float64) (*Rates, error) {
+	reference = strings.ToUpper(reference)
+	if len(reference) != 3 {
+		return nil, fmt.Errorf("invalid reference currency %q", reference)
+	}
+	r := &Rates{reference: reference, rates: map[string]float64{reference: 1}}
+	for currency, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("rate of %s must be positive, got %g", currency, rate)
+		}
+		currency = strings.ToUpper(currency)
+		if currency == reference && rate != 1 {
+			return nil, fmt.Errorf("rate of the reference currency %s must be 1, got %g", currency, rate)
+		}
+		r.rates[currency] = rate
+	}
+	return r, nil
+}
+
+// Default returns approximate rates of common currencies in USD
+func Default() *Rates {
+	r, err := New("USD", defaultRates)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// file is the layout of a rates file
+type file struct {
+	Reference string             `yaml:"reference"`
+	Rates     map[string]float64 `yaml:"rates"`
+}
+
+// LoadFile reads rates from a YAML file
+func LoadFile(path string) (*Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r, err := New(f.Reference, f.Rates)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Reference returns the currency amounts are converted into
+func (r *Rates) Reference() string {
+	return r.reference
+}
+
+// Convert returns an amount in currency in the reference currency, or false
+// if there is no rate for the currency
+func (r *Rates) Convert(amount float64, currency string) (float64, bool) {
+	rate, ok := r.rates[strings.ToUpper(currency)]
+	if !ok {
+		return 0, false
+	}
+	return amount * rate, true
+}
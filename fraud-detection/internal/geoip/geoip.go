@@ -0,0 +1,145 @@
+// Package geoip resolves the country, network and anonymity of IP addresses
+// from local MaxMind-format database files, such as GeoLite2-Country,
+// GeoLite2-ASN and GeoIP2-Anonymous-IP.
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is what the databases know about an IP address. Country is empty
+// and ASN zero when unknown; the anonymity flags are only meaningful when
+// AnonymityKnown is set.
+type Location struct {
+	Country          string
+	ASN              uint
+	Organization     string
+	Private          bool
+	AnonymityKnown   bool
+	Anonymous        bool
+	AnonymousVPN     bool
+	Hosting          bool
+	PublicProxy      bool
+	ResidentialProxy bool
+	TorExitNode      bool
+}
+
+// Resolver looks up IP addresses
+type Resolver interface {
+	Lookup(ip net.IP) (Location, error)
+}
+
+// countryRecord is the part of a country or city database record that is used
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// asnRecord is an ASN database record
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// anonymousRecord is an anonymous IP database record
+type anonymousRecord struct {
+	Anonymous        bool `maxminddb:"is_anonymous"`
+	AnonymousVPN     bool `maxminddb:"is_anonymous_vpn"`
+	Hosting          bool `maxminddb:"is_hosting_provider"`
+	PublicProxy      bool `maxminddb:"is_public_proxy"`
+	ResidentialProxy bool `maxminddb:"is_residential_proxy"`
+	TorExitNode      bool `maxminddb:"is_tor_exit_node"`
+}
+
+// Database is a Resolver reading memory-mapped database files. Each of the
+// country, ASN and anonymous IP databases is optional.
+type Database struct {
+	country   *maxminddb.Reader
+	asn       *maxminddb.Reader
+	anonymous *maxminddb.Reader
+}
+
+// Open opens the database files at the given paths, skipping empty paths
+func Open(countryPath, asnPath, anonymousPath string) (*Database, error) {
+	d := &Database{}
+	for _, db := range []struct {
+		path   string
+		reader **maxminddb.Reader
+	}{
+		{countryPath, &d.country},
+		{asnPath, &d.asn},
+		{anonymousPath, &d.anonymous},
+	} {
+		if db.path == "" {
+			continue
+		}
+		reader, err := maxminddb.Open(db.path)
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("failed to open GeoIP database %s: %w", db.path, err)
+		}
+		*db.reader = reader
+	}
+	return d, nil
+}
+
+// Close closes the database files
+func (d *Database) Close() error {
+	var errs []error
+	for _, reader := range []*maxminddb.Reader{d.country, d.asn, d.anonymous} {
+		if reader != nil {
+			errs = append(errs, reader.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Lookup resolves an IP address. Private and loopback addresses are not
+// looked up.
+func (d *Database) Lookup(ip net.IP) (Location, error) {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+		return Location{Private: true}, nil
+	}
+
+	var location Location
+	if d.country != nil {
+		var record countryRecord
+		if err := d.country.Lookup(ip, &record); err != nil {
+			return Location{}, fmt.Errorf("failed to look up country of %s: %w", ip, err)
+		}
+		location.Country = record.Country.ISOCode
+		if location.Country == "" {
+			location.Country = record.RegisteredCountry.ISOCode
+		}
+	}
+	if d.asn != nil {
+		var record asnRecord
+		if err := d.asn.Lookup(ip, &record); err != nil {
+			return Location{}, fmt.Errorf("failed to look up network of %s: %w", ip, err)
+		}
+		location.ASN = record.Number
+		location.Organization = record.Organization
+	}
+	if d.anonymous != nil {
+		var record anonymousRecord
+		if err := d.anonymous.Lookup(ip, &record); err != nil {
+			return Location{}, fmt.Errorf("failed to look up anonymity of %s: %w", ip, err)
+		}
+		location.AnonymityKnown = true
+		location.Anonymous = record.Anonymous
+		location.AnonymousVPN = record.AnonymousVPN
+		location.Hosting = record.Hosting
+		location.PublicProxy = record.PublicProxy
+		location.ResidentialProxy = record.ResidentialProxy
+		location.TorExitNode = record.TorExitNode
+	}
+	return location, nil
+}
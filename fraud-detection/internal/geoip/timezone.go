@@ -0,0 +1,68 @@
+package geoip
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+// zone.tab and zone1970.tab are copied from the IANA time zone database. Both
+// map time zone names to the countries they are used in: zone.tab has a zone
+// for every country, zone1970.tab lists every country sharing a zone.
+var (
+	//go:embed zone.tab
+	zoneTab string
+	//go:embed zone1970.tab
+	zone1970Tab string
+
+	timezonesOnce sync.Once
+	timezones     map[string][]string
+)
+
+// TimezoneCountries returns the ISO 3166-1 alpha-2 codes of the countries
+// that use an IANA time zone, such as Europe/Berlin, or nil when the zone is
+// not tied to a country, like UTC, or unknown
+func TimezoneCountries(name string) []string {
+	timezonesOnce.Do(func() {
+		timezones = make(map[string][]string)
+		parseZoneTab(zoneTab)
+		parseZoneTab(zone1970Tab)
+	})
+	return timezones[name]
+}
+
+// TimezoneInCountry reports whether an IANA time zone is used in a country.
+// ok is false when the countries of the zone are unknown.
+func TimezoneInCountry(name, country string) (in bool, ok bool) {
+	countries := TimezoneCountries(name)
+	for _, c := range countries {
+		if c == country {
+			return true, true
+		}
+	}
+	return false, len(countries) > 0
+}
+
+// parseZoneTab adds the zones of a zone table, whose lines are tab-separated
+// country codes, coordinates and zone name
+func parseZoneTab(table string) {
+	for _, line := range strings.Split(table, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			continue
+		}
+		zone := fields[2]
+		for _, country := range strings.Split(fields[0], ",") {
+			known := false
+			for _, c := range timezones[zone] {
+				known = known || c == country
+			}
+			if !known {
+				timezones[zone] = append(timezones[zone], country)
+			}
+		}
+	}
+}
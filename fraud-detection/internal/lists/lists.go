@@ -0,0 +1,146 @@
+// Package lists matches payments against the fraud blocklists and allowlists.
+//
+// An entry blocks or trusts a card fingerprint, email, UPI VPA, IP address,
+// device or customer, either for one merchant or for all of them. Entries are
+// managed through the gateway; fraud detection only reads them, except that
+// it blocklists the card, email, VPA and device of payments confirmed
+// fraudulent by a chargeback, a refund or the merchant's report.
+package lists
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// List is a list consulted before a payment is scored
+type List string
+
+// Lists
+const (
+	ListBlock List = "BLOCK"
+	ListAllow List = "ALLOW"
+)
+
+// Type is what an entry matches
+type Type string
+
+// Types
+const (
+	TypeCard     Type = "CARD"
+	TypeEmail    Type = "EMAIL"
+	TypeVPA      Type = "VPA"
+	TypeIP       Type = "IP"
+	TypeDevice   Type = "DEVICE"
+	TypeCustomer Type = "CUSTOMER"
+)
+
+// types holds the known types
+var types = map[Type]bool{
+	TypeCard:     true,
+	TypeEmail:    true,
+	TypeVPA:      true,
+	TypeIP:       true,
+	TypeDevice:   true,
+	TypeCustomer: true,
+}
+
+// Source is where an entry came from
+type Source string
+
+// Sources
+const (
+	SourceManual         Source = "MANUAL"
+	SourceChargeback     Source = "CHARGEBACK"
+	SourceRefund         Source = "REFUND"
+	SourceMerchantReport Source = "MERCHANT_REPORT"
+)
+
+// Value is a value of a payment that entries can match
+type Value struct {
+	Type  Type
+	Value string
+}
+
+// Entry is a list entry. MerchantID is empty for entries that apply to every
+// merchant; ExpiresAt is nil for entries that apply until removed.
+type Entry struct {
+	ID         string
+	List       List
+	Type       Type
+	Value      string
+	MerchantID string
+	Reason     string
+	Source     Source
+	CreatedBy  string
+	ExpiresAt  *time.Time
+}
+
+// Scope describes whom an entry applies to
+func (e Entry) Scope() string {
+	if e.MerchantID == "" {
+		return "global"
+	}
+	return "merchant"
+}
+
+// Store holds the list entries
+type Store interface {
+	// Match returns the entries that apply to a merchant's payments, have
+	// not expired and match any of the values
+	Match(ctx context.Context, merchantID string, values []Value) ([]Entry, error)
+
+	// Add adds entries and records them in the audit trail. An entry that is
+	// already listed for the same scope is renewed.
+	Add(ctx context.Context, entries ...Entry) error
+}
+
+// ChargebackPolicy is what a payment confirmed fraudulent, by a chargeback or
+// otherwise, adds to the blocklist: the payment values of the given types,
+// for the payment's merchant or, if Global, for all merchants, expiring after
+// TTL unless it is zero
+type ChargebackPolicy struct {
+	Types  []Type
+	TTL    time.Duration
+	Global bool
+}
+
+// ParseTypes parses entry type names
+func ParseTypes(names []string) ([]Type, error) {
+	parsed := make([]Type, 0, len(names))
+	for _, name := range names {
+		t := Type(strings.ToUpper(strings.TrimSpace(name)))
+		if !types[t] {
+			return nil, fmt.Errorf("unknown list entry type %q", name)
+		}
+		parsed = append(parsed, t)
+	}
+	return parsed, nil
+}
+
+// Normalize returns the form a value of the given type is listed and matched
+// in, the same as the gateway uses, and false if it is not a valid value
+func Normalize(t Type, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	switch t {
+	case TypeEmail, TypeVPA:
+		value = strings.ToLower(value)
+	case TypeIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", false
+		}
+		value = ip.String()
+	case TypeCustomer:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return "", false
+		}
+		value = id.String()
+	}
+	return value, value != ""
+}
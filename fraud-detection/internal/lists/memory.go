@@ -0,0 +1,69 @@
+package lists
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore is an in-process Store, used in mock mode. It has no audit trail.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	entries []Entry
+}
+
+// NewMemoryStore creates a new, empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Match returns the live entries that apply to a merchant's payments and match any of the values
+func (s *MemoryStore) Match(ctx context.Context, merchantID string, values []Value) ([]Entry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	var matched []Entry
+	for _, entry := range s.entries {
+		if entry.ExpiresAt != nil && !entry.ExpiresAt.After(now) {
+			continue
+		}
+		if entry.MerchantID != "" && entry.MerchantID != merchantID {
+			continue
+		}
+		for _, v := range values {
+			if entry.Type == v.Type && entry.Value == v.Value {
+				matched = append(matched, entry)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// Add adds entries, renewing those already listed
+func (s *MemoryStore) Add(ctx context.Context, entries ...Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, entry := range entries {
+		renewed := false
+		for i, listed := range s.entries {
+			if listed.List == entry.List && listed.Type == entry.Type && listed.Value == entry.Value && listed.MerchantID == entry.MerchantID {
+				s.entries[i].ExpiresAt = entry.ExpiresAt
+				if entry.Reason != "" {
+					s.entries[i].Reason = entry.Reason
+				}
+				renewed = true
+				break
+			}
+		}
+		if !renewed {
+			entry.ID = uuid.NewString()
+			s.entries = append(s.entries, entry)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package lists
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// PostgresStore is a Store backed by the fraud_list_entries table, shared
+// with the gateway that manages the lists
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgresStore
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Match returns the live entries that apply to a merchant's payments and match any of the values
+func (s *PostgresStore) Match(ctx context.Context, merchantID string, values []Value) ([]Entry, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	entryTypes := make([]string, 0, len(values))
+	entryValues := make([]string, 0, len(values))
+	for _, v := range values {
+		entryTypes = append(entryTypes, string(v.Type))
+		entryValues = append(entryValues, v.Value)
+	}
+
+	query := `
+        SELECT id, list, entry_type, value, merchant_id, reason, source, created_by, expires_at
+        FROM fraud_list_entries
+        WHERE removed_at IS NULL
+            AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+            AND (merchant_id IS NULL OR merchant_id = $1)
+            AND (entry_type, value) IN (SELECT * FROM unnest($2::text[], $3::text[]))
+    `
+	rows, err := s.db.QueryContext(ctx, query, merchantID, pq.Array(entryTypes), pq.Array(entryValues))
+	if err != nil {
+		return nil, fmt.Errorf("failed to match list entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var (
+			entry            Entry
+			merchant, reason sql.NullString
+			expiresAt        sql.NullTime
+		)
+		err := rows.Scan(&entry.ID, &entry.List, &entry.Type, &entry.Value, &merchant, &reason, &entry.Source, &entry.CreatedBy, &expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan list entry: %w", err)
+		}
+		entry.MerchantID = merchant.String
+		entry.Reason = reason.String
+		if expiresAt.Valid {
+			entry.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to match list entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Add adds entries, renewing those already listed, and records them in the
+// audit trail in a single transaction
+func (s *PostgresStore) Add(ctx context.Context, entries ...Entry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		reason := sql.NullString{String: entry.Reason, Valid: entry.Reason != ""}
+		var id string
+		err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO fraud_list_entries (
+                list, entry_type, value, merchant_id, reason, source, created_by, expires_at
+            ) VALUES (
+                $1, $2, $3, $4, $5, $6, $7, $8
+            )
+            ON CONFLICT (list, entry_type, value, (COALESCE(merchant_id, '00000000-0000-0000-0000-000000000000'::uuid)))
+                WHERE removed_at IS NULL
+            DO UPDATE SET reason = COALESCE(EXCLUDED.reason, fraud_list_entries.reason),
+                expires_at = EXCLUDED.expires_at, updated_at = CURRENT_TIMESTAMP
+            RETURNING id`,
+			entry.List,
+			entry.Type,
+			entry.Value,
+			sql.NullString{String: entry.MerchantID, Valid: entry.MerchantID != ""},
+			reason,
+			entry.Source,
+			entry.CreatedBy,
+			entry.ExpiresAt,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to add list entry: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO fraud_list_audit (entry_id, action, actor, reason, expires_at) VALUES ($1, 'ADDED', $2, $3, $4)`,
+			id,
+			entry.CreatedBy,
+			reason,
+			entry.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record list entry audit: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit list entries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package ml
+
+import (
+	"math"
+	"strings"
+)
+
+// categorical are the string attributes that are one-hot encoded, as
+// name=value features such as payment_method=UPI
+var categorical = []string{
+	"payment_method",
+	"currency",
+	"billing_country",
+	"card_issuing_country",
+	"ip.country",
+}
+
+// Extract builds the feature vector of a payment from its rule attributes.
+// Numeric attributes, such as amount, velocity.card.1h.count or
+// device.customers, are features as they are, and boolean ones, such as
+// device.new or ip.hosting, are 1 or 0. Categorical attributes are one-hot
+// encoded. Derived features are:
+//
+//	amount_log                    log(1 + amount)
+//	billing_card_country_match    1 if the billing and card countries agree
+//	ip_billing_country_match      1 if the IP and billing countries agree
+//
+// Attributes that are unknown for a payment leave their features out, so
+// models can tell missing values apart from zeros.
+func Extract(attributes map[string]interface{}) Features {
+	features := make(Features, len(attributes)+4)
+	for name, value := range attributes {
+		switch v := value.(type) {
+		case float64:
+			features[name] = v
+		case bool:
+			features[name] = boolFeature(v)
+		}
+	}
+	for _, name := range categorical {
+		if value, ok := attributes[name].(string); ok && value != "" {
+			features[name+"="+strings.ToUpper(value)] = 1
+		}
+	}
+
+	if amount, ok := attributes["amount"].(float64); ok && amount >= 0 {
+		features["amount_log"] = math.Log1p(amount)
+	}
+	billing, _ := attributes["billing_country"].(string)
+	card, _ := attributes["card_issuing_country"].(string)
+	ip, _ := attributes["ip.country"].(string)
+	if billing != "" && card != "" {
+		features["billing_card_country_match"] = boolFeature(billing == card)
+	}
+	if billing != "" && ip != "" {
+		features["ip_billing_country_match"] = boolFeature(billing == ip)
+	}
+	return features
+}
+
+// boolFeature encodes a boolean as 1 or 0
+func boolFeature(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
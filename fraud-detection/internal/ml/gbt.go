@@ -0,0 +1,125 @@
+package ml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// GBT is a gradient-boosted tree ensemble for binary classification
+type GBT struct {
+	BaseMargin float64 `json:"base_margin"`
+	Trees      []*Node `json:"trees"`
+
+	meta header
+	// trees holds each tree's nodes by ID
+	trees []map[int]*Node
+}
+
+// Node is a split or a leaf of a tree, in XGBoost's JSON dump layout. A node
+// with children is a split; any other node is a leaf.
+type Node struct {
+	ID             int      `json:"nodeid"`
+	Split          string   `json:"split"`
+	SplitCondition float64  `json:"split_condition"`
+	Yes            int      `json:"yes"`
+	No             int      `json:"no"`
+	Missing        *int     `json:"missing"`
+	Children       []*Node  `json:"children"`
+	Leaf           *float64 `json:"leaf"`
+}
+
+// parseGBT reads a gradient-boosted tree ensemble and checks that every
+// split leads to nodes of its tree
+func parseGBT(h header, data []byte) (*GBT, error) {
+	model := &GBT{}
+	if err := json.Unmarshal(data, model); err != nil {
+		return nil, fmt.Errorf("failed to parse gbt model: %w", err)
+	}
+	model.meta = h
+	if len(model.Trees) == 0 {
+		return nil, errors.New("gbt model without trees")
+	}
+
+	var errs []error
+	for i, root := range model.Trees {
+		if root == nil {
+			errs = append(errs, fmt.Errorf("tree %d: empty", i))
+			continue
+		}
+		nodes := make(map[int]*Node)
+		indexNodes(root, nodes)
+		for _, node := range nodes {
+			if len(node.Children) == 0 {
+				if node.Leaf == nil {
+					errs = append(errs, fmt.Errorf("tree %d: node %d is neither a split nor a leaf", i, node.ID))
+				}
+				continue
+			}
+			if node.Split == "" {
+				errs = append(errs, fmt.Errorf("tree %d: node %d splits on no feature", i, node.ID))
+			}
+			targets := []int{node.Yes, node.No}
+			if node.Missing != nil {
+				targets = append(targets, *node.Missing)
+			}
+			for _, target := range targets {
+				if _, ok := nodes[target]; !ok || target == node.ID {
+					errs = append(errs, fmt.Errorf("tree %d: node %d leads to unknown node %d", i, node.ID, target))
+				}
+			}
+		}
+		model.trees = append(model.trees, nodes)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// indexNodes adds a node and its descendants to nodes
+func indexNodes(node *Node, nodes map[int]*Node) {
+	nodes[node.ID] = node
+	for _, child := range node.Children {
+		if child != nil {
+			indexNodes(child, nodes)
+		}
+	}
+}
+
+// Name returns the name of the model
+func (m *GBT) Name() string {
+	return m.meta.Name
+}
+
+// Version returns the version of the model
+func (m *GBT) Version() string {
+	return m.meta.Version
+}
+
+// Score returns the logistic function of the base margin plus the leaf
+// reached in every tree
+func (m *GBT) Score(features Features) (float64, error) {
+	margin := m.BaseMargin
+	for i, nodes := range m.trees {
+		node := m.Trees[i]
+		// Each step moves to another node of the tree, so a path longer than
+		// the tree has nodes has gone round in a loop
+		for steps := 0; len(node.Children) > 0; steps++ {
+			if steps > len(nodes) {
+				return 0, fmt.Errorf("tree %d loops at node %d", i, node.ID)
+			}
+			value, ok := features[node.Split]
+			next := node.No
+			switch {
+			case !ok && node.Missing != nil:
+				next = *node.Missing
+			case !ok || value < node.SplitCondition:
+				next = node.Yes
+			}
+			node = nodes[next]
+		}
+		margin += *node.Leaf
+	}
+	return sigmoid(margin), nil
+}
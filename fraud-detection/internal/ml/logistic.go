@@ -0,0 +1,50 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Logistic is a logistic regression model. Missing features contribute
+// nothing, as if they were 0.
+type Logistic struct {
+	Intercept float64            `json:"intercept"`
+	Weights   map[string]float64 `json:"weights"`
+
+	meta header
+}
+
+// parseLogistic reads a logistic regression model
+func parseLogistic(h header, data []byte) (*Logistic, error) {
+	model := &Logistic{}
+	if err := json.Unmarshal(data, model); err != nil {
+		return nil, fmt.Errorf("failed to parse logistic model: %w", err)
+	}
+	model.meta = h
+	for feature, weight := range model.Weights {
+		if math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("invalid weight of %s", feature)
+		}
+	}
+	return model, nil
+}
+
+// Name returns the name of the model
+func (m *Logistic) Name() string {
+	return m.meta.Name
+}
+
+// Version returns the version of the model
+func (m *Logistic) Version() string {
+	return m.meta.Version
+}
+
+// Score returns the logistic function of the weighted sum of the features
+func (m *Logistic) Score(features Features) (float64, error) {
+	margin := m.Intercept
+	for feature, weight := range m.Weights {
+		margin += weight * features[feature]
+	}
+	return sigmoid(margin), nil
+}
@@ -0,0 +1,87 @@
+// Package ml scores payments with machine-learning models exported to JSON
+// and evaluated in-process.
+//
+// Models see a payment as a vector of named features, built by Extract from
+// the same attributes fraud rules see. Two model types are supported, chosen
+// by the type field of the model file:
+//
+//	{"type": "logistic", "name": "lr", "version": "2024-06-01",
+//	 "intercept": -3.2, "weights": {"amount_log": 0.4, "device.new": 1.1}}
+//
+//	{"type": "gbt", "name": "gbt", "version": "7", "base_margin": -1.5,
+//	 "trees": [{"nodeid": 0, "split": "velocity.card.1h.count",
+//	            "split_condition": 3, "yes": 1, "no": 2, "missing": 1,
+//	            "children": [{"nodeid": 1, "leaf": -0.2},
+//	                         {"nodeid": 2, "leaf": 0.6}]}]}
+//
+// Gradient-boosted trees use the layout of XGBoost's JSON model dump: a
+// feature below split_condition follows yes, otherwise no, and a missing
+// feature follows missing. Both types return the logistic function of their
+// margin, a fraud probability between 0 and 1.
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Features is a payment's feature vector. Features that could not be
+// computed for a payment are absent.
+type Features map[string]float64
+
+// Model scores feature vectors
+type Model interface {
+	// Name and Version identify the model in fraud checks
+	Name() string
+	Version() string
+
+	// Score returns the probability that a payment is fraudulent
+	Score(features Features) (float64, error)
+}
+
+// header is the part of a model file common to all model types
+type header struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Parse reads a model from its JSON export
+func Parse(data []byte) (Model, error) {
+	var h header
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %w", err)
+	}
+	if h.Name == "" {
+		return nil, fmt.Errorf("model without a name")
+	}
+
+	switch h.Type {
+	case "logistic":
+		return parseLogistic(h, data)
+	case "gbt":
+		return parseGBT(h, data)
+	default:
+		return nil, fmt.Errorf("unknown model type %q", h.Type)
+	}
+}
+
+// Load reads a model from the JSON file at path
+func Load(path string) (Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model: %w", err)
+	}
+	model, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return model, nil
+}
+
+// sigmoid maps a margin to a probability
+func sigmoid(margin float64) float64 {
+	return 1 / (1 + math.Exp(-margin))
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/events"
+)
+
+// PaymentFromEvent converts a payment event payload to a payment
+func PaymentFromEvent(event events.Payment) Payment {
+	payment := Payment{
+		ID:                 event.ID,
+		MerchantID:         event.MerchantID,
+		Amount:             event.Money(),
+		Currency:           event.Currency,
+		Status:             PaymentStatus(event.Status),
+		PaymentMethodID:    event.PaymentMethodID,
+		PaymentMethodType:  PaymentMethod(event.PaymentMethodType),
+		Description:        event.Description,
+		Metadata:           make(map[string]interface{}, len(event.Metadata)),
+		IdempotencyKey:     event.IdempotencyKey,
+		ReferenceID:        event.ReferenceID,
+		ClientIP:           event.ClientIP,
+		BillingCountry:     event.BillingCountry,
+		CardIssuingCountry: event.CardIssuingCountry,
+		CreatedAt:          event.CreatedAt,
+		UpdatedAt:          event.UpdatedAt,
+	}
+	if event.CustomerID != nil {
+		payment.CustomerID = *event.CustomerID
+	}
+	if event.Device != nil {
+		payment.Device = &Device{
+			ID:        event.Device.ID,
+			UserAgent: event.Device.UserAgent,
+			Screen:    event.Device.Screen,
+			Timezone:  event.Device.Timezone,
+		}
+	}
+	if event.Session != nil {
+		payment.Session = &Session{
+			ID:             event.Session.ID,
+			AccountAgeDays: event.Session.AccountAgeDays,
+		}
+	}
+	for key, value := range event.Metadata {
+		payment.Metadata[key] = value
+	}
+	return payment
+}
+
+// Event converts the fraud check to the shared fraud check event payload
+func (c FraudCheck) Event() events.FraudCheck {
+	event := events.FraudCheck{
+		PaymentID:    c.PaymentID,
+		MerchantID:   c.MerchantID,
+		RiskScore:    c.RiskScore,
+		IsFraudulent: c.IsFraudulent,
+		Decision:     string(c.Decision),
+		Reason:       c.Reason,
+		ReasonCodes:  c.ReasonCodes,
+		Checks:       make([]events.FraudCheckItem, 0, len(c.Checks)),
+		ModelScores:  make([]events.ModelScore, 0, len(c.ModelScores)),
+		CreatedAt:    c.CreatedAt,
+	}
+	if c.CustomerID != uuid.Nil {
+		customerID := c.CustomerID
+		event.CustomerID = &customerID
+	}
+	for _, check := range c.Checks {
+		event.Checks = append(event.Checks, events.FraudCheckItem{
+			Type:   check.Type,
+			Score:  check.Score,
+			Weight: check.Weight,
+			Info:   check.Info,
+			Rules:  check.Rules,
+		})
+	}
+	for _, score := range c.ModelScores {
+		event.ModelScores = append(event.ModelScores, events.ModelScore{
+			Model:    score.Model,
+			Version:  score.Version,
+			Score:    score.Score,
+			Shadow:   score.Shadow,
+			Decision: string(score.Decision),
+		})
+	}
+	return event
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/money"
 )
 
 // PaymentStatus represents the status of a payment
@@ -39,7 +40,7 @@ type Payment struct {
 	ID               uuid.UUID      `json:"id"`
 	MerchantID       uuid.UUID      `json:"merchant_id"`
 	CustomerID       uuid.UUID      `json:"customer_id,omitempty"`
-	Amount           float64        `json:"amount"`
+	Amount           money.Decimal  `json:"amount"`
 	Currency         string         `json:"currency"`
 	Status           PaymentStatus  `json:"status"`
 	PaymentMethodID  *uuid.UUID     `json:"payment_method_id,omitempty"`
@@ -48,18 +49,39 @@ type Payment struct {
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 	IdempotencyKey   string         `json:"idempotency_key,omitempty"`
 	ReferenceID      string         `json:"reference_id,omitempty"`
+	ClientIP         string         `json:"client_ip,omitempty"`
+	BillingCountry   string         `json:"billing_country,omitempty"`
+	CardIssuingCountry string       `json:"card_issuing_country,omitempty"`
+	Device           *Device        `json:"device,omitempty"`
+	Session          *Session       `json:"session,omitempty"`
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
 }
 
-// PaymentEvent represents a payment event received from or published to Kafka
-type PaymentEvent struct {
-	ID        uuid.UUID     `json:"id"`
-	Type      string        `json:"type"`
-	Payment   Payment       `json:"payment"`
-	Timestamp time.Time     `json:"timestamp"`
+// Device describes the device a payment was made from
+type Device struct {
+	ID        string `json:"id"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Screen    string `json:"screen,omitempty"`
+	Timezone  string `json:"timezone,omitempty"`
 }
 
+// Session describes the customer session a payment was made in
+type Session struct {
+	ID             string `json:"id,omitempty"`
+	AccountAgeDays *int   `json:"account_age_days,omitempty"`
+}
+
+// FraudDecision is the outcome of screening a payment
+type FraudDecision string
+
+// Fraud decisions
+const (
+	FraudDecisionAllow  FraudDecision = "ALLOW"
+	FraudDecisionReview FraudDecision = "REVIEW"
+	FraudDecisionBlock  FraudDecision = "BLOCK"
+)
+
 // FraudCheck represents a fraud check result
 type FraudCheck struct {
 	PaymentID   uuid.UUID      `json:"payment_id"`
@@ -67,22 +89,30 @@ type FraudCheck struct {
 	CustomerID  uuid.UUID      `json:"customer_id,omitempty"`
 	RiskScore   float64        `json:"risk_score"`
 	IsFraudulent bool          `json:"is_fraudulent"`
+	Decision    FraudDecision  `json:"decision"`
 	Reason      string         `json:"reason,omitempty"`
+	ReasonCodes []string       `json:"reason_codes,omitempty"`
 	Checks      []FraudCheckItem `json:"checks"`
+	ModelScores []ModelScore   `json:"model_scores,omitempty"`
+	Features    map[string]float64 `json:"features,omitempty"`
 	CreatedAt   time.Time      `json:"created_at"`
 }
 
 // FraudCheckItem represents an individual fraud check item
 type FraudCheckItem struct {
-	Type  string  `json:"type"`
-	Score float64 `json:"score"`
-	Info  string  `json:"info,omitempty"`
+	Type   string   `json:"type"`
+	Score  float64  `json:"score"`
+	Weight float64  `json:"weight"`
+	Info   string   `json:"info,omitempty"`
+	Rules  []string `json:"rules,omitempty"`
+} 
+// ModelScore is the score a machine-learning model gave a payment. A shadow
+// model's score did not affect the decision; Decision is the one the payment
+// would have got with the model live.
+type ModelScore struct {
+	Model    string        `json:"model"`
+	Version  string        `json:"version,omitempty"`
+	Score    float64       `json:"score"`
+	Shadow   bool          `json:"shadow"`
+	Decision FraudDecision `json:"decision"`
 }
-
-// FraudEvent represents a fraud check event to be published to Kafka
-type FraudEvent struct {
-	ID        uuid.UUID  `json:"id"`
-	Type      string     `json:"type"`
-	FraudCheck FraudCheck `json:"fraud_check"`
-	Timestamp time.Time  `json:"timestamp"`
-} 
\ No newline at end of file
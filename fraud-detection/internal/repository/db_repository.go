@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yourusername/fortexa/fraud-detection/internal/feedback"
+	"github.com/yourusername/fortexa/fraud-detection/internal/lists"
+	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// DBRepository handles database operations
+type DBRepository struct {
+	db       *sql.DB
+	outbox   *outbox.PostgresStore
+	lists    *lists.PostgresStore
+	feedback *feedback.PostgresStore
+}
+
+// NewDBRepository creates a new database repository
+func NewDBRepository(connectionString string) (*DBRepository, error) {
+	db, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	// Test the connection
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Set connection pool settings
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	return &DBRepository{
+		db:       db,
+		outbox:   outbox.NewPostgresStore(db),
+		lists:    lists.NewPostgresStore(db),
+		feedback: feedback.NewPostgresStore(db),
+	}, nil
+}
+
+// Close closes the database connection
+func (r *DBRepository) Close() error {
+	return r.db.Close()
+}
+
+// Outbox returns the store the outbox relay reads from
+func (r *DBRepository) Outbox() outbox.Store {
+	return r.outbox
+}
+
+// Lists returns the store of the fraud blocklists and allowlists
+func (r *DBRepository) Lists() lists.Store {
+	return r.lists
+}
+
+// Feedback returns the store of the fraud labels of payments
+func (r *DBRepository) Feedback() feedback.Store {
+	return r.feedback
+}
+
+// HasFraudCheck reports whether a fraud check of a payment was recorded
+func (r *DBRepository) HasFraudCheck(ctx context.Context, paymentID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM fraud_checks WHERE payment_id = $1)`, paymentID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up fraud check: %w", err)
+	}
+	return exists, nil
+}
+
+// RecordFraudCheck stores a fraud check, opens a review case if the payment
+// is held for review, and enqueues the messages announcing the check in a
+// single transaction. A payment is recorded only once, so a redelivered
+// payment event neither reopens its case nor republishes its decision.
+func (r *DBRepository) RecordFraudCheck(ctx context.Context, check models.FraudCheck, messages ...outbox.Message) error {
+	checks, err := json.Marshal(check.Checks)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fraud check items: %w", err)
+	}
+	modelScores := []byte("[]")
+	if len(check.ModelScores) > 0 {
+		if modelScores, err = json.Marshal(check.ModelScores); err != nil {
+			return fmt.Errorf("failed to marshal model scores: %w", err)
+		}
+	}
+	features := []byte("{}")
+	if len(check.Features) > 0 {
+		if features, err = json.Marshal(check.Features); err != nil {
+			return fmt.Errorf("failed to marshal features: %w", err)
+		}
+	}
+	var customerID sql.NullString
+	if check.CustomerID != uuid.Nil {
+		customerID = sql.NullString{String: check.CustomerID.String(), Valid: true}
+	}
+	reasonCodes := check.ReasonCodes
+	if reasonCodes == nil {
+		reasonCodes = []string{}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var checkID string
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO fraud_checks (
+            payment_id, merchant_id, customer_id, risk_score, decision, reason,
+            reason_codes, checks, model_scores, features, created_at
+        ) VALUES (
+            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+        )
+        ON CONFLICT (payment_id) DO NOTHING
+        RETURNING id`,
+		check.PaymentID,
+		check.MerchantID,
+		customerID,
+		check.RiskScore,
+		check.Decision,
+		sql.NullString{String: check.Reason, Valid: check.Reason != ""},
+		pq.Array(reasonCodes),
+		string(checks),
+		string(modelScores),
+		string(features),
+		check.CreatedAt,
+	).Scan(&checkID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Fraud check for payment %s already recorded", check.PaymentID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert fraud check: %w", err)
+	}
+
+	if check.Decision == models.FraudDecisionReview {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO fraud_cases (payment_id, fraud_check_id, merchant_id, created_at) VALUES ($1, $2, $3, $4)`,
+			check.PaymentID,
+			checkID,
+			check.MerchantID,
+			check.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to open fraud case: %w", err)
+		}
+	}
+
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fraud check: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/fraud-detection/internal/feedback"
+	"github.com/yourusername/fortexa/fraud-detection/internal/lists"
+	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	outbox   *outbox.MemoryStore
+	lists    *lists.MemoryStore
+	feedback *feedback.MemoryStore
+
+	mu     sync.Mutex
+	checks map[uuid.UUID]bool
+}
+
+// NewMockRepository creates a new mock repository for demonstration
+func NewMockRepository() Repository {
+	log.Println("Using mock repository for database operations")
+	return &MockRepository{
+		outbox:   outbox.NewMemoryStore(),
+		lists:    lists.NewMemoryStore(),
+		feedback: feedback.NewMemoryStore(),
+		checks:   make(map[uuid.UUID]bool),
+	}
+}
+
+// RecordFraudCheck mocks storing a fraud check and enqueues its messages in
+// memory, once per payment
+func (r *MockRepository) RecordFraudCheck(ctx context.Context, check models.FraudCheck, messages ...outbox.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.checks[check.PaymentID] {
+		log.Printf("[MOCK] Fraud check for payment %s already recorded", check.PaymentID)
+		return nil
+	}
+	r.checks[check.PaymentID] = true
+	log.Printf("[MOCK] Recorded fraud check for payment %s", check.PaymentID)
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// HasFraudCheck mocks looking up a recorded fraud check in memory
+func (r *MockRepository) HasFraudCheck(ctx context.Context, paymentID uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.checks[paymentID], nil
+}
+
+// Outbox returns the in-memory outbox store
+func (r *MockRepository) Outbox() outbox.Store {
+	return r.outbox
+}
+
+// Lists returns the in-memory list store, which starts out empty
+func (r *MockRepository) Lists() lists.Store {
+	return r.lists
+}
+
+// Feedback returns the in-memory label store
+func (r *MockRepository) Feedback() feedback.Store {
+	return r.feedback
+}
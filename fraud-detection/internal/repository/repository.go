@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/fraud-detection/internal/feedback"
+	"github.com/yourusername/fortexa/fraud-detection/internal/lists"
+	"github.com/yourusername/fortexa/fraud-detection/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// Repository defines the interface for database operations
+type Repository interface {
+	// RecordFraudCheck stores the outcome of a fraud check, opening a review
+	// case for a payment held for review, and enqueues the messages announcing
+	// it in the same transaction
+	RecordFraudCheck(ctx context.Context, check models.FraudCheck, messages ...outbox.Message) error
+
+	// HasFraudCheck reports whether a fraud check of a payment was recorded
+	HasFraudCheck(ctx context.Context, paymentID uuid.UUID) (bool, error)
+
+	// Outbox returns the store the outbox relay reads from
+	Outbox() outbox.Store
+
+	// Lists returns the store of the fraud blocklists and allowlists
+	Lists() lists.Store
+
+	// Feedback returns the store of the fraud labels of payments
+	Feedback() feedback.Store
+}
+
+// Ensure DBRepository implements Repository interface
+var _ Repository = (*DBRepository)(nil)
@@ -0,0 +1,624 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Attributes are the values an expression can refer to, keyed by dotted name
+// such as amount, metadata.location or velocity.customer.1h.count. Values are
+// float64, string, bool or nil; names that are not present evaluate to null.
+type Attributes map[string]interface{}
+
+// Expression is a compiled condition over payment attributes. The language
+// supports number, string, boolean and null literals, lists in brackets,
+// arithmetic (+ - * /), comparisons (== != < <= > >=), the in and contains
+// operators, and/or/not (also written && || !) and the functions lower and
+// upper. Comparing null with anything but == and != is false rather than an
+// error, so rules over optional metadata need no guards.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses an expression
+func Compile(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Match evaluates the expression as a condition. A null result does not match.
+func (e *Expression) Match(attributes Attributes) (bool, error) {
+	value, err := e.root.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	default:
+		return false, fmt.Errorf("expression %q is %s, not a condition", e.source, typeName(value))
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators are the symbolic operators, longest first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "(", ")", "[", "]", ","}
+
+// lex splits an expression into tokens
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(source) && source[i+1] >= '0' && source[i+1] <= '9':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			value, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", source[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], value: value})
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(source) && source[end] != source[i] {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			text := source[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(source[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid string %s", source[i:end+1])
+				}
+				text = unquoted
+			}
+			tokens = append(tokens, token{kind: tokenString, text: source[i : end+1], value: text})
+			i = end + 1
+
+		case unicode.IsLetter(c) || c == '_':
+			// Names are dotted paths whose later segments may start with a digit
+			start := i
+			for i < len(source) && (isNameChar(source[i]) || source[i] == '.' && i+1 < len(source) && isNameChar(source[i+1])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i]})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parser is a recursive descent parser; each parse method handles one level
+// of precedence, from or (lowest) to primary expressions (highest)
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return fmt.Errorf("expected %q but found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "in", "contains")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: "-", left: literalNode{0.0}, right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return literalNode{t.value}, nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			fn, ok := functions[t.text]
+			if !ok {
+				return nil, fmt.Errorf("unknown function %s", t.text)
+			}
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%s takes one argument", t.text)
+			}
+			return callNode{name: t.text, fn: fn, arg: args[0]}, nil
+		}
+		return nameNode{t.text}, nil
+
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return listNode{items}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s", t)
+}
+
+// parseList parses comma separated expressions up to the closing token
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	if _, ok := p.accept(closing); ok {
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := p.accept(closing); ok {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// functions are the functions expressions can call
+var functions = map[string]func(interface{}) (interface{}, error){
+	"lower": stringFunction(strings.ToLower),
+	"upper": stringFunction(strings.ToUpper),
+}
+
+func stringFunction(fn func(string) string) func(interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return fn(v), nil
+		default:
+			return nil, fmt.Errorf("expected a string but got %s", typeName(value))
+		}
+	}
+}
+
+type node interface {
+	eval(attributes Attributes) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(Attributes) (interface{}, error) {
+	return n.value, nil
+}
+
+type nameNode struct{ name string }
+
+func (n nameNode) eval(attributes Attributes) (interface{}, error) {
+	return attributes[n.name], nil
+}
+
+type listNode struct{ items []node }
+
+func (n listNode) eval(attributes Attributes) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(attributes)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type callNode struct {
+	name string
+	fn   func(interface{}) (interface{}, error)
+	arg  node
+}
+
+func (n callNode) eval(attributes Attributes) (interface{}, error) {
+	value, err := n.arg.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	result, err := n.fn(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return result, nil
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(attributes Attributes) (interface{}, error) {
+	value, err := evalBool(n.operand, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return !value, nil
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(attributes Attributes) (interface{}, error) {
+	left, err := evalBool(n.left, attributes)
+	if err != nil || !left {
+		return false, err
+	}
+	return evalBool(n.right, attributes)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(attributes Attributes) (interface{}, error) {
+	left, err := evalBool(n.left, attributes)
+	if err != nil || left {
+		return left, err
+	}
+	return evalBool(n.right, attributes)
+}
+
+// evalBool evaluates a node as a condition, treating null as false
+func evalBool(n node, attributes Attributes) (bool, error) {
+	value, err := n.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected a condition but got %s", typeName(value))
+	}
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n binaryNode) eval(attributes Attributes) (interface{}, error) {
+	left, err := n.left.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		list, ok := right.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		for _, item := range list {
+			if equal(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "contains":
+		if list, ok := left.([]interface{}); ok {
+			for _, item := range list {
+				if equal(item, right) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		l, lok := left.(string)
+		r, rok := right.(string)
+		return lok && rok && strings.Contains(l, r), nil
+	}
+
+	if left == nil || right == nil {
+		// Ordering and arithmetic over missing values yield nothing
+		if n.op == "<" || n.op == "<=" || n.op == ">" || n.op == ">=" {
+			return false, nil
+		}
+		return nil, nil
+	}
+
+	if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply %s to string and %s", n.op, typeName(right))
+		}
+		switch n.op {
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "+":
+			return l + r, nil
+		}
+		return nil, fmt.Errorf("cannot apply %s to strings", n.op)
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", n.op, typeName(left), typeName(right))
+	}
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	default:
+		if r == 0 {
+			return nil, nil
+		}
+		return l / r, nil
+	}
+}
+
+// equal compares two values; values of different types are never equal
+func equal(left, right interface{}) bool {
+	switch l := left.(type) {
+	case []interface{}:
+		return false
+	default:
+		if _, ok := right.([]interface{}); ok {
+			return false
+		}
+		return l == right
+	}
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,177 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// payment are the attributes of a payment the tests evaluate expressions over
+var payment = Attributes{
+	"amount":            1500.0,
+	"currency":          "USD",
+	"international":     true,
+	"metadata.location": "Berlin",
+	"tags":              []interface{}{"vip", "repeat"},
+}
+
+func TestCompileRejects(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", "unexpected end of expression"},
+		{"amount >", "unexpected end of expression"},
+		{"amount > 10 10", `unexpected "10"`},
+		{"(amount > 10", `expected ")"`},
+		{"amount > 10)", `unexpected ")"`},
+		{"1 < amount < 10", `unexpected "<"`},
+		{"amount == = 10", "unexpected character '='"},
+		{"currency in [\"USD\" \"EUR\"]", `expected ","`},
+		{"currency == \"USD", "unterminated string"},
+		{"amount > 1.2.3", `invalid number "1.2.3"`},
+		{"amount # 10", `unexpected character '#'`},
+		{"title(currency) == \"Usd\"", "unknown function title"},
+		{"lower(currency, currency) == \"usd\"", "lower takes one argument"},
+		{"lower() == \"usd\"", "lower takes one argument"},
+		{"amount > 10 and", "unexpected end of expression"},
+		{"not", "unexpected end of expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile(%q) error = %v, want %q", tt.source, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		// * and / bind tighter than + and -, which bind tighter than comparisons
+		{"1 + 2 * 3 == 7", true},
+		{"(1 + 2) * 3 == 9", true},
+		{"10 - 4 - 3 == 3", true},
+		{"12 / 3 / 2 == 2", true},
+		{"-2 * 3 == -6", true},
+		{"- -2 == 2", true},
+		{"amount / 3 + 500 > 999", true},
+		// and binds tighter than or
+		{"true or false and false", true},
+		{"(true or false) and false", false},
+		{"false and false or true", true},
+		{"true || false && false", true},
+		// not binds looser than comparisons and tighter than and
+		{"not amount > 2000", true},
+		{"not currency == \"USD\" and amount > 0", false},
+		{"not (currency == \"USD\" and amount > 2000)", true},
+		{"!!international", true},
+		// in and contains compare whole sides
+		{"currency in [\"EU\" + \"R\", \"US\" + \"D\"]", true},
+		{"tags contains \"v\" + \"ip\"", true},
+		{"metadata.location contains \"erl\"", true},
+		{"lower(currency) == \"usd\" and upper(\"usd\") == currency", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := expr.Match(payment)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchUnknownFields(t *testing.T) {
+	// Names that are not present are null: ordering them and arithmetic on
+	// them do not match, and they only equal null
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"metadata.unknown == null", true},
+		{"metadata.unknown != null", false},
+		{"metadata.unknown == \"Berlin\"", false},
+		{"metadata.unknown != \"Berlin\"", true},
+		{"metadata.unknown > 10", false},
+		{"metadata.unknown <= 10", false},
+		{"metadata.unknown", false},
+		{"not metadata.unknown", true},
+		{"metadata.unknown + 1 > 0", false},
+		{"metadata.unknown * 2 == null", true},
+		{"metadata.unknown in [null]", true},
+		{"metadata.unknown contains \"a\"", false},
+		{"lower(metadata.unknown) == null", true},
+		{"amount / 0 == null", true},
+		{"metadata.unknown > 10 or amount > 1000", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := expr.Match(payment)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTypeMismatch(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"currency > 10", "cannot apply > to string and number"},
+		{"amount > \"10\"", "cannot apply > to number and string"},
+		{"currency - \"D\" == \"US\"", "cannot apply - to strings"},
+		{"international + 1 > 0", "cannot apply + to boolean and number"},
+		{"tags > 1", "cannot apply > to list and number"},
+		{"amount and true", "expected a condition but got number"},
+		{"not currency", "expected a condition but got string"},
+		{"amount", `expression "amount" is number, not a condition`},
+		{"amount + 1", `expression "amount + 1" is number, not a condition`},
+		{"lower(amount) == \"1500\"", "lower: expected a string but got number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := expr.Match(payment)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Match() = %t, %v, want error %q", got, err, tt.want)
+			}
+			if got {
+				t.Errorf("Match() = true for an expression that cannot be evaluated")
+			}
+		})
+	}
+
+	// Values of different types are never equal rather than an error
+	for _, source := range []string{"amount == \"1500\"", "currency == 1", "international == \"true\"", "tags == [\"vip\", \"repeat\"]"} {
+		expr, err := Compile(source)
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v", source, err)
+		}
+		if got, err := expr.Match(payment); got || err != nil {
+			t.Errorf("Match(%q) = %t, %v, want false", source, got, err)
+		}
+	}
+}
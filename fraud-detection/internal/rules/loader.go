@@ -0,0 +1,78 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Loader is a Provider that keeps the rule set of a file and reloads it when
+// the file changes, so rules can be edited without restarting the service. A
+// changed file that fails to compile is reported and the previous rules stay
+// in effect.
+type Loader struct {
+	path    string
+	current atomic.Pointer[Set]
+	data    []byte
+}
+
+// NewLoader creates a Loader and compiles the rule file at path
+func NewLoader(path string) (*Loader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	l := &Loader{path: path, data: data}
+	l.current.Store(set)
+	return l, nil
+}
+
+// Rules returns the current rule set
+func (l *Loader) Rules() *Set {
+	return l.current.Load()
+}
+
+// Run checks the rule file for changes every interval until the context is canceled
+func (l *Loader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.reload()
+		}
+	}
+}
+
+// reload compiles the rule file if its content changed
+func (l *Loader) reload() {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		log.Printf("Failed to read rules from %s: %v", l.path, err)
+		return
+	}
+	if bytes.Equal(data, l.data) {
+		return
+	}
+	l.data = data
+
+	set, err := Parse(data)
+	if err != nil {
+		log.Printf("Keeping previous rules, %s is invalid: %v", l.path, err)
+		return
+	}
+	l.current.Store(set)
+	log.Printf("Reloaded rules from %s", l.path)
+}
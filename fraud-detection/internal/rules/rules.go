@@ -0,0 +1,424 @@
+// Package rules evaluates declarative fraud rules over payment attributes.
+//
+// Rules are grouped into checks, each of which produces one score. A check
+// scores as its highest-scoring rule whose condition matches, or as its
+// default when none match. A rule can also force a review or block the
+// payment whatever the overall score. The overall score weighs each check
+// by its weight, 1 unless configured, and is compared with the review and
+// block thresholds of the file, falling back to the service configuration.
+//
+// Merchants can override individual rules or defaults, disable rules, add
+// their own, and set their own weights and thresholds. Rule files are YAML:
+//
+//	thresholds: {review: 0.45, block: 0.7}
+//	weights: {velocity_check: 2}
+//	checks:
+//	  - name: amount_check
+//	    default: {score: 0.1, info: Normal transaction amount}
+//	    rules:
+//	      - name: large_amount
+//	        when: amount > 10000
+//	        score: 0.8
+//	        info: Unusually large transaction amount
+//	      - name: sanctioned_currency
+//	        when: currency in ["XYZ"]
+//	        score: 1
+//	        action: block
+//	        info: Currency is not accepted
+//	merchants:
+//	  6f1c...:
+//	    thresholds: {review: 0.3, block: 0.6}
+//	    weights: {amount_check: 0.5}
+//	    checks:
+//	      - name: amount_check
+//	        rules:
+//	          - name: large_amount
+//	            when: amount > 50000
+//
+// Conditions use the expression language described on Expression.
+package rules
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_rules.yaml
+var defaultRules []byte
+
+// Action is what a fired rule does to the decision regardless of the score
+type Action string
+
+// Actions
+const (
+	ActionNone   Action = ""
+	ActionReview Action = "review"
+	ActionBlock  Action = "block"
+)
+
+// actionRanks orders actions from weakest to strongest
+var actionRanks = map[Action]int{
+	ActionNone:   0,
+	ActionReview: 1,
+	ActionBlock:  2,
+}
+
+// Thresholds are the risk scores above which payments are held for review or blocked
+type Thresholds struct {
+	Review float64 `yaml:"review"`
+	Block  float64 `yaml:"block"`
+}
+
+// validate checks that the thresholds are scores and that review comes before block
+func (t Thresholds) validate() error {
+	if t.Review < 0 || t.Review > 1 || t.Block < 0 || t.Block > 1 {
+		return errors.New("thresholds must be between 0 and 1")
+	}
+	if t.Review > t.Block {
+		return errors.New("review threshold must not be above block threshold")
+	}
+	return nil
+}
+
+// Result is the outcome of one check. Rule is the fired rule that set the
+// score, empty when the default applies. Action is the strongest action of
+// the fired rules and ActionRule the rule that took it.
+type Result struct {
+	Check      string
+	Score      float64
+	Info       string
+	Rule       string
+	Rules      []string
+	Action     Action
+	ActionRule string
+}
+
+// Provider supplies the current rule set
+type Provider interface {
+	Rules() *Set
+}
+
+// Set is a compiled rule set
+type Set struct {
+	global    *policy
+	merchants map[string]*policy
+}
+
+// policy is everything that applies to the payments of a merchant
+type policy struct {
+	checks     []*check
+	weights    map[string]float64
+	thresholds *Thresholds
+}
+
+// check is a compiled check with its rules in file order
+type check struct {
+	name         string
+	defaultScore float64
+	defaultInfo  string
+	rules        []*rule
+}
+
+// rule is a compiled rule
+type rule struct {
+	name   string
+	when   *Expression
+	score  float64
+	info   string
+	action Action
+}
+
+// file is the YAML layout of a rule file
+type file struct {
+	Thresholds *Thresholds             `yaml:"thresholds"`
+	Weights    map[string]float64      `yaml:"weights"`
+	Checks     []checkSpec             `yaml:"checks"`
+	Merchants  map[string]merchantSpec `yaml:"merchants"`
+}
+
+type merchantSpec struct {
+	Thresholds *Thresholds        `yaml:"thresholds"`
+	Weights    map[string]float64 `yaml:"weights"`
+	Checks     []checkSpec        `yaml:"checks"`
+}
+
+type checkSpec struct {
+	Name    string       `yaml:"name"`
+	Default *outcomeSpec `yaml:"default"`
+	Rules   []ruleSpec   `yaml:"rules"`
+}
+
+type outcomeSpec struct {
+	Score float64 `yaml:"score"`
+	Info  string  `yaml:"info"`
+}
+
+type ruleSpec struct {
+	Name     string   `yaml:"name"`
+	When     string   `yaml:"when"`
+	Score    *float64 `yaml:"score"`
+	Info     string   `yaml:"info"`
+	Action   *Action  `yaml:"action"`
+	Disabled bool     `yaml:"disabled"`
+}
+
+// Parse compiles a rule file
+func Parse(data []byte) (*Set, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	var errs []error
+	global, err := mergePolicy(&policy{}, f.Thresholds, f.Weights, f.Checks)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	set := &Set{global: global, merchants: make(map[string]*policy, len(f.Merchants))}
+	for merchantID, merchant := range f.Merchants {
+		p, err := mergePolicy(global, merchant.Thresholds, merchant.Weights, merchant.Checks)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("merchant %s: %w", merchantID, err))
+			continue
+		}
+		set.merchants[merchantID] = p
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// LoadFile compiles the rule file at path
+func LoadFile(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Default returns the built-in rule set, used when no rule file is configured
+func Default() *Set {
+	set, err := Parse(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default rules: %v", err))
+	}
+	return set
+}
+
+// Rules returns the set itself, so a fixed set can be used as a Provider
+func (s *Set) Rules() *Set {
+	return s
+}
+
+// Evaluate runs the checks that apply to a merchant. A rule whose condition
+// cannot be evaluated does not match; the errors are returned alongside the
+// results.
+func (s *Set) Evaluate(merchantID string, attributes Attributes) ([]Result, error) {
+	checks := s.policy(merchantID).checks
+
+	var errs []error
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		result := Result{Check: c.name, Score: c.defaultScore, Info: c.defaultInfo}
+		matched := false
+		for _, r := range c.rules {
+			ok, err := r.when.Match(attributes)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %s.%s: %w", c.name, r.name, err))
+				continue
+			}
+			if !ok {
+				continue
+			}
+			result.Rules = append(result.Rules, r.name)
+			if actionRanks[r.action] > actionRanks[result.Action] {
+				result.Action = r.action
+				result.ActionRule = r.name
+			}
+			if !matched || r.score > result.Score {
+				result.Score = r.score
+				result.Info = r.info
+				result.Rule = r.name
+			}
+			matched = true
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// Weight returns the weight of a check in a merchant's overall score
+func (s *Set) Weight(merchantID, check string) float64 {
+	if weight, ok := s.policy(merchantID).weights[check]; ok {
+		return weight
+	}
+	return 1
+}
+
+// Thresholds returns a merchant's review and block thresholds, and false if
+// the rule file leaves them to the service configuration
+func (s *Set) Thresholds(merchantID string) (Thresholds, bool) {
+	thresholds := s.policy(merchantID).thresholds
+	if thresholds == nil {
+		return Thresholds{}, false
+	}
+	return *thresholds, true
+}
+
+// policy returns the policy of a merchant
+func (s *Set) policy(merchantID string) *policy {
+	if p, ok := s.merchants[merchantID]; ok {
+		return p
+	}
+	return s.global
+}
+
+// mergePolicy applies thresholds, weights and check specs on top of base
+func mergePolicy(base *policy, thresholds *Thresholds, weights map[string]float64, specs []checkSpec) (*policy, error) {
+	var errs []error
+	p := &policy{thresholds: base.thresholds, weights: make(map[string]float64, len(base.weights)+len(weights))}
+	if thresholds != nil {
+		if err := thresholds.validate(); err != nil {
+			errs = append(errs, err)
+		}
+		p.thresholds = thresholds
+	}
+	for check, weight := range base.weights {
+		p.weights[check] = weight
+	}
+	for check, weight := range weights {
+		if weight < 0 {
+			errs = append(errs, fmt.Errorf("weight of %s must not be negative", check))
+		}
+		p.weights[check] = weight
+	}
+
+	checks, err := mergeChecks(base.checks, specs)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	p.checks = checks
+	return p, errors.Join(errs...)
+}
+
+// mergeChecks applies check specs on top of base checks
+func mergeChecks(base []*check, specs []checkSpec) ([]*check, error) {
+	checks := make([]*check, len(base))
+	copy(checks, base)
+
+	var errs []error
+	for _, spec := range specs {
+		index := -1
+		for i, c := range checks {
+			if c.name == spec.Name {
+				index = i
+			}
+		}
+		if index < 0 {
+			c, err := mergeCheck(nil, spec)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			checks = append(checks, c)
+			continue
+		}
+		c, err := mergeCheck(checks[index], spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		checks[index] = c
+	}
+	return checks, errors.Join(errs...)
+}
+
+// mergeCheck compiles a check spec on top of base, which may be nil. Rules
+// named like a base rule replace it, keeping the base's condition, score or
+// info where the spec leaves them out; disabled rules are removed.
+func mergeCheck(base *check, spec checkSpec) (*check, error) {
+	if spec.Name == "" {
+		return nil, errors.New("check without a name")
+	}
+
+	c := &check{name: spec.Name}
+	if base != nil {
+		*c = *base
+		c.rules = make([]*rule, len(base.rules))
+		copy(c.rules, base.rules)
+	}
+	if spec.Default != nil {
+		c.defaultScore = spec.Default.Score
+		c.defaultInfo = spec.Default.Info
+	}
+
+	var errs []error
+	for _, rs := range spec.Rules {
+		if rs.Name == "" {
+			errs = append(errs, fmt.Errorf("check %s: rule without a name", c.name))
+			continue
+		}
+
+		index := -1
+		for i, r := range c.rules {
+			if r.name == rs.Name {
+				index = i
+			}
+		}
+		if rs.Disabled {
+			if index >= 0 {
+				c.rules = append(c.rules[:index], c.rules[index+1:]...)
+			}
+			continue
+		}
+
+		r := &rule{name: rs.Name}
+		if index >= 0 {
+			*r = *c.rules[index]
+		}
+		if rs.When != "" {
+			when, err := Compile(rs.When)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %s.%s: %w", c.name, rs.Name, err))
+				continue
+			}
+			r.when = when
+		}
+		if rs.Score != nil {
+			r.score = *rs.Score
+		}
+		if rs.Info != "" {
+			r.info = rs.Info
+		}
+		if rs.Action != nil {
+			r.action = *rs.Action
+		}
+
+		switch {
+		case r.when == nil:
+			errs = append(errs, fmt.Errorf("rule %s.%s: missing condition", c.name, rs.Name))
+		case r.score < 0 || r.score > 1:
+			errs = append(errs, fmt.Errorf("rule %s.%s: score must be between 0 and 1", c.name, rs.Name))
+		case r.action != ActionNone && r.action != ActionReview && r.action != ActionBlock:
+			errs = append(errs, fmt.Errorf("rule %s.%s: action must be review or block", c.name, rs.Name))
+		case index >= 0:
+			c.rules[index] = r
+		default:
+			c.rules = append(c.rules, r)
+		}
+	}
+	if c.defaultScore < 0 || c.defaultScore > 1 {
+		errs = append(errs, fmt.Errorf("check %s: default score must be between 0 and 1", c.name))
+	}
+	return c, errors.Join(errs...)
+}
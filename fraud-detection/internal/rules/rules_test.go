@@ -0,0 +1,66 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRejectsInvalidConditions(t *testing.T) {
+	tests := []struct {
+		name string
+		when string
+		want string
+	}{
+		{"bad syntax", "amount > ", "rule amount_check.large_amount: invalid expression"},
+		{"unknown function", "round(amount) > 10", "unknown function round"},
+		{"unbalanced parentheses", "(amount > 10", `expected ")"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "checks: [{name: amount_check, rules: [{name: large_amount, when: '" + tt.when + "', score: 0.8}]}]"
+			_, err := Parse([]byte(data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateReportsMismatchedRules(t *testing.T) {
+	set, err := Parse([]byte(`
+checks:
+  - name: amount_check
+    default: {score: 0.1, info: Normal transaction amount}
+    rules:
+      - name: currency_as_number
+        when: currency > 100
+        score: 1
+        action: block
+      - name: large_amount
+        when: amount > 1000
+        score: 0.8
+      - name: unknown_field
+        when: metadata.unknown > 10
+        score: 0.9
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// The mismatched rule does not match and is reported, while the others
+	// are evaluated as usual
+	results, err := set.Evaluate("", payment)
+	if err == nil || !strings.Contains(err.Error(), "rule amount_check.currency_as_number: cannot apply > to string and number") {
+		t.Errorf("Evaluate() error = %v, want the mismatched rule reported", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Evaluate() = %d results, want 1", len(results))
+	}
+	result := results[0]
+	if result.Rule != "large_amount" || result.Score != 0.8 || result.Action != ActionNone {
+		t.Errorf("Evaluate() = rule %q, score %v, action %q, want large_amount, 0.8, none", result.Rule, result.Score, result.Action)
+	}
+	if len(result.Rules) != 1 {
+		t.Errorf("Evaluate() fired %v, want [large_amount]", result.Rules)
+	}
+}
@@ -0,0 +1,152 @@
+package velocity
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// shardCount is the number of independently locked shards of a MemoryStore
+const shardCount = 32
+
+// sweepInterval is how often a shard drops keys that have been idle for the retention period
+const sweepInterval = time.Minute
+
+// MemoryStore is an in-process Store. Keys are spread over shards with their
+// own locks, so workers recording payments of different customers rarely wait
+// for each other. Each fraud-detection instance only sees the payments of the
+// partitions it consumes; use RedisStore to share velocity across instances.
+type MemoryStore struct {
+	shards [shardCount]memoryShard
+
+	seenMutex sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// memoryShard holds the series of a subset of keys
+type memoryShard struct {
+	mutex     sync.Mutex
+	series    map[Key]*series
+	lastSweep time.Time
+}
+
+// series holds the buckets of every window of one key
+type series struct {
+	windows  [][]bucket
+	lastSeen time.Time
+}
+
+// bucket is one slot of a window's ring. index identifies the time span the
+// slot currently holds; a slot is reused once its span has left the window.
+type bucket struct {
+	index int64
+	count int64
+	sum   float64
+}
+
+// NewMemoryStore creates a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{seen: make(map[string]time.Time)}
+	for i := range s.shards {
+		s.shards[i].series = make(map[Key]*series)
+	}
+	return s
+}
+
+// Record adds a payment to every window of each key and returns the velocity
+// of each key and window, including the payment
+func (s *MemoryStore) Record(ctx context.Context, paymentID string, keys []Key, amount float64, at time.Time) ([]Velocity, error) {
+	record := s.markSeen(paymentID)
+
+	velocities := make([]Velocity, 0, len(keys)*len(Windows))
+	for _, key := range keys {
+		velocities = append(velocities, s.shard(key).record(key, record, amount, at)...)
+	}
+	return velocities, nil
+}
+
+// markSeen remembers a payment and reports whether it is new
+func (s *MemoryStore) markSeen(paymentID string) bool {
+	s.seenMutex.Lock()
+	defer s.seenMutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for id, seenAt := range s.seen {
+			if now.Sub(seenAt) > Retention {
+				delete(s.seen, id)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if _, ok := s.seen[paymentID]; ok {
+		return false
+	}
+	s.seen[paymentID] = now
+	return true
+}
+
+// shard returns the shard holding a key
+func (s *MemoryStore) shard(key Key) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key.String()))
+	return &s.shards[h.Sum32()%shardCount]
+}
+
+// record optionally adds a payment to a key's windows and returns their velocity
+func (sh *memoryShard) record(key Key, add bool, amount float64, at time.Time) []Velocity {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(sh.lastSweep) > sweepInterval {
+		for k, ser := range sh.series {
+			if now.Sub(ser.lastSeen) > Retention {
+				delete(sh.series, k)
+			}
+		}
+		sh.lastSweep = now
+	}
+
+	ser, ok := sh.series[key]
+	if !ok {
+		ser = &series{windows: make([][]bucket, len(Windows))}
+		for i, window := range Windows {
+			ser.windows[i] = make([]bucket, window.buckets())
+		}
+		sh.series[key] = ser
+	}
+	ser.lastSeen = now
+
+	velocities := make([]Velocity, 0, len(Windows))
+	for i, window := range Windows {
+		ring := ser.windows[i]
+		current := window.bucketIndex(at)
+
+		if add {
+			slot := &ring[current%int64(len(ring))]
+			switch {
+			case slot.index == current:
+				slot.count++
+				slot.sum += amount
+			case slot.index < current:
+				*slot = bucket{index: current, count: 1, sum: amount}
+			}
+			// A slot already holding a later span means the payment is
+			// older than the window and no longer counts
+		}
+
+		velocity := Velocity{Key: key, Window: window}
+		for _, b := range ring {
+			if b.index > current-int64(len(ring)) && b.index <= current {
+				velocity.Count += b.count
+				velocity.Sum += b.sum
+			}
+		}
+		velocities = append(velocities, velocity)
+	}
+	return velocities
+}
@@ -0,0 +1,113 @@
+package velocity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recordScript records a payment and reads back the velocity of its keys in
+// one atomic step. KEYS[1] marks the payment as recorded; the remaining keys
+// are one hash per key and window, grouped by key in the order of Windows, with
+// a count and a sum field per bucket. ARGV holds the amount, the lifetime of
+// the marker, and for each window the current bucket, the number of buckets
+// and the lifetime of the hash. Buckets that have left the window are deleted.
+var recordScript = redis.NewScript(`
+local record = redis.call('SET', KEYS[1], '1', 'NX', 'EX', ARGV[2])
+local windows = (#ARGV - 2) / 3
+local result = {}
+for i = 2, #KEYS do
+  local w = (i - 2) % windows
+  local current = tonumber(ARGV[3 + w * 3])
+  local buckets = tonumber(ARGV[4 + w * 3])
+  if record then
+    redis.call('HINCRBY', KEYS[i], current .. ':c', 1)
+    redis.call('HINCRBYFLOAT', KEYS[i], current .. ':s', ARGV[1])
+    redis.call('EXPIRE', KEYS[i], ARGV[5 + w * 3])
+  end
+  local fields = redis.call('HGETALL', KEYS[i])
+  local count, sum = 0, 0
+  for j = 1, #fields, 2 do
+    local bucket, kind = string.match(fields[j], '^(%d+):(%a)$')
+    bucket = tonumber(bucket)
+    if bucket <= current - buckets then
+      redis.call('HDEL', KEYS[i], fields[j])
+    elseif bucket <= current then
+      if kind == 'c' then
+        count = count + tonumber(fields[j + 1])
+      else
+        sum = sum + tonumber(fields[j + 1])
+      end
+    end
+  end
+  result[#result + 1] = count
+  result[#result + 1] = tostring(sum)
+end
+return result
+`)
+
+// RedisStore is a Store kept in Redis, so velocity is shared by every
+// fraud-detection instance. All keys of a payment are updated by one script,
+// which requires them to live on one node; it is not meant for Redis Cluster.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a new RedisStore whose keys start with prefix
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Record adds a payment to every window of each key and returns the velocity
+// of each key and window, including the payment
+func (s *RedisStore) Record(ctx context.Context, paymentID string, keys []Key, amount float64, at time.Time) ([]Velocity, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	redisKeys := make([]string, 0, 1+len(keys)*len(Windows))
+	redisKeys = append(redisKeys, s.prefix+"payment:"+paymentID)
+	for _, key := range keys {
+		for _, window := range Windows {
+			redisKeys = append(redisKeys, s.prefix+key.String()+":"+window.Name)
+		}
+	}
+
+	args := make([]interface{}, 0, 2+len(Windows)*3)
+	args = append(args, strconv.FormatFloat(amount, 'f', -1, 64), int64(Retention/time.Second))
+	for _, window := range Windows {
+		// Keep a hash for one bucket longer than its window so the oldest
+		// bucket is still there to be read
+		ttl := (window.Length + window.Bucket) / time.Second
+		args = append(args, window.bucketIndex(at), window.buckets(), int64(ttl))
+	}
+
+	values, err := recordScript.Run(ctx, s.client, redisKeys, args...).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record payment velocity: %w", err)
+	}
+	if len(values) != len(keys)*len(Windows)*2 {
+		return nil, fmt.Errorf("failed to record payment velocity: unexpected reply of %d values", len(values))
+	}
+
+	velocities := make([]Velocity, 0, len(keys)*len(Windows))
+	for i, key := range keys {
+		for j, window := range Windows {
+			offset := (i*len(Windows) + j) * 2
+			count, _ := values[offset].(int64)
+			sum, err := strconv.ParseFloat(fmt.Sprint(values[offset+1]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse velocity sum of %s: %w", key, err)
+			}
+			velocities = append(velocities, Velocity{Key: key, Window: window, Count: count, Sum: sum})
+		}
+	}
+	return velocities, nil
+}
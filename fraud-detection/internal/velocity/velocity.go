@@ -0,0 +1,181 @@
+// Package velocity keeps sliding-window counts and sums of payments per
+// customer, card, IP address, device and merchant.
+//
+// Each window is tracked as a ring of fixed-size time buckets, so a window
+// slides one bucket at a time: the 1 hour window, for example, covers the
+// current minute and the 59 before it. Stores are safe for concurrent use and
+// count each payment once, however often it is recorded.
+package velocity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dimension is what payments are grouped by
+type Dimension string
+
+// Dimensions
+const (
+	DimensionCustomer Dimension = "customer"
+	DimensionCard     Dimension = "card"
+	DimensionIP       Dimension = "ip"
+	DimensionDevice   Dimension = "device"
+	DimensionMerchant Dimension = "merchant"
+)
+
+// dimensions holds the known dimensions
+var dimensions = map[Dimension]bool{
+	DimensionCustomer: true,
+	DimensionCard:     true,
+	DimensionIP:       true,
+	DimensionDevice:   true,
+	DimensionMerchant: true,
+}
+
+// Window is a sliding time window and the size of the buckets it is tracked in
+type Window struct {
+	Name   string
+	Length time.Duration
+	Bucket time.Duration
+}
+
+// buckets returns the number of buckets in the window
+func (w Window) buckets() int64 {
+	return int64(w.Length / w.Bucket)
+}
+
+// bucketIndex returns the index of the bucket holding t
+func (w Window) bucketIndex(t time.Time) int64 {
+	return t.UnixMilli() / w.Bucket.Milliseconds()
+}
+
+// Windows are the windows tracked for every key
+var Windows = []Window{
+	{Name: "1m", Length: time.Minute, Bucket: 5 * time.Second},
+	{Name: "1h", Length: time.Hour, Bucket: time.Minute},
+	{Name: "24h", Length: 24 * time.Hour, Bucket: 15 * time.Minute},
+}
+
+// Retention is how long a payment is remembered, the length of the longest window
+var Retention = 24 * time.Hour
+
+// Key identifies the payments of one customer, card, IP address, device or merchant
+type Key struct {
+	Dimension Dimension
+	Value     string
+}
+
+// String returns the key as dimension:value
+func (k Key) String() string {
+	return string(k.Dimension) + ":" + k.Value
+}
+
+// Velocity is the number and total amount of a key's payments in a window
+type Velocity struct {
+	Key    Key
+	Window Window
+	Count  int64
+	Sum    float64
+}
+
+// Store records payments and reports the velocity of their keys
+type Store interface {
+	// Record adds a payment to every window of each key and returns the
+	// velocity of each key and window, including the payment. A payment that
+	// was already recorded is not counted again, so retries are safe.
+	Record(ctx context.Context, paymentID string, keys []Key, amount float64, at time.Time) ([]Velocity, error)
+}
+
+// Measure is what a limit applies to
+type Measure string
+
+// Measures
+const (
+	MeasureCount Measure = "count"
+	MeasureSum   Measure = "sum"
+)
+
+// Limit is the highest count or sum of payments a key may reach in a window
+// before the payment is considered risky
+type Limit struct {
+	Dimension Dimension
+	Window    string
+	Measure   Measure
+	Value     float64
+}
+
+// String returns the limit in the format ParseLimit reads
+func (l Limit) String() string {
+	return fmt.Sprintf("%s.%s.%s=%s", l.Dimension, l.Window, l.Measure, strconv.FormatFloat(l.Value, 'f', -1, 64))
+}
+
+// Observed returns the value of v the limit applies to
+func (l Limit) Observed(v Velocity) float64 {
+	if l.Measure == MeasureSum {
+		return v.Sum
+	}
+	return float64(v.Count)
+}
+
+// Applies reports whether the limit applies to v
+func (l Limit) Applies(v Velocity) bool {
+	return l.Dimension == v.Key.Dimension && l.Window == v.Window.Name
+}
+
+// ParseLimit parses a limit written as dimension.window.measure=value, for
+// example customer.1h.count=10 or card.24h.sum=50000
+func ParseLimit(spec string) (Limit, error) {
+	name, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid velocity limit %q: expected dimension.window.measure=value", spec)
+	}
+	parts := strings.Split(name, ".")
+	if len(parts) != 3 {
+		return Limit{}, fmt.Errorf("invalid velocity limit %q: expected dimension.window.measure=value", spec)
+	}
+
+	limit := Limit{Dimension: Dimension(parts[0]), Window: parts[1], Measure: Measure(parts[2])}
+	if !dimensions[limit.Dimension] {
+		return Limit{}, fmt.Errorf("invalid velocity limit %q: unknown dimension %s", spec, parts[0])
+	}
+	if _, ok := windowByName(limit.Window); !ok {
+		return Limit{}, fmt.Errorf("invalid velocity limit %q: unknown window %s", spec, parts[1])
+	}
+	if limit.Measure != MeasureCount && limit.Measure != MeasureSum {
+		return Limit{}, fmt.Errorf("invalid velocity limit %q: measure must be count or sum", spec)
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed <= 0 {
+		return Limit{}, fmt.Errorf("invalid velocity limit %q: value must be a positive number", spec)
+	}
+	limit.Value = parsed
+	return limit, nil
+}
+
+// ParseLimits parses a list of limits
+func ParseLimits(specs []string) ([]Limit, error) {
+	limits := make([]Limit, 0, len(specs))
+	for _, spec := range specs {
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+// windowByName returns the window with the given name
+func windowByName(name string) (Window, bool) {
+	for _, window := range Windows {
+		if window.Name == name {
+			return window, true
+		}
+	}
+	return Window{}, false
+}
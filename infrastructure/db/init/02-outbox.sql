@@ -0,0 +1,26 @@
+-- Transactional outbox shared by all services
+--
+-- Rows are inserted in the same transaction as the state change they describe
+-- and relayed to Kafka afterwards, so an event is never lost when Kafka is down.
+
+CREATE TABLE outbox_events (
+  id UUID PRIMARY KEY,
+  topic VARCHAR(100) NOT NULL,
+  message_key VARCHAR(100),
+  payload BYTEA NOT NULL,
+  headers JSONB,
+  status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events(available_at, created_at) WHERE status = 'PENDING';
+
+-- Messages with the same topic and key are relayed in order: one is held back
+-- while an older one is waiting to be retried
+CREATE INDEX idx_outbox_events_pending_key ON outbox_events(topic, message_key, created_at) WHERE status = 'PENDING';
+
+COMMENT ON TABLE outbox_events IS 'Stores events waiting to be relayed to Kafka';
@@ -0,0 +1,8 @@
+-- Fraud screening outcomes on payments
+--
+-- Payments that fraud screening holds for manual review wait in REVIEW until
+-- an analyst approves or rejects them. Declined payments record why.
+
+ALTER TYPE payment_status ADD VALUE 'REVIEW' AFTER 'INITIATED';
+
+ALTER TABLE payments ADD COLUMN decline_code VARCHAR(50);
@@ -0,0 +1,9 @@
+-- Where payments come from, for fraud screening
+--
+-- The gateway records the client IP of each payment request, and merchants can
+-- pass the billing and card issuing countries as ISO 3166-1 alpha-2 codes.
+
+ALTER TABLE payments
+    ADD COLUMN client_ip VARCHAR(45),
+    ADD COLUMN billing_country CHAR(2),
+    ADD COLUMN card_issuing_country CHAR(2);
@@ -0,0 +1,8 @@
+-- Device and session signals of payments, for fraud screening
+--
+-- Merchants can describe the device and customer session a payment was made
+-- from; both are optional and kept as sent.
+
+ALTER TABLE payments
+    ADD COLUMN device JSONB,
+    ADD COLUMN session JSONB;
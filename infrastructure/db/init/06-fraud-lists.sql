@@ -0,0 +1,45 @@
+-- Fraud blocklists and allowlists
+--
+-- Entries block or trust a card fingerprint, email, UPI VPA, IP address,
+-- device or customer, either for one merchant or, without a merchant, for all
+-- of them. Fraud detection consults them before scoring a payment and adds
+-- the card, email, VPA and device of confirmed chargebacks to the blocklist.
+-- Entries with an expiry stop applying once it has passed; removed entries
+-- are kept for the audit trail.
+
+CREATE TABLE fraud_list_entries (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  list VARCHAR(10) NOT NULL CHECK (list IN ('BLOCK', 'ALLOW')),
+  entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('CARD', 'EMAIL', 'VPA', 'IP', 'DEVICE', 'CUSTOMER')),
+  value VARCHAR(256) NOT NULL,
+  merchant_id UUID REFERENCES merchants(id),
+  reason TEXT,
+  source VARCHAR(20) NOT NULL DEFAULT 'MANUAL',
+  created_by VARCHAR(100) NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE,
+  removed_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One live entry per list, value and scope; adding it again renews it
+CREATE UNIQUE INDEX idx_fraud_list_entries_value ON fraud_list_entries (
+  list, entry_type, value, COALESCE(merchant_id, '00000000-0000-0000-0000-000000000000'::uuid)
+) WHERE removed_at IS NULL;
+
+CREATE INDEX idx_fraud_list_entries_merchant_id ON fraud_list_entries(merchant_id);
+
+CREATE TABLE fraud_list_audit (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  entry_id UUID REFERENCES fraud_list_entries(id) NOT NULL,
+  action VARCHAR(20) NOT NULL,
+  actor VARCHAR(100) NOT NULL,
+  reason TEXT,
+  expires_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fraud_list_audit_entry_id ON fraud_list_audit(entry_id);
+
+COMMENT ON TABLE fraud_list_entries IS 'Stores fraud blocklist and allowlist entries';
+COMMENT ON TABLE fraud_list_audit IS 'Stores every change to fraud list entries';
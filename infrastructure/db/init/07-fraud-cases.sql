@@ -0,0 +1,70 @@
+-- Fraud checks, review cases and training labels
+--
+-- Fraud detection stores the outcome of every screening, with the checks,
+-- model scores and feature vector behind it, and opens a case for each
+-- payment it holds for review. Analysts work the queue of open cases through
+-- the gateway; approving or rejecting a case resolves the held payment and
+-- labels it as legitimate or fraudulent. Labeled fraud checks are the
+-- training data of fraud models.
+
+CREATE TABLE fraud_checks (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  payment_id UUID REFERENCES payments(id) NOT NULL UNIQUE,
+  merchant_id UUID REFERENCES merchants(id) NOT NULL,
+  customer_id UUID,
+  risk_score DOUBLE PRECISION NOT NULL,
+  decision VARCHAR(10) NOT NULL CHECK (decision IN ('ALLOW', 'REVIEW', 'BLOCK')),
+  reason TEXT,
+  reason_codes TEXT[] NOT NULL DEFAULT '{}',
+  checks JSONB NOT NULL DEFAULT '[]',
+  model_scores JSONB NOT NULL DEFAULT '[]',
+  features JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fraud_checks_merchant_id ON fraud_checks(merchant_id, created_at);
+
+CREATE TABLE fraud_cases (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  payment_id UUID REFERENCES payments(id) NOT NULL UNIQUE,
+  fraud_check_id UUID REFERENCES fraud_checks(id) NOT NULL,
+  merchant_id UUID REFERENCES merchants(id) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'APPROVED', 'REJECTED')),
+  reviewer VARCHAR(100),
+  note TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_fraud_cases_status ON fraud_cases(status, created_at);
+CREATE INDEX idx_fraud_cases_merchant_id ON fraud_cases(merchant_id);
+
+CREATE TABLE fraud_labels (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  payment_id UUID REFERENCES payments(id) NOT NULL,
+  label VARCHAR(20) NOT NULL CHECK (label IN ('FRAUD', 'LEGITIMATE')),
+  source VARCHAR(20) NOT NULL,
+  actor VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fraud_labels_payment_id ON fraud_labels(payment_id, created_at);
+
+-- The latest label of each screened payment, with what screening saw
+CREATE VIEW fraud_training_examples AS
+SELECT DISTINCT ON (l.payment_id)
+  l.payment_id,
+  c.merchant_id,
+  c.features,
+  c.risk_score,
+  c.decision,
+  l.label,
+  l.source,
+  l.created_at AS labeled_at
+FROM fraud_labels l
+JOIN fraud_checks c ON c.payment_id = l.payment_id
+ORDER BY l.payment_id, l.created_at DESC;
+
+COMMENT ON TABLE fraud_checks IS 'Stores the outcome of screening each payment for fraud';
+COMMENT ON TABLE fraud_cases IS 'Stores the review cases of payments held by fraud screening';
+COMMENT ON TABLE fraud_labels IS 'Stores whether payments turned out fraudulent or legitimate, and who said so';
@@ -0,0 +1,23 @@
+-- Fraud outcomes fed back to fraud detection
+--
+-- Besides analysts' reviews, payments are labeled by chargebacks (FRAUD for
+-- fraud chargebacks, LEGITIMATE for service disputes), refunds the merchant
+-- marks as fraudulent, and merchants' own reports. Fraud detection records
+-- these labels as it consumes the events announcing them, so a label may be
+-- delivered more than once; the same label from the same source at the same
+-- time is only recorded once.
+
+CREATE UNIQUE INDEX idx_fraud_labels_outcome ON fraud_labels(payment_id, source, label, created_at);
+
+-- Screened payments and those labeled fraudulent per merchant, over the last
+-- 90 days of screening
+CREATE VIEW merchant_fraud_rates AS
+SELECT
+  c.merchant_id,
+  COUNT(*) AS payments,
+  COUNT(*) FILTER (WHERE t.label = 'FRAUD') AS fraud_payments,
+  COUNT(*) FILTER (WHERE t.label = 'FRAUD')::DOUBLE PRECISION / COUNT(*) AS fraud_rate
+FROM fraud_checks c
+LEFT JOIN fraud_training_examples t ON t.payment_id = c.payment_id
+WHERE c.created_at > CURRENT_TIMESTAMP - INTERVAL '90 days'
+GROUP BY c.merchant_id;
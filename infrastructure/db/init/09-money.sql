@@ -0,0 +1,17 @@
+-- Exact amounts in every currency
+--
+-- Amounts were stored with two decimals, which cannot hold currencies whose
+-- minor unit is a thousandth, such as KWD. Amount columns now hold up to
+-- three decimals; each amount has as many as its currency's exponent (0 for
+-- JPY, 2 for USD, 3 for KWD), which the services enforce. The integer part
+-- holds the largest amount the gateway accepts, 999999999999999.
+
+ALTER TABLE payments ALTER COLUMN amount TYPE NUMERIC(18, 3);
+ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC(18, 3);
+ALTER TABLE settlements
+  ALTER COLUMN amount TYPE NUMERIC(18, 3),
+  ALTER COLUMN fees TYPE NUMERIC(18, 3),
+  ALTER COLUMN net_amount TYPE NUMERIC(18, 3);
+ALTER TABLE settlement_items
+  ALTER COLUMN amount TYPE NUMERIC(18, 3),
+  ALTER COLUMN fees TYPE NUMERIC(18, 3);
@@ -0,0 +1,33 @@
+-- Settlement items
+--
+-- Every payment a settlement pays out is linked to it by a settlement item,
+-- which records the payment's amount, the fee and tax charged on it and the
+-- net amount paid for it. A settlement's amounts are the sums of its items'.
+-- The settlement engine creates a settlement, its items and moves the
+-- payments to SETTLED in one transaction, so a payment is settled at most
+-- once even when settlement windows overlap.
+
+-- Captured payments the settlement engine has taken up for settlement
+ALTER TABLE payments ADD COLUMN settlement_ready BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX idx_payments_settlement_ready ON payments(merchant_id, currency, created_at)
+  WHERE settlement_ready AND status = 'CAPTURED';
+
+-- Settlements as the settlement engine records them
+ALTER TABLE settlements RENAME COLUMN fees TO fee_amount;
+ALTER TABLE settlements RENAME COLUMN transaction_count TO payment_count;
+ALTER TABLE settlements
+  ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'INR',
+  ADD COLUMN tax_amount NUMERIC(18, 3) NOT NULL DEFAULT 0,
+  ADD COLUMN bank_account_id VARCHAR(100),
+  ADD COLUMN settlement_method VARCHAR(20),
+  ADD COLUMN reference VARCHAR(100);
+
+ALTER TABLE settlement_items RENAME COLUMN fees TO fee_amount;
+ALTER TABLE settlement_items
+  ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'INR',
+  ADD COLUMN payment_method VARCHAR(20),
+  ADD COLUMN tax_amount NUMERIC(18, 3) NOT NULL DEFAULT 0,
+  ADD COLUMN net_amount NUMERIC(18, 3) NOT NULL DEFAULT 0;
+
+-- A payment belongs to one settlement
+CREATE UNIQUE INDEX idx_settlement_items_payment_id ON settlement_items(payment_id);
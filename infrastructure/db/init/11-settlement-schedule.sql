@@ -0,0 +1,28 @@
+-- Merchant settlement cycles
+--
+-- Each merchant is settled at the cut-offs of its settlement cycle: every
+-- day, on a weekday or on a day of the month, at a time of day in the
+-- merchant's time zone. Cut-offs on weekends and bank holidays move to the
+-- next business day. A settlement takes the merchant's unsettled captured
+-- payments created up to its cut-off. Merchants without a row here, and
+-- unset columns, use the settlement engine's defaults.
+
+CREATE TABLE merchant_settlement_configs (
+  merchant_id UUID PRIMARY KEY REFERENCES merchants(id),
+  settlement_cycle VARCHAR(10) CHECK (settlement_cycle IN ('DAILY', 'WEEKLY', 'MONTHLY')),
+  -- 1 (Monday) to 7 (Sunday) for weekly cycles, the day of the month for monthly ones
+  preferred_settlement_day INTEGER CHECK (preferred_settlement_day BETWEEN 1 AND 31),
+  timezone VARCHAR(64),
+  cutoff_time VARCHAR(5) CHECK (cutoff_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+  settlement_method VARCHAR(20),
+  bank_account_id VARCHAR(100),
+  fee_percent NUMERIC(7, 4) CHECK (fee_percent BETWEEN 0 AND 100),
+  minimum_settlement_amount NUMERIC(18, 3) CHECK (minimum_settlement_amount >= 0),
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The cut-off a settlement took payments up to; a merchant is settled again
+-- once its cycle passes a later one
+ALTER TABLE settlements ADD COLUMN cutoff_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX idx_settlements_merchant_cutoff ON settlements(merchant_id, cutoff_at);
@@ -0,0 +1,54 @@
+-- Rolling reserves and payout delays
+--
+-- A settlement is paid out payout_delay_days business days after its cut-off
+-- (T+N). A merchant with a rolling reserve has reserve_percent of each
+-- settlement's net amount held for reserve_hold_days days, after which a later
+-- settlement releases it; minimum_reserve_amount is kept held at all times.
+-- Held and released amounts are separate movements of the reserve ledger, and
+-- the merchant is paid net_amount - reserve_held + reserve_released.
+
+ALTER TABLE merchant_settlement_configs
+  ADD COLUMN payout_delay_days INTEGER CHECK (payout_delay_days >= 0),
+  ADD COLUMN reserve_percent NUMERIC(7, 4) CHECK (reserve_percent BETWEEN 0 AND 100),
+  ADD COLUMN reserve_hold_days INTEGER CHECK (reserve_hold_days >= 0),
+  ADD COLUMN minimum_reserve_amount NUMERIC(18, 3) CHECK (minimum_reserve_amount >= 0);
+
+ALTER TABLE settlements
+  ADD COLUMN reserve_held NUMERIC(18, 3) NOT NULL DEFAULT 0,
+  ADD COLUMN reserve_released NUMERIC(18, 3) NOT NULL DEFAULT 0,
+  ADD COLUMN payout_amount NUMERIC(18, 3),
+  ADD COLUMN payout_date TIMESTAMP WITH TIME ZONE;
+
+-- Settlements created before payouts were delayed were paid out at once
+UPDATE settlements SET payout_amount = net_amount, payout_date = settlement_date
+  WHERE payout_amount IS NULL;
+ALTER TABLE settlements ALTER COLUMN payout_amount SET NOT NULL;
+
+-- A merchant is settled once per currency and cut-off
+CREATE UNIQUE INDEX idx_settlements_merchant_currency_cutoff
+  ON settlements(merchant_id, currency, cutoff_at);
+
+-- Settlements waiting for their payout date
+CREATE INDEX idx_settlements_payout_date ON settlements(payout_date)
+  WHERE status = 'PENDING';
+
+-- Reserve ledger: a HOLD movement holds part of a settlement until release_at,
+-- and RELEASE movements, each recorded on a later settlement, release all or
+-- part of a hold
+CREATE TABLE reserve_movements (
+  id UUID PRIMARY KEY,
+  merchant_id UUID NOT NULL REFERENCES merchants(id),
+  settlement_id UUID NOT NULL REFERENCES settlements(id),
+  currency VARCHAR(3) NOT NULL,
+  movement_type VARCHAR(10) NOT NULL CHECK (movement_type IN ('HOLD', 'RELEASE')),
+  amount NUMERIC(18, 3) NOT NULL CHECK (amount > 0),
+  release_at TIMESTAMP WITH TIME ZONE,
+  hold_id UUID REFERENCES reserve_movements(id),
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CHECK ((movement_type = 'HOLD' AND release_at IS NOT NULL AND hold_id IS NULL)
+      OR (movement_type = 'RELEASE' AND release_at IS NULL AND hold_id IS NOT NULL))
+);
+
+CREATE INDEX idx_reserve_movements_merchant ON reserve_movements(merchant_id, currency, movement_type);
+CREATE INDEX idx_reserve_movements_hold_id ON reserve_movements(hold_id);
+CREATE INDEX idx_reserve_movements_settlement_id ON reserve_movements(settlement_id);
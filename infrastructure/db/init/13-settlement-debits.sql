@@ -0,0 +1,45 @@
+-- Refund and chargeback debits, and negative balances
+--
+-- Every captured payment is credited to its merchant by exactly one
+-- settlement, even when it is refunded or charged back first, and every refund
+-- and chargeback is a debit the merchant's next settlement deducts. When a
+-- settlement's debits exceed its credits, it pays nothing out and carries the
+-- negative balance forward to the merchant's next settlement in the currency.
+-- A balance negative for longer than negative_balance_days is recovered from
+-- the merchant by invoice or direct debit, as negative_balance_recovery says.
+
+-- Refunded and charged back payments are credited like captured ones
+DROP INDEX idx_payments_settlement_ready;
+CREATE INDEX idx_payments_settlement_ready ON payments(merchant_id, currency, created_at)
+  WHERE settlement_ready AND status IN ('CAPTURED', 'REFUNDED', 'CHARGEBACK');
+
+CREATE TABLE merchant_debits (
+  id UUID PRIMARY KEY,
+  merchant_id UUID NOT NULL REFERENCES merchants(id),
+  payment_id UUID NOT NULL REFERENCES payments(id),
+  debit_type VARCHAR(20) NOT NULL CHECK (debit_type IN ('REFUND', 'CHARGEBACK')),
+  amount NUMERIC(18, 3) NOT NULL CHECK (amount > 0),
+  currency VARCHAR(3) NOT NULL,
+  -- The settlement that deducted the debit; NULL until one does
+  settlement_id UUID REFERENCES settlements(id),
+  occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A payment is refunded or charged back once
+CREATE UNIQUE INDEX idx_merchant_debits_payment_type ON merchant_debits(payment_id, debit_type);
+CREATE INDEX idx_merchant_debits_pending ON merchant_debits(merchant_id, currency, occurred_at)
+  WHERE settlement_id IS NULL;
+
+ALTER TABLE settlements
+  ADD COLUMN debit_amount NUMERIC(18, 3) NOT NULL DEFAULT 0,
+  ADD COLUMN debit_count INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN balance_brought_forward NUMERIC(18, 3) NOT NULL DEFAULT 0 CHECK (balance_brought_forward <= 0),
+  ADD COLUMN balance_carried_forward NUMERIC(18, 3) NOT NULL DEFAULT 0 CHECK (balance_carried_forward <= 0),
+  ADD COLUMN negative_since TIMESTAMP WITH TIME ZONE,
+  ADD COLUMN recovery_method VARCHAR(20) CHECK (recovery_method IN ('INVOICE', 'DIRECT_DEBIT')),
+  ADD COLUMN recovery_amount NUMERIC(18, 3) NOT NULL DEFAULT 0 CHECK (recovery_amount >= 0);
+
+ALTER TABLE merchant_settlement_configs
+  ADD COLUMN negative_balance_recovery VARCHAR(20) CHECK (negative_balance_recovery IN ('NONE', 'INVOICE', 'DIRECT_DEBIT')),
+  ADD COLUMN negative_balance_days INTEGER CHECK (negative_balance_days >= 0);
@@ -0,0 +1,19 @@
+-- Pricing plans and tax rules
+--
+-- A merchant's fees are charged by its pricing plan, one of the plans of the
+-- settlement engine's pricing file, with per payment method rates, fixed plus
+-- percentage fees, monthly volume tiers and minimum and maximum fees. A
+-- merchant without a plan is charged its fee_percent. Tax is charged on fees
+-- at the rate of the merchant's tax jurisdiction. Each settlement item records
+-- how its payment was priced and taxed.
+
+ALTER TABLE merchant_settlement_configs
+  ADD COLUMN pricing_plan VARCHAR(50),
+  ADD COLUMN tax_jurisdiction VARCHAR(10);
+
+ALTER TABLE settlement_items
+  ADD COLUMN pricing_plan VARCHAR(50),
+  ADD COLUMN fee_percent NUMERIC(7, 4) NOT NULL DEFAULT 0,
+  ADD COLUMN fixed_fee NUMERIC(18, 3) NOT NULL DEFAULT 0,
+  ADD COLUMN tax_jurisdiction VARCHAR(10),
+  ADD COLUMN tax_percent NUMERIC(7, 4) NOT NULL DEFAULT 0;
@@ -0,0 +1,33 @@
+-- Settlement payouts
+--
+-- A settlement is paid out by submitting a payout to the bank, such as in a
+-- bank file, under a reference of its own. The settlement is PROCESSING until
+-- the bank reports the payout paid, completing it, or failed, failing it with
+-- the bank's reason. A payout the bank paid and later returned fails its
+-- settlement too. Failed settlements are retried with a new payout, so each
+-- attempt is a row of settlement_payouts.
+
+CREATE TABLE settlement_payouts (
+  id UUID PRIMARY KEY,
+  settlement_id UUID NOT NULL REFERENCES settlements(id),
+  merchant_id UUID NOT NULL REFERENCES merchants(id),
+  reference VARCHAR(50) NOT NULL UNIQUE,
+  batch_id VARCHAR(100) NOT NULL,
+  attempt INTEGER NOT NULL CHECK (attempt > 0),
+  amount NUMERIC(18, 3) NOT NULL CHECK (amount > 0),
+  currency VARCHAR(3) NOT NULL,
+  bank_account_id VARCHAR(100),
+  status VARCHAR(20) NOT NULL CHECK (status IN ('SUBMITTED', 'PAID', 'FAILED')),
+  failure_reason TEXT,
+  submitted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  completed_at TIMESTAMP WITH TIME ZONE,
+  UNIQUE (settlement_id, attempt)
+);
+
+CREATE INDEX idx_settlement_payouts_settlement_id ON settlement_payouts(settlement_id);
+
+-- Failed settlements waiting to be retried
+CREATE INDEX idx_settlements_failed ON settlements(id) WHERE status = 'FAILED';
+
+-- Payouts awaiting an outcome, checked for batches that were never submitted
+CREATE INDEX idx_settlement_payouts_submitted ON settlement_payouts(submitted_at) WHERE status = 'SUBMITTED';
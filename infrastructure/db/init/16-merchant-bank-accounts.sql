@@ -0,0 +1,49 @@
+-- Merchant bank accounts
+--
+-- Merchants register the bank accounts they are paid out to through the
+-- gateway. An account is an Indian account number with the IFSC of its branch,
+-- or an IBAN with an optional BIC; the account number or IBAN is stored
+-- encrypted, with its last four characters in the clear for display.
+--
+-- A registered account is PENDING until a penny drop verifies it: a token
+-- deposit the account must take, to a beneficiary name matching the holder
+-- name. A VERIFIED account becomes the merchant's payout account and the
+-- account it replaces is REPLACED; a merchant has at most one VERIFIED
+-- account. Settlements are only paid out to a verified account, and not until
+-- a cooldown has passed since it replaced another.
+
+CREATE TABLE merchant_bank_accounts (
+  id UUID PRIMARY KEY,
+  merchant_id UUID NOT NULL REFERENCES merchants(id),
+  holder_name VARCHAR(140) NOT NULL,
+  scheme VARCHAR(4) NOT NULL CHECK (scheme IN ('IFSC', 'IBAN')),
+  account_number_encrypted TEXT NOT NULL,
+  account_number_last4 VARCHAR(4) NOT NULL,
+  ifsc VARCHAR(11),
+  bic VARCHAR(11),
+  status VARCHAR(20) NOT NULL CHECK (status IN ('PENDING', 'VERIFIED', 'FAILED', 'REPLACED')),
+  -- The penny drop: the bank's reference of the deposit, the name it has on
+  -- the account and why verification failed
+  verification_reference VARCHAR(50),
+  registered_name VARCHAR(140),
+  failure_reason TEXT,
+  verified_at TIMESTAMP WITH TIME ZONE,
+  -- The payout account this one replaced when it was verified
+  replaces_account_id UUID REFERENCES merchant_bank_accounts(id),
+  replaced_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  CHECK ((scheme = 'IFSC') = (ifsc IS NOT NULL)),
+  CHECK ((status IN ('VERIFIED', 'REPLACED')) = (verified_at IS NOT NULL))
+);
+
+CREATE INDEX idx_merchant_bank_accounts_merchant_id ON merchant_bank_accounts(merchant_id, created_at);
+
+-- The payout account of each merchant
+CREATE UNIQUE INDEX idx_merchant_bank_accounts_payout ON merchant_bank_accounts(merchant_id)
+  WHERE status = 'VERIFIED';
+
+-- Payouts are paid to the merchant's payout account, not to the one set in
+-- its settlement configuration
+COMMENT ON COLUMN merchant_settlement_configs.bank_account_id IS
+  'Deprecated: payouts go to the merchant''s VERIFIED merchant_bank_accounts row';
@@ -0,0 +1,21 @@
+-- Partial refunds
+--
+-- A payment may be refunded in parts up to its amount. The payment engine
+-- records each refund it applies under the ID the gateway gave it, so a
+-- redelivered refund command is applied once while separate refunds of the
+-- same payment are all applied. Each refund is a debit of its own, recorded
+-- under the refund's ID; a chargeback's debit is recorded under an ID derived
+-- from its payment, which is charged back once.
+
+CREATE TABLE payment_refunds (
+  id UUID PRIMARY KEY,
+  payment_id UUID NOT NULL REFERENCES payments(id),
+  amount NUMERIC(18, 3) NOT NULL CHECK (amount > 0),
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_payment_refunds_payment_id ON payment_refunds(payment_id);
+
+-- Debits are unique by ID, so a payment may have several refund debits
+DROP INDEX idx_merchant_debits_payment_type;
+CREATE INDEX idx_merchant_debits_payment_id ON merchant_debits(payment_id);
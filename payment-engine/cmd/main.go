@@ -11,6 +11,10 @@ import (
 	"github.com/segmentio/kafka-go"
 	"github.com/yourusername/fortexa/payment-engine/internal/config"
 	"github.com/yourusername/fortexa/payment-engine/internal/handlers"
+	"github.com/yourusername/fortexa/payment-engine/internal/repository"
+	"github.com/yourusername/fortexa/pkg/dlq"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
 func main() {
@@ -31,10 +35,25 @@ func main() {
 		cancel()
 	}()
 
-	// Create Kafka reader for consuming payment events
+	var repo repository.Repository
+
+	// Check if running in mock mode
+	if cfg.App.MockMode {
+		log.Println("Running in MOCK MODE - No database connection required")
+		repo = repository.NewMockRepository()
+	} else {
+		dbRepo, err := repository.NewDBRepository(cfg.Database.ConnectionString())
+		if err != nil {
+			log.Fatalf("Failed to initialize database repository: %v", err)
+		}
+		defer dbRepo.Close()
+		repo = dbRepo
+	}
+
+	// Create Kafka reader for consuming payment commands
 	kafkaReader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:     cfg.Kafka.Brokers,
-		Topic:       cfg.Kafka.PaymentsTopic,
+		Topic:       cfg.Kafka.PaymentCommandsTopic,
 		GroupID:     cfg.Kafka.ConsumerGroup,
 		MinBytes:    10e3, // 10KB
 		MaxBytes:    10e6, // 10MB
@@ -43,18 +62,31 @@ func main() {
 	})
 	defer kafkaReader.Close()
 
-	// Create Kafka writer for publishing events
-	kafkaWriter := &kafka.Writer{
-		Addr:         kafka.TCP(cfg.Kafka.Brokers...),
-		Topic:        cfg.Kafka.PaymentsTopic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireAll,
-		Async:        false,
-	}
+	// Create Kafka writer for relaying events from the outbox and dead-lettering messages
+	kafkaWriter := outbox.NewWriter(cfg.Kafka.Brokers...)
 	defer kafkaWriter.Close()
 
+	// Start the outbox relay
+	relay := outbox.NewRelay(
+		repo.Outbox(),
+		kafkaWriter,
+		time.Duration(cfg.Outbox.PollIntervalMs)*time.Millisecond,
+		cfg.Outbox.BatchSize,
+	)
+	go relay.Run(ctx)
+
 	// Create payment handler
-	paymentHandler := handlers.NewPaymentHandler(kafkaReader, kafkaWriter)
+	paymentHandler := handlers.NewPaymentHandler(
+		kafkaReader,
+		repo,
+		events.NewProducer(cfg.App.Name),
+		cfg.Kafka.PaymentCommandsTopic,
+		cfg.Kafka.PaymentEventsTopic,
+		cfg.Kafka.Concurrency,
+		cfg.Kafka.QueueSize,
+		dlq.NewPublisher(kafkaWriter, cfg.Kafka.DeadLetterTopic, cfg.App.Name),
+		cfg.Kafka.MaxAttempts,
+	)
 
 	// Start the payment handler
 	log.Println("Starting payment processing engine")
@@ -1,98 +1,44 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
+	sharedconfig "github.com/yourusername/fortexa/pkg/config"
 )
 
 // Config holds all configuration for the service
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Kafka    KafkaConfig
+	App      AppConfig             `yaml:"app"`
+	Database sharedconfig.Database `yaml:"database"`
+	Kafka    KafkaConfig           `yaml:"kafka"`
+	Outbox   sharedconfig.Outbox   `yaml:"outbox"`
 }
 
 // AppConfig holds the configuration for the application
 type AppConfig struct {
-	Name            string
-	Environment     string
-	ShutdownTimeout int
-}
-
-// DatabaseConfig holds the configuration for the database
-type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Name            string `yaml:"name" env:"APP_NAME" default:"payment-engine" required:"true"`
+	Environment     string `yaml:"environment" env:"APP_ENV" default:"development"`
+	ShutdownTimeout int    `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"5" min:"1"`
+	MockMode        bool   `yaml:"mock_mode" env:"MOCK_MODE"`
 }
 
 // KafkaConfig holds the configuration for Kafka
 type KafkaConfig struct {
-	Brokers         []string
-	PaymentsTopic   string
-	SettlementTopic string
-	FraudTopic      string
-	ConsumerGroup   string
+	Brokers              []string `yaml:"brokers" env:"KAFKA_BROKERS" default:"localhost:9092" required:"true"`
+	PaymentCommandsTopic string   `yaml:"payment_commands_topic" env:"KAFKA_PAYMENT_COMMANDS_TOPIC" default:"payments.commands" required:"true"`
+	PaymentEventsTopic   string   `yaml:"payment_events_topic" env:"KAFKA_PAYMENT_EVENTS_TOPIC" default:"payments.events" required:"true"`
+	SettlementTopic      string   `yaml:"settlement_topic" env:"KAFKA_SETTLEMENT_TOPIC" default:"settlements.events"`
+	FraudTopic           string   `yaml:"fraud_topic" env:"KAFKA_FRAUD_TOPIC" default:"fraud.events"`
+	ConsumerGroup        string   `yaml:"consumer_group" env:"KAFKA_CONSUMER_GROUP" default:"payment-engine" required:"true"`
+	Concurrency          int      `yaml:"concurrency" env:"KAFKA_CONSUMER_CONCURRENCY" default:"8" min:"1"`
+	QueueSize            int      `yaml:"queue_size" env:"KAFKA_CONSUMER_QUEUE_SIZE" default:"100" min:"1"`
+	MaxAttempts          int      `yaml:"max_attempts" env:"KAFKA_CONSUMER_MAX_ATTEMPTS" default:"5" min:"1"`
+	DeadLetterTopic      string   `yaml:"dead_letter_topic" env:"KAFKA_DLQ_TOPIC" default:"payment-engine.dlq" required:"true"`
 }
 
-// New returns a new Config struct
+// New loads the configuration from defaults, the optional config file and the
+// environment. It exits on invalid configuration, and prints the configuration
+// and exits when run with --print-config.
 func New() *Config {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
-	}
-
-	return &Config{
-		App: AppConfig{
-			Name:            getEnv("APP_NAME", "payment-engine"),
-			Environment:     getEnv("APP_ENV", "development"),
-			ShutdownTimeout: getEnvAsInt("SHUTDOWN_TIMEOUT", 5),
-		},
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "fortexa"),
-			Password: getEnv("DB_PASSWORD", "fortexa123"),
-			DBName:   getEnv("DB_NAME", "fortexa"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
-		},
-		Kafka: KafkaConfig{
-			Brokers:         getEnvAsSlice("KAFKA_BROKERS", []string{"localhost:9092"}),
-			PaymentsTopic:   getEnv("KAFKA_PAYMENTS_TOPIC", "payments"),
-			SettlementTopic: getEnv("KAFKA_SETTLEMENT_TOPIC", "settlements"),
-			FraudTopic:      getEnv("KAFKA_FRAUD_TOPIC", "fraud"),
-			ConsumerGroup:   getEnv("KAFKA_CONSUMER_GROUP", "payment-engine"),
-		},
-	}
+	cfg := &Config{}
+	sharedconfig.MustLoad(cfg)
+	return cfg
 }
-
-// Simple helper function to read an environment or return a default value
-func getEnv(key string, defaultVal string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultVal
-}
-
-// Simple helper function to read an environment variable into a string slice or return a default value
-func getEnvAsSlice(key string, defaultVal []string) []string {
-	if value, exists := os.LookupEnv(key); exists {
-		return []string{value}
-	}
-	return defaultVal
-}
-
-// Simple helper function to read an environment variable into an integer or return a default value
-func getEnvAsInt(key string, defaultVal int) int {
-	valueStr := getEnv(key, "")
-	if value, err := strconv.Atoi(valueStr); err == nil {
-		return value
-	}
-	return defaultVal
-} 
\ No newline at end of file
@@ -2,7 +2,8 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -10,96 +11,179 @@ import (
 	"github.com/segmentio/kafka-go"
 	"github.com/yourusername/fortexa/payment-engine/internal/models"
 	"github.com/yourusername/fortexa/payment-engine/internal/processors"
+	"github.com/yourusername/fortexa/payment-engine/internal/repository"
+	"github.com/yourusername/fortexa/pkg/consumer"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/money"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
-// PaymentHandler handles payment-related events from Kafka
+// PaymentHandler handles payment commands from Kafka
 type PaymentHandler struct {
-	kafkaReader *kafka.Reader
-	kafkaWriter *kafka.Writer
+	kafkaReader   *kafka.Reader
+	repository    repository.Repository
+	producer      events.Producer
+	commandsTopic string
+	eventsTopic   string
+	concurrency   int
+	queueSize     int
+	deadLetter    consumer.DeadLetterer
+	maxAttempts   int
 }
 
-// NewPaymentHandler creates a new PaymentHandler
-func NewPaymentHandler(reader *kafka.Reader, writer *kafka.Writer) *PaymentHandler {
+// NewPaymentHandler creates a new PaymentHandler. The reader consumes
+// commandsTopic; follow-up commands are written back to commandsTopic and
+// facts about payments to eventsTopic. Events are not written to Kafka
+// directly but recorded in the outbox together with the payment state.
+// Messages are processed by concurrency workers, each buffering up to queueSize messages.
+// Messages that cannot be processed within maxAttempts are sent to deadLetter.
+func NewPaymentHandler(
+	reader *kafka.Reader,
+	repository repository.Repository,
+	producer events.Producer,
+	commandsTopic string,
+	eventsTopic string,
+	concurrency int,
+	queueSize int,
+	deadLetter consumer.DeadLetterer,
+	maxAttempts int,
+) *PaymentHandler {
 	return &PaymentHandler{
-		kafkaReader: reader,
-		kafkaWriter: writer,
+		kafkaReader:   reader,
+		repository:    repository,
+		producer:      producer,
+		commandsTopic: commandsTopic,
+		eventsTopic:   eventsTopic,
+		concurrency:   concurrency,
+		queueSize:     queueSize,
+		deadLetter:    deadLetter,
+		maxAttempts:   maxAttempts,
 	}
 }
 
-// Start begins listening for payment events
+// Start begins listening for payment commands. Commands for the same payment are
+// processed in order, and offsets are committed only after processing succeeds.
 func (h *PaymentHandler) Start(ctx context.Context) error {
 	log.Println("Payment handler started")
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Payment handler shutting down")
-			return nil
-		default:
-			message, err := h.kafkaReader.ReadMessage(ctx)
-			if err != nil {
-				log.Printf("Error reading message: %v", err)
-				continue
-			}
-
-			// Process the message
-			go h.processMessage(ctx, message)
+	err := consumer.New(h.kafkaReader, h.processMessage, h.concurrency, h.queueSize).
+		WithDeadLetter(h.deadLetter, h.maxAttempts).
+		Run(ctx)
+
+	log.Println("Payment handler shutting down")
+	return err
+}
+
+// commandTransition is the status change a command makes: the statuses a
+// payment may be in for the command to apply, and those in which the command
+// was already applied or was overtaken, so a redelivered command is skipped
+type commandTransition struct {
+	from    []models.PaymentStatus
+	reached []models.PaymentStatus
+}
+
+// commandTransitions are the status changes of payment commands. A failed
+// payment is final, so every command on it is skipped. A payment may be
+// refunded in parts, so a refund is not skipped for a payment already
+// REFUNDED; each refund is applied once by its ID instead.
+var commandTransitions = map[string]commandTransition{
+	events.TypePaymentAuthorizationRequested: {
+		from: []models.PaymentStatus{models.PaymentStatusInitiated, models.PaymentStatusReview},
+		reached: []models.PaymentStatus{models.PaymentStatusAuthorized, models.PaymentStatusCaptured, models.PaymentStatusSettled,
+			models.PaymentStatusRefunded, models.PaymentStatusChargeback, models.PaymentStatusFailed},
+	},
+	events.TypePaymentCaptureRequested: {
+		from: []models.PaymentStatus{models.PaymentStatusAuthorized},
+		reached: []models.PaymentStatus{models.PaymentStatusCaptured, models.PaymentStatusSettled,
+			models.PaymentStatusRefunded, models.PaymentStatusChargeback, models.PaymentStatusFailed},
+	},
+	events.TypePaymentRefundRequested: {
+		from:    []models.PaymentStatus{models.PaymentStatusCaptured, models.PaymentStatusSettled, models.PaymentStatusRefunded},
+		reached: []models.PaymentStatus{models.PaymentStatusFailed},
+	},
+	events.TypePaymentReviewRequested: {
+		from: []models.PaymentStatus{models.PaymentStatusInitiated},
+		reached: []models.PaymentStatus{models.PaymentStatusReview, models.PaymentStatusAuthorized, models.PaymentStatusCaptured,
+			models.PaymentStatusSettled, models.PaymentStatusRefunded, models.PaymentStatusChargeback, models.PaymentStatusFailed},
+	},
+	events.TypePaymentDeclineRequested: {
+		from:    []models.PaymentStatus{models.PaymentStatusInitiated, models.PaymentStatusReview},
+		reached: []models.PaymentStatus{models.PaymentStatusFailed},
+	},
+}
+
+// hasStatus reports whether status is one of statuses
+func hasStatus(statuses []models.PaymentStatus, status models.PaymentStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
 		}
 	}
+	return false
 }
 
-// processMessage processes a Kafka message containing a payment event
-func (h *PaymentHandler) processMessage(ctx context.Context, message kafka.Message) {
+// processMessage processes a Kafka message containing a payment command.
+// Commands are delivered at least once, so a command is checked against the
+// stored status of its payment first: one already applied is skipped, and one
+// the payment's status does not allow is rejected.
+func (h *PaymentHandler) processMessage(ctx context.Context, message kafka.Message) error {
 	log.Printf("Processing message with key: %s", string(message.Key))
 
-	var event models.PaymentEvent
-	if err := json.Unmarshal(message.Value, &event); err != nil {
-		log.Printf("Error unmarshaling event: %v", err)
-		return
+	command, err := events.Decode(message.Value)
+	if err != nil {
+		return consumer.Permanent(err)
 	}
 
-	log.Printf("Received payment event: %s, Payment ID: %s", event.Type, event.Payment.ID)
-
-	switch event.Type {
-	case "payment.initiated":
-		h.handlePaymentInitiated(ctx, event)
-	case "payment.authorization.requested":
-		h.handlePaymentAuthorizationRequested(ctx, event)
-	case "payment.capture.requested":
-		h.handlePaymentCaptureRequested(ctx, event)
-	case "payment.refund.requested":
-		h.handlePaymentRefundRequested(ctx, event)
-	default:
-		log.Printf("Unknown event type: %s", event.Type)
+	var payload events.Payment
+	if err := command.DecodePayload(&payload); err != nil {
+		return consumer.Permanent(err)
 	}
-}
+	payment := models.PaymentFromEvent(payload)
 
-// handlePaymentInitiated processes a payment.initiated event
-func (h *PaymentHandler) handlePaymentInitiated(ctx context.Context, event models.PaymentEvent) {
-	payment := event.Payment
+	log.Printf("Received payment command: %s, Payment ID: %s", command.Type, payment.ID)
 
-	// Create a new event for authorization
-	authEvent := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      "payment.authorization.requested",
-		Payment:   payment,
-		Timestamp: time.Now(),
+	transition, ok := commandTransitions[command.Type]
+	if !ok {
+		return consumer.Permanent(fmt.Errorf("unknown command type: %s", command.Type))
+	}
+	// A payment the gateway has not stored is in the status the command carries
+	status, err := h.repository.GetPaymentStatus(ctx, payment.ID)
+	if errors.Is(err, repository.ErrPaymentNotFound) {
+		status = payment.Status
+	} else if err != nil {
+		return err
+	}
+	if hasStatus(transition.reached, status) {
+		log.Printf("Skipping command %s for payment %s, already %s", command.Type, payment.ID, status)
+		return nil
+	}
+	if !hasStatus(transition.from, status) {
+		return consumer.Permanent(fmt.Errorf("command %s is not allowed for payment %s in status %s", command.Type, payment.ID, status))
 	}
 
-	// Publish the authorization event
-	h.publishEvent(ctx, payment.ID.String(), authEvent)
+	switch command.Type {
+	case events.TypePaymentAuthorizationRequested:
+		return h.handlePaymentAuthorizationRequested(ctx, command, payment)
+	case events.TypePaymentCaptureRequested:
+		return h.handlePaymentCaptureRequested(ctx, command, payment)
+	case events.TypePaymentRefundRequested:
+		return h.handlePaymentRefundRequested(ctx, command, payment)
+	case events.TypePaymentReviewRequested:
+		return h.handlePaymentReviewRequested(ctx, command, payment)
+	case events.TypePaymentDeclineRequested:
+		return h.handlePaymentDeclineRequested(ctx, command, payment)
+	default:
+		return consumer.Permanent(fmt.Errorf("unknown command type: %s", command.Type))
+	}
 }
 
 // handlePaymentAuthorizationRequested processes a payment.authorization.requested event
-func (h *PaymentHandler) handlePaymentAuthorizationRequested(ctx context.Context, event models.PaymentEvent) {
-	payment := event.Payment
-
+func (h *PaymentHandler) handlePaymentAuthorizationRequested(ctx context.Context, command events.Envelope, payment models.Payment) error {
 	// Get the appropriate payment processor for the payment method
 	processor, err := processors.PaymentProcessorFactory(payment.PaymentMethodType)
 	if err != nil {
 		log.Printf("Error creating processor: %v", err)
-		h.publishFailedEvent(ctx, payment, "payment.authorization.failed", err.Error())
-		return
+		return h.publishFailedEvent(ctx, command, payment, events.TypePaymentAuthorizationFailed, err.Error())
 	}
 
 	// Create an authorization request
@@ -144,8 +228,7 @@ func (h *PaymentHandler) handlePaymentAuthorizationRequested(ctx context.Context
 			errorMsg = authRes.Error
 		}
 		log.Printf("Authorization failed: %s", errorMsg)
-		h.publishFailedEvent(ctx, payment, "payment.authorization.failed", errorMsg)
-		return
+		return h.publishFailedEvent(ctx, command, payment, events.TypePaymentAuthorizationFailed, errorMsg)
 	}
 
 	// Update payment status to AUTHORIZED
@@ -159,128 +242,154 @@ func (h *PaymentHandler) handlePaymentAuthorizationRequested(ctx context.Context
 	payment.Metadata["authorization_id"] = authRes.AuthorizationID
 	payment.Metadata["processor_id"] = authRes.ProcessorID
 
-	// Create a new event for authorization successful
-	authSuccessEvent := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      "payment.authorized",
-		Payment:   payment,
-		Timestamp: time.Now(),
-	}
-
-	// Publish the authorization successful event
-	h.publishEvent(ctx, payment.ID.String(), authSuccessEvent)
-
-	// For automatic capture, create a capture request event
-	captureEvent := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      "payment.capture.requested",
-		Payment:   payment,
-		Timestamp: time.Now(),
-	}
-
-	// Publish the capture request event
-	h.publishEvent(ctx, payment.ID.String(), captureEvent)
+	// Publish the authorization successful event and, for automatic capture, a capture command
+	return h.publishEvents(ctx, command, payment, events.TypePaymentAuthorized, events.TypePaymentCaptureRequested)
 }
 
 // handlePaymentCaptureRequested processes a payment.capture.requested event
-func (h *PaymentHandler) handlePaymentCaptureRequested(ctx context.Context, event models.PaymentEvent) {
-	payment := event.Payment
-
+func (h *PaymentHandler) handlePaymentCaptureRequested(ctx context.Context, command events.Envelope, payment models.Payment) error {
 	// Get the appropriate payment processor for the payment method
 	processor, err := processors.PaymentProcessorFactory(payment.PaymentMethodType)
 	if err != nil {
 		log.Printf("Error creating processor: %v", err)
-		h.publishFailedEvent(ctx, payment, "payment.capture.failed", err.Error())
-		return
+		return h.publishFailedEvent(ctx, command, payment, events.TypePaymentCaptureFailed, err.Error())
 	}
 
 	// Process the capture
 	err = processor.Capture(payment.ID, payment.Amount)
 	if err != nil {
 		log.Printf("Capture failed: %v", err)
-		h.publishFailedEvent(ctx, payment, "payment.capture.failed", err.Error())
-		return
+		return h.publishFailedEvent(ctx, command, payment, events.TypePaymentCaptureFailed, err.Error())
 	}
 
 	// Update payment status to CAPTURED
 	payment.Status = models.PaymentStatusCaptured
 	payment.UpdatedAt = time.Now()
 
-	// Create a new event for capture successful
-	captureSuccessEvent := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      "payment.captured",
-		Payment:   payment,
-		Timestamp: time.Now(),
-	}
-
-	// Publish the capture successful event
-	h.publishEvent(ctx, payment.ID.String(), captureSuccessEvent)
+	// Publish the capture successful event; the settlement engine picks up captured payments from it
+	return h.publishEvents(ctx, command, payment, events.TypePaymentCaptured)
+}
 
-	// Create a settlement request event
-	settlementEvent := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      "payment.settlement.requested",
-		Payment:   payment,
-		Timestamp: time.Now(),
+// handlePaymentRefundRequested processes a payment.refund.requested event. A
+// payment may be refunded in parts up to its amount; each refund is named by
+// the ID the gateway gave it, or by the command's ID, and a refund already
+// applied is skipped.
+func (h *PaymentHandler) handlePaymentRefundRequested(ctx context.Context, command events.Envelope, payment models.Payment) error {
+	refundID := command.ID
+	if id, ok := payment.Metadata["refund_id"].(string); ok {
+		if parsed, err := uuid.Parse(id); err == nil {
+			refundID = parsed
+		}
 	}
 
-	// Publish the settlement request event
-	h.publishEvent(ctx, payment.ID.String(), settlementEvent)
-}
-
-// handlePaymentRefundRequested processes a payment.refund.requested event
-func (h *PaymentHandler) handlePaymentRefundRequested(ctx context.Context, event models.PaymentEvent) {
-	payment := event.Payment
+	refunds, err := h.repository.GetRefunds(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+	refunded := money.Zero
+	for _, refund := range refunds {
+		if refund.ID == refundID {
+			log.Printf("Skipping refund %s of payment %s, already applied", refundID, payment.ID)
+			return nil
+		}
+		refunded = refunded.Add(refund.Amount)
+	}
+	remaining := payment.Amount.Sub(refunded)
 
 	// Get the appropriate payment processor for the payment method
 	processor, err := processors.PaymentProcessorFactory(payment.PaymentMethodType)
 	if err != nil {
 		log.Printf("Error creating processor: %v", err)
-		h.publishFailedEvent(ctx, payment, "payment.refund.failed", err.Error())
-		return
+		return h.publishFailedEvent(ctx, command, payment, events.TypePaymentRefundFailed, err.Error())
 	}
 
 	// Get refund amount from metadata (in a real implementation, this would be part of the refund request)
-	refundAmount := payment.Amount // Default to full refund
-	if amount, ok := payment.Metadata["refund_amount"].(float64); ok {
-		refundAmount = amount
+	refundAmount := remaining // Default to refunding what is left
+	// Metadata values arrive as strings in events
+	if amount, ok := payment.Metadata["refund_amount"].(string); ok {
+		parsed, err := money.Parse(amount)
+		if err != nil || parsed.Sign() <= 0 || parsed.Cmp(remaining) > 0 {
+			log.Printf("Invalid refund amount %q for payment %s, %s left to refund", amount, payment.ID, remaining)
+			return h.publishFailedEvent(ctx, command, payment, events.TypePaymentRefundFailed, fmt.Sprintf("invalid refund amount %q, %s left to refund", amount, remaining))
+		}
+		refundAmount = parsed
+	}
+	if refundAmount.Sign() <= 0 {
+		return h.publishFailedEvent(ctx, command, payment, events.TypePaymentRefundFailed, "payment is fully refunded")
 	}
 
 	// Process the refund
 	err = processor.Refund(payment.ID, refundAmount)
 	if err != nil {
 		log.Printf("Refund failed: %v", err)
-		h.publishFailedEvent(ctx, payment, "payment.refund.failed", err.Error())
-		return
+		return h.publishFailedEvent(ctx, command, payment, events.TypePaymentRefundFailed, err.Error())
 	}
 
 	// Update payment status to REFUNDED
 	payment.Status = models.PaymentStatusRefunded
 	payment.UpdatedAt = time.Now()
+	refund := models.Refund{ID: refundID, PaymentID: payment.ID, Amount: refundAmount, CreatedAt: payment.UpdatedAt}
 
-	// Update refund details in metadata
+	// Update refund details in metadata; refunded_amount is the total of the
+	// payment's refunds
 	if payment.Metadata == nil {
 		payment.Metadata = make(map[string]interface{})
 	}
-	payment.Metadata["refund_amount"] = refundAmount
-	payment.Metadata["refund_id"] = uuid.New().String()
-	payment.Metadata["refund_time"] = time.Now().Format(time.RFC3339)
+	payment.Metadata["refund_id"] = refund.ID.String()
+	payment.Metadata["refund_amount"] = refund.Amount.String()
+	payment.Metadata["refunded_amount"] = refunded.Add(refund.Amount).String()
+	payment.Metadata["refund_time"] = refund.CreatedAt.Format(time.RFC3339)
 
-	// Create a new event for refund successful
-	refundSuccessEvent := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      "payment.refunded",
-		Payment:   payment,
-		Timestamp: time.Now(),
+	// Record the refund and publish the refund successful event
+	messages, err := h.eventMessages(command, payment, events.TypePaymentRefunded)
+	if err != nil {
+		return err
+	}
+	if err := h.repository.SaveRefund(ctx, payment, refund, messages...); err != nil {
+		return fmt.Errorf("failed to save refund %s of payment %s: %w", refund.ID, payment.ID, err)
+	}
+	log.Printf("Recorded event: %s, Payment ID: %s, Refund ID: %s", events.TypePaymentRefunded, payment.ID, refund.ID)
+	return nil
+}
+
+// handlePaymentReviewRequested processes a payment.review.requested event. The
+// payment is held in REVIEW until an analyst approves it, which requests its
+// authorization, or rejects it, which requests its decline.
+func (h *PaymentHandler) handlePaymentReviewRequested(ctx context.Context, command events.Envelope, payment models.Payment) error {
+	// Update payment status to REVIEW
+	payment.Status = models.PaymentStatusReview
+	payment.UpdatedAt = time.Now()
+
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]interface{})
 	}
+	payment.Metadata["review_time"] = time.Now().Format(time.RFC3339)
 
-	// Publish the refund successful event
-	h.publishEvent(ctx, payment.ID.String(), refundSuccessEvent)
+	// Publish the review hold so analysts can pick the payment up
+	return h.publishEvents(ctx, command, payment, events.TypePaymentReviewRequired)
+}
+
+// handlePaymentDeclineRequested processes a payment.decline.requested event,
+// failing a payment that fraud screening blocked or an analyst rejected
+func (h *PaymentHandler) handlePaymentDeclineRequested(ctx context.Context, command events.Envelope, payment models.Payment) error {
+	// Update payment status to FAILED with a decline code
+	payment.Status = models.PaymentStatusFailed
+	payment.UpdatedAt = time.Now()
+	if payment.DeclineCode == "" {
+		payment.DeclineCode = models.DeclineCodeFraudSuspected
+	}
+
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]interface{})
+	}
+	payment.Metadata["failure_time"] = time.Now().Format(time.RFC3339)
+
+	// Publish the decline event
+	return h.publishEvents(ctx, command, payment, events.TypePaymentDeclined)
 }
 
 // publishFailedEvent publishes a failure event with the error message
-func (h *PaymentHandler) publishFailedEvent(ctx context.Context, payment models.Payment, eventType, errorMessage string) {
+func (h *PaymentHandler) publishFailedEvent(ctx context.Context, command events.Envelope, payment models.Payment, eventType, errorMessage string) error {
 	// Update payment status to FAILED
 	payment.Status = models.PaymentStatusFailed
 	payment.UpdatedAt = time.Now()
@@ -292,35 +401,51 @@ func (h *PaymentHandler) publishFailedEvent(ctx context.Context, payment models.
 	payment.Metadata["error"] = errorMessage
 	payment.Metadata["failure_time"] = time.Now().Format(time.RFC3339)
 
-	// Create a failure event
-	failureEvent := models.PaymentEvent{
-		ID:        uuid.New(),
-		Type:      eventType,
-		Payment:   payment,
-		Timestamp: time.Now(),
-	}
-
 	// Publish the failure event
-	h.publishEvent(ctx, payment.ID.String(), failureEvent)
+	return h.publishEvents(ctx, command, payment, eventType)
 }
 
-// publishEvent publishes an event to Kafka
-func (h *PaymentHandler) publishEvent(ctx context.Context, key string, event models.PaymentEvent) {
-	eventJSON, err := json.Marshal(event)
+// publishEvents saves the payment and records events of the given types,
+// caused by command, in the outbox in a single transaction; the outbox relay
+// publishes them to Kafka afterwards. Commands go to the commands topic and
+// facts to the events topic, all keyed by payment ID.
+func (h *PaymentHandler) publishEvents(ctx context.Context, command events.Envelope, payment models.Payment, eventTypes ...string) error {
+	messages, err := h.eventMessages(command, payment, eventTypes...)
 	if err != nil {
-		log.Printf("Error marshaling event: %v", err)
-		return
+		return err
 	}
 
-	err = h.kafkaWriter.WriteMessages(ctx, kafka.Message{
-		Key:   []byte(key),
-		Value: eventJSON,
-	})
+	if err := h.repository.SavePayment(ctx, payment, messages...); err != nil {
+		return fmt.Errorf("failed to save payment %s: %w", payment.ID, err)
+	}
 
-	if err != nil {
-		log.Printf("Error publishing event: %v", err)
-		return
+	for _, eventType := range eventTypes {
+		log.Printf("Recorded event: %s, Payment ID: %s", eventType, payment.ID)
 	}
+	return nil
+}
 
-	log.Printf("Published event: %s, Payment ID: %s", event.Type, event.Payment.ID)
-} 
\ No newline at end of file
+// eventMessages creates the outbox messages of events of the given types about
+// payment, caused by command
+func (h *PaymentHandler) eventMessages(command events.Envelope, payment models.Payment, eventTypes ...string) ([]outbox.Message, error) {
+	payload := payment.Event()
+	messages := make([]outbox.Message, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		event, err := h.producer.Caused(command, eventType, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		topic := h.eventsTopic
+		if events.IsCommand(eventType) {
+			topic = h.commandsTopic
+		}
+
+		message, err := event.Message(topic, payment.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
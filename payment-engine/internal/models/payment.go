@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/money"
 )
 
 // PaymentStatus represents the status of a payment
@@ -12,6 +13,7 @@ type PaymentStatus string
 // Payment statuses
 const (
 	PaymentStatusInitiated  PaymentStatus = "INITIATED"
+	PaymentStatusReview     PaymentStatus = "REVIEW"
 	PaymentStatusAuthorized PaymentStatus = "AUTHORIZED"
 	PaymentStatusCaptured   PaymentStatus = "CAPTURED"
 	PaymentStatusSettled    PaymentStatus = "SETTLED"
@@ -20,6 +22,10 @@ const (
 	PaymentStatusChargeback PaymentStatus = "CHARGEBACK"
 )
 
+// DeclineCodeFraudSuspected is the decline code of payments blocked by fraud
+// screening or rejected by a fraud analyst
+const DeclineCodeFraudSuspected = "FRAUD_SUSPECTED"
+
 // PaymentMethod represents the payment method used
 type PaymentMethod string
 
@@ -39,7 +45,7 @@ type Payment struct {
 	ID               uuid.UUID      `json:"id"`
 	MerchantID       uuid.UUID      `json:"merchant_id"`
 	CustomerID       uuid.UUID      `json:"customer_id,omitempty"`
-	Amount           float64        `json:"amount"`
+	Amount           money.Decimal  `json:"amount"`
 	Currency         string         `json:"currency"`
 	Status           PaymentStatus  `json:"status"`
 	PaymentMethodID  *uuid.UUID     `json:"payment_method_id,omitempty"`
@@ -48,22 +54,43 @@ type Payment struct {
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 	IdempotencyKey   string         `json:"idempotency_key,omitempty"`
 	ReferenceID      string         `json:"reference_id,omitempty"`
+	DeclineCode      string         `json:"decline_code,omitempty"`
+	ClientIP         string         `json:"client_ip,omitempty"`
+	BillingCountry   string         `json:"billing_country,omitempty"`
+	CardIssuingCountry string       `json:"card_issuing_country,omitempty"`
+	Device           *Device        `json:"device,omitempty"`
+	Session          *Session       `json:"session,omitempty"`
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
 }
 
-// PaymentEvent represents a payment event received from or published to Kafka
-type PaymentEvent struct {
+// Refund is a refund applied to a payment. A payment may be refunded in
+// parts, each refund named by the ID the gateway gave it.
+type Refund struct {
 	ID        uuid.UUID     `json:"id"`
-	Type      string        `json:"type"`
-	Payment   Payment       `json:"payment"`
-	Timestamp time.Time     `json:"timestamp"`
+	PaymentID uuid.UUID     `json:"payment_id"`
+	Amount    money.Decimal `json:"amount"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// Device describes the device a payment was made from
+type Device struct {
+	ID        string `json:"id"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Screen    string `json:"screen,omitempty"`
+	Timezone  string `json:"timezone,omitempty"`
+}
+
+// Session describes the customer session a payment was made in
+type Session struct {
+	ID             string `json:"id,omitempty"`
+	AccountAgeDays *int   `json:"account_age_days,omitempty"`
 }
 
 // PaymentAuthorizationRequest represents a request to authorize a payment with a payment processor
 type PaymentAuthorizationRequest struct {
 	PaymentID       uuid.UUID      `json:"payment_id"`
-	Amount          float64        `json:"amount"`
+	Amount          money.Decimal  `json:"amount"`
 	Currency        string         `json:"currency"`
 	PaymentMethodType PaymentMethod `json:"payment_method_type"`
 	CardDetails     *CardDetails   `json:"card_details,omitempty"`
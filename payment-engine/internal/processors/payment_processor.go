@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/yourusername/fortexa/payment-engine/internal/models"
+	"github.com/yourusername/fortexa/pkg/money"
 )
 
 // Payment processor errors
@@ -25,8 +26,8 @@ var (
 // PaymentProcessor defines the interface for processing payments
 type PaymentProcessor interface {
 	Authorize(req models.PaymentAuthorizationRequest) (models.PaymentAuthorizationResponse, error)
-	Capture(paymentID uuid.UUID, amount float64) error
-	Refund(paymentID uuid.UUID, amount float64) error
+	Capture(paymentID uuid.UUID, amount money.Decimal) error
+	Refund(paymentID uuid.UUID, amount money.Decimal) error
 }
 
 // PaymentProcessorFactory creates the appropriate payment processor for a payment method
@@ -107,15 +108,15 @@ func (p *CardProcessor) Authorize(req models.PaymentAuthorizationRequest) (model
 }
 
 // Capture completes a previously authorized card payment
-func (p *CardProcessor) Capture(paymentID uuid.UUID, amount float64) error {
-	log.Printf("Capturing card payment for payment ID: %s, amount: %.2f", paymentID, amount)
+func (p *CardProcessor) Capture(paymentID uuid.UUID, amount money.Decimal) error {
+	log.Printf("Capturing card payment for payment ID: %s, amount: %s", paymentID, amount)
 	// In a real implementation, this would call the payment gateway to capture the authorized amount
 	return nil
 }
 
 // Refund processes a refund for a card payment
-func (p *CardProcessor) Refund(paymentID uuid.UUID, amount float64) error {
-	log.Printf("Refunding card payment for payment ID: %s, amount: %.2f", paymentID, amount)
+func (p *CardProcessor) Refund(paymentID uuid.UUID, amount money.Decimal) error {
+	log.Printf("Refunding card payment for payment ID: %s, amount: %s", paymentID, amount)
 	// In a real implementation, this would call the payment gateway to process a refund
 	return nil
 }
@@ -165,15 +166,15 @@ func (p *UPIProcessor) Authorize(req models.PaymentAuthorizationRequest) (models
 }
 
 // Capture completes a previously authorized UPI payment
-func (p *UPIProcessor) Capture(paymentID uuid.UUID, amount float64) error {
-	log.Printf("Capturing UPI payment for payment ID: %s, amount: %.2f", paymentID, amount)
+func (p *UPIProcessor) Capture(paymentID uuid.UUID, amount money.Decimal) error {
+	log.Printf("Capturing UPI payment for payment ID: %s, amount: %s", paymentID, amount)
 	// UPI payments are typically captured immediately during authorization
 	return nil
 }
 
 // Refund processes a refund for a UPI payment
-func (p *UPIProcessor) Refund(paymentID uuid.UUID, amount float64) error {
-	log.Printf("Refunding UPI payment for payment ID: %s, amount: %.2f", paymentID, amount)
+func (p *UPIProcessor) Refund(paymentID uuid.UUID, amount money.Decimal) error {
+	log.Printf("Refunding UPI payment for payment ID: %s, amount: %s", paymentID, amount)
 	// In a real implementation, this would call the UPI provider to process a refund
 	return nil
 }
@@ -223,15 +224,15 @@ func (p *BankProcessor) Authorize(req models.PaymentAuthorizationRequest) (model
 }
 
 // Capture completes a previously authorized bank transfer
-func (p *BankProcessor) Capture(paymentID uuid.UUID, amount float64) error {
-	log.Printf("Capturing bank transfer for payment ID: %s, amount: %.2f", paymentID, amount)
+func (p *BankProcessor) Capture(paymentID uuid.UUID, amount money.Decimal) error {
+	log.Printf("Capturing bank transfer for payment ID: %s, amount: %s", paymentID, amount)
 	// Bank transfers typically take some time to settle
 	return nil
 }
 
 // Refund processes a refund for a bank transfer
-func (p *BankProcessor) Refund(paymentID uuid.UUID, amount float64) error {
-	log.Printf("Refunding bank transfer for payment ID: %s, amount: %.2f", paymentID, amount)
+func (p *BankProcessor) Refund(paymentID uuid.UUID, amount money.Decimal) error {
+	log.Printf("Refunding bank transfer for payment ID: %s, amount: %s", paymentID, amount)
 	// In a real implementation, this would initiate a return bank transfer
 	return nil
 } 
\ No newline at end of file
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/yourusername/fortexa/payment-engine/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// DBRepository handles database operations
+type DBRepository struct {
+	db     *sql.DB
+	outbox *outbox.PostgresStore
+}
+
+// NewDBRepository creates a new database repository
+func NewDBRepository(connectionString string) (*DBRepository, error) {
+	db, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	// Test the connection
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Set connection pool settings
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	return &DBRepository{db: db, outbox: outbox.NewPostgresStore(db)}, nil
+}
+
+// Close closes the database connection
+func (r *DBRepository) Close() error {
+	return r.db.Close()
+}
+
+// Outbox returns the store the outbox relay reads from
+func (r *DBRepository) Outbox() outbox.Store {
+	return r.outbox
+}
+
+// GetPaymentStatus gets the stored status of a payment
+func (r *DBRepository) GetPaymentStatus(ctx context.Context, paymentID uuid.UUID) (models.PaymentStatus, error) {
+	var status models.PaymentStatus
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM payments WHERE id = $1`, paymentID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrPaymentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get payment status: %w", err)
+	}
+	return status, nil
+}
+
+// SavePayment persists the current state of a payment, records the transition in
+// the transactions table and enqueues the outbox messages in a single transaction
+func (r *DBRepository) SavePayment(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := savePayment(ctx, tx, payment); err != nil {
+		return err
+	}
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment: %w", err)
+	}
+
+	return nil
+}
+
+// GetRefunds gets the refunds applied to a payment, oldest first
+func (r *DBRepository) GetRefunds(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, payment_id, amount, created_at
+        FROM payment_refunds
+        WHERE payment_id = $1
+        ORDER BY created_at
+    `, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	defer rows.Close()
+
+	var refunds []models.Refund
+	for rows.Next() {
+		var refund models.Refund
+		if err := rows.Scan(&refund.ID, &refund.PaymentID, &refund.Amount, &refund.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// SaveRefund persists the current state of a refunded payment, records the
+// refund and enqueues the outbox messages in a single transaction. Recording
+// a refund twice fails on its ID.
+func (r *DBRepository) SaveRefund(ctx context.Context, payment models.Payment, refund models.Refund, messages ...outbox.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := savePayment(ctx, tx, payment); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO payment_refunds (id, payment_id, amount, created_at) VALUES ($1, $2, $3, $4)`,
+		refund.ID,
+		refund.PaymentID,
+		refund.Amount,
+		refund.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record refund: %w", err)
+	}
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refund: %w", err)
+	}
+
+	return nil
+}
+
+// savePayment upserts a payment and records the transition in the
+// transactions table within tx
+func savePayment(ctx context.Context, tx *sql.Tx, payment models.Payment) error {
+	metadata, err := json.Marshal(payment.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment metadata: %w", err)
+	}
+	var device, session sql.NullString
+	if payment.Device != nil {
+		encoded, err := json.Marshal(payment.Device)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payment device: %w", err)
+		}
+		device = sql.NullString{String: string(encoded), Valid: true}
+	}
+	if payment.Session != nil {
+		encoded, err := json.Marshal(payment.Session)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payment session: %w", err)
+		}
+		session = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	// The gateway normally creates the row, but upsert so that a replayed
+	// event for an unknown payment does not lose its state
+	query := `
+        INSERT INTO payments (
+            id, merchant_id, customer_id, amount, currency, status,
+            payment_method_id, payment_method_type, description, metadata,
+            reference_id, decline_code, client_ip, billing_country,
+            card_issuing_country, device, session, created_at, updated_at
+        ) VALUES (
+            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
+        )
+        ON CONFLICT (id) DO UPDATE
+        SET status = EXCLUDED.status, metadata = EXCLUDED.metadata,
+            decline_code = EXCLUDED.decline_code, updated_at = EXCLUDED.updated_at
+    `
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		payment.ID,
+		payment.MerchantID,
+		nullableUUID(payment.CustomerID),
+		payment.Amount,
+		payment.Currency,
+		payment.Status,
+		payment.PaymentMethodID,
+		payment.PaymentMethodType,
+		payment.Description,
+		string(metadata),
+		payment.ReferenceID,
+		sql.NullString{String: payment.DeclineCode, Valid: payment.DeclineCode != ""},
+		sql.NullString{String: payment.ClientIP, Valid: payment.ClientIP != ""},
+		sql.NullString{String: payment.BillingCountry, Valid: payment.BillingCountry != ""},
+		sql.NullString{String: payment.CardIssuingCountry, Valid: payment.CardIssuingCountry != ""},
+		device,
+		session,
+		payment.CreatedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save payment: %w", err)
+	}
+
+	errorMessage, _ := payment.Metadata["error"].(string)
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO transactions (payment_id, amount, status, error_message) VALUES ($1, $2, $3, $4)`,
+		payment.ID,
+		payment.Amount,
+		payment.Status,
+		sql.NullString{String: errorMessage, Valid: errorMessage != ""},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record payment transaction: %w", err)
+	}
+	return nil
+}
+
+// nullableUUID maps the zero UUID to NULL
+func nullableUUID(id uuid.UUID) interface{} {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/payment-engine/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	outbox *outbox.MemoryStore
+
+	mu       sync.Mutex
+	statuses map[uuid.UUID]models.PaymentStatus
+	refunds  map[uuid.UUID][]models.Refund
+}
+
+// NewMockRepository creates a new mock repository for demonstration. The
+// statuses of saved payments and their refunds are kept in memory.
+func NewMockRepository() Repository {
+	log.Println("Using mock repository for database operations")
+	return &MockRepository{
+		outbox:   outbox.NewMemoryStore(),
+		statuses: make(map[uuid.UUID]models.PaymentStatus),
+		refunds:  make(map[uuid.UUID][]models.Refund),
+	}
+}
+
+// GetPaymentStatus mocks getting the status of a payment saved in memory
+func (r *MockRepository) GetPaymentStatus(ctx context.Context, paymentID uuid.UUID) (models.PaymentStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status, ok := r.statuses[paymentID]
+	if !ok {
+		return "", ErrPaymentNotFound
+	}
+	return status, nil
+}
+
+// SavePayment mocks persisting a payment and enqueues its messages in memory
+func (r *MockRepository) SavePayment(ctx context.Context, payment models.Payment, messages ...outbox.Message) error {
+	r.mu.Lock()
+	r.statuses[payment.ID] = payment.Status
+	r.mu.Unlock()
+	log.Printf("[MOCK] Saved payment %s with status %s", payment.ID, payment.Status)
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// GetRefunds mocks getting the refunds of a payment saved in memory
+func (r *MockRepository) GetRefunds(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.Refund(nil), r.refunds[paymentID]...), nil
+}
+
+// SaveRefund mocks persisting a refunded payment and its refund, and enqueues
+// its messages in memory
+func (r *MockRepository) SaveRefund(ctx context.Context, payment models.Payment, refund models.Refund, messages ...outbox.Message) error {
+	r.mu.Lock()
+	for _, applied := range r.refunds[payment.ID] {
+		if applied.ID == refund.ID {
+			r.mu.Unlock()
+			return fmt.Errorf("refund %s already recorded", refund.ID)
+		}
+	}
+	r.statuses[payment.ID] = payment.Status
+	r.refunds[payment.ID] = append(r.refunds[payment.ID], refund)
+	r.mu.Unlock()
+	log.Printf("[MOCK] Saved refund %s of %s for payment %s", refund.ID, refund.Amount, payment.ID)
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// Outbox returns the in-memory outbox store
+func (r *MockRepository) Outbox() outbox.Store {
+	return r.outbox
+}
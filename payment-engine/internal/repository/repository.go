@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/payment-engine/internal/models"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// ErrPaymentNotFound is returned when a payment is not stored yet
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// Repository defines the interface for database operations
+type Repository interface {
+	// GetPaymentStatus gets the stored status of a payment, or
+	// ErrPaymentNotFound
+	GetPaymentStatus(ctx context.Context, paymentID uuid.UUID) (models.PaymentStatus, error)
+
+	// SavePayment persists the current state of a payment and enqueues the
+	// messages describing the change in the same transaction
+	SavePayment(ctx context.Context, payment models.Payment, messages ...outbox.Message) error
+
+	// GetRefunds gets the refunds applied to a payment
+	GetRefunds(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error)
+
+	// SaveRefund persists the current state of a refunded payment, records
+	// the refund applied to it and enqueues the messages describing the
+	// change in the same transaction
+	SaveRefund(ctx context.Context, payment models.Payment, refund models.Refund, messages ...outbox.Message) error
+
+	// Outbox returns the store the outbox relay reads from
+	Outbox() outbox.Store
+}
+
+// Ensure DBRepository implements Repository interface
+var _ Repository = (*DBRepository)(nil)
@@ -0,0 +1,191 @@
+// Package bankaccount validates, encrypts and verifies the bank accounts
+// merchants are paid out to.
+//
+// An account is either an Indian account, identified by its account number
+// and the IFSC of its branch, or an account identified by its IBAN, with an
+// optional BIC. Account numbers and IBANs are stored encrypted with a Cipher,
+// and an account is verified with a penny drop: a token deposit whose
+// acceptance proves the account exists and whose beneficiary name, as
+// registered with the bank, must match the account holder's.
+package bankaccount
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Validation errors
+var (
+	ErrInvalidHolderName    = errors.New("invalid account holder name")
+	ErrInvalidAccountNumber = errors.New("invalid account number")
+	ErrInvalidIFSC          = errors.New("invalid IFSC")
+	ErrInvalidIBAN          = errors.New("invalid IBAN")
+	ErrInvalidBIC           = errors.New("invalid BIC")
+)
+
+// Scheme is how a bank account is identified
+type Scheme string
+
+// Account identification schemes
+const (
+	// SchemeIFSC is an Indian account number at the branch of an IFSC
+	SchemeIFSC Scheme = "IFSC"
+	// SchemeIBAN is an IBAN, with the BIC of its bank if known
+	SchemeIBAN Scheme = "IBAN"
+)
+
+// Account is the details of a bank account
+type Account struct {
+	HolderName string
+	Scheme     Scheme
+	// Number is the account number of an IFSC account, or the IBAN of an
+	// IBAN account
+	Number string
+	IFSC   string
+	BIC    string
+}
+
+var (
+	ifscPattern          = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	bicPattern           = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	accountNumberPattern = regexp.MustCompile(`^[0-9]{9,18}$`)
+	ibanPattern          = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]+$`)
+)
+
+// ibanLengths are the lengths of the IBANs of the countries that issue them
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16,
+	"BG": 22, "BH": 22, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28,
+	"CZ": 24, "DE": 22, "DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24,
+	"FI": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18,
+	"GR": 27, "GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23,
+	"IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LC": 32,
+	"LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24, "ME": 22,
+	"MK": 19, "MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24,
+	"PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "SA": 24,
+	"SC": 31, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "TL": 23, "TN": 24,
+	"TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+}
+
+// Normalize validates an account and returns it in canonical form: codes in
+// upper case, and account numbers and IBANs without spaces. The scheme is
+// taken from the details given when it is not set.
+func (a Account) Normalize() (Account, error) {
+	a.HolderName = strings.Join(strings.Fields(a.HolderName), " ")
+	if a.HolderName == "" {
+		return Account{}, ErrInvalidHolderName
+	}
+	if a.Scheme == "" {
+		a.Scheme = SchemeIFSC
+		if a.IFSC == "" {
+			a.Scheme = SchemeIBAN
+		}
+	}
+
+	var err error
+	switch a.Scheme {
+	case SchemeIFSC:
+		if a.Number, err = NormalizeAccountNumber(a.Number); err != nil {
+			return Account{}, err
+		}
+		if a.IFSC, err = NormalizeIFSC(a.IFSC); err != nil {
+			return Account{}, err
+		}
+	case SchemeIBAN:
+		if a.IFSC != "" {
+			return Account{}, fmt.Errorf("%w: IBAN accounts have no IFSC", ErrInvalidIFSC)
+		}
+		if a.Number, err = NormalizeIBAN(a.Number); err != nil {
+			return Account{}, err
+		}
+	default:
+		return Account{}, fmt.Errorf("unknown account scheme %q", a.Scheme)
+	}
+	if a.BIC != "" {
+		if a.BIC, err = NormalizeBIC(a.BIC); err != nil {
+			return Account{}, err
+		}
+	}
+	return a, nil
+}
+
+// NormalizeAccountNumber validates an Indian account number of 9 to 18
+// digits, ignoring spaces
+func NormalizeAccountNumber(number string) (string, error) {
+	number = strings.Join(strings.Fields(number), "")
+	if !accountNumberPattern.MatchString(number) {
+		return "", fmt.Errorf("%w: must be 9 to 18 digits", ErrInvalidAccountNumber)
+	}
+	return number, nil
+}
+
+// NormalizeIFSC validates an IFSC: four letters of the bank, a zero and six
+// characters of the branch
+func NormalizeIFSC(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !ifscPattern.MatchString(code) {
+		return "", fmt.Errorf("%w %q", ErrInvalidIFSC, code)
+	}
+	return code, nil
+}
+
+// NormalizeIBAN validates an IBAN, ignoring spaces: its length must be that of
+// its country's IBANs and its check digits must hold
+func NormalizeIBAN(iban string) (string, error) {
+	iban = strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+	if !ibanPattern.MatchString(iban) {
+		return "", fmt.Errorf("%w: malformed", ErrInvalidIBAN)
+	}
+	length, ok := ibanLengths[iban[:2]]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown country %s", ErrInvalidIBAN, iban[:2])
+	}
+	if len(iban) != length {
+		return "", fmt.Errorf("%w: %s IBANs have %d characters", ErrInvalidIBAN, iban[:2], length)
+	}
+	if ibanChecksum(iban) != 1 {
+		return "", fmt.Errorf("%w: wrong check digits", ErrInvalidIBAN)
+	}
+	return iban, nil
+}
+
+// ibanChecksum returns the ISO 7064 MOD 97-10 remainder of an IBAN with its
+// first four characters moved to the end and letters as 10 to 35; it is 1 for
+// a valid IBAN
+func ibanChecksum(iban string) int {
+	remainder := 0
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	return remainder
+}
+
+// NormalizeBIC validates a BIC of 8 or 11 characters
+func NormalizeBIC(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !bicPattern.MatchString(code) {
+		return "", fmt.Errorf("%w %q", ErrInvalidBIC, code)
+	}
+	return code, nil
+}
+
+// Last4 returns the last four characters of an account number or IBAN, the
+// part of it that may be shown
+func Last4(number string) string {
+	if len(number) <= 4 {
+		return number
+	}
+	return number[len(number)-4:]
+}
+
+// Mask masks all but the last four characters of an account number or IBAN
+func Mask(number string) string {
+	last4 := Last4(number)
+	return strings.Repeat("X", len(number)-len(last4)) + last4
+}
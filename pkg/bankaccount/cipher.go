@@ -0,0 +1,77 @@
+package bankaccount
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// keySize is the size of AES-256 keys
+const keySize = 32
+
+// Cipher errors
+var (
+	ErrInvalidKey        = errors.New("encryption key must be 32 bytes, base64 encoded")
+	ErrInvalidCiphertext = errors.New("invalid encrypted account number")
+)
+
+// Cipher encrypts account numbers with AES-256-GCM. Ciphertexts are base64
+// encoded, with the random nonce they were sealed with first, so the same
+// number encrypts differently each time.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher with a base64 encoded 32 byte key
+func NewCipher(key string) (*Cipher, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != keySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// GenerateKey returns a random base64 encoded key for NewCipher
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt encrypts an account number
+func (c *Cipher) Encrypt(number string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(number), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts an account number encrypted with the same key, or returns
+// ErrInvalidCiphertext
+func (c *Cipher) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	number, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(number), nil
+}
@@ -0,0 +1,159 @@
+package bankaccount
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// Deposit is what the bank reported of a token deposit into an account
+type Deposit struct {
+	// Reference is the bank's reference of the deposit, such as a UTR
+	Reference string
+	// Accepted is whether the account took the deposit; Reason is why not
+	Accepted bool
+	Reason   string
+	// RegisteredName is the beneficiary name the bank has on the account
+	RegisteredName string
+}
+
+// Bank makes the token deposits of penny drops
+type Bank interface {
+	Deposit(account Account, amount money.Decimal, currency string) (Deposit, error)
+}
+
+// Verification is the outcome of a penny drop
+type Verification struct {
+	Reference      string
+	Amount         money.Decimal
+	Currency       string
+	RegisteredName string
+	// Verified is whether the account took the deposit and its registered
+	// name matches the holder name; Reason is why not
+	Verified bool
+	Reason   string
+}
+
+// pennies are the token deposits of penny drops by scheme
+var pennies = map[Scheme]struct {
+	amount   money.Decimal
+	currency string
+}{
+	SchemeIFSC: {money.New(100, 2), "INR"},
+	SchemeIBAN: {money.New(1, 2), "EUR"},
+}
+
+// PennyDrop verifies an account by depositing a token amount into it, 1 INR
+// into IFSC accounts and 0.01 EUR into IBAN accounts. The account is verified
+// if it takes the deposit and the name the bank has on it matches the holder
+// name. An error means the deposit could not be made, not that the account
+// failed verification.
+func PennyDrop(bank Bank, account Account) (Verification, error) {
+	penny, ok := pennies[account.Scheme]
+	if !ok {
+		return Verification{}, fmt.Errorf("unknown account scheme %q", account.Scheme)
+	}
+	deposit, err := bank.Deposit(account, penny.amount, penny.currency)
+	if err != nil {
+		return Verification{}, fmt.Errorf("penny drop failed: %w", err)
+	}
+
+	verification := Verification{
+		Reference:      deposit.Reference,
+		Amount:         penny.amount,
+		Currency:       penny.currency,
+		RegisteredName: deposit.RegisteredName,
+	}
+	switch {
+	case !deposit.Accepted:
+		verification.Reason = "deposit rejected: " + deposit.Reason
+	case !NamesMatch(account.HolderName, deposit.RegisteredName):
+		verification.Reason = fmt.Sprintf("holder name %q does not match the name registered with the bank %q",
+			account.HolderName, deposit.RegisteredName)
+	default:
+		verification.Verified = true
+	}
+	return verification, nil
+}
+
+// nameSynonyms are the spellings of words in names that mean the same
+var nameSynonyms = map[string]string{
+	"PVT":  "PRIVATE",
+	"LTD":  "LIMITED",
+	"CO":   "COMPANY",
+	"CORP": "CORPORATION",
+	"INC":  "INCORPORATED",
+	"&":    "AND",
+}
+
+// nameTitles are the words of names banks may or may not record
+var nameTitles = map[string]bool{
+	"MR": true, "MRS": true, "MS": true, "DR": true, "SHRI": true, "SMT": true, "M/S": true,
+}
+
+// NamesMatch reports whether two names of an account holder are the same,
+// ignoring case, punctuation, titles and abbreviations such as PVT LTD
+func NamesMatch(a, b string) bool {
+	normalized := normalizeName(a)
+	return normalized != "" && normalized == normalizeName(b)
+}
+
+// normalizeName returns the words of a name in upper case, with titles left
+// out and synonyms spelled out
+func normalizeName(name string) string {
+	var words []string
+	for _, word := range strings.Fields(strings.ToUpper(name)) {
+		if nameTitles[strings.TrimRight(word, ".")] {
+			continue
+		}
+		if synonym, ok := nameSynonyms[strings.TrimRight(word, ".")]; ok {
+			words = append(words, synonym)
+			continue
+		}
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, word)
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// StubBank is a bank for running without one. It takes deposits into every
+// account and reports the holder name as registered, except that account
+// numbers and IBANs ending in 0000 do not exist and those ending in 9999 are
+// registered to someone else.
+type StubBank struct{}
+
+// NewStubBank creates a stub bank
+func NewStubBank() *StubBank {
+	return &StubBank{}
+}
+
+// Deposit pretends to deposit an amount into an account
+func (b *StubBank) Deposit(account Account, amount money.Decimal, currency string) (Deposit, error) {
+	reference := make([]byte, 6)
+	if _, err := rand.Read(reference); err != nil {
+		return Deposit{}, err
+	}
+	deposit := Deposit{Reference: "PD" + strings.ToUpper(hex.EncodeToString(reference))}
+	switch {
+	case strings.HasSuffix(account.Number, "0000"):
+		deposit.Reason = "no such account"
+	case strings.HasSuffix(account.Number, "9999"):
+		deposit.Accepted = true
+		deposit.RegisteredName = "SOMEONE ELSE"
+	default:
+		deposit.Accepted = true
+		deposit.RegisteredName = strings.ToUpper(account.HolderName)
+	}
+	return deposit, nil
+}
@@ -0,0 +1,241 @@
+// Command avrogen generates Go types from the latest version of every subject
+// in a schema directory (see package schema for the layout).
+//
+// Usage:
+//
+//	avrogen -dir schemas -pkg events -out schemas_gen.go
+//
+// Avro types map to Go types as follows: records to structs, uuid strings to
+// uuid.UUID, timestamp-millis longs to time.Time, ["null", T] unions to *T,
+// arrays to slices and maps to map[string]T. Field names are converted from
+// snake_case to Go names.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hamba/avro"
+	"github.com/yourusername/fortexa/pkg/schema"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	dir := flag.String("dir", "schemas", "schema directory")
+	pkg := flag.String("pkg", "", "Go package name")
+	out := flag.String("out", "schemas_gen.go", "output file")
+	flag.Parse()
+
+	if *pkg == "" {
+		log.Fatal("Error: -pkg is required")
+	}
+
+	registry, err := schema.Load(os.DirFS(*dir))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	g := &generator{imports: make(map[string]bool), generated: make(map[string]bool)}
+	for _, subject := range registry.Subjects() {
+		latest, version, err := registry.Latest(subject)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		record, ok := latest.(*avro.RecordSchema)
+		if !ok {
+			log.Fatalf("Error: schema %s is not a record", subject)
+		}
+		if err := g.record(record, fmt.Sprintf("%s/v%d.avsc", subject, version)); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+
+	source, err := g.file(*pkg)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// generator accumulates the generated type declarations
+type generator struct {
+	decls     bytes.Buffer
+	imports   map[string]bool
+	generated map[string]bool
+}
+
+// record generates a struct for a record schema and any records nested in it
+func (g *generator) record(record *avro.RecordSchema, source string) error {
+	name := record.Name()
+	if g.generated[name] {
+		return nil
+	}
+	g.generated[name] = true
+
+	var nested []*avro.RecordSchema
+	var fields bytes.Buffer
+	for _, field := range record.Fields() {
+		typ, err := g.goType(field.Type(), &nested)
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %w", name, field.Name(), err)
+		}
+		if doc := field.Doc(); doc != "" {
+			fmt.Fprintf(&fields, "\t// %s\n", doc)
+		}
+		fmt.Fprintf(&fields, "\t%s %s `avro:\"%s\" json:\"%s\"`\n", goName(field.Name()), typ, field.Name(), field.Name())
+	}
+
+	fmt.Fprintf(&g.decls, "\n// %s is generated from the %s record in %s.\n", name, record.FullName(), source)
+	if doc := record.Doc(); doc != "" {
+		g.decls.WriteString("//\n")
+		for _, line := range wrap(doc, 76) {
+			fmt.Fprintf(&g.decls, "// %s\n", line)
+		}
+	}
+	fmt.Fprintf(&g.decls, "type %s struct {\n%s}\n", name, fields.String())
+
+	for _, child := range nested {
+		if err := g.record(child, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goType returns the Go type for a schema, collecting nested records
+func (g *generator) goType(s avro.Schema, nested *[]*avro.RecordSchema) (string, error) {
+	switch s := s.(type) {
+	case *avro.PrimitiveSchema:
+		if logical := s.Logical(); logical != nil {
+			switch logical.Type() {
+			case avro.UUID:
+				g.imports["github.com/google/uuid"] = true
+				return "uuid.UUID", nil
+			case avro.TimestampMillis:
+				g.imports["time"] = true
+				return "time.Time", nil
+			default:
+				return "", fmt.Errorf("unsupported logical type %s", logical.Type())
+			}
+		}
+		switch s.Type() {
+		case avro.Boolean:
+			return "bool", nil
+		case avro.Int:
+			return "int", nil
+		case avro.Long:
+			return "int64", nil
+		case avro.Float:
+			return "float32", nil
+		case avro.Double:
+			return "float64", nil
+		case avro.String:
+			return "string", nil
+		case avro.Bytes:
+			return "[]byte", nil
+		}
+	case *avro.RecordSchema:
+		*nested = append(*nested, s)
+		return s.Name(), nil
+	case *avro.RefSchema:
+		return s.Schema().(avro.NamedSchema).Name(), nil
+	case *avro.EnumSchema:
+		return "string", nil
+	case *avro.ArraySchema:
+		items, err := g.goType(s.Items(), nested)
+		return "[]" + items, err
+	case *avro.MapSchema:
+		values, err := g.goType(s.Values(), nested)
+		return "map[string]" + values, err
+	case *avro.UnionSchema:
+		if s.Nullable() {
+			_, i := s.Indices()
+			typ, err := g.goType(s.Types()[i], nested)
+			return "*" + typ, err
+		}
+	}
+	return "", fmt.Errorf("unsupported schema type %s", s.Type())
+}
+
+// file renders the complete, formatted Go source file
+func (g *generator) file(pkg string) ([]byte, error) {
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by avrogen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(imports) > 0 {
+		buf.WriteString("import (\n")
+		// Standard library imports first, then the rest
+		for _, std := range []bool{true, false} {
+			for _, path := range imports {
+				if isStd(path) == std {
+					fmt.Fprintf(&buf, "\t%q\n", path)
+				}
+			}
+			if std {
+				buf.WriteString("\n")
+			}
+		}
+		buf.WriteString(")\n")
+	}
+	buf.Write(g.decls.Bytes())
+
+	return format.Source(buf.Bytes())
+}
+
+// initialisms are written in upper case in Go names
+var initialisms = map[string]bool{"id": true, "url": true, "api": true, "ip": true}
+
+// goName converts a snake_case field name to an exported Go name
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		if initialisms[part] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// isStd reports whether an import path belongs to the standard library
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+// wrap splits text into lines of at most width characters
+func wrap(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,389 @@
+// Command dlqctl lists, inspects and replays messages in a dead-letter topic.
+//
+// Usage:
+//
+//	dlqctl list    -topic payment-engine.dlq [-limit 50]
+//	dlqctl inspect -topic payment-engine.dlq -partition 0 -offset 12
+//	dlqctl replay  -topic payment-engine.dlq -partition 0 -offset 12 [-dry-run]
+//	dlqctl replay  -topic payment-engine.dlq -all [-include-replayed] [-dry-run]
+//
+// Replayed messages are written back to the topic they originally came from,
+// with their original key, value and headers. Each replay is then marked in
+// the dead-letter topic, and replaying all messages skips the ones marked
+// replayed unless -include-replayed is given. A replayed message that fails
+// again is dead-lettered anew and can be replayed again. Brokers default to
+// KAFKA_BROKERS.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/fortexa/pkg/config"
+	"github.com/yourusername/fortexa/pkg/dlq"
+	"github.com/yourusername/fortexa/pkg/events"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = runList(os.Args[2:])
+	case "inspect":
+		err = runInspect(os.Args[2:])
+	case "replay":
+		err = runReplay(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// usage prints the available commands and exits
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlqctl <list|inspect|replay> -topic <dlq topic> [flags]")
+	os.Exit(2)
+}
+
+// commonFlags holds the flags shared by every command
+type commonFlags struct {
+	brokers string
+	topic   string
+}
+
+// register adds the common flags to a flag set
+func (f *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.brokers, "brokers", getEnv("KAFKA_BROKERS", "localhost:9092"), "comma-separated Kafka brokers")
+	fs.StringVar(&f.topic, "topic", "", "dead-letter topic")
+}
+
+// brokerList splits the brokers flag
+func (f *commonFlags) brokerList() []string {
+	return config.SplitList(f.brokers)
+}
+
+// runList prints a summary line for each message in the dead-letter topic
+func runList(args []string) error {
+	var common commonFlags
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	common.register(fs)
+	limit := fs.Int("limit", 100, "maximum number of messages to list")
+	fs.Parse(args)
+
+	if common.topic == "" {
+		return errors.New("-topic is required")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tOFFSET\tKEY\tSERVICE\tSOURCE\tATTEMPTS\tFAILED AT\tERROR")
+
+	count := 0
+	err := readTopic(common.brokerList(), common.topic, func(message kafka.Message) bool {
+		if _, ok := dlq.Replayed(message); ok {
+			return true
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			message.Partition,
+			message.Offset,
+			string(message.Key),
+			dlq.Header(message, dlq.HeaderService),
+			dlq.Header(message, dlq.HeaderSourceTopic),
+			dlq.Header(message, dlq.HeaderAttempts),
+			dlq.Header(message, dlq.HeaderFailedAt),
+			truncate(dlq.Header(message, dlq.HeaderError), 80),
+		)
+		count++
+		return count < *limit
+	})
+	w.Flush()
+
+	return err
+}
+
+// runInspect prints the headers and value of a single message
+func runInspect(args []string) error {
+	var common commonFlags
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	common.register(fs)
+	partition := fs.Int("partition", 0, "partition of the message")
+	offset := fs.Int64("offset", -1, "offset of the message")
+	fs.Parse(args)
+
+	if common.topic == "" || *offset < 0 {
+		return errors.New("-topic and -offset are required")
+	}
+
+	message, err := readMessage(common.brokerList(), common.topic, *partition, *offset)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Topic:     %s\n", message.Topic)
+	fmt.Printf("Partition: %d\n", message.Partition)
+	fmt.Printf("Offset:    %d\n", message.Offset)
+	fmt.Printf("Key:       %s\n", string(message.Key))
+	fmt.Printf("Time:      %s\n", message.Time.Format(time.RFC3339))
+	fmt.Println("Headers:")
+	for _, header := range message.Headers {
+		fmt.Printf("  %s: %s\n", header.Key, string(header.Value))
+	}
+
+	// Values are normally event envelopes; show anything else as it is
+	envelope, err := events.Decode(message.Value)
+	if err != nil {
+		fmt.Printf("Value (not an event envelope: %v):\n", err)
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, message.Value, "  ", "  ") == nil {
+			fmt.Printf("  %s\n", pretty.String())
+		} else {
+			fmt.Printf("  %q\n", message.Value)
+		}
+		return nil
+	}
+
+	fmt.Println("Envelope:")
+	fmt.Printf("  ID:             %s\n", envelope.ID)
+	fmt.Printf("  Type:           %s\n", envelope.Type)
+	fmt.Printf("  Schema version: %d\n", envelope.SchemaVersion)
+	fmt.Printf("  Correlation ID: %s\n", envelope.CorrelationID)
+	if envelope.CausationID != nil {
+		fmt.Printf("  Causation ID:   %s\n", envelope.CausationID)
+	}
+	fmt.Printf("  Producer:       %s\n", envelope.Producer)
+	fmt.Printf("  Occurred at:    %s\n", envelope.OccurredAt.Format(time.RFC3339))
+	fmt.Println("Payload:")
+
+	var payload interface{}
+	if err := envelope.DecodePayload(&payload); err != nil {
+		fmt.Printf("  %v\n", err)
+		return nil
+	}
+	pretty, err := json.MarshalIndent(payload, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format payload: %w", err)
+	}
+	fmt.Printf("  %s\n", pretty)
+
+	return nil
+}
+
+// runReplay writes one or all dead-lettered messages back to their source topics
+func runReplay(args []string) error {
+	var common commonFlags
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	common.register(fs)
+	partition := fs.Int("partition", 0, "partition of the message to replay")
+	offset := fs.Int64("offset", -1, "offset of the message to replay")
+	all := fs.Bool("all", false, "replay every message in the topic that was not replayed before")
+	includeReplayed := fs.Bool("include-replayed", false, "with -all, also replay messages that were replayed before")
+	dryRun := fs.Bool("dry-run", false, "print what would be replayed without writing anything")
+	fs.Parse(args)
+
+	if common.topic == "" || (*offset < 0 && !*all) {
+		return errors.New("-topic and either -offset or -all are required")
+	}
+
+	brokers := common.brokerList()
+
+	var messages []kafka.Message
+	if *all {
+		var dead []kafka.Message
+		replayed := make(map[string]bool)
+		err := readTopic(brokers, common.topic, func(message kafka.Message) bool {
+			if position, ok := dlq.Replayed(message); ok {
+				replayed[position] = true
+			} else {
+				dead = append(dead, message)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		skipped := 0
+		for _, message := range dead {
+			if replayed[dlq.Position(message)] && !*includeReplayed {
+				skipped++
+				continue
+			}
+			messages = append(messages, message)
+		}
+		if skipped > 0 {
+			fmt.Printf("Skipping %d messages replayed before; use -include-replayed to replay them again\n", skipped)
+		}
+	} else {
+		message, err := readMessage(brokers, common.topic, *partition, *offset)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	replays := make([]kafka.Message, 0, len(messages))
+	markers := make([]kafka.Message, 0, len(messages))
+	for _, message := range messages {
+		replay, err := dlq.ReplayMessage(message)
+		if err != nil {
+			return err
+		}
+		replays = append(replays, replay)
+		markers = append(markers, dlq.ReplayedMarker(message))
+		fmt.Printf("%s -> %s (key %s)\n", dlq.Position(message), replay.Topic, string(replay.Key))
+	}
+
+	if *dryRun || len(replays) == 0 {
+		fmt.Printf("%d messages would be replayed\n", len(replays))
+		return nil
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer writer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := writer.WriteMessages(ctx, replays...); err != nil {
+		return fmt.Errorf("failed to replay messages: %w", err)
+	}
+	// Marking only after the replay means a failure here can replay a message
+	// twice, which consumers tolerate, but never skips one that was not replayed
+	if err := writer.WriteMessages(ctx, markers...); err != nil {
+		return fmt.Errorf("replayed %d messages but failed to mark them replayed: %w", len(replays), err)
+	}
+
+	fmt.Printf("Replayed %d messages\n", len(replays))
+	return nil
+}
+
+// readTopic calls fn for every message currently in the topic, partition by
+// partition, until fn returns false
+func readTopic(brokers []string, topic string, fn func(kafka.Message) bool) error {
+	conn, err := kafka.Dial("tcp", brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read partitions of %s: %w", topic, err)
+	}
+
+	for _, partition := range partitions {
+		more, err := readPartition(brokers[0], topic, partition.ID, -1, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readMessage returns the message at the given partition and offset
+func readMessage(brokers []string, topic string, partition int, offset int64) (kafka.Message, error) {
+	var found *kafka.Message
+	_, err := readPartition(brokers[0], topic, partition, offset, func(message kafka.Message) bool {
+		found = &message
+		return false
+	})
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	if found == nil || found.Offset != offset {
+		return kafka.Message{}, fmt.Errorf("no message at %s/%d@%d", topic, partition, offset)
+	}
+	return *found, nil
+}
+
+// readPartition calls fn for each message of a partition starting at offset
+// (or the first available offset when negative) up to the current end of the
+// partition. It reports false if fn stopped the iteration.
+func readPartition(broker, topic string, partition int, offset int64, fn func(kafka.Message) bool) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := kafka.DialLeader(ctx, "tcp", broker, topic, partition)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to leader of %s/%d: %w", topic, partition, err)
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return false, fmt.Errorf("failed to read offsets of %s/%d: %w", topic, partition, err)
+	}
+	if offset < first {
+		offset = first
+	}
+	if offset >= last {
+		return true, nil
+	}
+	if _, err := conn.Seek(offset, kafka.SeekAbsolute); err != nil {
+		return false, fmt.Errorf("failed to seek %s/%d to %d: %w", topic, partition, offset, err)
+	}
+
+	for offset < last {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		batch := conn.ReadBatch(1, 10e6)
+		for offset < last {
+			message, err := batch.ReadMessage()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				batch.Close()
+				return false, fmt.Errorf("failed to read %s/%d: %w", topic, partition, err)
+			}
+			offset = message.Offset + 1
+			if !fn(message) {
+				batch.Close()
+				return false, nil
+			}
+		}
+		if err := batch.Close(); err != nil && !errors.Is(err, io.EOF) {
+			return false, fmt.Errorf("failed to read %s/%d: %w", topic, partition, err)
+		}
+	}
+
+	return true, nil
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+// getEnv reads an environment variable or returns a default value
+func getEnv(key, defaultVal string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultVal
+}
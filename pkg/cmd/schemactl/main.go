@@ -0,0 +1,122 @@
+// Command schemactl validates and evolves the event schemas in a schema
+// directory (see package schema for the layout).
+//
+// Usage:
+//
+//	schemactl check    [-dir events/schemas]
+//	schemactl register [-dir events/schemas] -subject payment -file payment.avsc
+//
+// check loads every subject and fails if any version is not fully compatible
+// with the earlier ones. register checks a candidate schema against all
+// versions of its subject and, if it is compatible, stores it as the next
+// version. Run go generate ./events afterwards to update the Go types.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/yourusername/fortexa/pkg/schema"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "check":
+		err = runCheck(os.Args[2:])
+	case "register":
+		err = runRegister(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// usage prints the available commands and exits
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: schemactl <check|register> [-dir <schema dir>] [flags]")
+	os.Exit(2)
+}
+
+// runCheck validates every subject in the schema directory
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	dir := fs.String("dir", "events/schemas", "schema directory")
+	fs.Parse(args)
+
+	registry, err := schema.Load(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+
+	for _, subject := range registry.Subjects() {
+		fmt.Printf("%s: %d versions, compatible\n", subject, registry.Versions(subject))
+	}
+	return nil
+}
+
+// runRegister stores a compatible schema as the next version of its subject
+func runRegister(args []string) error {
+	fs := flag.NewFlagSet("register", flag.ExitOnError)
+	dir := fs.String("dir", "events/schemas", "schema directory")
+	subject := fs.String("subject", "", "subject to register the schema under")
+	file := fs.String("file", "", "Avro schema file")
+	dryRun := fs.Bool("dry-run", false, "only check compatibility")
+	fs.Parse(args)
+
+	if *subject == "" || *file == "" {
+		return errors.New("-subject and -file are required")
+	}
+
+	registry, err := schema.Load(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+
+	source, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	candidate, err := schema.Parse(string(source))
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	version, err := registry.Check(*subject, candidate)
+	if errors.Is(err, schema.ErrUnchanged) {
+		fmt.Printf("%s: schema is already registered as v%d\n", *subject, version)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: incompatible schema: %w", *subject, err)
+	}
+
+	if *dryRun {
+		fmt.Printf("%s: schema is compatible and would be registered as v%d\n", *subject, version)
+		return nil
+	}
+
+	target := filepath.Join(*dir, *subject, fmt.Sprintf("v%d.avsc", version))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create subject directory: %w", err)
+	}
+	if err := os.WriteFile(target, source, 0o644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+
+	fmt.Printf("%s: registered v%d at %s\n", *subject, version, target)
+	return nil
+}
@@ -0,0 +1,393 @@
+// Package config loads service configuration into tagged structs.
+//
+// Every leaf field of a configuration struct describes where its value comes
+// from with struct tags:
+//
+//	Brokers []string `yaml:"brokers" env:"KAFKA_BROKERS" default:"localhost:9092" required:"true"`
+//
+// Values are applied in order: the default tag, then an optional YAML config
+// file, then the environment. The env tag may list several comma-separated
+// names; the first one that is set and not empty wins, which lets a service
+// keep accepting a deprecated name. Lists are comma-separated in defaults and
+// environment variables.
+//
+// Supported field types are string, bool, int, int64, float64, []string and
+// time.Duration. After loading, fields are validated against their required,
+// min, max and oneof (pipe-separated) tags, and any struct implementing
+// Validator is validated too. Fields tagged secret:"true" are redacted when
+// the configuration is printed.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the value of secret fields in printed configuration
+const Redacted = "[REDACTED]"
+
+// Validator is implemented by configuration structs with checks that struct
+// tags cannot express
+type Validator interface {
+	Validate() error
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// MustLoad loads cfg for a service's main package. It reads a .env file if
+// there is one, takes the config file from the --config flag or CONFIG_FILE,
+// and exits on invalid configuration. With --print-config it prints the
+// effective configuration with secrets redacted and exits.
+func MustLoad(cfg interface{}) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	file := fs.String("config", "", "path to a YAML config file (default $CONFIG_FILE)")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	fs.Parse(os.Args[1:])
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+	if *file == "" {
+		*file = os.Getenv("CONFIG_FILE")
+	}
+
+	err := Load(cfg, *file)
+	if *printConfig {
+		if printErr := Print(os.Stdout, cfg); printErr != nil {
+			log.Fatalf("Failed to print configuration: %v", printErr)
+		}
+		if err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+}
+
+// Load fills cfg, a pointer to a struct, from defaults, the YAML file (if
+// file is not empty) and the environment, then validates it
+func Load(cfg interface{}, file string) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: expected a pointer to a struct, got %T", cfg)
+	}
+
+	if err := walk(v.Elem(), "", applyDefault); err != nil {
+		return err
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", file, err)
+		}
+	}
+
+	if err := walk(v.Elem(), "", applyEnv); err != nil {
+		return err
+	}
+	return Validate(cfg)
+}
+
+// Validate checks the required, min, max and oneof tags of every field and
+// calls Validate on every struct that implements Validator. All failures are
+// reported together.
+func Validate(cfg interface{}) error {
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		if err := validateField(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", describe(field, path), err))
+		}
+		return nil
+	})
+	validateStructs(reflect.ValueOf(cfg), &errs)
+	return errors.Join(errs...)
+}
+
+// validateStructs calls Validate on v and every nested struct implementing Validator
+func validateStructs(v reflect.Value, errs *[]error) {
+	if validator, ok := v.Interface().(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			*errs = append(*errs, err)
+		}
+	}
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.Kind() == reflect.Struct && v.Type().Field(i).IsExported() {
+			validateStructs(field.Addr(), errs)
+		}
+	}
+}
+
+// Print writes cfg as YAML with secret fields redacted. Each field is
+// annotated with the environment variables it can be set with.
+func Print(w io.Writer, cfg interface{}) error {
+	node, err := toNode(reflect.ValueOf(cfg).Elem())
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// toNode converts a configuration struct to a YAML mapping node
+func toNode(v reflect.Value) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: yamlName(field)}
+		var value *yaml.Node
+		switch {
+		case v.Field(i).Kind() == reflect.Struct:
+			child, err := toNode(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			value = child
+		case field.Tag.Get("secret") == "true" && !v.Field(i).IsZero():
+			value = &yaml.Node{Kind: yaml.ScalarNode, Value: Redacted}
+		default:
+			value = &yaml.Node{}
+			if err := value.Encode(v.Field(i).Interface()); err != nil {
+				return nil, fmt.Errorf("failed to encode %s: %w", field.Name, err)
+			}
+			if value.Kind == yaml.SequenceNode {
+				value.Style = yaml.FlowStyle
+			}
+		}
+		if env := field.Tag.Get("env"); env != "" {
+			value.LineComment = strings.ReplaceAll(env, ",", ", ")
+		}
+
+		node.Content = append(node.Content, key, value)
+	}
+	return node, nil
+}
+
+// SplitList splits a comma-separated list, trimming spaces and dropping
+// empty elements
+func SplitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// fieldFunc is called for every leaf field of a configuration struct
+type fieldFunc func(field reflect.StructField, value reflect.Value, path string) error
+
+// walk calls fn for every exported leaf field of v, recursing into nested structs
+func walk(v reflect.Value, prefix string, fn fieldFunc) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		path := yamlName(field)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if v.Field(i).Kind() == reflect.Struct {
+			if err := walk(v.Field(i), path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(field, v.Field(i), path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDefault sets a field from its default tag
+func applyDefault(field reflect.StructField, value reflect.Value, path string) error {
+	raw, ok := field.Tag.Lookup("default")
+	if !ok {
+		return nil
+	}
+	if err := set(value, raw); err != nil {
+		return fmt.Errorf("%s: invalid default %q: %w", path, raw, err)
+	}
+	return nil
+}
+
+// applyEnv sets a field from the first of its environment variables that is set
+func applyEnv(field reflect.StructField, value reflect.Value, path string) error {
+	for _, name := range strings.Split(field.Tag.Get("env"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		if err := set(value, raw); err != nil {
+			return fmt.Errorf("%s: invalid value: %w", name, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// set parses raw into a field according to its type
+func set(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", value.Type())
+		}
+		value.Set(reflect.ValueOf(SplitList(raw)))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// validateField checks a field against its validation tags
+func validateField(field reflect.StructField, value reflect.Value) error {
+	if field.Tag.Get("required") == "true" && (value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0)) {
+		return errors.New("is required")
+	}
+
+	if oneof, ok := field.Tag.Lookup("oneof"); ok && value.Kind() == reflect.String {
+		allowed := strings.Split(oneof, "|")
+		found := false
+		for _, a := range allowed {
+			if value.String() == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("must be one of %s, got %q", strings.Join(allowed, ", "), value.String())
+		}
+	}
+
+	if raw, ok := field.Tag.Lookup("min"); ok {
+		limit, err := parseLimit(value, raw)
+		if err != nil {
+			return err
+		}
+		if compare(value, limit) < 0 {
+			return fmt.Errorf("must be at least %s, got %v", raw, value.Interface())
+		}
+	}
+	if raw, ok := field.Tag.Lookup("max"); ok {
+		limit, err := parseLimit(value, raw)
+		if err != nil {
+			return err
+		}
+		if compare(value, limit) > 0 {
+			return fmt.Errorf("must be at most %s, got %v", raw, value.Interface())
+		}
+	}
+	return nil
+}
+
+// parseLimit parses a min or max tag as a value of the field's type
+func parseLimit(value reflect.Value, raw string) (reflect.Value, error) {
+	limit := reflect.New(value.Type()).Elem()
+	if err := set(limit, raw); err != nil {
+		return limit, fmt.Errorf("invalid limit %q: %w", raw, err)
+	}
+	return limit, nil
+}
+
+// compare compares two numeric values of the same type
+func compare(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int64:
+		switch {
+		case a.Int() < b.Int():
+			return -1
+		case a.Int() > b.Int():
+			return 1
+		}
+	case reflect.Float64:
+		switch {
+		case a.Float() < b.Float():
+			return -1
+		case a.Float() > b.Float():
+			return 1
+		}
+	}
+	return 0
+}
+
+// yamlName returns the key of a field in config files
+func yamlName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("yaml"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// describe names a field by its config file path and environment variables
+func describe(field reflect.StructField, path string) string {
+	if env := field.Tag.Get("env"); env != "" {
+		return fmt.Sprintf("%s (%s)", path, strings.Split(env, ",")[0])
+	}
+	return path
+}
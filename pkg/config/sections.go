@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Database holds the PostgreSQL connection settings shared by all services
+type Database struct {
+	URL      string `yaml:"url" env:"DB_CONNECTION_STRING" secret:"true"`
+	Host     string `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port     string `yaml:"port" env:"DB_PORT" default:"5432"`
+	User     string `yaml:"user" env:"DB_USER" default:"fortexa"`
+	Password string `yaml:"password" env:"DB_PASSWORD" default:"fortexa123" secret:"true"`
+	Name     string `yaml:"name" env:"DB_NAME" default:"fortexa"`
+	SSLMode  string `yaml:"ssl_mode" env:"DB_SSL_MODE" default:"disable" oneof:"disable|allow|prefer|require|verify-ca|verify-full"`
+}
+
+// ConnectionString returns URL if it is set, and otherwise builds a
+// connection string from the individual settings
+func (d Database) ConnectionString() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf(
+		"postgresql://%s@%s:%s/%s?sslmode=%s",
+		url.UserPassword(d.User, d.Password).String(),
+		d.Host,
+		d.Port,
+		d.Name,
+		d.SSLMode,
+	)
+}
+
+// Validate checks that the database can be located
+func (d Database) Validate() error {
+	if d.URL == "" && (d.Host == "" || d.Name == "") {
+		return errors.New("database: DB_CONNECTION_STRING or both DB_HOST and DB_NAME must be set")
+	}
+	return nil
+}
+
+// Outbox holds the settings of the outbox relay
+type Outbox struct {
+	PollIntervalMs int `yaml:"poll_interval_ms" env:"OUTBOX_POLL_INTERVAL_MS" default:"500" min:"1"`
+	BatchSize      int `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE" default:"100" min:"1"`
+}
+
+// BankAccounts holds the settings of merchant bank accounts shared by the
+// services that store and pay them
+type BankAccounts struct {
+	// EncryptionKey is the base64 encoded AES-256 key account numbers are
+	// encrypted with
+	EncryptionKey string `yaml:"encryption_key" env:"BANK_ACCOUNT_ENCRYPTION_KEY" secret:"true"`
+}
+
+// Validate checks that the encryption key, if set, is 32 bytes
+func (b BankAccounts) Validate() error {
+	if b.EncryptionKey == "" {
+		return nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(b.EncryptionKey); err != nil || len(key) != 32 {
+		return errors.New("bank accounts: BANK_ACCOUNT_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Retry backoff bounds for messages whose handler fails
+const (
+	minRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff = 30 * time.Second
+)
+
+// commitInterval is how often completed offsets are committed to Kafka
+const commitInterval = time.Second
+
+// Reader is the subset of *kafka.Reader used by the consumer
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Handler processes a single message. Returning an error causes the message
+// to be retried; its offset is only committed once the handler succeeds or
+// the message has been dead-lettered.
+type Handler func(ctx context.Context, message kafka.Message) error
+
+// DeadLetterer stores messages that could not be processed
+type DeadLetterer interface {
+	DeadLetter(ctx context.Context, message kafka.Message, cause error, attempts int) error
+}
+
+// Consumer reads messages from Kafka and processes them on a fixed pool of
+// workers. Messages are routed to workers by key, so all messages for the
+// same key (e.g. a payment ID) are handled one at a time and in order.
+// Each worker has a bounded queue; when it is full the consumer stops
+// fetching until the worker catches up.
+type Consumer struct {
+	reader      Reader
+	handler     Handler
+	workers     int
+	queueSize   int
+	offsets     *offsetTracker
+	deadLetter  DeadLetterer
+	maxAttempts int
+}
+
+// New creates a new Consumer with the given number of workers and per-worker queue size
+func New(reader Reader, handler Handler, workers, queueSize int) *Consumer {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	return &Consumer{
+		reader:    reader,
+		handler:   handler,
+		workers:   workers,
+		queueSize: queueSize,
+		offsets:   newOffsetTracker(),
+	}
+}
+
+// WithDeadLetter makes the consumer hand messages to deadLetter once the
+// handler failed maxAttempts times or returned a Permanent error, instead
+// of retrying them forever
+func (c *Consumer) WithDeadLetter(deadLetter DeadLetterer, maxAttempts int) *Consumer {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	c.deadLetter = deadLetter
+	c.maxAttempts = maxAttempts
+	return c
+}
+
+// Run consumes messages until the context is canceled. In-flight messages are
+// allowed to finish and completed offsets are committed before it returns.
+func (c *Consumer) Run(ctx context.Context) error {
+	queues := make([]chan kafka.Message, c.workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, c.queueSize)
+		wg.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer wg.Done()
+			c.work(ctx, queue)
+		}(queues[i])
+	}
+
+	commitDone := make(chan struct{})
+	stopCommitting := make(chan struct{})
+	go func() {
+		defer close(commitDone)
+		c.commitLoop(stopCommitting)
+	}()
+
+	err := c.fetch(ctx, queues)
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+	close(stopCommitting)
+	<-commitDone
+
+	return err
+}
+
+// fetch reads messages and dispatches them to the worker queues
+func (c *Consumer) fetch(ctx context.Context, queues []chan kafka.Message) error {
+	for {
+		message, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			log.Printf("Error fetching message: %v", err)
+			if !sleep(ctx, time.Second) {
+				return nil
+			}
+			continue
+		}
+
+		c.offsets.track(message)
+
+		// Blocks when the worker's queue is full, which applies backpressure to the fetch loop
+		select {
+		case queues[c.workerFor(message)] <- message:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// work handles the messages of a single worker queue in order
+func (c *Consumer) work(ctx context.Context, queue <-chan kafka.Message) {
+	for message := range queue {
+		if c.handle(ctx, message) {
+			c.offsets.done(message)
+		}
+	}
+}
+
+// handle runs the handler until it succeeds, the message is dead-lettered or
+// the context is canceled. It reports whether the message's offset may be committed.
+func (c *Consumer) handle(ctx context.Context, message kafka.Message) bool {
+	backoff := minRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.handler(ctx, message)
+		if err == nil {
+			return true
+		}
+
+		if IsPermanent(err) || (c.deadLetter != nil && attempt >= c.maxAttempts) {
+			return c.handleFailure(ctx, message, err, attempt)
+		}
+
+		log.Printf("Error handling message %s/%d@%d (attempt %d), retrying in %s: %v",
+			message.Topic, message.Partition, message.Offset, attempt, backoff, err)
+
+		if !sleep(ctx, backoff) {
+			return false
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// handleFailure dead-letters a message that will not be retried any further.
+// Without a dead-letter destination, permanently failing messages are skipped.
+func (c *Consumer) handleFailure(ctx context.Context, message kafka.Message, cause error, attempts int) bool {
+	if c.deadLetter == nil {
+		log.Printf("Skipping message %s/%d@%d: %v", message.Topic, message.Partition, message.Offset, cause)
+		return true
+	}
+
+	backoff := minRetryBackoff
+	for {
+		err := c.deadLetter.DeadLetter(ctx, message, cause, attempts)
+		if err == nil {
+			log.Printf("Dead-lettered message %s/%d@%d after %d attempts: %v",
+				message.Topic, message.Partition, message.Offset, attempts, cause)
+			return true
+		}
+
+		// The message must not be committed until it is safely stored somewhere
+		log.Printf("Error dead-lettering message %s/%d@%d, retrying in %s: %v",
+			message.Topic, message.Partition, message.Offset, backoff, err)
+
+		if !sleep(ctx, backoff) {
+			return false
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// commitLoop periodically commits the offsets of completed messages
+func (c *Consumer) commitLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(commitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			c.commit()
+			return
+		case <-ticker.C:
+			c.commit()
+		}
+	}
+}
+
+// commit commits the highest contiguous completed offset of each partition
+func (c *Consumer) commit() {
+	messages := c.offsets.committable()
+	if len(messages) == 0 {
+		return
+	}
+
+	// Use a fresh context so the final commit still happens during shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.reader.CommitMessages(ctx, messages...); err != nil {
+		log.Printf("Error committing offsets: %v", err)
+		c.offsets.retryCommit(messages)
+	}
+}
+
+// workerFor returns the index of the worker responsible for a message
+func (c *Consumer) workerFor(message kafka.Message) int {
+	if len(message.Key) == 0 {
+		return message.Partition % c.workers
+	}
+	hash := fnv.New32a()
+	hash.Write(message.Key)
+	return int(hash.Sum32() % uint32(c.workers))
+}
+
+// nextBackoff doubles a retry backoff up to the maximum
+func nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// sleep waits for the given duration and reports false if the context was canceled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
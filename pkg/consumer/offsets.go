@@ -0,0 +1,95 @@
+package consumer
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// partitionKey identifies a topic partition
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+// trackedMessage is a fetched message and whether it has been handled
+type trackedMessage struct {
+	message kafka.Message
+	done    bool
+}
+
+// offsetTracker works out which offsets are safe to commit. Messages of one
+// partition can finish out of order on different workers, but committing an
+// offset acknowledges everything before it, so only the contiguous prefix of
+// handled messages is committed.
+type offsetTracker struct {
+	mutex      sync.Mutex
+	partitions map[partitionKey][]*trackedMessage
+	pending    map[partitionKey]kafka.Message
+}
+
+// newOffsetTracker creates a new offsetTracker
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[partitionKey][]*trackedMessage),
+		pending:    make(map[partitionKey]kafka.Message),
+	}
+}
+
+// track records a fetched message; messages must be tracked in fetch order
+func (t *offsetTracker) track(message kafka.Message) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := partitionKey{topic: message.Topic, partition: message.Partition}
+	t.partitions[key] = append(t.partitions[key], &trackedMessage{message: message})
+}
+
+// done marks a message as handled
+func (t *offsetTracker) done(message kafka.Message) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := partitionKey{topic: message.Topic, partition: message.Partition}
+	for _, tracked := range t.partitions[key] {
+		if tracked.message.Offset == message.Offset {
+			tracked.done = true
+			return
+		}
+	}
+}
+
+// committable returns, per partition, the last message of the handled prefix
+func (t *offsetTracker) committable() []kafka.Message {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for key, tracked := range t.partitions {
+		i := 0
+		for i < len(tracked) && tracked[i].done {
+			t.pending[key] = tracked[i].message
+			i++
+		}
+		t.partitions[key] = tracked[i:]
+	}
+
+	messages := make([]kafka.Message, 0, len(t.pending))
+	for key, message := range t.pending {
+		messages = append(messages, message)
+		delete(t.pending, key)
+	}
+	return messages
+}
+
+// retryCommit puts back messages whose commit failed so the next commit includes them
+func (t *offsetTracker) retryCommit(messages []kafka.Message) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, message := range messages {
+		key := partitionKey{topic: message.Topic, partition: message.Partition}
+		if current, exists := t.pending[key]; !exists || current.Offset < message.Offset {
+			t.pending[key] = message
+		}
+	}
+}
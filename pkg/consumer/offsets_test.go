@@ -0,0 +1,119 @@
+package consumer
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// message returns a message of a partition of the payments topic at an offset
+func message(partition int, offset int64) kafka.Message {
+	return kafka.Message{Topic: "payments", Partition: partition, Offset: offset}
+}
+
+// offsets returns the partition:offset pairs of messages, ordered by partition
+func offsets(messages []kafka.Message) [][2]int64 {
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Partition < messages[j].Partition })
+	result := make([][2]int64, 0, len(messages))
+	for _, message := range messages {
+		result = append(result, [2]int64{int64(message.Partition), message.Offset})
+	}
+	return result
+}
+
+func equalOffsets(got, want [][2]int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOffsetTrackerCommitsContiguousPrefix(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(10); offset < 14; offset++ {
+		tracker.track(message(0, offset))
+	}
+
+	// Later offsets finishing first are not committed past the unfinished 10
+	tracker.done(message(0, 12))
+	tracker.done(message(0, 11))
+	if got := offsets(tracker.committable()); len(got) != 0 {
+		t.Fatalf("committable() = %v, want nothing while offset 10 is in flight", got)
+	}
+
+	// Finishing 10 commits up to 12, the end of the handled prefix
+	tracker.done(message(0, 10))
+	if got := offsets(tracker.committable()); !equalOffsets(got, [][2]int64{{0, 12}}) {
+		t.Fatalf("committable() = %v, want [[0 12]]", got)
+	}
+	if got := offsets(tracker.committable()); len(got) != 0 {
+		t.Fatalf("committable() = %v, want nothing committed twice", got)
+	}
+
+	tracker.done(message(0, 13))
+	if got := offsets(tracker.committable()); !equalOffsets(got, [][2]int64{{0, 13}}) {
+		t.Fatalf("committable() = %v, want [[0 13]]", got)
+	}
+}
+
+func TestOffsetTrackerPartitionsAreIndependent(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.track(message(0, 5))
+	tracker.track(message(1, 7))
+	tracker.track(message(0, 6))
+	tracker.track(message(1, 8))
+
+	// Partition 1 is committed although partition 0 still waits for offset 5
+	tracker.done(message(0, 6))
+	tracker.done(message(1, 7))
+	tracker.done(message(1, 8))
+	if got := offsets(tracker.committable()); !equalOffsets(got, [][2]int64{{1, 8}}) {
+		t.Fatalf("committable() = %v, want [[1 8]]", got)
+	}
+
+	// The same partition number of another topic is tracked apart
+	tracker.track(kafka.Message{Topic: "settlements", Partition: 0, Offset: 1})
+	tracker.done(kafka.Message{Topic: "settlements", Partition: 0, Offset: 1})
+	tracker.done(message(0, 5))
+	got := tracker.committable()
+	if len(got) != 2 {
+		t.Fatalf("committable() = %v, want both topics", got)
+	}
+	for _, message := range got {
+		if message.Topic == "payments" && message.Offset != 6 || message.Topic == "settlements" && message.Offset != 1 {
+			t.Errorf("committable() = %s offset %d, want payments 6 and settlements 1", message.Topic, message.Offset)
+		}
+	}
+}
+
+func TestOffsetTrackerRetryCommit(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(0); offset < 3; offset++ {
+		tracker.track(message(0, offset))
+	}
+	tracker.done(message(0, 0))
+	failed := tracker.committable()
+
+	// A failed commit is retried with the next one, which moves it forward
+	// when more messages were handled in the meantime
+	tracker.done(message(0, 1))
+	tracker.retryCommit(failed)
+	if got := offsets(tracker.committable()); !equalOffsets(got, [][2]int64{{0, 1}}) {
+		t.Fatalf("committable() = %v, want [[0 1]]", got)
+	}
+
+	// An older failed commit does not move the offset back
+	tracker.done(message(0, 2))
+	pending := tracker.committable()
+	tracker.retryCommit(pending)
+	tracker.retryCommit(failed)
+	if got := offsets(tracker.committable()); !equalOffsets(got, [][2]int64{{0, 2}}) {
+		t.Fatalf("committable() = %v, want [[0 2]]", got)
+	}
+}
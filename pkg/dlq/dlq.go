@@ -0,0 +1,138 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to dead-lettered messages. The original headers are kept as they are.
+const (
+	HeaderError           = "dlq-error"
+	HeaderAttempts        = "dlq-attempts"
+	HeaderService         = "dlq-service"
+	HeaderSourceTopic     = "dlq-source-topic"
+	HeaderSourcePartition = "dlq-source-partition"
+	HeaderSourceOffset    = "dlq-source-offset"
+	HeaderFailedAt        = "dlq-failed-at"
+	HeaderReplayedFrom    = "dlq-replayed-from"
+	HeaderReplayed        = "dlq-replayed"
+)
+
+// headerPrefix is shared by all headers added by this package
+const headerPrefix = "dlq-"
+
+// TopicFor returns the conventional dead-letter topic name for a service
+func TopicFor(service string) string {
+	return service + ".dlq"
+}
+
+// Publisher writes messages that a service failed to process to its dead-letter topic
+type Publisher struct {
+	writer  *kafka.Writer
+	topic   string
+	service string
+}
+
+// NewPublisher creates a new Publisher. The writer must not have a fixed topic.
+func NewPublisher(writer *kafka.Writer, topic, service string) *Publisher {
+	return &Publisher{
+		writer:  writer,
+		topic:   topic,
+		service: service,
+	}
+}
+
+// DeadLetter writes the message to the dead-letter topic along with the error,
+// the number of processing attempts and where the message came from
+func (p *Publisher) DeadLetter(ctx context.Context, message kafka.Message, cause error, attempts int) error {
+	headers := make([]kafka.Header, 0, len(message.Headers)+7)
+	for _, header := range message.Headers {
+		// A replayed message that fails again gets fresh failure details
+		if !strings.HasPrefix(header.Key, headerPrefix) {
+			headers = append(headers, header)
+		}
+	}
+	headers = append(headers,
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderService, Value: []byte(p.service)},
+		kafka.Header{Key: HeaderSourceTopic, Value: []byte(message.Topic)},
+		kafka.Header{Key: HeaderSourcePartition, Value: []byte(strconv.Itoa(message.Partition))},
+		kafka.Header{Key: HeaderSourceOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	err := p.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   p.topic,
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write to dead-letter topic %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+// Header returns the value of the named header, or an empty string
+func Header(message kafka.Message, key string) string {
+	for _, header := range message.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// ReplayMessage rebuilds the original message from a dead-lettered one so it
+// can be written back to its source topic
+func ReplayMessage(message kafka.Message) (kafka.Message, error) {
+	sourceTopic := Header(message, HeaderSourceTopic)
+	if sourceTopic == "" {
+		return kafka.Message{}, fmt.Errorf("message %s/%d@%d has no %s header",
+			message.Topic, message.Partition, message.Offset, HeaderSourceTopic)
+	}
+
+	headers := make([]kafka.Header, 0, len(message.Headers)+1)
+	for _, header := range message.Headers {
+		if !strings.HasPrefix(header.Key, headerPrefix) {
+			headers = append(headers, header)
+		}
+	}
+	headers = append(headers, kafka.Header{Key: HeaderReplayedFrom, Value: []byte(Position(message))})
+
+	return kafka.Message{
+		Topic:   sourceTopic,
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	}, nil
+}
+
+// Position identifies a message by its topic, partition and offset
+func Position(message kafka.Message) string {
+	return fmt.Sprintf("%s/%d@%d", message.Topic, message.Partition, message.Offset)
+}
+
+// ReplayedMarker returns the marker written to the dead-letter topic once a
+// dead-lettered message was replayed, so that later replays of the topic can
+// skip it. A marker has no value and is not a dead-lettered message itself.
+func ReplayedMarker(message kafka.Message) kafka.Message {
+	return kafka.Message{
+		Topic:   message.Topic,
+		Key:     message.Key,
+		Headers: []kafka.Header{{Key: HeaderReplayed, Value: []byte(Position(message))}},
+	}
+}
+
+// Replayed returns the position of the dead-lettered message a marker records
+// as replayed, and false if the message is not a marker
+func Replayed(message kafka.Message) (string, bool) {
+	position := Header(message, HeaderReplayed)
+	return position, position != ""
+}
@@ -0,0 +1,193 @@
+package events
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamba/avro"
+	"github.com/yourusername/fortexa/pkg/outbox"
+	"github.com/yourusername/fortexa/pkg/schema"
+)
+
+//go:generate go run ../cmd/avrogen -dir schemas -pkg events -out schemas_gen.go
+
+// Kafka headers copied from the envelope so messages can be routed and
+// traced without decoding the value
+const (
+	HeaderEventType     = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderCorrelationID = "correlation-id"
+	HeaderProducer      = "producer"
+	HeaderContentType   = "content-type"
+)
+
+// ContentType identifies the encoding of message values
+const ContentType = "application/vnd.fortexa.envelope+avro"
+
+//go:embed schemas
+var schemaFiles embed.FS
+
+// Registry holds every version of every event schema. Loading it checks that
+// each subject's versions are fully compatible, so an incompatible schema
+// change fails at startup instead of breaking consumers.
+var Registry = mustLoadRegistry()
+
+// mustLoadRegistry loads the embedded schemas
+func mustLoadRegistry() *schema.Registry {
+	fsys, err := fs.Sub(schemaFiles, "schemas")
+	if err != nil {
+		panic(err)
+	}
+	registry, err := schema.Load(fsys)
+	if err != nil {
+		panic(fmt.Sprintf("invalid event schemas: %v", err))
+	}
+	return registry
+}
+
+// Producer creates envelopes on behalf of a service
+type Producer struct {
+	name string
+}
+
+// NewProducer creates a new Producer for the named service
+func NewProducer(name string) Producer {
+	return Producer{name: name}
+}
+
+// New creates an envelope that starts a new flow; its correlation ID is its own ID
+func (p Producer) New(eventType string, payload interface{}) (Envelope, error) {
+	envelope, err := p.envelope(eventType, payload)
+	if err != nil {
+		return Envelope{}, err
+	}
+	envelope.CorrelationID = envelope.ID
+	return envelope, nil
+}
+
+// Caused creates an envelope for an event caused by another one. It keeps the
+// cause's correlation ID and records the cause's ID as its causation ID.
+func (p Producer) Caused(cause Envelope, eventType string, payload interface{}) (Envelope, error) {
+	envelope, err := p.envelope(eventType, payload)
+	if err != nil {
+		return Envelope{}, err
+	}
+	causationID := cause.ID
+	envelope.CorrelationID = cause.CorrelationID
+	envelope.CausationID = &causationID
+	return envelope, nil
+}
+
+// envelope builds an envelope whose payload is encoded with the latest schema
+// of the event type
+func (p Producer) envelope(eventType string, payload interface{}) (Envelope, error) {
+	subject, ok := Subject(eventType)
+	if !ok {
+		return Envelope{}, fmt.Errorf("unknown event type: %s", eventType)
+	}
+	writer, version, err := Registry.Latest(subject)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	data, err := avro.Marshal(writer, payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to encode %s payload: %w", eventType, err)
+	}
+
+	return Envelope{
+		ID:            uuid.New(),
+		Type:          eventType,
+		SchemaVersion: version,
+		Producer:      p.name,
+		OccurredAt:    time.Now().UTC(),
+		Payload:       data,
+	}, nil
+}
+
+// Encode returns the wire format of the envelope: one byte holding the
+// envelope schema version, followed by the envelope's Avro encoding
+func (e Envelope) Encode() ([]byte, error) {
+	writer, version, err := Registry.Latest(SubjectEnvelope)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := avro.Marshal(writer, e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s envelope: %w", e.Type, err)
+	}
+	return append([]byte{byte(version)}, data...), nil
+}
+
+// Decode parses an envelope from its wire format and checks that it is complete
+func Decode(data []byte) (Envelope, error) {
+	if len(data) < 2 {
+		return Envelope{}, errors.New("message is too short to be an envelope")
+	}
+
+	writer, err := Registry.Schema(SubjectEnvelope, int(data[0]))
+	if err != nil {
+		return Envelope{}, fmt.Errorf("unsupported envelope: %w", err)
+	}
+
+	var envelope Envelope
+	if err := avro.Unmarshal(writer, data[1:], &envelope); err != nil {
+		return Envelope{}, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+
+	switch {
+	case envelope.ID == uuid.Nil:
+		return Envelope{}, errors.New("envelope has no id")
+	case envelope.Type == "":
+		return Envelope{}, errors.New("envelope has no type")
+	case envelope.SchemaVersion < 1:
+		return Envelope{}, fmt.Errorf("envelope %s has no schema version", envelope.ID)
+	}
+
+	return envelope, nil
+}
+
+// DecodePayload decodes the payload into v using the schema version it was
+// written with. Fields the writer did not have keep their zero value and
+// fields v does not have are skipped, so payloads written with an older or
+// newer compatible schema decode without errors.
+func (e Envelope) DecodePayload(v interface{}) error {
+	subject, ok := Subject(e.Type)
+	if !ok {
+		return fmt.Errorf("unknown event type: %s", e.Type)
+	}
+	writer, err := Registry.Schema(subject, e.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	if err := avro.Unmarshal(writer, e.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Message builds an outbox message carrying the encoded envelope, with its
+// type, schema version, correlation ID and producer copied into the headers
+func (e Envelope) Message(topic, key string) (outbox.Message, error) {
+	value, err := e.Encode()
+	if err != nil {
+		return outbox.Message{}, err
+	}
+
+	message := outbox.NewMessage(topic, key, value)
+	message.Headers = map[string]string{
+		HeaderEventType:     e.Type,
+		HeaderSchemaVersion: strconv.Itoa(e.SchemaVersion),
+		HeaderCorrelationID: e.CorrelationID.String(),
+		HeaderProducer:      e.Producer,
+		HeaderContentType:   ContentType,
+	}
+	return message, nil
+}
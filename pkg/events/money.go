@@ -0,0 +1,94 @@
+package events
+
+import "github.com/yourusername/fortexa/pkg/money"
+
+// amount returns an amount carried in minor units, or, in events written
+// before minor units were added, the float it was carried as rounded to the
+// currency
+func amount(minor int64, float float64, currency string) money.Decimal {
+	if minor != 0 || float == 0 {
+		return money.FromMinor(minor, currency)
+	}
+	return money.FromFloat(float).RoundTo(currency, money.RoundHalfEven)
+}
+
+// Money returns the payment's exact amount
+func (p Payment) Money() money.Decimal {
+	return amount(p.AmountMinor, p.Amount, p.Currency)
+}
+
+// SetMoney sets the payment's amount, in minor units and as a float for
+// consumers that predate them
+func (p *Payment) SetMoney(amount money.Decimal) {
+	p.AmountMinor = amount.Minor(p.Currency)
+	p.Amount = amount.Float64()
+}
+
+// Amounts returns the settlement's exact amount, fee, tax and net amount
+func (s Settlement) Amounts() (amt, fee, tax, net money.Decimal) {
+	return amount(s.AmountMinor, s.Amount, s.Currency),
+		amount(s.FeeAmountMinor, s.FeeAmount, s.Currency),
+		amount(s.TaxAmountMinor, s.TaxAmount, s.Currency),
+		amount(s.NetAmountMinor, s.NetAmount, s.Currency)
+}
+
+// SetAmounts sets the settlement's amount, fee, tax and net amount, in minor
+// units and as floats for consumers that predate them
+func (s *Settlement) SetAmounts(amt, fee, tax, net money.Decimal) {
+	s.AmountMinor, s.Amount = amt.Minor(s.Currency), amt.Float64()
+	s.FeeAmountMinor, s.FeeAmount = fee.Minor(s.Currency), fee.Float64()
+	s.TaxAmountMinor, s.TaxAmount = tax.Minor(s.Currency), tax.Float64()
+	s.NetAmountMinor, s.NetAmount = net.Minor(s.Currency), net.Float64()
+}
+
+// Reserves returns the part of the settlement's net amount held in the
+// merchant's rolling reserve and the earlier reserves released with it
+func (s Settlement) Reserves() (held, released money.Decimal) {
+	return money.FromMinor(s.ReserveHeldMinor, s.Currency), money.FromMinor(s.ReserveReleasedMinor, s.Currency)
+}
+
+// SetReserves sets the reserve held from the settlement and the reserves
+// released with it
+func (s *Settlement) SetReserves(held, released money.Decimal) {
+	s.ReserveHeldMinor = held.Minor(s.Currency)
+	s.ReserveReleasedMinor = released.Minor(s.Currency)
+}
+
+// Debits returns the refunds and chargebacks the settlement deducted
+func (s Settlement) Debits() money.Decimal {
+	return money.FromMinor(s.DebitAmountMinor, s.Currency)
+}
+
+// Balances returns the negative balances the settlement brought forward and
+// carried forward, both zero or negative, and the balance it recovered from
+// the merchant
+func (s Settlement) Balances() (broughtForward, carriedForward, recovered money.Decimal) {
+	return money.FromMinor(s.BalanceBroughtForwardMinor, s.Currency),
+		money.FromMinor(s.BalanceCarriedForwardMinor, s.Currency),
+		money.FromMinor(s.RecoveryAmountMinor, s.Currency)
+}
+
+// SetDebits sets the refunds and chargebacks the settlement deducted
+func (s *Settlement) SetDebits(debits money.Decimal, count int) {
+	s.DebitAmountMinor = debits.Minor(s.Currency)
+	s.DebitCount = count
+}
+
+// SetBalances sets the negative balances the settlement brought forward and
+// carried forward and the balance it recovered from the merchant
+func (s *Settlement) SetBalances(broughtForward, carriedForward, recovered money.Decimal) {
+	s.BalanceBroughtForwardMinor = broughtForward.Minor(s.Currency)
+	s.BalanceCarriedForwardMinor = carriedForward.Minor(s.Currency)
+	s.RecoveryAmountMinor = recovered.Minor(s.Currency)
+}
+
+// Payout returns the amount the merchant is paid for the settlement: the net
+// amount less the debits and the reserve held, plus the reserves released and
+// the negative balance brought forward, with what remains negative carried
+// forward or recovered
+func (s Settlement) Payout() money.Decimal {
+	_, _, _, net := s.Amounts()
+	held, released := s.Reserves()
+	broughtForward, carriedForward, recovered := s.Balances()
+	return net.Sub(s.Debits()).Sub(held).Add(released).Add(broughtForward).Sub(carriedForward).Add(recovered)
+}
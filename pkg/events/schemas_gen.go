@@ -0,0 +1,192 @@
+// Code generated by avrogen. DO NOT EDIT.
+
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Envelope is generated from the fortexa.events.Envelope record in envelope/v1.avsc.
+//
+// Wraps every event exchanged between services. The payload is the Avro
+// encoding of the schema registered for the event type, at schema_version.
+type Envelope struct {
+	ID            uuid.UUID  `avro:"id" json:"id"`
+	Type          string     `avro:"type" json:"type"`
+	SchemaVersion int        `avro:"schema_version" json:"schema_version"`
+	CorrelationID uuid.UUID  `avro:"correlation_id" json:"correlation_id"`
+	CausationID   *uuid.UUID `avro:"causation_id" json:"causation_id"`
+	Producer      string     `avro:"producer" json:"producer"`
+	OccurredAt    time.Time  `avro:"occurred_at" json:"occurred_at"`
+	Payload       []byte     `avro:"payload" json:"payload"`
+}
+
+// FraudCheck is generated from the fortexa.events.FraudCheck record in fraud_check/v5.avsc.
+//
+// The result of analyzing a payment for fraud.
+type FraudCheck struct {
+	PaymentID    uuid.UUID  `avro:"payment_id" json:"payment_id"`
+	MerchantID   uuid.UUID  `avro:"merchant_id" json:"merchant_id"`
+	CustomerID   *uuid.UUID `avro:"customer_id" json:"customer_id"`
+	RiskScore    float64    `avro:"risk_score" json:"risk_score"`
+	IsFraudulent bool       `avro:"is_fraudulent" json:"is_fraudulent"`
+	// ALLOW, REVIEW or BLOCK; empty for checks made before decisions were recorded.
+	Decision string `avro:"decision" json:"decision"`
+	Reason   string `avro:"reason" json:"reason"`
+	// The factors that contributed most to the decision, strongest first.
+	ReasonCodes []string         `avro:"reason_codes" json:"reason_codes"`
+	Checks      []FraudCheckItem `avro:"checks" json:"checks"`
+	ModelScores []ModelScore     `avro:"model_scores" json:"model_scores"`
+	CreatedAt   time.Time        `avro:"created_at" json:"created_at"`
+}
+
+// FraudCheckItem is generated from the fortexa.events.FraudCheckItem record in fraud_check/v5.avsc.
+//
+// The outcome of a single fraud check.
+type FraudCheckItem struct {
+	Type  string  `avro:"type" json:"type"`
+	Score float64 `avro:"score" json:"score"`
+	Info  string  `avro:"info" json:"info"`
+	// The weight of the check in the risk score.
+	Weight float64 `avro:"weight" json:"weight"`
+	// The rules that fired, in the order they were evaluated.
+	Rules []string `avro:"rules" json:"rules"`
+}
+
+// ModelScore is generated from the fortexa.events.ModelScore record in fraud_check/v5.avsc.
+//
+// The score of a machine-learning model, live or in shadow mode.
+type ModelScore struct {
+	Model   string `avro:"model" json:"model"`
+	Version string `avro:"version" json:"version"`
+	// The fraud probability the model gave the payment.
+	Score float64 `avro:"score" json:"score"`
+	// Whether the model was only scored for comparison and did not affect the decision.
+	Shadow bool `avro:"shadow" json:"shadow"`
+	// The decision the payment gets, or would have got, with this model live.
+	Decision string `avro:"decision" json:"decision"`
+}
+
+// FraudReview is generated from the fortexa.events.FraudReview record in fraud_review/v1.avsc.
+//
+// An analyst's decision on a fraud case opened for a payment held for review.
+type FraudReview struct {
+	CaseID     uuid.UUID `avro:"case_id" json:"case_id"`
+	PaymentID  uuid.UUID `avro:"payment_id" json:"payment_id"`
+	MerchantID uuid.UUID `avro:"merchant_id" json:"merchant_id"`
+	// approve, which releases the payment to authorization, or reject, which declines it.
+	Decision string `avro:"decision" json:"decision"`
+	// The training label the decision gives the payment, FRAUD or LEGITIMATE.
+	Label      string    `avro:"label" json:"label"`
+	Reviewer   string    `avro:"reviewer" json:"reviewer"`
+	Note       string    `avro:"note" json:"note"`
+	ReviewedAt time.Time `avro:"reviewed_at" json:"reviewed_at"`
+}
+
+// Payment is generated from the fortexa.events.Payment record in payment/v5.avsc.
+//
+// A payment as carried by payment commands and payment events.
+type Payment struct {
+	ID         uuid.UUID  `avro:"id" json:"id"`
+	MerchantID uuid.UUID  `avro:"merchant_id" json:"merchant_id"`
+	CustomerID *uuid.UUID `avro:"customer_id" json:"customer_id"`
+	// The amount as a float, for consumers that predate amount_minor; use amount_minor where it is set.
+	Amount float64 `avro:"amount" json:"amount"`
+	// The exact amount in minor units of the currency, such as cents, or 1 for 1 JPY. Zero in events written before it was added.
+	AmountMinor       int64             `avro:"amount_minor" json:"amount_minor"`
+	Currency          string            `avro:"currency" json:"currency"`
+	Status            string            `avro:"status" json:"status"`
+	PaymentMethodType string            `avro:"payment_method_type" json:"payment_method_type"`
+	PaymentMethodID   *uuid.UUID        `avro:"payment_method_id" json:"payment_method_id"`
+	Description       string            `avro:"description" json:"description"`
+	Metadata          map[string]string `avro:"metadata" json:"metadata"`
+	IdempotencyKey    string            `avro:"idempotency_key" json:"idempotency_key"`
+	ReferenceID       string            `avro:"reference_id" json:"reference_id"`
+	// Why the payment was declined, such as FRAUD_SUSPECTED; empty unless it was.
+	DeclineCode string `avro:"decline_code" json:"decline_code"`
+	// The IP address the payment was requested from, as seen by the gateway.
+	ClientIP string `avro:"client_ip" json:"client_ip"`
+	// ISO 3166-1 alpha-2 country of the billing address, if given.
+	BillingCountry string `avro:"billing_country" json:"billing_country"`
+	// ISO 3166-1 alpha-2 country the card was issued in, if given.
+	CardIssuingCountry string    `avro:"card_issuing_country" json:"card_issuing_country"`
+	Device             *Device   `avro:"device" json:"device"`
+	Session            *Session  `avro:"session" json:"session"`
+	CreatedAt          time.Time `avro:"created_at" json:"created_at"`
+	UpdatedAt          time.Time `avro:"updated_at" json:"updated_at"`
+}
+
+// Device is generated from the fortexa.events.Device record in payment/v5.avsc.
+//
+// The device a payment was made from, as reported by the merchant's client.
+type Device struct {
+	// A stable fingerprint of the device.
+	ID        string `avro:"id" json:"id"`
+	UserAgent string `avro:"user_agent" json:"user_agent"`
+	// Screen resolution, such as 1920x1080.
+	Screen string `avro:"screen" json:"screen"`
+	// IANA time zone of the device, such as Europe/Berlin.
+	Timezone string `avro:"timezone" json:"timezone"`
+}
+
+// Session is generated from the fortexa.events.Session record in payment/v5.avsc.
+//
+// The customer session a payment was made in.
+type Session struct {
+	ID string `avro:"id" json:"id"`
+	// Days since the customer's account was created, if known.
+	AccountAgeDays *int `avro:"account_age_days" json:"account_age_days"`
+}
+
+// Settlement is generated from the fortexa.events.Settlement record in settlement/v4.avsc.
+//
+// A settlement of captured payments to a merchant. The merchant is paid
+// net_amount_minor less debit_amount_minor, reserve_held_minor and
+// balance_carried_forward_minor, plus reserve_released_minor,
+// balance_brought_forward_minor and recovery_amount_minor, which is never
+// negative.
+type Settlement struct {
+	ID               uuid.UUID `avro:"id" json:"id"`
+	MerchantID       uuid.UUID `avro:"merchant_id" json:"merchant_id"`
+	Amount           float64   `avro:"amount" json:"amount"`
+	Currency         string    `avro:"currency" json:"currency"`
+	Status           string    `avro:"status" json:"status"`
+	PaymentCount     int       `avro:"payment_count" json:"payment_count"`
+	FeeAmount        float64   `avro:"fee_amount" json:"fee_amount"`
+	TaxAmount        float64   `avro:"tax_amount" json:"tax_amount"`
+	NetAmount        float64   `avro:"net_amount" json:"net_amount"`
+	SettlementDate   time.Time `avro:"settlement_date" json:"settlement_date"`
+	BankAccountID    string    `avro:"bank_account_id" json:"bank_account_id"`
+	SettlementMethod string    `avro:"settlement_method" json:"settlement_method"`
+	Reference        string    `avro:"reference" json:"reference"`
+	// The exact amount in minor units of the currency. Zero in events written before it was added.
+	AmountMinor int64 `avro:"amount_minor" json:"amount_minor"`
+	// The exact fee in minor units of the currency.
+	FeeAmountMinor int64 `avro:"fee_amount_minor" json:"fee_amount_minor"`
+	// The exact tax in minor units of the currency.
+	TaxAmountMinor int64 `avro:"tax_amount_minor" json:"tax_amount_minor"`
+	// The exact net amount in minor units of the currency; amount_minor less fee_amount_minor and tax_amount_minor.
+	NetAmountMinor int64 `avro:"net_amount_minor" json:"net_amount_minor"`
+	// The part of the net amount held in the merchant's rolling reserve, in minor units of the currency.
+	ReserveHeldMinor int64 `avro:"reserve_held_minor" json:"reserve_held_minor"`
+	// Earlier reserves released with the settlement, in minor units of the currency.
+	ReserveReleasedMinor int64 `avro:"reserve_released_minor" json:"reserve_released_minor"`
+	// Refunds and chargebacks deducted by the settlement, in minor units of the currency.
+	DebitAmountMinor int64 `avro:"debit_amount_minor" json:"debit_amount_minor"`
+	// The number of refunds and chargebacks deducted by the settlement.
+	DebitCount int `avro:"debit_count" json:"debit_count"`
+	// The negative balance brought forward from the merchant's previous settlement, in minor units of the currency; zero or negative.
+	BalanceBroughtForwardMinor int64 `avro:"balance_brought_forward_minor" json:"balance_brought_forward_minor"`
+	// The negative balance carried forward to the merchant's next settlement, in minor units of the currency; zero or negative.
+	BalanceCarriedForwardMinor int64 `avro:"balance_carried_forward_minor" json:"balance_carried_forward_minor"`
+	// INVOICE or DIRECT_DEBIT when the settlement recovers a persistent negative balance from the merchant; empty otherwise.
+	RecoveryMethod string `avro:"recovery_method" json:"recovery_method"`
+	// The negative balance recovered from the merchant, in minor units of the currency.
+	RecoveryAmountMinor int64 `avro:"recovery_amount_minor" json:"recovery_amount_minor"`
+	// When the settlement is paid out; null in events written before payouts were delayed, which were paid out at once.
+	PayoutDate *time.Time `avro:"payout_date" json:"payout_date"`
+	CreatedAt  time.Time  `avro:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `avro:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,128 @@
+// Package events defines the envelope shared by all services and the catalogue
+// of event types exchanged over Kafka.
+//
+// Payment traffic is split into two topics:
+//
+//   - payments.commands carries requests for the payment engine to act on a
+//     payment (authorize, capture, refund). Only the payment engine consumes it.
+//   - payments.events carries facts about what happened to a payment. The
+//     payment engine never consumes it, so it cannot see its own output.
+//
+// Fraud screening is a blocking stage of the payment flow: the fraud
+// detection service screens every payment.initiated event and answers with a
+// command for the payment engine, authorization when the payment is allowed,
+// a review hold or a decline. Each decision is also published to fraud.events,
+// and so is the decision an analyst makes on a payment held for review.
+// Confirmed chargebacks and merchants' fraud reports, recorded through the
+// gateway, are published to payments.events as payment.charged_back and
+// payment.fraud_reported. Together with refunds of fraudulent payments, they
+// label payments for fraud detection and feed the fraud blocklists.
+// Settlements are published to settlements.events.
+//
+// Payloads are defined once as Avro schemas in the schemas directory, which
+// acts as the schema registry, and the Go types in schemas_gen.go are
+// generated from them. To change a payload, add a new schema version with
+// schemactl register (which rejects incompatible changes) and run go generate.
+//
+// Amounts are carried exactly, in minor units of their currency (see package
+// money), alongside the floats older consumers read. Use the Money and
+// Amounts methods to read them, which fall back to the floats for events
+// written before minor units were added.
+package events
+
+// Payment commands, published to the payment commands topic
+const (
+	TypePaymentAuthorizationRequested = "payment.authorization.requested"
+	TypePaymentCaptureRequested       = "payment.capture.requested"
+	TypePaymentRefundRequested        = "payment.refund.requested"
+	TypePaymentReviewRequested        = "payment.review.requested"
+	TypePaymentDeclineRequested       = "payment.decline.requested"
+)
+
+// Payment facts, published to the payment events topic
+const (
+	TypePaymentInitiated           = "payment.initiated"
+	TypePaymentAuthorized          = "payment.authorized"
+	TypePaymentAuthorizationFailed = "payment.authorization.failed"
+	TypePaymentCaptured            = "payment.captured"
+	TypePaymentCaptureFailed       = "payment.capture.failed"
+	TypePaymentRefunded            = "payment.refunded"
+	TypePaymentRefundFailed        = "payment.refund.failed"
+	TypePaymentReviewRequired      = "payment.review.required"
+	TypePaymentDeclined            = "payment.declined"
+	TypePaymentChargedBack         = "payment.charged_back"
+	TypePaymentFraudReported       = "payment.fraud_reported"
+)
+
+// Fraud facts, published to the fraud events topic
+const (
+	TypeFraudCheckCompleted  = "fraud.check.completed"
+	TypeFraudReviewCompleted = "fraud.review.completed"
+)
+
+// Settlement facts, published to the settlement events topic
+const (
+	TypeSettlementCreated = "settlement.created"
+)
+
+// commands holds the event types that are commands rather than facts
+var commands = map[string]bool{
+	TypePaymentAuthorizationRequested: true,
+	TypePaymentCaptureRequested:       true,
+	TypePaymentRefundRequested:        true,
+	TypePaymentReviewRequested:        true,
+	TypePaymentDeclineRequested:       true,
+}
+
+// IsCommand reports whether an event type is a command, which belongs on a
+// commands topic, rather than a fact
+func IsCommand(eventType string) bool {
+	return commands[eventType]
+}
+
+// Payload schema subjects in the registry
+const (
+	SubjectEnvelope    = "envelope"
+	SubjectPayment     = "payment"
+	SubjectFraudCheck  = "fraud_check"
+	SubjectFraudReview = "fraud_review"
+	SubjectSettlement  = "settlement"
+)
+
+// subjects maps each event type to the registry subject of its payload
+var subjects = map[string]string{
+	TypePaymentAuthorizationRequested: SubjectPayment,
+	TypePaymentCaptureRequested:       SubjectPayment,
+	TypePaymentRefundRequested:        SubjectPayment,
+	TypePaymentReviewRequested:        SubjectPayment,
+	TypePaymentDeclineRequested:       SubjectPayment,
+	TypePaymentInitiated:              SubjectPayment,
+	TypePaymentAuthorized:             SubjectPayment,
+	TypePaymentAuthorizationFailed:    SubjectPayment,
+	TypePaymentCaptured:               SubjectPayment,
+	TypePaymentCaptureFailed:          SubjectPayment,
+	TypePaymentRefunded:               SubjectPayment,
+	TypePaymentRefundFailed:           SubjectPayment,
+	TypePaymentReviewRequired:         SubjectPayment,
+	TypePaymentDeclined:               SubjectPayment,
+	TypePaymentChargedBack:            SubjectPayment,
+	TypePaymentFraudReported:          SubjectPayment,
+	TypeFraudCheckCompleted:           SubjectFraudCheck,
+	TypeFraudReviewCompleted:          SubjectFraudReview,
+	TypeSettlementCreated:             SubjectSettlement,
+}
+
+// Subject returns the registry subject of an event type's payload
+func Subject(eventType string) (string, bool) {
+	subject, ok := subjects[eventType]
+	return subject, ok
+}
+
+// SchemaVersion returns the current schema version of an event type's payload
+func SchemaVersion(eventType string) (int, bool) {
+	subject, ok := subjects[eventType]
+	if !ok {
+		return 0, false
+	}
+	return Registry.Versions(subject), true
+}
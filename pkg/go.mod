@@ -0,0 +1,20 @@
+module github.com/yourusername/fortexa/pkg
+
+go 1.20
+
+require (
+	github.com/google/uuid v1.3.1
+	github.com/hamba/avro v1.6.6
+	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.10.9
+	github.com/segmentio/kafka-go v0.4.44
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/json-iterator/go v1.1.12 // indirect
+	github.com/klauspost/compress v1.16.7 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/pierrec/lz4/v4 v4.1.18 // indirect
+)
@@ -0,0 +1,43 @@
+package money
+
+import "strings"
+
+// defaultExponent is the exponent of currencies not in exponents
+const defaultExponent = 2
+
+// exponents are the ISO 4217 exponents of currencies whose minor unit is not
+// a hundredth of their major unit
+var exponents = map[string]int{
+	"BIF": 0,
+	"CLP": 0,
+	"DJF": 0,
+	"GNF": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KMF": 0,
+	"KRW": 0,
+	"PYG": 0,
+	"RWF": 0,
+	"UGX": 0,
+	"VND": 0,
+	"VUV": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"XPF": 0,
+	"BHD": 3,
+	"IQD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// Exponent returns the number of decimals of a currency's minor unit: 0 for
+// JPY, 3 for KWD and 2 for most others, including unknown currencies
+func Exponent(currency string) int {
+	if exponent, ok := exponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return defaultExponent
+}
@@ -0,0 +1,100 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes d as a JSON number with its exact digits, such as 10.50
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or a string holding one, written
+// without an exponent. Numbers are read from their digits, never through a
+// float. null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	parsed, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalText encodes d as its decimal string, for use as map keys and in
+// text formats
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a decimal string
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Scan reads d from a DECIMAL or NUMERIC column, which drivers return as
+// text. NULL scans as 0.
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Zero
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	case int64:
+		*d = New(v, 0)
+		return nil
+	case float64:
+		*d = FromFloat(v)
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T into Decimal", src)
+	}
+}
+
+// Value stores d as its decimal string, which Postgres reads exactly into
+// DECIMAL and NUMERIC columns
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Format writes d for fmt verbs: %s and %v print its exact digits, and %f
+// with a precision rounds half up to that many decimals, so %.2f prints 10.50
+func (d Decimal) Format(f fmt.State, verb rune) {
+	s := d.String()
+	if verb == 'f' {
+		if precision, ok := f.Precision(); ok {
+			s = d.Round(precision, RoundHalfUp).String()
+		}
+	}
+	if width, ok := f.Width(); ok && len(s) < width {
+		pad := string(bytes.Repeat([]byte(" "), width-len(s)))
+		if f.Flag('-') {
+			s += pad
+		} else {
+			s = pad + s
+		}
+	}
+	fmt.Fprint(f, s)
+}
@@ -0,0 +1,250 @@
+// Package money holds amounts of money as exact decimals.
+//
+// Amounts are never floating-point: a Decimal is an arbitrary-precision
+// integer scaled by a power of ten, so adding, subtracting and multiplying
+// amounts is exact. Only rounding loses precision, and every rounding names
+// its RoundingMode. Decimals are also used for rates, such as fee
+// percentages, so fees are computed without float error too.
+//
+// Each currency has an exponent, the number of decimals of its minor unit:
+// 2 for most currencies, 0 for JPY and 3 for KWD (see Exponent). Amounts of a
+// currency are rounded to its exponent with RoundTo, checked with Fits, and
+// converted to and from integer minor units with Minor and FromMinor, the
+// form events carry them in.
+//
+// Decimals encode to JSON as numbers with their exact digits and decode from
+// numbers or strings, and they scan from and are stored in DECIMAL columns
+// as decimal strings.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrSyntax is returned when a string is not a decimal number
+var ErrSyntax = errors.New("money: invalid decimal")
+
+// Decimal is an exact decimal number, units × 10^-scale. The zero value is 0.
+// Decimals are values and safe to copy; methods never modify their receiver.
+// Compare them with Cmp, since 1.5 and 1.50 are different Decimals of equal
+// value.
+type Decimal struct {
+	units *big.Int // nil is 0
+	scale int32
+}
+
+// Zero is the decimal 0
+var Zero = Decimal{}
+
+// New returns units × 10^-scale, so New(1050, 2) is 10.50
+func New(units int64, scale int) Decimal {
+	return Decimal{units: big.NewInt(units), scale: int32(scale)}
+}
+
+// FromMinor returns an amount of a currency given in its minor units, so
+// FromMinor(1050, "USD") is 10.50 and FromMinor(1050, "JPY") is 1050
+func FromMinor(minor int64, currency string) Decimal {
+	return New(minor, Exponent(currency))
+}
+
+// FromFloat returns the decimal with the shortest representation that
+// parses back to f, which is the decimal f was written as: FromFloat(0.1) is
+// exactly 0.1. It is meant for values that were decimals before they were
+// stored as floats, such as configuration; round amounts to their currency
+// afterwards. Infinities and NaN return 0.
+func FromFloat(f float64) Decimal {
+	d, err := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return Zero
+	}
+	return d
+}
+
+// Parse parses a decimal number such as 10, -0.05 or 10.50. The number of
+// decimals written is kept: Parse("10.50").String() is "10.50". Exponents, as
+// in 1e5, are rejected: amounts are written out in full.
+func Parse(s string) (Decimal, error) {
+	mantissa, sign := s, ""
+	if strings.HasPrefix(mantissa, "-") || strings.HasPrefix(mantissa, "+") {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	digits := whole + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	units, ok := new(big.Int).SetString(sign+digits, 10)
+	if !ok {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	return Decimal{units: units, scale: int32(len(fraction))}, nil
+}
+
+// MustParse is like Parse but panics if s is not a decimal number. It is
+// meant for constants.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// int returns the units of d, never nil
+func (d Decimal) int() *big.Int {
+	if d.units == nil {
+		return new(big.Int)
+	}
+	return d.units
+}
+
+// rescale returns the units of d scaled to a scale at least d's
+func (d Decimal) rescale(scale int32) *big.Int {
+	units := new(big.Int).Set(d.int())
+	if scale > d.scale {
+		units.Mul(units, pow10(int(scale-d.scale)))
+	}
+	return units
+}
+
+// align returns the units of a and b at their common scale
+func align(a, b Decimal) (*big.Int, *big.Int, int32) {
+	scale := a.scale
+	if b.scale > scale {
+		scale = b.scale
+	}
+	return a.rescale(scale), b.rescale(scale), scale
+}
+
+// Add returns d + e
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b, scale := align(d, e)
+	return Decimal{units: a.Add(a, b), scale: scale}
+}
+
+// Sub returns d - e
+func (d Decimal) Sub(e Decimal) Decimal {
+	a, b, scale := align(d, e)
+	return Decimal{units: a.Sub(a, b), scale: scale}
+}
+
+// Mul returns d × e, exactly: its scale is the sum of theirs
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{units: new(big.Int).Mul(d.int(), e.int()), scale: d.scale + e.scale}
+}
+
+// Percent returns percent % of d, exactly
+func (d Decimal) Percent(percent Decimal) Decimal {
+	product := d.Mul(percent)
+	product.scale += 2
+	return product
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	return Decimal{units: new(big.Int).Abs(d.int()), scale: d.scale}
+}
+
+// Cmp compares the values of d and e, returning -1, 0 or +1
+func (d Decimal) Cmp(e Decimal) int {
+	a, b, _ := align(d, e)
+	return a.Cmp(b)
+}
+
+// Sign returns -1, 0 or +1 as d is negative, zero or positive
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// IsZero reports whether d is 0
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Decimals returns the number of decimals d is written with
+func (d Decimal) Decimals() int {
+	return int(d.scale)
+}
+
+// Round returns d rounded to the given number of decimals with a rounding
+// mode. The result has exactly that many decimals, so rounding 10.5 to two
+// decimals gives 10.50.
+func (d Decimal) Round(decimals int, mode RoundingMode) Decimal {
+	scale := int32(decimals)
+	if scale >= d.scale {
+		return Decimal{units: d.rescale(scale), scale: scale}
+	}
+	return Decimal{units: round(d.int(), pow10(int(d.scale-scale)), mode), scale: scale}
+}
+
+// RoundTo returns d rounded to the minor unit of a currency with a rounding mode
+func (d Decimal) RoundTo(currency string, mode RoundingMode) Decimal {
+	return d.Round(Exponent(currency), mode)
+}
+
+// Fits reports whether d is a whole number of minor units of a currency, so
+// 10.50 fits USD but not JPY
+func (d Decimal) Fits(currency string) bool {
+	return d.Cmp(d.Round(Exponent(currency), RoundDown)) == 0
+}
+
+// Minor returns d in minor units of a currency, so 10.50 USD is 1050. Amounts
+// with more decimals than the currency has are rounded half to even; round
+// them explicitly beforehand where the rounding matters. It panics if the
+// amount does not fit in an int64.
+func (d Decimal) Minor(currency string) int64 {
+	units := d.Round(Exponent(currency), RoundHalfEven).int()
+	if !units.IsInt64() {
+		panic(fmt.Sprintf("money: %s %s out of range", d, currency))
+	}
+	return units.Int64()
+}
+
+// Float64 returns the nearest float64 to d, for scores and statistics that
+// need not be exact
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.int(), pow10(int(d.scale))).Float64()
+	return f
+}
+
+// String returns d with the decimals it is written with, such as -0.05
+func (d Decimal) String() string {
+	units := d.int()
+	digits := new(big.Int).Abs(units).String()
+	sign := ""
+	if units.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale <= 0 {
+		return sign + digits + strings.Repeat("0", int(-d.scale))
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Sum returns the exact sum of decimals
+func Sum(decimals ...Decimal) Decimal {
+	var sum Decimal
+	for _, d := range decimals {
+		sum = sum.Add(d)
+	}
+	return sum
+}
+
+// pow10 returns 10^n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,172 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"10", "10"},
+		{"-0.05", "-0.05"},
+		{"10.50", "10.50"},
+		{"+1.5", "1.5"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{"0.000", "0.000"},
+		{"123456789012345678901234567890.12", "123456789012345678901234567890.12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if got := d.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, input := range []string{"", "1e5", "1.5E3", "1.2.3", "-", "+", ".", "abc", "1,000", " 1", "1 ", "--1", "0x10"} {
+		t.Run(input, func(t *testing.T) {
+			if d, err := Parse(input); !errors.Is(err, ErrSyntax) {
+				t.Errorf("Parse(%q) = %s, %v, want ErrSyntax", input, d, err)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	values := []string{"2.5", "-2.5", "3.5", "2.1", "-2.1", "2.9", "-2.9", "-2.0"}
+	tests := []struct {
+		mode RoundingMode
+		want []string
+	}{
+		{RoundHalfUp, []string{"3", "-3", "4", "2", "-2", "3", "-3", "-2"}},
+		{RoundHalfEven, []string{"2", "-2", "4", "2", "-2", "3", "-3", "-2"}},
+		{RoundHalfDown, []string{"2", "-2", "3", "2", "-2", "3", "-3", "-2"}},
+		{RoundDown, []string{"2", "-2", "3", "2", "-2", "2", "-2", "-2"}},
+		{RoundUp, []string{"3", "-3", "4", "3", "-3", "3", "-3", "-2"}},
+		{RoundFloor, []string{"2", "-3", "3", "2", "-3", "2", "-3", "-2"}},
+		{RoundCeiling, []string{"3", "-2", "4", "3", "-2", "3", "-2", "-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			for i, value := range values {
+				if got := MustParse(value).Round(0, tt.mode).String(); got != tt.want[i] {
+					t.Errorf("Round(%s, 0) = %s, want %s", value, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRoundDecimals(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		mode     RoundingMode
+		want     string
+	}{
+		{"10.125", 2, RoundHalfUp, "10.13"},
+		{"10.125", 2, RoundHalfEven, "10.12"},
+		{"10.135", 2, RoundHalfEven, "10.14"},
+		{"-10.125", 2, RoundHalfUp, "-10.13"},
+		{"-10.125", 2, RoundHalfDown, "-10.12"},
+		{"10.1251", 2, RoundHalfDown, "10.13"},
+		{"10.5", 2, RoundHalfEven, "10.50"},
+		{"0.0049", 2, RoundCeiling, "0.01"},
+		{"-0.0049", 2, RoundCeiling, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.value).Round(tt.decimals, tt.mode).String(); got != tt.want {
+			t.Errorf("Round(%s, %d, %s) = %s, want %s", tt.value, tt.decimals, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMinor(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+	}{
+		{"10.50", "USD", 1050},
+		{"10.5", "usd", 1050},
+		{"-0.05", "EUR", -5},
+		{"-10.505", "USD", -1050},
+		{"1050", "JPY", 1050},
+		{"10.5", "JPY", 10},
+		{"11.5", "JPY", 12},
+		{"10.125", "KWD", 10125},
+		{"1.2345", "KWD", 1234},
+		{"-1.2355", "KWD", -1236},
+		{"92233720368547758.07", "USD", 9223372036854775807},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.amount).Minor(tt.currency); got != tt.want {
+			t.Errorf("Minor(%s %s) = %d, want %d", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMinorOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Minor() did not panic for an amount beyond int64")
+		}
+	}()
+	MustParse("92233720368547758.08").Minor("USD")
+}
+
+func TestFromMinor(t *testing.T) {
+	tests := []struct {
+		minor    int64
+		currency string
+		want     string
+	}{
+		{1050, "USD", "10.50"},
+		{-5, "USD", "-0.05"},
+		{0, "USD", "0.00"},
+		{1050, "JPY", "1050"},
+		{-1050, "JPY", "-1050"},
+		{10125, "KWD", "10.125"},
+		{5, "KWD", "0.005"},
+		{-5, "KWD", "-0.005"},
+	}
+	for _, tt := range tests {
+		d := FromMinor(tt.minor, tt.currency)
+		if got := d.String(); got != tt.want {
+			t.Errorf("FromMinor(%d, %s) = %s, want %s", tt.minor, tt.currency, got, tt.want)
+		}
+		if got := d.Minor(tt.currency); got != tt.minor {
+			t.Errorf("FromMinor(%d, %s).Minor() = %d", tt.minor, tt.currency, got)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		d    Decimal
+		want string
+	}{
+		{Zero, "0"},
+		{New(0, 2), "0.00"},
+		{New(1050, 2), "10.50"},
+		{New(-5, 2), "-0.05"},
+		{New(5, 3), "0.005"},
+		{New(5, -2), "500"},
+		{New(-1, 0), "-1"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+}
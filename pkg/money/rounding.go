@@ -0,0 +1,93 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// RoundingMode says which way to round a value between two multiples
+type RoundingMode int
+
+// Rounding modes. The half modes round to the nearest multiple and differ
+// only in how they break ties.
+const (
+	// RoundHalfUp rounds ties away from zero: 2.5 to 3, -2.5 to -3
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds ties to the even multiple: 2.5 to 2, 3.5 to 4
+	RoundHalfEven
+	// RoundHalfDown rounds ties towards zero: 2.5 to 2, -2.5 to -2
+	RoundHalfDown
+	// RoundDown rounds towards zero, truncating: 2.9 to 2, -2.9 to -2
+	RoundDown
+	// RoundUp rounds away from zero: 2.1 to 3, -2.1 to -3
+	RoundUp
+	// RoundFloor rounds towards negative infinity: 2.9 to 2, -2.1 to -3
+	RoundFloor
+	// RoundCeiling rounds towards positive infinity: 2.1 to 3, -2.9 to -2
+	RoundCeiling
+)
+
+// roundingModeNames names the rounding modes in configuration
+var roundingModeNames = map[RoundingMode]string{
+	RoundHalfUp:   "HALF_UP",
+	RoundHalfEven: "HALF_EVEN",
+	RoundHalfDown: "HALF_DOWN",
+	RoundDown:     "DOWN",
+	RoundUp:       "UP",
+	RoundFloor:    "FLOOR",
+	RoundCeiling:  "CEILING",
+}
+
+// String returns the name of the rounding mode, such as HALF_EVEN
+func (m RoundingMode) String() string {
+	if name, ok := roundingModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// ParseRoundingMode parses a rounding mode name: HALF_UP, HALF_EVEN,
+// HALF_DOWN, DOWN, UP, FLOOR or CEILING
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	for mode, modeName := range roundingModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("money: unknown rounding mode %q", name)
+}
+
+// round returns units / divisor rounded to an integer with a rounding mode
+func round(units, divisor *big.Int, mode RoundingMode) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(units, divisor, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// The remainder has the sign of units; away from zero is that direction
+	sign := int64(units.Sign())
+	half := new(big.Int).Abs(remainder)
+	half.Lsh(half, 1)
+	tie := half.Cmp(divisor)
+
+	away := false
+	switch mode {
+	case RoundHalfUp:
+		away = tie >= 0
+	case RoundHalfEven:
+		away = tie > 0 || tie == 0 && quotient.Bit(0) == 1
+	case RoundHalfDown:
+		away = tie > 0
+	case RoundDown:
+	case RoundUp:
+		away = true
+	case RoundFloor:
+		away = sign < 0
+	case RoundCeiling:
+		away = sign > 0
+	}
+	if away {
+		quotient.Add(quotient, big.NewInt(sign))
+	}
+	return quotient
+}
@@ -0,0 +1,109 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore is an in-process Store used when services run without a database.
+// Messages do not survive a restart, so it only provides the ordering and retry
+// behaviour of the outbox, not its durability.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	messages []*memoryMessage
+}
+
+type memoryMessage struct {
+	Message
+	status string
+}
+
+// NewMemoryStore creates a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Add enqueues messages for delivery
+func (s *MemoryStore) Add(messages ...Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, message := range messages {
+		s.messages = append(s.messages, &memoryMessage{Message: message, status: StatusPending})
+	}
+}
+
+// FetchPending claims up to limit messages that are due for delivery, oldest first.
+// Like the Postgres store, it skips messages queued behind an older message with the
+// same topic and key that is not due.
+func (s *MemoryStore) FetchPending(ctx context.Context, limit int) ([]Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	var messages []Message
+	blocked := make(map[[2]string]bool)
+	for _, message := range s.messages {
+		if len(messages) >= limit {
+			break
+		}
+		if message.status != StatusPending {
+			continue
+		}
+		key := [2]string{message.Topic, message.Key}
+		if message.AvailableAt.After(now) {
+			if message.Key != "" {
+				blocked[key] = true
+			}
+			continue
+		}
+		if blocked[key] {
+			continue
+		}
+		message.AvailableAt = now.Add(claimLease)
+		messages = append(messages, message.Message)
+	}
+
+	return messages, nil
+}
+
+// MarkSent removes the given messages from the store
+func (s *MemoryStore) MarkSent(ctx context.Context, ids []uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sent := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		sent[id] = true
+	}
+
+	remaining := s.messages[:0]
+	for _, message := range s.messages {
+		if !sent[message.ID] {
+			remaining = append(remaining, message)
+		}
+	}
+	s.messages = remaining
+
+	return nil
+}
+
+// MarkFailed records a failed delivery attempt and schedules the next one
+func (s *MemoryStore) MarkFailed(ctx context.Context, id uuid.UUID, nextAttempt time.Time, reason string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, message := range s.messages {
+		if message.ID == id {
+			message.Attempts++
+			message.LastError = reason
+			message.AvailableAt = nextAttempt
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fetchIDs fetches up to limit pending messages and returns their IDs
+func fetchIDs(t *testing.T, store *MemoryStore, limit int) []uuid.UUID {
+	t.Helper()
+	messages, err := store.FetchPending(context.Background(), limit)
+	if err != nil {
+		t.Fatalf("FetchPending() error = %v", err)
+	}
+	ids := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
+
+// expireLeases makes every claimed message due again, as if its lease ran out
+func expireLeases(store *MemoryStore) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for _, message := range store.messages {
+		if message.AvailableAt.After(time.Now()) {
+			message.AvailableAt = time.Now().Add(-time.Second)
+		}
+	}
+}
+
+func equalIDs(got, want []uuid.UUID) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryStoreFetchPendingKeepsKeyOrder(t *testing.T) {
+	store := NewMemoryStore()
+	a1 := NewMessage("payments", "a", []byte("a1"))
+	a2 := NewMessage("payments", "a", []byte("a2"))
+	b1 := NewMessage("payments", "b", []byte("b1"))
+	other := NewMessage("settlements", "a", []byte("other"))
+	store.Add(a1, a2, b1, other)
+
+	if got := fetchIDs(t, store, 1); !equalIDs(got, []uuid.UUID{a1.ID}) {
+		t.Fatalf("first fetch = %v, want [a1]", got)
+	}
+
+	// a2 waits behind a1 while a1 is leased; other keys and topics do not
+	if got := fetchIDs(t, store, 10); !equalIDs(got, []uuid.UUID{b1.ID, other.ID}) {
+		t.Fatalf("second fetch = %v, want [b1 other]", got)
+	}
+
+	// A failed a1 still holds a2 back until it is retried and sent
+	if err := store.MarkFailed(context.Background(), a1.ID, time.Now().Add(time.Hour), "broker unavailable"); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+	if err := store.MarkSent(context.Background(), []uuid.UUID{b1.ID, other.ID}); err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+	if got := fetchIDs(t, store, 10); len(got) != 0 {
+		t.Fatalf("fetch while a1 waits for its retry = %v, want nothing", got)
+	}
+
+	// Once a1 is due, it is fetched with a2 behind it, in order, so the
+	// relay publishes them in one ordered write
+	expireLeases(store)
+	if got := fetchIDs(t, store, 10); !equalIDs(got, []uuid.UUID{a1.ID, a2.ID}) {
+		t.Fatalf("fetch after a1 is due = %v, want [a1 a2]", got)
+	}
+}
+
+func TestMemoryStoreFetchPendingLimit(t *testing.T) {
+	store := NewMemoryStore()
+	a1 := NewMessage("payments", "a", []byte("a1"))
+	a2 := NewMessage("payments", "a", []byte("a2"))
+	b1 := NewMessage("payments", "b", []byte("b1"))
+	store.Add(a1, a2, b1)
+
+	// The oldest messages are claimed first, and a2 is held back behind the
+	// a1 claimed by the earlier fetch
+	if got := fetchIDs(t, store, 1); !equalIDs(got, []uuid.UUID{a1.ID}) {
+		t.Fatalf("first fetch = %v, want [a1]", got)
+	}
+	if got := fetchIDs(t, store, 1); !equalIDs(got, []uuid.UUID{b1.ID}) {
+		t.Fatalf("second fetch = %v, want [b1]", got)
+	}
+	if err := store.MarkSent(context.Background(), []uuid.UUID{a1.ID}); err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+	if got := fetchIDs(t, store, 1); !equalIDs(got, []uuid.UUID{a2.ID}) {
+		t.Fatalf("fetch after a1 is sent = %v, want [a2]", got)
+	}
+}
+
+func TestMemoryStoreFetchPendingUnkeyedMessages(t *testing.T) {
+	store := NewMemoryStore()
+	first := NewMessage("payments", "", []byte("first"))
+	second := NewMessage("payments", "", []byte("second"))
+	store.Add(first, second)
+	if err := store.MarkFailed(context.Background(), first.ID, time.Now().Add(time.Hour), "broker unavailable"); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+
+	// Messages without a key are not ordered, so a retried one holds nothing back
+	if got := fetchIDs(t, store, 10); !equalIDs(got, []uuid.UUID{second.ID}) {
+		t.Errorf("fetch = %v, want [second]", got)
+	}
+}
+
+func TestMemoryStoreLeaseExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	message := NewMessage("payments", "a", []byte("a1"))
+	store.Add(message)
+
+	if got := fetchIDs(t, store, 10); !equalIDs(got, []uuid.UUID{message.ID}) {
+		t.Fatalf("first fetch = %v, want [a1]", got)
+	}
+	// A leased message is hidden from other relays
+	if got := fetchIDs(t, store, 10); len(got) != 0 {
+		t.Fatalf("fetch during the lease = %v, want nothing", got)
+	}
+	store.mutex.Lock()
+	leasedUntil := store.messages[0].AvailableAt
+	store.mutex.Unlock()
+	if until := time.Until(leasedUntil); until <= 0 || until > claimLease {
+		t.Errorf("message leased for %s, want up to %s", until, claimLease)
+	}
+
+	// The relay died without marking it, so it is delivered again once the
+	// lease expires
+	expireLeases(store)
+	if got := fetchIDs(t, store, 10); !equalIDs(got, []uuid.UUID{message.ID}) {
+		t.Fatalf("fetch after the lease expired = %v, want [a1]", got)
+	}
+	if err := store.MarkSent(context.Background(), []uuid.UUID{message.ID}); err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+	expireLeases(store)
+	if got := fetchIDs(t, store, 10); len(got) != 0 {
+		t.Errorf("fetch after sending = %v, want nothing", got)
+	}
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message statuses
+const (
+	StatusPending = "PENDING"
+	StatusSent    = "SENT"
+)
+
+// Message represents an event waiting in the outbox to be published to Kafka
+type Message struct {
+	ID          uuid.UUID         `json:"id"`
+	Topic       string            `json:"topic"`
+	Key         string            `json:"key"`
+	Value       []byte            `json:"value"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attempts    int               `json:"attempts"`
+	LastError   string            `json:"last_error,omitempty"`
+	AvailableAt time.Time         `json:"available_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// NewMessage creates a new outbox message for the given topic and key
+func NewMessage(topic, key string, value []byte) Message {
+	now := time.Now()
+	return Message{
+		ID:          uuid.New(),
+		Topic:       topic,
+		Key:         key,
+		Value:       value,
+		AvailableAt: now,
+		CreatedAt:   now,
+	}
+}
+
+// NewJSONMessage creates a new outbox message with the JSON encoding of event as its value
+func NewJSONMessage(topic, key string, event interface{}) (Message, error) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+	return NewMessage(topic, key, value), nil
+}
+
+// Store defines the operations the relay needs to deliver outbox messages
+type Store interface {
+	// FetchPending claims up to limit messages that are due for delivery, oldest first
+	FetchPending(ctx context.Context, limit int) ([]Message, error)
+
+	// MarkSent records that the given messages were published
+	MarkSent(ctx context.Context, ids []uuid.UUID) error
+
+	// MarkFailed records a failed delivery attempt and schedules the next one
+	MarkFailed(ctx context.Context, id uuid.UUID, nextAttempt time.Time, reason string) error
+}
+
+// Execer is implemented by both *sql.DB and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Insert writes messages to the outbox table using exec, which should be the
+// transaction that also writes the state change the messages describe
+func Insert(ctx context.Context, exec Execer, messages ...Message) error {
+	query := `
+        INSERT INTO outbox_events (
+            id, topic, message_key, payload, headers, status, available_at, created_at
+        ) VALUES (
+            $1, $2, $3, $4, $5, $6, $7, $8
+        )
+    `
+
+	for _, message := range messages {
+		var headers interface{}
+		if len(message.Headers) > 0 {
+			headersJSON, err := json.Marshal(message.Headers)
+			if err != nil {
+				return fmt.Errorf("failed to marshal outbox headers: %w", err)
+			}
+			headers = string(headersJSON)
+		}
+
+		_, err := exec.ExecContext(
+			ctx,
+			query,
+			message.ID,
+			message.Topic,
+			message.Key,
+			message.Value,
+			headers,
+			StatusPending,
+			message.AvailableAt,
+			message.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert outbox message: %w", err)
+		}
+	}
+
+	return nil
+}
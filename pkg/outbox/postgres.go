@@ -0,0 +1,140 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// claimLease is how long a fetched message is hidden from other relays while it is being published
+const claimLease = 30 * time.Second
+
+// PostgresStore is a Store backed by the outbox_events table
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgresStore
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// FetchPending claims up to limit messages that are due for delivery, oldest first.
+// Claimed messages are leased so that concurrent relays do not publish them twice;
+// if this relay dies before marking them, they become available again when the lease expires.
+// A message is not claimed while an older message with the same topic and key is
+// still pending but not due, because it is waiting to be retried or leased by another
+// relay, so messages for the same entity are never published out of order.
+func (s *PostgresStore) FetchPending(ctx context.Context, limit int) ([]Message, error) {
+	query := `
+        UPDATE outbox_events
+        SET available_at = $1
+        WHERE id IN (
+            SELECT e.id
+            FROM outbox_events e
+            WHERE e.status = $2 AND e.available_at <= $3
+                AND NOT EXISTS (
+                    SELECT 1
+                    FROM outbox_events o
+                    WHERE o.status = $2 AND o.available_at > $3
+                        AND o.topic = e.topic AND o.message_key = e.message_key AND e.message_key <> ''
+                        AND o.created_at < e.created_at
+                )
+            ORDER BY e.created_at
+            LIMIT $4
+            FOR UPDATE OF e SKIP LOCKED
+        )
+        RETURNING id, topic, message_key, payload, headers, attempts, COALESCE(last_error, ''), created_at
+    `
+
+	now := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, now.Add(claimLease), StatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var message Message
+		var key sql.NullString
+		var headers []byte
+
+		err := rows.Scan(
+			&message.ID,
+			&message.Topic,
+			&key,
+			&message.Value,
+			&headers,
+			&message.Attempts,
+			&message.LastError,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+
+		message.Key = key.String
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &message.Headers); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal outbox headers: %w", err)
+			}
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox rows: %w", err)
+	}
+
+	// RETURNING does not preserve the subquery order
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	return messages, nil
+}
+
+// MarkSent records that the given messages were published
+func (s *PostgresStore) MarkSent(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	query := `
+        UPDATE outbox_events
+        SET status = $1, sent_at = $2
+        WHERE id = ANY($3::uuid[])
+    `
+	_, err := s.db.ExecContext(ctx, query, StatusSent, time.Now(), pq.Array(idStrings))
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox messages as sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed delivery attempt and schedules the next one
+func (s *PostgresStore) MarkFailed(ctx context.Context, id uuid.UUID, nextAttempt time.Time, reason string) error {
+	query := `
+        UPDATE outbox_events
+        SET attempts = attempts + 1, last_error = $1, available_at = $2
+        WHERE id = $3
+    `
+	_, err := s.db.ExecContext(ctx, query, reason, nextAttempt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message as failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// Retry backoff bounds for messages that fail to publish
+const (
+	minRetryBackoff = 1 * time.Second
+	maxRetryBackoff = 5 * time.Minute
+)
+
+// Publisher is the subset of *kafka.Writer used by the relay
+type Publisher interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Relay moves messages from the outbox to Kafka. Delivery is at-least-once:
+// a message is only marked sent after Kafka acknowledged it, and failed
+// messages are retried with exponential backoff until they succeed.
+type Relay struct {
+	store        Store
+	publisher    Publisher
+	pollInterval time.Duration
+	batchSize    int
+}
+
+// NewRelay creates a new Relay. The publisher must not have a fixed topic,
+// since every outbox message carries its own.
+func NewRelay(store Store, publisher Publisher, pollInterval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		store:        store,
+		publisher:    publisher,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+	}
+}
+
+// NewWriter creates a Kafka writer suitable for the relay. Messages are
+// partitioned by key so that all events for the same entity stay in order.
+func NewWriter(brokers ...string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Async:        false,
+	}
+}
+
+// Run relays messages until the context is canceled
+func (r *Relay) Run(ctx context.Context) {
+	log.Println("Outbox relay started")
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that is due before waiting for the next tick
+		for {
+			relayed, err := r.relayBatch(ctx)
+			if err != nil {
+				log.Printf("Error relaying outbox messages: %v", err)
+				break
+			}
+			if relayed < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox relay shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayBatch publishes a single batch of pending messages and returns how many were fetched
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	messages, err := r.store.FetchPending(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	kafkaMessages := make([]kafka.Message, len(messages))
+	for i, message := range messages {
+		kafkaMessages[i] = toKafkaMessage(message)
+	}
+
+	err = r.publisher.WriteMessages(ctx, kafkaMessages...)
+
+	// Kafka reports per-message errors when only part of the batch failed.
+	// Messages with the same topic and key share a partition, so they
+	// usually fail together. A failed message still holds back the newer
+	// messages with its topic and key, which are not fetched again until it
+	// is sent; only newer ones already in this batch may overtake it, when
+	// the writer split the partition's messages over several requests.
+	var writeErrors kafka.WriteErrors
+	if err != nil && !errors.As(err, &writeErrors) {
+		writeErrors = make(kafka.WriteErrors, len(messages))
+		for i := range writeErrors {
+			writeErrors[i] = err
+		}
+	}
+
+	var sent []uuid.UUID
+	for i, message := range messages {
+		if writeErrors != nil && writeErrors[i] != nil {
+			r.markFailed(ctx, message, writeErrors[i])
+			continue
+		}
+		sent = append(sent, message.ID)
+	}
+
+	if err := r.store.MarkSent(ctx, sent); err != nil {
+		// The messages were published, so the worst case is a duplicate delivery on the next run
+		return len(messages), err
+	}
+
+	if len(sent) > 0 {
+		log.Printf("Relayed %d outbox messages", len(sent))
+	}
+	return len(messages), nil
+}
+
+// markFailed schedules a retry for a message that could not be published
+func (r *Relay) markFailed(ctx context.Context, message Message, cause error) {
+	backoff := minRetryBackoff << uint(message.Attempts)
+	if backoff <= 0 || backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+
+	log.Printf("Error publishing outbox message %s to %s (attempt %d), retrying in %s: %v",
+		message.ID, message.Topic, message.Attempts+1, backoff, cause)
+
+	if err := r.store.MarkFailed(ctx, message.ID, time.Now().Add(backoff), cause.Error()); err != nil {
+		log.Printf("Error recording outbox failure for message %s: %v", message.ID, err)
+	}
+}
+
+// toKafkaMessage converts an outbox message into a Kafka message
+func toKafkaMessage(message Message) kafka.Message {
+	kafkaMessage := kafka.Message{
+		Topic: message.Topic,
+		Key:   []byte(message.Key),
+		Value: message.Value,
+	}
+	for key, value := range message.Headers {
+		kafkaMessage.Headers = append(kafkaMessage.Headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	return kafkaMessage
+}
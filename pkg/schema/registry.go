@@ -0,0 +1,178 @@
+// Package schema is a local, file-based stand-in for a schema registry.
+//
+// Schemas are Avro schemas stored one file per version:
+//
+//	<subject>/v1.avsc
+//	<subject>/v2.avsc
+//
+// Versions of a subject must be numbered from 1 without gaps, and every
+// version must be fully compatible with all earlier ones: data written with
+// any version can be read with any other. This lets producers and consumers
+// be upgraded in any order without breaking each other.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/hamba/avro"
+)
+
+// versionFile matches schema file names and captures the version
+var versionFile = regexp.MustCompile(`^v([1-9][0-9]*)\.avsc$`)
+
+// Registry holds every version of every subject
+type Registry struct {
+	subjects map[string][]avro.Schema
+}
+
+// Load reads all schemas from fsys and checks that each subject's versions
+// are fully compatible with each other
+func Load(fsys fs.FS) (*Registry, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema directory: %w", err)
+	}
+
+	r := &Registry{subjects: make(map[string][]avro.Schema)}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		schemas, err := loadSubject(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		r.subjects[entry.Name()] = schemas
+	}
+
+	return r, nil
+}
+
+// loadSubject reads and validates all versions of a subject
+func loadSubject(fsys fs.FS, subject string) ([]avro.Schema, error) {
+	entries, err := fs.ReadDir(fsys, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subject %s: %w", subject, err)
+	}
+
+	sources := make(map[int]string)
+	for _, entry := range entries {
+		match := versionFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected file %s in subject %s", entry.Name(), subject)
+		}
+		version, _ := strconv.Atoi(match[1])
+
+		data, err := fs.ReadFile(fsys, path.Join(subject, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s/%s: %w", subject, entry.Name(), err)
+		}
+		sources[version] = string(data)
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("subject %s has no schemas", subject)
+	}
+
+	var schemas []avro.Schema
+	for version := 1; version <= len(sources); version++ {
+		source, ok := sources[version]
+		if !ok {
+			return nil, fmt.Errorf("subject %s is missing version %d", subject, version)
+		}
+
+		parsed, err := Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema %s v%d: %w", subject, version, err)
+		}
+		if err := CheckCompatibility(schemas, parsed); err != nil {
+			return nil, fmt.Errorf("schema %s v%d: %w", subject, version, err)
+		}
+		schemas = append(schemas, parsed)
+	}
+
+	return schemas, nil
+}
+
+// Parse parses an Avro schema. Each schema gets its own cache, so different
+// versions of the same named record do not clash.
+func Parse(source string) (avro.Schema, error) {
+	return avro.ParseWithCache(source, "", &avro.SchemaCache{})
+}
+
+// CheckCompatibility checks that candidate is fully compatible with every
+// existing version: it can read data written with each of them, and each of
+// them can read data written with it
+func CheckCompatibility(existing []avro.Schema, candidate avro.Schema) error {
+	compatibility := avro.NewSchemaCompatibility()
+	for i, schema := range existing {
+		if err := compatibility.Compatible(candidate, schema); err != nil {
+			return fmt.Errorf("cannot read data written with v%d: %w", i+1, err)
+		}
+		if err := compatibility.Compatible(schema, candidate); err != nil {
+			return fmt.Errorf("v%d cannot read data written with it: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Subjects returns the names of all subjects, sorted
+func (r *Registry) Subjects() []string {
+	subjects := make([]string, 0, len(r.subjects))
+	for subject := range r.subjects {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+// Schema returns the given version of a subject
+func (r *Registry) Schema(subject string, version int) (avro.Schema, error) {
+	schemas, ok := r.subjects[subject]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema subject: %s", subject)
+	}
+	if version < 1 || version > len(schemas) {
+		return nil, fmt.Errorf("unknown version %d of schema %s (latest is %d)", version, subject, len(schemas))
+	}
+	return schemas[version-1], nil
+}
+
+// Latest returns the newest version of a subject and its version number
+func (r *Registry) Latest(subject string) (avro.Schema, int, error) {
+	schemas, ok := r.subjects[subject]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown schema subject: %s", subject)
+	}
+	return schemas[len(schemas)-1], len(schemas), nil
+}
+
+// Versions returns the number of versions of a subject
+func (r *Registry) Versions(subject string) int {
+	return len(r.subjects[subject])
+}
+
+// ErrUnchanged is returned by Check when a candidate schema is identical to
+// the latest version of its subject
+var ErrUnchanged = errors.New("schema is identical to the latest version")
+
+// Check validates a candidate new version of a subject and returns the
+// version number it would be registered as. A subject that does not exist
+// yet starts at version 1.
+func (r *Registry) Check(subject string, candidate avro.Schema) (int, error) {
+	schemas := r.subjects[subject]
+	if n := len(schemas); n > 0 && schemas[n-1].Fingerprint() == candidate.Fingerprint() {
+		return n, ErrUnchanged
+	}
+	if err := CheckCompatibility(schemas, candidate); err != nil {
+		return 0, err
+	}
+	return len(schemas) + 1, nil
+}
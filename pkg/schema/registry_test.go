@@ -0,0 +1,145 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hamba/avro"
+)
+
+// eventSchemas is where the schemas embedded by package events live
+const eventSchemas = "../events/schemas"
+
+// baseSchema is the schema the candidates of the compatibility tests change
+const baseSchema = `{
+  "type": "record",
+  "name": "Payment",
+  "namespace": "fortexa.test",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "amount", "type": "double"},
+    {"name": "note", "type": "string", "default": ""}
+  ]
+}`
+
+func TestEventSchemasAreCompatible(t *testing.T) {
+	registry, err := Load(os.DirFS(eventSchemas))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	subjects := registry.Subjects()
+	if len(subjects) == 0 {
+		t.Fatal("Load() found no subjects")
+	}
+	for _, subject := range subjects {
+		versions := registry.Versions(subject)
+		for version := 2; version <= versions; version++ {
+			previous, err := registry.Schema(subject, version-1)
+			if err != nil {
+				t.Fatalf("Schema(%s, %d) error = %v", subject, version-1, err)
+			}
+			current, err := registry.Schema(subject, version)
+			if err != nil {
+				t.Fatalf("Schema(%s, %d) error = %v", subject, version, err)
+			}
+			if err := CheckCompatibility([]avro.Schema{previous}, current); err != nil {
+				t.Errorf("%s v%d is not compatible with v%d: %v", subject, version, version-1, err)
+			}
+		}
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	base, err := Parse(baseSchema)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		fields     string
+		compatible bool
+	}{
+		{
+			name:       "added field with default",
+			fields:     `{"name": "id", "type": "string"}, {"name": "amount", "type": "double"}, {"name": "note", "type": "string", "default": ""}, {"name": "currency", "type": "string", "default": "INR"}`,
+			compatible: true,
+		},
+		{
+			name:       "removed field with default",
+			fields:     `{"name": "id", "type": "string"}, {"name": "amount", "type": "double"}`,
+			compatible: true,
+		},
+		{
+			name:   "removed field without default",
+			fields: `{"name": "id", "type": "string"}, {"name": "note", "type": "string", "default": ""}`,
+		},
+		{
+			name:   "added field without default",
+			fields: `{"name": "id", "type": "string"}, {"name": "amount", "type": "double"}, {"name": "note", "type": "string", "default": ""}, {"name": "currency", "type": "string"}`,
+		},
+		{
+			name:   "changed field type",
+			fields: `{"name": "id", "type": "string"}, {"name": "amount", "type": "string"}, {"name": "note", "type": "string", "default": ""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate, err := Parse(`{"type": "record", "name": "Payment", "namespace": "fortexa.test", "fields": [` + tt.fields + `]}`)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			err = CheckCompatibility([]avro.Schema{base}, candidate)
+			if tt.compatible && err != nil {
+				t.Errorf("CheckCompatibility() error = %v, want nil", err)
+			}
+			if !tt.compatible && err == nil {
+				t.Error("CheckCompatibility() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestRegistryCheck(t *testing.T) {
+	registry, err := Load(fstest.MapFS{
+		"payment/v1.avsc": {Data: []byte(baseSchema)},
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	unchanged, err := Parse(baseSchema)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if version, err := registry.Check("payment", unchanged); !errors.Is(err, ErrUnchanged) || version != 1 {
+		t.Errorf("Check(unchanged) = %d, %v, want 1, ErrUnchanged", version, err)
+	}
+
+	if version, err := registry.Check("refund", unchanged); err != nil || version != 1 {
+		t.Errorf("Check(new subject) = %d, %v, want 1, nil", version, err)
+	}
+}
+
+func TestLoadRejectsIncompatibleVersion(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"payment/v1.avsc": {Data: []byte(baseSchema)},
+		"payment/v2.avsc": {Data: []byte(`{"type": "record", "name": "Payment", "namespace": "fortexa.test", "fields": [{"name": "id", "type": "long"}]}`)},
+	})
+	if err == nil {
+		t.Error("Load() = nil, want an error for an incompatible v2")
+	}
+}
+
+func TestLoadRejectsMissingVersion(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"payment/v1.avsc": {Data: []byte(baseSchema)},
+		"payment/v3.avsc": {Data: []byte(baseSchema)},
+	})
+	if err == nil {
+		t.Error("Load() = nil, want an error for a missing v2")
+	}
+}
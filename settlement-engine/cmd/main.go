@@ -2,27 +2,29 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
+	"time"
 
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/calendar"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/config"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/handler"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/payout"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/pricing"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/processor"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/repository"
-	"github.com/joho/godotenv"
 	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/dlq"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/money"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
 func main() {
-	// Load environment variables from .env file
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: Error loading .env file: %v", err)
-	}
-
 	// Initialize configuration
 	cfg := config.LoadConfig()
 
@@ -39,38 +41,39 @@ func main() {
 		cancel()
 	}()
 
+	// Bank account numbers are decrypted with the key the API gateway
+	// encrypted them with; mock mode makes up a key if none is set
+	accountKey := cfg.BankAccounts.EncryptionKey
+	if accountKey == "" && cfg.App.MockMode {
+		log.Println("Warning: BANK_ACCOUNT_ENCRYPTION_KEY not set, using a temporary bank account encryption key")
+		var err error
+		if accountKey, err = bankaccount.GenerateKey(); err != nil {
+			log.Fatalf("Failed to generate bank account encryption key: %v", err)
+		}
+	}
+	accountCipher, err := bankaccount.NewCipher(accountKey)
+	if err != nil {
+		log.Fatalf("Invalid bank account encryption key: %v", err)
+	}
+
 	var repo repository.Repository
 
 	// Check if running in mock mode
-	mockMode := strings.ToLower(os.Getenv("MOCK_MODE")) == "true"
+	mockMode := cfg.App.MockMode
 
 	if mockMode {
 		// Use mock repository
 		log.Println("Running in MOCK MODE - No database connection required")
-		repo = repository.NewMockRepository()
+		repo = repository.NewMockRepository(accountCipher)
 	} else {
 		// Use real database repository
-		connectionString := os.Getenv("DB_CONNECTION_STRING")
-		if connectionString == "" {
-			// Construct connection string from individual parameters
-			connectionString = fmt.Sprintf(
-				"postgresql://%s:%s@%s:%s/%s?sslmode=%s",
-				cfg.Database.User,
-				cfg.Database.Password,
-				cfg.Database.Host,
-				cfg.Database.Port,
-				cfg.Database.Name,
-				cfg.Database.SSLMode,
-			)
-		}
-
-		log.Printf("Connecting to database with connection string: %s", connectionString)
-		dbRepo, err := repository.NewDBRepository(connectionString)
+		log.Println("Connecting to database")
+		dbRepo, err := repository.NewDBRepository(cfg.Database.ConnectionString())
 		if err != nil {
 			log.Printf("Failed to initialize database repository: %v", err)
 			log.Println("Falling back to MOCK MODE")
 			mockMode = true
-			repo = repository.NewMockRepository()
+			repo = repository.NewMockRepository(accountCipher)
 		} else {
 			log.Println("Successfully connected to database")
 			repo = dbRepo
@@ -79,34 +82,120 @@ func main() {
 
 	// Create Kafka reader for payment events
 	paymentReader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:     []string{cfg.Kafka.Broker},
-		Topic:       cfg.Kafka.PaymentTopic,
+		Brokers:     cfg.Kafka.Brokers,
+		Topic:       cfg.Kafka.PaymentEventsTopic,
 		GroupID:     cfg.Kafka.ConsumerGroup,
 		StartOffset: kafka.LastOffset,
 		MinBytes:    10e3, // 10KB
 		MaxBytes:    10e6, // 10MB
 	})
 
-	// Create Kafka writer for settlement events
-	settlementWriter := &kafka.Writer{
-		Addr:     kafka.TCP(cfg.Kafka.Broker),
-		Topic:    cfg.Kafka.SettlementTopic,
-		Balancer: &kafka.LeastBytes{},
+	// Create Kafka writer for relaying settlement events from the outbox and dead-lettering payment events
+	settlementWriter := outbox.NewWriter(cfg.Kafka.Brokers...)
+	defer settlementWriter.Close()
+
+	// Start the outbox relay
+	relay := outbox.NewRelay(
+		repo.Outbox(),
+		settlementWriter,
+		time.Duration(cfg.Outbox.PollIntervalMs)*time.Millisecond,
+		cfg.Outbox.BatchSize,
+	)
+	go relay.Run(ctx)
+
+	// The rounding mode is one of the names validated with the configuration
+	roundingMode, err := money.ParseRoundingMode(cfg.Settlement.RoundingMode)
+	if err != nil {
+		log.Fatalf("Invalid settlement rounding mode: %v", err)
+	}
+
+	// Load the bank holiday calendar; cut-offs on its holidays and weekends
+	// move to the next business day
+	holidays := calendar.Default()
+	if cfg.Settlement.HolidayCalendar != "" {
+		holidays, err = calendar.LoadFile(cfg.Settlement.HolidayCalendar)
+		if err != nil {
+			log.Fatalf("Invalid holiday calendar: %v", err)
+		}
+	}
+
+	// Load the pricing plans and tax rules; without them, merchants are
+	// charged a fee percent and GST
+	prices := pricing.Default()
+	if cfg.Settlement.PricingFile != "" {
+		prices, err = pricing.LoadFile(cfg.Settlement.PricingFile)
+		if err != nil {
+			log.Fatalf("Invalid pricing: %v", err)
+		}
+	}
+	if _, ok := prices.Plan(cfg.Settlement.DefaultPricingPlan); cfg.Settlement.DefaultPricingPlan != "" && !ok {
+		log.Fatalf("Unknown default pricing plan %q", cfg.Settlement.DefaultPricingPlan)
+	}
+	if _, ok := prices.Tax(cfg.Settlement.TaxJurisdiction); !ok {
+		log.Fatalf("No tax rule for default tax jurisdiction %q", cfg.Settlement.TaxJurisdiction)
+	}
+
+	// Pay settlements out through the bank, or simulate paying them
+	var payouts payout.Provider = payout.NewSimulatedProvider()
+	if cfg.Payout.Provider == "FILE" {
+		payouts, err = payout.NewFileProvider(
+			payout.Format(cfg.Payout.Format),
+			cfg.Payout.OutboxDir,
+			cfg.Payout.InboxDir,
+			payout.Debtor{Name: cfg.Payout.DebtorName, Account: cfg.Payout.DebtorAccount, BIC: cfg.Payout.DebtorBIC},
+		)
+		if err != nil {
+			log.Fatalf("Failed to create payout provider: %v", err)
+		}
+	}
+
+	// Merchants without a settlement configuration of their own are settled
+	// with the defaults
+	defaults := models.MerchantSettlementConfig{
+		SettlementCycle:         cfg.Settlement.DefaultSettlementCycle,
+		PreferredSettlementDay:  cfg.Settlement.PreferredSettlementDay,
+		Timezone:                cfg.Settlement.DefaultTimezone,
+		CutoffTime:              cfg.Settlement.CutoffTime,
+		SettlementMethod:        models.SettlementMethodBankTransfer,
+		FeePercent:              money.FromFloat(cfg.Settlement.DefaultFeePercent),
+		PricingPlan:             cfg.Settlement.DefaultPricingPlan,
+		TaxJurisdiction:         cfg.Settlement.TaxJurisdiction,
+		MinimumSettlementAmount: money.FromFloat(cfg.Settlement.MinimumSettlementAmount),
+		PayoutDelayDays:         cfg.Settlement.PayoutDelayDays,
+		ReservePercent:          money.FromFloat(cfg.Settlement.ReservePercent),
+		ReserveHoldDays:         cfg.Settlement.ReserveHoldDays,
+		MinimumReserveAmount:    money.FromFloat(cfg.Settlement.MinimumReserveAmount),
+		NegativeBalanceRecovery: models.RecoveryMethod(cfg.Settlement.NegativeBalanceRecovery),
+		NegativeBalanceDays:     cfg.Settlement.NegativeBalanceDays,
 	}
 
 	// Create settlement processor with repository
 	settlementProcessor := processor.NewSettlementProcessor(
 		repo,
-		cfg.Settlement.DefaultFeePercent,
-		cfg.Settlement.MinimumSettlementAmount,
+		defaults,
+		holidays,
+		prices,
+		roundingMode,
+		events.NewProducer(cfg.App.Name),
+		cfg.Kafka.SettlementTopic,
+		payouts,
+		cfg.Payout.MaxAttempts,
+		cfg.Payout.RetryInterval,
+		cfg.Payout.SubmitTimeout,
+		accountCipher,
+		cfg.Payout.AccountCooldown,
 	)
 
 	// Create and start settlement handler
 	settlementHandler := handler.NewSettlementHandler(
 		ctx,
 		paymentReader,
-		settlementWriter,
 		settlementProcessor,
+		cfg.Kafka.Concurrency,
+		cfg.Kafka.QueueSize,
+		dlq.NewPublisher(settlementWriter, cfg.Kafka.DeadLetterTopic, cfg.App.Name),
+		cfg.Kafka.MaxAttempts,
+		cfg.Settlement.ScheduleInterval,
 	)
 
 	log.Printf("Starting Settlement Engine service in %s mode", map[bool]string{true: "MOCK", false: "DATABASE"}[mockMode])
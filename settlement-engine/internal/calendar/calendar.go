@@ -0,0 +1,152 @@
+// Package calendar decides which days banks settle on.
+//
+// A calendar is made of the weekdays banks are closed every week and a list
+// of bank holidays. It is loaded from a YAML file:
+//
+//	weekend: [Saturday, Sunday]
+//	holidays:
+//	  - date: 2026-01-26
+//	    name: Republic Day
+//	  - date: 2026-08-15
+//	    name: Independence Day
+//
+// Dates are calendar days, so they apply in whatever time zone the day is
+// looked at: a merchant's cut-off on 26 January in Asia/Kolkata falls on a
+// holiday even though it is still 25 January in UTC.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dateLayout is the layout of holiday dates
+const dateLayout = "2006-01-02"
+
+// maxClosedDays bounds the search for a business day, so a calendar closed
+// every day cannot loop forever
+const maxClosedDays = 366
+
+// Calendar is a bank calendar
+type Calendar struct {
+	weekend  map[time.Weekday]bool
+	holidays map[string]string
+}
+
+// file is the YAML representation of a calendar
+type file struct {
+	Weekend  []string `yaml:"weekend"`
+	Holidays []struct {
+		Date string `yaml:"date"`
+		Name string `yaml:"name"`
+	} `yaml:"holidays"`
+}
+
+// weekdays maps weekday names to weekdays
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Parse parses a calendar from YAML. A calendar without a weekend is closed
+// on Saturdays and Sundays; use an empty list to settle every day of the week.
+func Parse(data []byte) (*Calendar, error) {
+	f := file{Weekend: []string{"Saturday", "Sunday"}}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+
+	var errs []error
+	c := &Calendar{weekend: make(map[time.Weekday]bool), holidays: make(map[string]string, len(f.Holidays))}
+	for _, name := range f.Weekend {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown weekday %q", name))
+			continue
+		}
+		c.weekend[day] = true
+	}
+	if len(c.weekend) == len(weekdays) {
+		errs = append(errs, errors.New("every day of the week is a weekend day"))
+	}
+	for _, holiday := range f.Holidays {
+		date, err := time.Parse(dateLayout, holiday.Date)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("holiday %q: invalid date %q, expected YYYY-MM-DD", holiday.Name, holiday.Date))
+			continue
+		}
+		c.holidays[date.Format(dateLayout)] = holiday.Name
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadFile loads a calendar from a YAML file
+func LoadFile(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Default returns the calendar used when none is configured, closed on
+// Saturdays and Sundays and without holidays
+func Default() *Calendar {
+	return &Calendar{
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		holidays: map[string]string{},
+	}
+}
+
+// Holiday returns the name of the holiday on the day of t, in t's location,
+// and whether it is one
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[t.Format(dateLayout)]
+	return name, ok
+}
+
+// IsBusinessDay reports whether banks settle on the day of t, in t's location
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	if c.weekend[t.Weekday()] {
+		return false
+	}
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// NextBusinessDay returns t if it falls on a business day, and otherwise the
+// same time of day on the first business day after it
+func (c *Calendar) NextBusinessDay(t time.Time) time.Time {
+	for i := 0; i < maxClosedDays && !c.IsBusinessDay(t); i++ {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// AddBusinessDays returns the same time of day as t on the n-th business day
+// after the day of t, so a payout due T+2 from a Friday cut-off falls on the
+// following Tuesday. Zero days gives the first business day from t on.
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	t = c.NextBusinessDay(t)
+	for i := 0; i < n; i++ {
+		t = c.NextBusinessDay(t.AddDate(0, 0, 1))
+	}
+	return t
+}
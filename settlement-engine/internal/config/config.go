@@ -1,144 +1,138 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
-	"strings"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/calendar"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/schedule"
+	sharedconfig "github.com/yourusername/fortexa/pkg/config"
 )
 
 // Config represents the application configuration
 type Config struct {
-	App        AppConfig
-	Database   DBConfig
-	Kafka      KafkaConfig
-	Settlement SettlementConfig
+	App          AppConfig                 `yaml:"app"`
+	Database     sharedconfig.Database     `yaml:"database"`
+	Kafka        KafkaConfig               `yaml:"kafka"`
+	Settlement   SettlementConfig          `yaml:"settlement"`
+	Payout       PayoutConfig              `yaml:"payout"`
+	Outbox       sharedconfig.Outbox       `yaml:"outbox"`
+	BankAccounts sharedconfig.BankAccounts `yaml:"bank_accounts"`
 }
 
 // AppConfig holds application-level configuration
 type AppConfig struct {
-	Name    string
-	Version string
-	Env     string
-}
-
-// DBConfig holds database configuration
-type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	Name     string
-	SSLMode  string
+	Name     string `yaml:"name" env:"APP_NAME" default:"settlement-engine" required:"true"`
+	Version  string `yaml:"version" env:"APP_VERSION" default:"1.0.0"`
+	Env      string `yaml:"env" env:"APP_ENV" default:"development"`
+	MockMode bool   `yaml:"mock_mode" env:"MOCK_MODE"`
 }
 
-// KafkaConfig holds Kafka configuration
+// KafkaConfig holds Kafka configuration. KAFKA_BROKER is still accepted for
+// deployments that predate KAFKA_BROKERS.
 type KafkaConfig struct {
-	Broker          string
-	PaymentTopic    string
-	SettlementTopic string
-	ConsumerGroup   string
+	Brokers            []string `yaml:"brokers" env:"KAFKA_BROKERS,KAFKA_BROKER" default:"localhost:9092" required:"true"`
+	PaymentEventsTopic string   `yaml:"payment_events_topic" env:"KAFKA_PAYMENT_EVENTS_TOPIC" default:"payments.events" required:"true"`
+	SettlementTopic    string   `yaml:"settlement_topic" env:"KAFKA_SETTLEMENT_TOPIC" default:"settlements.events" required:"true"`
+	ConsumerGroup      string   `yaml:"consumer_group" env:"KAFKA_CONSUMER_GROUP" default:"settlement-engine" required:"true"`
+	Concurrency        int      `yaml:"concurrency" env:"KAFKA_CONSUMER_CONCURRENCY" default:"8" min:"1"`
+	QueueSize          int      `yaml:"queue_size" env:"KAFKA_CONSUMER_QUEUE_SIZE" default:"100" min:"1"`
+	MaxAttempts        int      `yaml:"max_attempts" env:"KAFKA_CONSUMER_MAX_ATTEMPTS" default:"5" min:"1"`
+	DeadLetterTopic    string   `yaml:"dead_letter_topic" env:"KAFKA_DLQ_TOPIC" default:"settlement-engine.dlq" required:"true"`
 }
 
-// SettlementConfig holds settlement configuration
+// SettlementConfig holds settlement configuration. The cycle, day, cut-off
+// time, time zone, pricing, payout delay, reserves and negative balance
+// recovery are the defaults for merchants without their own settlement
+// configuration; see package schedule for how cut-offs fall.
+// SETTLEMENT_BATCH_TIME_END is still accepted for deployments that predate
+// SETTLEMENT_CUTOFF_TIME.
 type SettlementConfig struct {
-	DefaultFeePercent        float64
-	MinimumSettlementAmount  float64
-	DefaultSettlementCycle   string
-	PreferredSettlementDay   int
-	SettlementBatchTimeStart string
-	SettlementBatchTimeEnd   string
+	DefaultFeePercent       float64 `yaml:"default_fee_percent" env:"SETTLEMENT_DEFAULT_FEE_PERCENT" default:"2.5" min:"0" max:"100"`
+	MinimumSettlementAmount float64 `yaml:"minimum_amount" env:"SETTLEMENT_MINIMUM_AMOUNT" default:"100.0" min:"0"`
+	DefaultSettlementCycle  string  `yaml:"default_cycle" env:"SETTLEMENT_DEFAULT_CYCLE" default:"DAILY" oneof:"DAILY|WEEKLY|MONTHLY"`
+	PreferredSettlementDay  int     `yaml:"preferred_day" env:"SETTLEMENT_PREFERRED_DAY" default:"1" min:"1" max:"31"`
+	CutoffTime              string  `yaml:"cutoff_time" env:"SETTLEMENT_CUTOFF_TIME,SETTLEMENT_BATCH_TIME_END" default:"23:59"`
+	DefaultTimezone         string  `yaml:"default_timezone" env:"SETTLEMENT_DEFAULT_TIMEZONE" default:"Asia/Kolkata"`
+	// HolidayCalendar is a YAML file of weekend days and bank holidays; without
+	// one, cut-offs only move off Saturdays and Sundays
+	HolidayCalendar string `yaml:"holiday_calendar" env:"SETTLEMENT_HOLIDAY_CALENDAR"`
+	// PricingFile is a YAML file of pricing plans and tax rules; see package
+	// pricing. Merchants without a plan of their own are charged
+	// DefaultPricingPlan, or DefaultFeePercent without one, and taxed in
+	// TaxJurisdiction.
+	PricingFile        string `yaml:"pricing_file" env:"SETTLEMENT_PRICING_FILE"`
+	DefaultPricingPlan string `yaml:"default_pricing_plan" env:"SETTLEMENT_DEFAULT_PRICING_PLAN"`
+	TaxJurisdiction    string `yaml:"tax_jurisdiction" env:"SETTLEMENT_TAX_JURISDICTION" default:"IN"`
+	// ScheduleInterval is how often the scheduler looks for merchants whose
+	// cycle passed a cut-off
+	ScheduleInterval time.Duration `yaml:"schedule_interval" env:"SETTLEMENT_SCHEDULE_INTERVAL" default:"1m" min:"1s"`
+	// PayoutDelayDays is N of T+N: settlements are paid out that many business
+	// days after their cut-off
+	PayoutDelayDays int `yaml:"payout_delay_days" env:"SETTLEMENT_PAYOUT_DELAY_DAYS" default:"0" min:"0"`
+	// ReservePercent of each settlement's net amount is held for
+	// ReserveHoldDays days; MinimumReserveAmount stays held at all times
+	ReservePercent       float64 `yaml:"reserve_percent" env:"SETTLEMENT_RESERVE_PERCENT" default:"0" min:"0" max:"100"`
+	ReserveHoldDays      int     `yaml:"reserve_hold_days" env:"SETTLEMENT_RESERVE_HOLD_DAYS" default:"90" min:"1"`
+	MinimumReserveAmount float64 `yaml:"minimum_reserve_amount" env:"SETTLEMENT_MINIMUM_RESERVE_AMOUNT" default:"0" min:"0"`
+	// A balance left negative by refunds and chargebacks is carried forward to
+	// later settlements, and recovered with NegativeBalanceRecovery once it has
+	// been negative for NegativeBalanceDays days
+	NegativeBalanceRecovery string `yaml:"negative_balance_recovery" env:"SETTLEMENT_NEGATIVE_BALANCE_RECOVERY" default:"INVOICE" oneof:"NONE|INVOICE|DIRECT_DEBIT"`
+	NegativeBalanceDays     int    `yaml:"negative_balance_days" env:"SETTLEMENT_NEGATIVE_BALANCE_DAYS" default:"30" min:"0"`
+	// RoundingMode rounds fees, taxes and reserves to the minor unit of the
+	// currency
+	RoundingMode string `yaml:"rounding_mode" env:"SETTLEMENT_ROUNDING_MODE" default:"HALF_UP" oneof:"HALF_UP|HALF_EVEN|HALF_DOWN|DOWN|UP|FLOOR|CEILING"`
 }
 
-// LoadConfig loads configuration from environment variables
-func LoadConfig() Config {
-	return Config{
-		App: AppConfig{
-			Name:    getEnv("APP_NAME", "settlement-engine"),
-			Version: getEnv("APP_VERSION", "1.0.0"),
-			Env:     getEnv("APP_ENV", "development"),
-		},
-		Database: DBConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "fortexa"),
-			Password: getEnv("DB_PASSWORD", "fortexa123"),
-			Name:     getEnv("DB_NAME", "fortexa"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
-		},
-		Kafka: KafkaConfig{
-			Broker:          getEnv("KAFKA_BROKER", "localhost:9092"),
-			PaymentTopic:    getEnv("KAFKA_PAYMENT_TOPIC", "payment-events"),
-			SettlementTopic: getEnv("KAFKA_SETTLEMENT_TOPIC", "settlement-events"),
-			ConsumerGroup:   getEnv("KAFKA_CONSUMER_GROUP", "settlement-engine"),
-		},
-		Settlement: SettlementConfig{
-			DefaultFeePercent:        getEnvAsFloat("SETTLEMENT_DEFAULT_FEE_PERCENT", 2.5),
-			MinimumSettlementAmount:  getEnvAsFloat("SETTLEMENT_MINIMUM_AMOUNT", 100.0),
-			DefaultSettlementCycle:   getEnv("SETTLEMENT_DEFAULT_CYCLE", "DAILY"),
-			PreferredSettlementDay:   getEnvAsInt("SETTLEMENT_PREFERRED_DAY", 1),
-			SettlementBatchTimeStart: getEnv("SETTLEMENT_BATCH_TIME_START", "00:00"),
-			SettlementBatchTimeEnd:   getEnv("SETTLEMENT_BATCH_TIME_END", "23:59"),
-		},
+// Validate checks that the default settlement cycle has a valid day, cut-off
+// time and time zone
+func (s SettlementConfig) Validate() error {
+	if _, err := schedule.New(s.DefaultSettlementCycle, s.PreferredSettlementDay, s.CutoffTime, s.DefaultTimezone, calendar.Default()); err != nil {
+		return fmt.Errorf("settlement: %w", err)
 	}
+	return nil
 }
 
-// getEnv retrieves an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+// PayoutConfig holds payout configuration. The SIMULATED provider pays every
+// payout; the FILE provider writes a bank file of each batch of payouts to
+// OutboxDir, in Format, and reads the bank's response and return files from
+// InboxDir. Payouts are paid from the debtor account to merchants' verified
+// bank accounts.
+type PayoutConfig struct {
+	Provider      string `yaml:"provider" env:"SETTLEMENT_PAYOUT_PROVIDER" default:"SIMULATED" oneof:"SIMULATED|FILE"`
+	Format        string `yaml:"format" env:"SETTLEMENT_PAYOUT_FILE_FORMAT" default:"CSV" oneof:"CSV|PAIN001"`
+	OutboxDir     string `yaml:"outbox_dir" env:"SETTLEMENT_PAYOUT_OUTBOX_DIR" default:"payouts/outbox"`
+	InboxDir      string `yaml:"inbox_dir" env:"SETTLEMENT_PAYOUT_INBOX_DIR" default:"payouts/inbox"`
+	DebtorName    string `yaml:"debtor_name" env:"SETTLEMENT_PAYOUT_DEBTOR_NAME" default:"Fortexa"`
+	DebtorAccount string `yaml:"debtor_account" env:"SETTLEMENT_PAYOUT_DEBTOR_ACCOUNT"`
+	DebtorBIC     string `yaml:"debtor_bic" env:"SETTLEMENT_PAYOUT_DEBTOR_BIC"`
+	// A failed payout is retried RetryInterval after it failed, until the
+	// settlement was paid out MaxAttempts times
+	MaxAttempts   int           `yaml:"max_attempts" env:"SETTLEMENT_PAYOUT_MAX_ATTEMPTS" default:"3" min:"1"`
+	RetryInterval time.Duration `yaml:"retry_interval" env:"SETTLEMENT_PAYOUT_RETRY_INTERVAL" default:"24h" min:"0s"`
+	// Payouts recorded for a batch that was not submitted SubmitTimeout
+	// later, such as after a crash, fail and are retried
+	SubmitTimeout time.Duration `yaml:"submit_timeout" env:"SETTLEMENT_PAYOUT_SUBMIT_TIMEOUT" default:"1h" min:"1m"`
+	// Payouts to a bank account that replaced another are held until
+	// AccountCooldown after it was verified
+	AccountCooldown time.Duration `yaml:"account_cooldown" env:"SETTLEMENT_BANK_ACCOUNT_COOLDOWN" default:"72h" min:"0s"`
 }
 
-// getEnvAsInt retrieves an environment variable as an integer or returns a default value
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
-	}
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		log.Printf("Warning: Failed to parse %s as int, using default value %d: %v", key, defaultValue, err)
-		return defaultValue
+// Validate checks that pain.001 files have a debtor account to pay from
+func (p PayoutConfig) Validate() error {
+	if p.Provider == "FILE" && p.Format == "PAIN001" && p.DebtorAccount == "" {
+		return errors.New("payout: SETTLEMENT_PAYOUT_DEBTOR_ACCOUNT must be set for PAIN001 files")
 	}
-	return value
+	return nil
 }
 
-// getEnvAsFloat retrieves an environment variable as a float or returns a default value
-func getEnvAsFloat(key string, defaultValue float64) float64 {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
-	}
-	value, err := strconv.ParseFloat(valueStr, 64)
-	if err != nil {
-		log.Printf("Warning: Failed to parse %s as float, using default value %.2f: %v", key, defaultValue, err)
-		return defaultValue
-	}
-	return value
+// LoadConfig loads configuration from defaults, the optional config file and
+// environment variables. It exits on invalid configuration, and prints the
+// configuration and exits when run with --print-config.
+func LoadConfig() Config {
+	var cfg Config
+	sharedconfig.MustLoad(&cfg)
+	return cfg
 }
-
-// getEnvAsBool retrieves an environment variable as a boolean or returns a default value
-func getEnvAsBool(key string, defaultValue bool) bool {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
-	}
-	
-	value, err := strconv.ParseBool(valueStr)
-	if err != nil {
-		// Check for common string representations
-		valueStr = strings.ToLower(valueStr)
-		if valueStr == "true" || valueStr == "yes" || valueStr == "1" {
-			return true
-		}
-		if valueStr == "false" || valueStr == "no" || valueStr == "0" {
-			return false
-		}
-		
-		log.Printf("Warning: Failed to parse %s as bool, using default value %t: %v", key, defaultValue, err)
-		return defaultValue
-	}
-	return value
-} 
\ No newline at end of file
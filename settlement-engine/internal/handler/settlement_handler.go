@@ -2,188 +2,179 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/processor"
-	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
+	"github.com/yourusername/fortexa/pkg/consumer"
+	"github.com/yourusername/fortexa/pkg/events"
 )
 
 // SettlementHandler handles payment and settlement events
 type SettlementHandler struct {
 	ctx                 context.Context
 	kafkaReader         *kafka.Reader
-	kafkaWriter         *kafka.Writer
 	settlementProcessor *processor.SettlementProcessor
+	consumer            *consumer.Consumer
+	scheduleInterval    time.Duration
 }
 
-// NewSettlementHandler creates a new settlement handler
+// NewSettlementHandler creates a new settlement handler. Settlement events are
+// published by the outbox relay, so the handler only needs a reader. Payment
+// events that still fail after maxAttempts are handed to deadLetter. Every
+// scheduleInterval, merchants whose settlement cycle passed a cut-off are
+// settled.
 func NewSettlementHandler(
 	ctx context.Context,
 	kafkaReader *kafka.Reader,
-	kafkaWriter *kafka.Writer,
 	settlementProcessor *processor.SettlementProcessor,
+	concurrency int,
+	queueSize int,
+	deadLetter consumer.DeadLetterer,
+	maxAttempts int,
+	scheduleInterval time.Duration,
 ) *SettlementHandler {
-	return &SettlementHandler{
+	h := &SettlementHandler{
 		ctx:                 ctx,
 		kafkaReader:         kafkaReader,
-		kafkaWriter:         kafkaWriter,
 		settlementProcessor: settlementProcessor,
+		scheduleInterval:    scheduleInterval,
 	}
+	h.consumer = consumer.New(kafkaReader, h.handlePaymentEvent, concurrency, queueSize).
+		WithDeadLetter(deadLetter, maxAttempts)
+	return h
 }
 
 // Start begins processing payment events and creating settlements
 func (h *SettlementHandler) Start() error {
-	// Start a goroutine for consuming payment events
-	go h.consumePaymentEvents()
+	// Start a goroutine for settling merchants at their cut-offs
+	go h.scheduleSettlements()
 
-	// Start a goroutine for creating settlement batches
-	go h.createSettlementBatches()
+	// Consume payment events until the context is done
+	log.Println("Starting payment event consumer")
+	err := h.consumer.Run(h.ctx)
 
-	// Block until context is done
-	<-h.ctx.Done()
-	
 	log.Println("Shutting down settlement handler")
-	
+
 	// Close resources
 	if err := h.kafkaReader.Close(); err != nil {
 		log.Printf("Error closing Kafka reader: %v", err)
 	}
-	
-	if err := h.kafkaWriter.Close(); err != nil {
-		log.Printf("Error closing Kafka writer: %v", err)
-	}
-	
-	return nil
+
+	return err
 }
 
-// consumePaymentEvents consumes payment events from Kafka
-func (h *SettlementHandler) consumePaymentEvents() {
-	log.Println("Starting payment event consumer")
-	
-	for {
-		select {
-		case <-h.ctx.Done():
-			return
-		default:
-			// Set a timeout for reading messages
-			readCtx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
-			msg, err := h.kafkaReader.ReadMessage(readCtx)
-			cancel()
-			
-			if err != nil {
-				// If context was canceled or timeout, continue
-				if err == context.Canceled || err == context.DeadlineExceeded {
-					continue
-				}
-				
-				log.Printf("Error reading message from Kafka: %v", err)
-				time.Sleep(1 * time.Second) // Avoid tight loop on errors
-				continue
-			}
-			
-			log.Printf("Received payment event: %s", string(msg.Value))
-			
-			// Process payment event
-			var paymentEvent models.PaymentEvent
-			if err := json.Unmarshal(msg.Value, &paymentEvent); err != nil {
-				log.Printf("Error unmarshaling payment event: %v", err)
-				continue
-			}
-			
-			// Process the payment through the settlement processor
-			if err := h.settlementProcessor.ProcessPayment(paymentEvent); err != nil {
-				log.Printf("Error processing payment: %v", err)
-				continue
-			}
-			
-			log.Printf("Successfully processed payment %s", paymentEvent.Payment.ID)
+// handlePaymentEvent processes a single payment event. Returning an error
+// causes the event to be retried and eventually dead-lettered.
+func (h *SettlementHandler) handlePaymentEvent(ctx context.Context, msg kafka.Message) error {
+	event, err := events.Decode(msg.Value)
+	if err != nil {
+		return consumer.Permanent(err)
+	}
+
+	// Captured payments are credited to settlements; refunds and chargebacks
+	// are debited from them
+	switch event.Type {
+	case events.TypePaymentCaptured, events.TypePaymentRefunded, events.TypePaymentChargedBack:
+	default:
+		return nil
+	}
+
+	log.Printf("Received payment event: %s, type: %s", event.ID, event.Type)
+
+	var payload events.Payment
+	if err := event.DecodePayload(&payload); err != nil {
+		return consumer.Permanent(err)
+	}
+
+	if event.Type != events.TypePaymentCaptured {
+		debit, ok, err := models.DebitFromEvent(event.Type, event.OccurredAt, payload)
+		if err != nil {
+			return consumer.Permanent(err)
+		}
+		if !ok {
+			log.Printf("Event %s of type %s debits nothing, skipping", event.ID, event.Type)
+			return nil
+		}
+		if err := h.settlementProcessor.ProcessDebit(debit); err != nil {
+			return fmt.Errorf("failed to process %s debit of payment %s: %w", debit.Type, debit.PaymentID, err)
 		}
+		return nil
 	}
+	payment := models.PaymentFromEvent(payload)
+
+	// Process the payment through the settlement processor
+	if err := h.settlementProcessor.ProcessPayment(payment); err != nil {
+		return fmt.Errorf("failed to process payment %s: %w", payment.ID, err)
+	}
+
+	log.Printf("Successfully processed payment %s", payment.ID)
+	return nil
 }
 
-// createSettlementBatches periodically creates settlement batches
-func (h *SettlementHandler) createSettlementBatches() {
-	log.Println("Starting settlement batch creator")
+// scheduleSettlements periodically settles merchants whose settlement cycle
+// passed a cut-off
+func (h *SettlementHandler) scheduleSettlements() {
+	log.Printf("Starting settlement scheduler, checking cut-offs every %s", h.scheduleInterval)
 	
-	// Create a ticker for running settlement batch creation
-	ticker := time.NewTicker(24 * time.Hour) // Run once per day
+	ticker := time.NewTicker(h.scheduleInterval)
 	defer ticker.Stop()
 	
 	// Run once immediately on startup
-	h.runSettlementBatch()
+	h.runDueSettlements()
 	
 	for {
 		select {
 		case <-h.ctx.Done():
 			return
 		case <-ticker.C:
-			h.runSettlementBatch()
+			h.runDueSettlements()
 		}
 	}
 }
 
-// runSettlementBatch runs a single settlement batch creation
-func (h *SettlementHandler) runSettlementBatch() {
-	log.Println("Creating settlement batch")
-	
-	// Get current time for batch window
+// runDueSettlements creates the settlements that are due, pays out those
+// whose payout date has come and records the payout outcomes the bank
+// reported
+func (h *SettlementHandler) runDueSettlements() {
 	now := time.Now()
-	startDate := now.AddDate(0, 0, -1) // 1 day ago
-	endDate := now
-	
-	// Create settlement batch
-	settlements, err := h.settlementProcessor.CreateSettlementBatch(startDate, endDate)
+	settlements, err := h.settlementProcessor.CreateDueSettlements(now)
 	if err != nil {
-		log.Printf("Error creating settlement batch: %v", err)
-		return
+		log.Printf("Error creating due settlements: %v", err)
+	} else if len(settlements) > 0 {
+		log.Printf("Created %d settlements", len(settlements))
 	}
 	
-	log.Printf("Created %d settlements", len(settlements))
-	
-	// Publish settlement events
-	for _, settlement := range settlements {
-		h.publishSettlementEvent(settlement)
+	// Fail the payouts of batches that never reached the bank, so they are
+	// retried below
+	failed, err := h.settlementProcessor.FailUnsubmittedPayouts(now)
+	if err != nil {
+		log.Printf("Error failing unsubmitted payouts: %v", err)
 	}
-}
-
-// publishSettlementEvent publishes a settlement event to Kafka
-func (h *SettlementHandler) publishSettlementEvent(settlement models.Settlement) {
-	// Create settlement event
-	event := models.SettlementEvent{
-		ID:         uuid.New(),
-		Settlement: settlement,
-		Timestamp:  time.Now(),
+	if len(failed) > 0 {
+		log.Printf("Failed %d unsubmitted payouts", len(failed))
 	}
-	
-	// Marshal event to JSON
-	eventJSON, err := json.Marshal(event)
+
+	// Settlement events were recorded in the outbox with each settlement;
+	// pay out the settlements whose payout date has come, and retry failed
+	// payouts
+	paid, err := h.settlementProcessor.PayDueSettlements(now)
 	if err != nil {
-		log.Printf("Error marshaling settlement event: %v", err)
-		return
+		log.Printf("Error paying out settlements: %v", err)
 	}
-	
-	// Create Kafka message
-	message := kafka.Message{
-		Key:   []byte(settlement.ID.String()),
-		Value: eventJSON,
-		Time:  time.Now(),
+	if len(paid) > 0 {
+		log.Printf("Paid out %d settlements", len(paid))
 	}
-	
-	// Write message to Kafka
-	if err := h.kafkaWriter.WriteMessages(h.ctx, message); err != nil {
-		log.Printf("Error publishing settlement event: %v", err)
-		return
+
+	// Complete or fail the settlements the bank reported payouts of
+	results, err := h.settlementProcessor.ProcessPayoutResponses(now)
+	if err != nil {
+		log.Printf("Error processing payout responses: %v", err)
 	}
-	
-	log.Printf("Published settlement event for settlement %s", settlement.ID)
-	
-	// Process the settlement (mark as completed, etc.)
-	if err := h.settlementProcessor.ProcessSettlement(settlement); err != nil {
-		log.Printf("Error processing settlement: %v", err)
-		return
+	if len(results) > 0 {
+		log.Printf("Recorded %d payout results", len(results))
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// PaymentFromEvent converts a payment event payload to a payment. The
+// merchant's reference is used as the order ID.
+func PaymentFromEvent(event events.Payment) Payment {
+	return Payment{
+		ID:            event.ID,
+		MerchantID:    event.MerchantID,
+		OrderID:       event.ReferenceID,
+		Amount:        event.Money(),
+		Currency:      event.Currency,
+		PaymentMethod: event.PaymentMethodType,
+		Status:        event.Status,
+		CreatedAt:     event.CreatedAt,
+		UpdatedAt:     event.UpdatedAt,
+	}
+}
+
+// DebitFromEvent converts a payment.refunded or payment.charged_back event
+// that occurred at occurredAt to the debit of the payment's merchant. A
+// refund debits the refunded amount under the refund's ID, since a payment
+// may be refunded in parts; a chargeback debits the payment's amount under an
+// ID derived from the payment, since a payment is charged back once. It
+// returns false for other event types.
+func DebitFromEvent(eventType string, occurredAt time.Time, event events.Payment) (MerchantDebit, bool, error) {
+	debit := MerchantDebit{
+		MerchantID: event.MerchantID,
+		PaymentID:  event.ID,
+		Amount:     event.Money(),
+		Currency:   event.Currency,
+		OccurredAt: occurredAt,
+		CreatedAt:  time.Now(),
+	}
+	switch eventType {
+	case events.TypePaymentRefunded:
+		debit.Type = DebitTypeRefund
+		debit.ID = debitID(event.ID, debit.Type)
+		if id, ok := event.Metadata["refund_id"]; ok {
+			refundID, err := uuid.Parse(id)
+			if err != nil {
+				return MerchantDebit{}, false, fmt.Errorf("invalid refund ID %q", id)
+			}
+			debit.ID = refundID
+		}
+		if amount, ok := event.Metadata["refund_amount"]; ok {
+			refunded, err := money.Parse(amount)
+			if err != nil || refunded.Sign() <= 0 {
+				return MerchantDebit{}, false, fmt.Errorf("invalid refund amount %q", amount)
+			}
+			debit.Amount = refunded.RoundTo(event.Currency, money.RoundHalfEven)
+		}
+	case events.TypePaymentChargedBack:
+		debit.Type = DebitTypeChargeback
+		debit.ID = debitID(event.ID, debit.Type)
+	default:
+		return MerchantDebit{}, false, nil
+	}
+	return debit, true, nil
+}
+
+// debitID derives the ID of a payment's debit of a type, for a payment that
+// is debited once
+func debitID(paymentID uuid.UUID, debitType DebitType) uuid.UUID {
+	return uuid.NewSHA1(paymentID, []byte(debitType))
+}
+
+// Event converts the settlement to the shared settlement event payload
+func (s Settlement) Event() events.Settlement {
+	event := events.Settlement{
+		ID:               s.ID,
+		MerchantID:       s.MerchantID,
+		Currency:         s.Currency,
+		Status:           string(s.Status),
+		PaymentCount:     s.PaymentCount,
+		SettlementDate:   s.SettlementDate,
+		BankAccountID:    s.BankAccountID,
+		SettlementMethod: string(s.SettlementMethod),
+		Reference:        s.Reference,
+		RecoveryMethod:   string(s.RecoveryMethod),
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+	}
+	event.SetAmounts(s.Amount, s.FeeAmount, s.TaxAmount, s.NetAmount)
+	event.SetReserves(s.ReserveHeld, s.ReserveReleased)
+	event.SetDebits(s.DebitAmount, s.DebitCount)
+	event.SetBalances(s.BalanceBroughtForward, s.BalanceCarriedForward, s.RecoveryAmount)
+	if !s.PayoutDate.IsZero() {
+		payoutDate := s.PayoutDate
+		event.PayoutDate = &payoutDate
+	}
+	return event
+}
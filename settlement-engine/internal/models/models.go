@@ -4,15 +4,18 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/money"
 )
 
 // Payment statuses
 const (
-	PaymentStatusPending   = "PENDING"
-	PaymentStatusCaptured  = "CAPTURED"
-	PaymentStatusFailed    = "FAILED"
-	PaymentStatusRefunded  = "REFUNDED"
-	PaymentStatusSettled   = "SETTLED"
+	PaymentStatusPending    = "PENDING"
+	PaymentStatusCaptured   = "CAPTURED"
+	PaymentStatusFailed     = "FAILED"
+	PaymentStatusRefunded   = "REFUNDED"
+	PaymentStatusSettled    = "SETTLED"
+	PaymentStatusChargeback = "CHARGEBACK"
 )
 
 // SettlementStatus represents the status of a settlement
@@ -35,12 +38,51 @@ const (
 	SettlementMethodWallet       SettlementMethod = "WALLET"
 )
 
+// ReserveMovementType represents the type of a reserve ledger movement
+type ReserveMovementType string
+
+// Reserve movement type constants
+const (
+	ReserveMovementHold    ReserveMovementType = "HOLD"
+	ReserveMovementRelease ReserveMovementType = "RELEASE"
+)
+
+// DebitType represents the type of a merchant debit
+type DebitType string
+
+// Debit type constants
+const (
+	DebitTypeRefund     DebitType = "REFUND"
+	DebitTypeChargeback DebitType = "CHARGEBACK"
+)
+
+// PayoutStatus represents the status of a settlement payout
+type PayoutStatus string
+
+// Payout status constants
+const (
+	PayoutStatusSubmitted PayoutStatus = "SUBMITTED"
+	PayoutStatusPaid      PayoutStatus = "PAID"
+	PayoutStatusFailed    PayoutStatus = "FAILED"
+)
+
+// RecoveryMethod represents how a merchant's persistent negative balance is
+// recovered
+type RecoveryMethod string
+
+// Recovery method constants
+const (
+	RecoveryMethodNone        RecoveryMethod = "NONE"
+	RecoveryMethodInvoice     RecoveryMethod = "INVOICE"
+	RecoveryMethodDirectDebit RecoveryMethod = "DIRECT_DEBIT"
+)
+
 // Payment represents a payment transaction
 type Payment struct {
 	ID              uuid.UUID `json:"id"`
 	MerchantID      uuid.UUID `json:"merchant_id"`
 	OrderID         string    `json:"order_id"`
-	Amount          float64   `json:"amount"`
+	Amount          money.Decimal `json:"amount"`
 	Currency        string    `json:"currency"`
 	PaymentMethod   string    `json:"payment_method"`
 	Status          string    `json:"status"`
@@ -49,59 +91,337 @@ type Payment struct {
 	SettlementReady bool      `json:"settlement_ready"`
 }
 
-// PaymentEvent represents a payment event from Kafka
-type PaymentEvent struct {
-	ID        uuid.UUID `json:"id"`
-	Type      string    `json:"type"`
-	Payment   Payment   `json:"payment"`
-	Timestamp time.Time `json:"timestamp"`
+// PaymentSummary represents a summary of payments for a merchant in one currency
+type PaymentSummary struct {
+	MerchantID      uuid.UUID     `json:"merchant_id"`
+	TotalAmount     money.Decimal `json:"total_amount"`
+	Currency        string        `json:"currency"`
+	PaymentCount    int           `json:"payment_count"`
+	EarliestPayment time.Time     `json:"earliest_payment"`
+	LatestPayment   time.Time     `json:"latest_payment"`
+	Payments        []Payment     `json:"payments"`
 }
 
-// PaymentSummary represents a summary of payments for a merchant
-type PaymentSummary struct {
-	MerchantID      uuid.UUID `json:"merchant_id"`
-	TotalAmount     float64   `json:"total_amount"`
-	Currency        string    `json:"currency"`
-	PaymentCount    int       `json:"payment_count"`
-	EarliestPayment time.Time `json:"earliest_payment"`
-	LatestPayment   time.Time `json:"latest_payment"`
+// SummarizePayments groups payments by merchant and currency, in the order
+// each group's first payment comes in
+func SummarizePayments(payments []Payment) []PaymentSummary {
+	type key struct {
+		merchantID uuid.UUID
+		currency   string
+	}
+	var summaries []PaymentSummary
+	index := make(map[key]int)
+	for _, payment := range payments {
+		k := key{payment.MerchantID, payment.Currency}
+		i, ok := index[k]
+		if !ok {
+			i = len(summaries)
+			index[k] = i
+			summaries = append(summaries, PaymentSummary{
+				MerchantID:      payment.MerchantID,
+				Currency:        payment.Currency,
+				EarliestPayment: payment.CreatedAt,
+				LatestPayment:   payment.CreatedAt,
+			})
+		}
+		summary := &summaries[i]
+		summary.TotalAmount = summary.TotalAmount.Add(payment.Amount)
+		summary.PaymentCount++
+		if payment.CreatedAt.Before(summary.EarliestPayment) {
+			summary.EarliestPayment = payment.CreatedAt
+		}
+		if payment.CreatedAt.After(summary.LatestPayment) {
+			summary.LatestPayment = payment.CreatedAt
+		}
+		summary.Payments = append(summary.Payments, payment)
+	}
+	return summaries
 }
 
-// Settlement represents a settlement batch for a merchant
+// Settlement represents a settlement batch for a merchant. A negative balance
+// is brought forward from the merchant's previous settlement in the currency
+// and carried forward to its next; both balances are zero or negative.
+// PayoutAttempts counts the payouts submitted for it.
 type Settlement struct {
-	ID              uuid.UUID        `json:"id"`
-	MerchantID      uuid.UUID        `json:"merchant_id"`
-	Amount          float64          `json:"amount"`
-	Currency        string           `json:"currency"`
-	Status          SettlementStatus `json:"status"`
-	PaymentCount    int              `json:"payment_count"`
-	FeeAmount       float64          `json:"fee_amount"`
-	TaxAmount       float64          `json:"tax_amount"`
-	NetAmount       float64          `json:"net_amount"`
-	SettlementDate  time.Time        `json:"settlement_date"`
-	BankAccountID   string           `json:"bank_account_id"`
-	SettlementMethod SettlementMethod `json:"settlement_method"`
-	Reference       string           `json:"reference"`
-	CreatedAt       time.Time        `json:"created_at"`
-	UpdatedAt       time.Time        `json:"updated_at"`
-}
-
-// SettlementEvent represents a settlement event for Kafka
-type SettlementEvent struct {
-	ID         uuid.UUID  `json:"id"`
-	Settlement Settlement `json:"settlement"`
-	Timestamp  time.Time  `json:"timestamp"`
-}
-
-// MerchantSettlementConfig represents settlement configuration for a merchant
+	ID                    uuid.UUID        `json:"id"`
+	MerchantID            uuid.UUID        `json:"merchant_id"`
+	Amount                money.Decimal    `json:"amount"`
+	Currency              string           `json:"currency"`
+	Status                SettlementStatus `json:"status"`
+	PaymentCount          int              `json:"payment_count"`
+	FeeAmount             money.Decimal    `json:"fee_amount"`
+	TaxAmount             money.Decimal    `json:"tax_amount"`
+	NetAmount             money.Decimal    `json:"net_amount"`
+	SettlementDate        time.Time        `json:"settlement_date"`
+	CutoffAt              time.Time        `json:"cutoff_at"`
+	ReserveHeld           money.Decimal    `json:"reserve_held"`
+	ReserveReleased       money.Decimal    `json:"reserve_released"`
+	DebitAmount           money.Decimal    `json:"debit_amount"`
+	DebitCount            int              `json:"debit_count"`
+	BalanceBroughtForward money.Decimal    `json:"balance_brought_forward"`
+	BalanceCarriedForward money.Decimal    `json:"balance_carried_forward"`
+	NegativeSince         time.Time        `json:"negative_since"`
+	RecoveryMethod        RecoveryMethod   `json:"recovery_method"`
+	RecoveryAmount        money.Decimal    `json:"recovery_amount"`
+	PayoutAmount          money.Decimal    `json:"payout_amount"`
+	PayoutDate            time.Time        `json:"payout_date"`
+	PayoutAttempts        int              `json:"payout_attempts"`
+	BankAccountID         string           `json:"bank_account_id"`
+	SettlementMethod      SettlementMethod `json:"settlement_method"`
+	Reference             string           `json:"reference"`
+	CreatedAt             time.Time        `json:"created_at"`
+	UpdatedAt             time.Time        `json:"updated_at"`
+}
+
+// SettlementItem is a payment paid out by a settlement, with the fee and tax
+// charged on it and the net amount paid for it. The fee is FeePercent of the
+// amount plus FixedFee, as priced by PricingPlan, and the tax TaxPercent of
+// the fee, as charged in TaxJurisdiction.
+type SettlementItem struct {
+	ID              uuid.UUID     `json:"id"`
+	SettlementID    uuid.UUID     `json:"settlement_id"`
+	PaymentID       uuid.UUID     `json:"payment_id"`
+	Amount          money.Decimal `json:"amount"`
+	Currency        string        `json:"currency"`
+	PaymentMethod   string        `json:"payment_method"`
+	PricingPlan     string        `json:"pricing_plan"`
+	FeePercent      money.Decimal `json:"fee_percent"`
+	FixedFee        money.Decimal `json:"fixed_fee"`
+	FeeAmount       money.Decimal `json:"fee_amount"`
+	TaxJurisdiction string        `json:"tax_jurisdiction"`
+	TaxPercent      money.Decimal `json:"tax_percent"`
+	TaxAmount       money.Decimal `json:"tax_amount"`
+	NetAmount       money.Decimal `json:"net_amount"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+// AddItem adds an item to the settlement, adding its amounts to the
+// settlement's, so a settlement's amounts are always the sums of its items'
+func (s *Settlement) AddItem(item SettlementItem) {
+	s.Amount = s.Amount.Add(item.Amount)
+	s.FeeAmount = s.FeeAmount.Add(item.FeeAmount)
+	s.TaxAmount = s.TaxAmount.Add(item.TaxAmount)
+	s.NetAmount = s.NetAmount.Add(item.NetAmount)
+	s.PayoutAmount = s.PayoutAmount.Add(item.NetAmount)
+	s.PaymentCount++
+}
+
+// SettlementPayout is an attempt to pay a settlement out, submitted to the
+// bank in the batch BatchID under its own Reference
+type SettlementPayout struct {
+	ID            uuid.UUID     `json:"id"`
+	SettlementID  uuid.UUID     `json:"settlement_id"`
+	MerchantID    uuid.UUID     `json:"merchant_id"`
+	Reference     string        `json:"reference"`
+	BatchID       string        `json:"batch_id"`
+	Attempt       int           `json:"attempt"`
+	Amount        money.Decimal `json:"amount"`
+	Currency      string        `json:"currency"`
+	BankAccountID string        `json:"bank_account_id"`
+	Status        PayoutStatus  `json:"status"`
+	FailureReason string        `json:"failure_reason"`
+	SubmittedAt   time.Time     `json:"submitted_at"`
+	CompletedAt   time.Time     `json:"completed_at"`
+}
+
+// BankAccount is a merchant's payout account, the bank account a penny drop
+// verified last. Its account number, or IBAN, is only kept encrypted.
+// ReplacesAccountID is the payout account it replaced, or uuid.Nil for the
+// merchant's first.
+type BankAccount struct {
+	ID                     uuid.UUID          `json:"id"`
+	MerchantID             uuid.UUID          `json:"merchant_id"`
+	HolderName             string             `json:"holder_name"`
+	Scheme                 bankaccount.Scheme `json:"scheme"`
+	AccountNumberEncrypted string             `json:"-"`
+	IFSC                   string             `json:"ifsc"`
+	BIC                    string             `json:"bic"`
+	VerifiedAt             time.Time          `json:"verified_at"`
+	ReplacesAccountID      uuid.UUID          `json:"replaces_account_id"`
+}
+
+// MerchantDebit is a refund or chargeback of a payment, deducted from the
+// merchant's next settlement in its currency
+type MerchantDebit struct {
+	ID           uuid.UUID     `json:"id"`
+	MerchantID   uuid.UUID     `json:"merchant_id"`
+	PaymentID    uuid.UUID     `json:"payment_id"`
+	Type         DebitType     `json:"debit_type"`
+	Amount       money.Decimal `json:"amount"`
+	Currency     string        `json:"currency"`
+	SettlementID uuid.UUID     `json:"settlement_id"`
+	OccurredAt   time.Time     `json:"occurred_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// AddDebit deducts a debit from the settlement's payout
+func (s *Settlement) AddDebit(debit MerchantDebit) {
+	s.DebitAmount = s.DebitAmount.Add(debit.Amount)
+	s.PayoutAmount = s.PayoutAmount.Sub(debit.Amount)
+	s.DebitCount++
+}
+
+// CarriedBalance is the negative balance a merchant's latest settlement in a
+// currency carried forward, negative since NegativeSince
+type CarriedBalance struct {
+	Currency      string        `json:"currency"`
+	Amount        money.Decimal `json:"amount"`
+	NegativeSince time.Time     `json:"negative_since"`
+}
+
+// BringForward brings the negative balance of the merchant's previous
+// settlement into the settlement, deducting it from its payout
+func (s *Settlement) BringForward(balance CarriedBalance) {
+	s.BalanceBroughtForward = balance.Amount
+	s.NegativeSince = balance.NegativeSince
+	s.PayoutAmount = s.PayoutAmount.Add(balance.Amount)
+}
+
+// ReserveMovement is a movement of a merchant's rolling reserve ledger. A hold
+// keeps part of a settlement's net amount until ReleaseAt; a release, recorded
+// on a later settlement, pays out all or part of the hold HoldID.
+type ReserveMovement struct {
+	ID           uuid.UUID           `json:"id"`
+	MerchantID   uuid.UUID           `json:"merchant_id"`
+	SettlementID uuid.UUID           `json:"settlement_id"`
+	Currency     string              `json:"currency"`
+	Type         ReserveMovementType `json:"movement_type"`
+	Amount       money.Decimal       `json:"amount"`
+	ReleaseAt    time.Time           `json:"release_at"`
+	HoldID       uuid.UUID           `json:"hold_id"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+// ReserveHold is a reserve hold with what remains of it to release
+type ReserveHold struct {
+	ID           uuid.UUID     `json:"id"`
+	SettlementID uuid.UUID     `json:"settlement_id"`
+	Currency     string        `json:"currency"`
+	Outstanding  money.Decimal `json:"outstanding"`
+	ReleaseAt    time.Time     `json:"release_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// AddReserveMovement adds a movement to the settlement: a hold is taken from
+// its payout and a release added to it
+func (s *Settlement) AddReserveMovement(movement ReserveMovement) {
+	switch movement.Type {
+	case ReserveMovementHold:
+		s.ReserveHeld = s.ReserveHeld.Add(movement.Amount)
+		s.PayoutAmount = s.PayoutAmount.Sub(movement.Amount)
+	case ReserveMovementRelease:
+		s.ReserveReleased = s.ReserveReleased.Add(movement.Amount)
+		s.PayoutAmount = s.PayoutAmount.Add(movement.Amount)
+	}
+}
+
+// MerchantSettlementConfig represents settlement configuration for a merchant.
+// The merchant is settled at the cut-offs of its cycle, at CutoffTime (HH:MM)
+// in Timezone; see package schedule. Settlements are paid out PayoutDelayDays
+// business days after their cut-off, less ReservePercent of their net amount
+// held for ReserveHoldDays days; MinimumReserveAmount stays held at all times.
+// A balance negative for longer than NegativeBalanceDays is recovered with
+// NegativeBalanceRecovery. Fees are charged by PricingPlan, or FeePercent of
+// each payment without one, and taxed in TaxJurisdiction.
 type MerchantSettlementConfig struct {
 	MerchantID              uuid.UUID        `json:"merchant_id"`
 	SettlementCycle         string           `json:"settlement_cycle"` // DAILY, WEEKLY, MONTHLY
-	PreferredSettlementDay  int              `json:"preferred_settlement_day"` // Day of week/month
+	PreferredSettlementDay  int              `json:"preferred_settlement_day"` // Day of week (1 is Monday) or month
+	Timezone                string           `json:"timezone"`
+	CutoffTime              string           `json:"cutoff_time"`
 	SettlementMethod        SettlementMethod `json:"settlement_method"`
-	BankAccountID           string           `json:"bank_account_id"`
-	FeePercent              float64          `json:"fee_percent"`
-	MinimumSettlementAmount float64          `json:"minimum_settlement_amount"`
+	FeePercent              money.Decimal    `json:"fee_percent"`
+	PricingPlan             string           `json:"pricing_plan"`
+	TaxJurisdiction         string           `json:"tax_jurisdiction"`
+	MinimumSettlementAmount money.Decimal    `json:"minimum_settlement_amount"`
+	PayoutDelayDays         int              `json:"payout_delay_days"`
+	ReservePercent          money.Decimal    `json:"reserve_percent"`
+	ReserveHoldDays         int              `json:"reserve_hold_days"`
+	MinimumReserveAmount    money.Decimal    `json:"minimum_reserve_amount"`
+	NegativeBalanceRecovery RecoveryMethod   `json:"negative_balance_recovery"`
+	NegativeBalanceDays     int              `json:"negative_balance_days"`
 	CreatedAt               time.Time        `json:"created_at"`
 	UpdatedAt               time.Time        `json:"updated_at"`
-} 
\ No newline at end of file
+}
+
+// MerchantSettlementSettings is a merchant's own settlement configuration as
+// stored. Empty strings and nil numbers are unset and take the defaults, so a
+// merchant can be given a zero fee, reserve or payout delay.
+type MerchantSettlementSettings struct {
+	MerchantID              uuid.UUID
+	SettlementCycle         string
+	PreferredSettlementDay  *int
+	Timezone                string
+	CutoffTime              string
+	SettlementMethod        SettlementMethod
+	FeePercent              *money.Decimal
+	PricingPlan             string
+	TaxJurisdiction         string
+	MinimumSettlementAmount *money.Decimal
+	PayoutDelayDays         *int
+	ReservePercent          *money.Decimal
+	ReserveHoldDays         *int
+	MinimumReserveAmount    *money.Decimal
+	NegativeBalanceRecovery RecoveryMethod
+	NegativeBalanceDays     *int
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
+}
+
+// WithDefaults returns the configuration of the settings, with their unset
+// fields taken from defaults
+func (s MerchantSettlementSettings) WithDefaults(defaults MerchantSettlementConfig) MerchantSettlementConfig {
+	c := defaults
+	c.MerchantID = s.MerchantID
+	c.CreatedAt = s.CreatedAt
+	c.UpdatedAt = s.UpdatedAt
+	if s.SettlementCycle != "" {
+		c.SettlementCycle = s.SettlementCycle
+	}
+	if s.PreferredSettlementDay != nil {
+		c.PreferredSettlementDay = *s.PreferredSettlementDay
+	}
+	if s.Timezone != "" {
+		c.Timezone = s.Timezone
+	}
+	if s.CutoffTime != "" {
+		c.CutoffTime = s.CutoffTime
+	}
+	if s.SettlementMethod != "" {
+		c.SettlementMethod = s.SettlementMethod
+	}
+	// A fee percent of the merchant's own takes precedence over the default
+	// pricing plan
+	if s.FeePercent != nil {
+		c.FeePercent = *s.FeePercent
+		c.PricingPlan = ""
+	}
+	if s.PricingPlan != "" {
+		c.PricingPlan = s.PricingPlan
+	}
+	if s.TaxJurisdiction != "" {
+		c.TaxJurisdiction = s.TaxJurisdiction
+	}
+	if s.MinimumSettlementAmount != nil {
+		c.MinimumSettlementAmount = *s.MinimumSettlementAmount
+	}
+	if s.PayoutDelayDays != nil {
+		c.PayoutDelayDays = *s.PayoutDelayDays
+	}
+	if s.ReservePercent != nil {
+		c.ReservePercent = *s.ReservePercent
+	}
+	if s.ReserveHoldDays != nil {
+		c.ReserveHoldDays = *s.ReserveHoldDays
+	}
+	if s.MinimumReserveAmount != nil {
+		c.MinimumReserveAmount = *s.MinimumReserveAmount
+	}
+	if s.NegativeBalanceRecovery != "" {
+		c.NegativeBalanceRecovery = s.NegativeBalanceRecovery
+	}
+	if s.NegativeBalanceDays != nil {
+		c.NegativeBalanceDays = *s.NegativeBalanceDays
+	}
+	return c
+}
@@ -0,0 +1,108 @@
+package payout
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// csvDateLayout is the layout of value dates in CSV payout files
+const csvDateLayout = "2006-01-02"
+
+// csvHeader is the header row of CSV payout files
+var csvHeader = []string{
+	"payment_reference", "value_date", "beneficiary_code", "beneficiary_name",
+	"beneficiary_account", "beneficiary_bank_code", "amount", "currency", "narration",
+}
+
+// csvStatuses maps the statuses of CSV response files to payout outcomes;
+// RETURNED is a payout the bank paid and later returned
+var csvStatuses = map[string]Status{
+	"PAID":     StatusPaid,
+	"SUCCESS":  StatusPaid,
+	"FAILED":   StatusFailed,
+	"REJECTED": StatusFailed,
+	"RETURNED": StatusFailed,
+}
+
+// encodeCSV encodes a batch as a CSV payout file with a header row and a row
+// per payout. The beneficiary's bank code is the IFSC of its branch, or the
+// BIC of its bank.
+func encodeCSV(batch Batch) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, payout := range batch.Payouts {
+		bankCode := payout.Beneficiary.IFSC
+		if bankCode == "" {
+			bankCode = payout.Beneficiary.BIC
+		}
+		err := w.Write([]string{
+			payout.Reference,
+			payout.ExecutionDate.Format(csvDateLayout),
+			payout.MerchantID.String(),
+			payout.Beneficiary.HolderName,
+			payout.Beneficiary.Number,
+			bankCode,
+			payout.Amount.String(),
+			payout.Currency,
+			payout.Narration,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// decodeCSVResponse decodes a CSV response or return file. Its header row
+// names the payment_reference, status and, optionally, reason columns, in any
+// order.
+func decodeCSVResponse(data []byte) ([]Result, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no header row")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	reference, ok := columns["payment_reference"]
+	if !ok {
+		return nil, errors.New("no payment_reference column")
+	}
+	status, ok := columns["status"]
+	if !ok {
+		return nil, errors.New("no status column")
+	}
+	reason, hasReason := columns["reason"]
+
+	results := make([]Result, 0, len(rows)-1)
+	for line, row := range rows[1:] {
+		if reference >= len(row) || status >= len(row) {
+			return nil, fmt.Errorf("row %d: missing columns", line+2)
+		}
+		outcome, ok := csvStatuses[strings.ToUpper(strings.TrimSpace(row[status]))]
+		if !ok {
+			return nil, fmt.Errorf("row %d: unknown status %q", line+2, row[status])
+		}
+		result := Result{Reference: strings.TrimSpace(row[reference]), Status: outcome}
+		if hasReason && reason < len(row) {
+			result.Reason = strings.TrimSpace(row[reason])
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
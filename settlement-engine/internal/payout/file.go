@@ -0,0 +1,173 @@
+package payout
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format is a bank payout file format
+type Format string
+
+// Bank payout file formats
+const (
+	// FormatCSV is a NEFT-style CSV file, answered by CSV response files
+	FormatCSV Format = "CSV"
+	// FormatPain001 is an ISO 20022 pain.001 credit transfer initiation,
+	// answered by pain.002 payment status reports
+	FormatPain001 Format = "PAIN001"
+)
+
+// Debtor is the account payouts are paid from
+type Debtor struct {
+	Name    string
+	Account string
+	BIC     string
+}
+
+// FileProvider writes each batch of payouts to a bank file in an outbox
+// directory, for transfer to the bank, which may move transferred files to
+// the sent directory of the outbox. It reads the bank's response and return
+// files from an inbox directory. Handled responses are moved to the
+// processed directory of the inbox, and files it cannot read to its rejected
+// directory.
+type FileProvider struct {
+	format Format
+	outbox string
+	inbox  string
+	debtor Debtor
+}
+
+// NewFileProvider creates a provider writing bank files of a format to the
+// outbox directory and reading responses from the inbox directory, creating
+// the directories if needed
+func NewFileProvider(format Format, outbox, inbox string, debtor Debtor) (*FileProvider, error) {
+	if format != FormatCSV && format != FormatPain001 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	for _, dir := range []string{outbox, filepath.Join(outbox, "sent"), inbox, filepath.Join(inbox, "processed"), filepath.Join(inbox, "rejected")} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create payout directory: %w", err)
+		}
+	}
+	return &FileProvider{format: format, outbox: outbox, inbox: inbox, debtor: debtor}, nil
+}
+
+// Submit writes the batch to a bank file named after it. The file appears in
+// the outbox complete or not at all.
+func (p *FileProvider) Submit(batch Batch) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch p.format {
+	case FormatPain001:
+		data, err = encodePain001(batch, p.debtor)
+	default:
+		data, err = encodeCSV(batch)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode payout file: %w", err)
+	}
+
+	name := p.fileName(batch.ID)
+	path := filepath.Join(p.outbox, name)
+	tmp := filepath.Join(p.outbox, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write payout file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write payout file: %w", err)
+	}
+	return nil
+}
+
+// Submitted reports whether the bank file of a batch is in the outbox or its
+// sent directory
+func (p *FileProvider) Submitted(batchID string) (bool, error) {
+	name := p.fileName(batchID)
+	for _, path := range []string{filepath.Join(p.outbox, name), filepath.Join(p.outbox, "sent", name)} {
+		_, err := os.Stat(path)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, fmt.Errorf("failed to look for payout file: %w", err)
+		}
+	}
+	return false, nil
+}
+
+// fileName returns the name of the bank file of a batch
+func (p *FileProvider) fileName(batchID string) string {
+	if p.format == FormatPain001 {
+		return batchID + ".xml"
+	}
+	return batchID + ".csv"
+}
+
+// Responses reads the CSV and pain.002 XML files in the inbox, in the order of
+// their names. Files that cannot be read are moved to the rejected directory,
+// and reported in the error.
+func (p *FileProvider) Responses() ([]Response, error) {
+	entries, err := os.ReadDir(p.inbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payout inbox: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var (
+		responses []Response
+		errs      []error
+	)
+	for _, name := range names {
+		var decode func([]byte) ([]Result, error)
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".csv":
+			decode = decodeCSVResponse
+		case ".xml":
+			decode = decodePain002
+		default:
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(p.inbox, name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		results, err := decode(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			if err := p.move(name, "rejected"); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		responses = append(responses, Response{Name: name, Results: results})
+	}
+	return responses, errors.Join(errs...)
+}
+
+// Handled moves a response file to the processed directory
+func (p *FileProvider) Handled(response Response) error {
+	return p.move(response.Name, "processed")
+}
+
+// move moves a file of the inbox to one of its directories
+func (p *FileProvider) move(name, dir string) error {
+	if err := os.Rename(filepath.Join(p.inbox, name), filepath.Join(p.inbox, dir, name)); err != nil {
+		return fmt.Errorf("failed to move payout response %s to %s: %w", name, dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package payout
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// painDateLayout is the layout of ISO 20022 dates
+const painDateLayout = "2006-01-02"
+
+// pain001 is an ISO 20022 pain.001.001.03 customer credit transfer initiation
+type pain001 struct {
+	XMLName xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pain.001.001.03 Document"`
+	GrpHdr  struct {
+		MsgID    string `xml:"MsgId"`
+		CreDtTm  string `xml:"CreDtTm"`
+		NbOfTxs  int    `xml:"NbOfTxs"`
+		CtrlSum  string `xml:"CtrlSum"`
+		InitgPty struct {
+			Nm string `xml:"Nm"`
+		} `xml:"InitgPty"`
+	} `xml:"CstmrCdtTrfInitn>GrpHdr"`
+	PmtInf []painPaymentInfo `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+// painPaymentInfo is the payouts of a pain.001 to execute on one day
+type painPaymentInfo struct {
+	PmtInfID    string       `xml:"PmtInfId"`
+	PmtMtd      string       `xml:"PmtMtd"`
+	NbOfTxs     int          `xml:"NbOfTxs"`
+	CtrlSum     string       `xml:"CtrlSum"`
+	ReqdExctnDt string       `xml:"ReqdExctnDt"`
+	DbtrNm      string       `xml:"Dbtr>Nm"`
+	DbtrAcctID  string       `xml:"DbtrAcct>Id>Othr>Id"`
+	DbtrAgt     painAgent    `xml:"DbtrAgt>FinInstnId"`
+	CdtTrfTxInf []painCredit `xml:"CdtTrfTxInf"`
+}
+
+// painAgent identifies a bank by its BIC, a branch by its clearing system
+// member ID, or either as not provided
+type painAgent struct {
+	BIC      string        `xml:"BIC,omitempty"`
+	Clearing *painClearing `xml:"ClrSysMmbId,omitempty"`
+	Other    *painOther    `xml:"Othr,omitempty"`
+}
+
+// painClearing identifies a branch by its ID in a clearing system, such as
+// INFSC for IFSCs
+type painClearing struct {
+	System   string `xml:"ClrSysId>Cd"`
+	MemberID string `xml:"MmbId"`
+}
+
+// painAccount identifies an account by its IBAN or another account number
+type painAccount struct {
+	IBAN  string     `xml:"IBAN,omitempty"`
+	Other *painOther `xml:"Othr,omitempty"`
+}
+
+// painOther is an identification other than a BIC
+type painOther struct {
+	ID string `xml:"Id"`
+}
+
+// painCredit is a payout of a pain.001
+type painCredit struct {
+	EndToEndID string      `xml:"PmtId>EndToEndId"`
+	Amount     painAmount  `xml:"Amt>InstdAmt"`
+	CdtrAgt    *painAgent  `xml:"CdtrAgt>FinInstnId,omitempty"`
+	CdtrNm     string      `xml:"Cdtr>Nm"`
+	CdtrAcct   painAccount `xml:"CdtrAcct>Id"`
+	Ustrd      string      `xml:"RmtInf>Ustrd,omitempty"`
+}
+
+// painAmount is an amount with its currency
+type painAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// encodePain001 encodes a batch as a pain.001 file, with a payment
+// information block per execution date
+func encodePain001(batch Batch, debtor Debtor) ([]byte, error) {
+	var doc pain001
+	doc.GrpHdr.MsgID = batch.ID
+	doc.GrpHdr.CreDtTm = batch.CreatedAt.UTC().Format("2006-01-02T15:04:05")
+	doc.GrpHdr.NbOfTxs = len(batch.Payouts)
+	doc.GrpHdr.InitgPty.Nm = debtor.Name
+
+	agent := painAgent{BIC: debtor.BIC}
+	if agent.BIC == "" {
+		agent.Other = &painOther{ID: "NOTPROVIDED"}
+	}
+	total := money.Zero
+	sums := []money.Decimal{}
+	blocks := make(map[string]int)
+	for _, payout := range batch.Payouts {
+		date := payout.ExecutionDate.Format(painDateLayout)
+		i, ok := blocks[date]
+		if !ok {
+			i = len(doc.PmtInf)
+			blocks[date] = i
+			doc.PmtInf = append(doc.PmtInf, painPaymentInfo{
+				PmtInfID:    fmt.Sprintf("%s-%d", batch.ID, i+1),
+				PmtMtd:      "TRF",
+				ReqdExctnDt: date,
+				DbtrNm:      debtor.Name,
+				DbtrAcctID:  debtor.Account,
+				DbtrAgt:     agent,
+			})
+			sums = append(sums, money.Zero)
+		}
+		block := &doc.PmtInf[i]
+		block.CdtTrfTxInf = append(block.CdtTrfTxInf, painCredit{
+			EndToEndID: payout.Reference,
+			Amount:     painAmount{Currency: payout.Currency, Value: payout.Amount.String()},
+			CdtrAgt:    creditorAgent(payout.Beneficiary),
+			CdtrNm:     payout.Beneficiary.HolderName,
+			CdtrAcct:   creditorAccount(payout.Beneficiary),
+			Ustrd:      payout.Narration,
+		})
+		block.NbOfTxs++
+		sums[i] = sums[i].Add(payout.Amount)
+		total = total.Add(payout.Amount)
+	}
+	for i := range doc.PmtInf {
+		doc.PmtInf[i].CtrlSum = sums[i].String()
+	}
+	doc.GrpHdr.CtrlSum = total.String()
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// creditorAgent identifies the bank of a beneficiary by its BIC, or the
+// branch of an Indian account by its IFSC; without either it is left out
+func creditorAgent(beneficiary bankaccount.Account) *painAgent {
+	switch {
+	case beneficiary.BIC != "":
+		return &painAgent{BIC: beneficiary.BIC}
+	case beneficiary.IFSC != "":
+		return &painAgent{Clearing: &painClearing{System: "INFSC", MemberID: beneficiary.IFSC}}
+	}
+	return nil
+}
+
+// creditorAccount identifies the account of a beneficiary by its IBAN, or
+// its account number
+func creditorAccount(beneficiary bankaccount.Account) painAccount {
+	if beneficiary.Scheme == bankaccount.SchemeIBAN {
+		return painAccount{IBAN: beneficiary.Number}
+	}
+	return painAccount{Other: &painOther{ID: beneficiary.Number}}
+}
+
+// pain002 is the part of an ISO 20022 pain.002 payment status report the
+// outcomes of payouts are read from
+type pain002 struct {
+	XMLName xml.Name `xml:"Document"`
+	Report  *struct {
+		Transactions []struct {
+			EndToEndID string `xml:"OrgnlEndToEndId"`
+			Status     string `xml:"TxSts"`
+			Reasons    []struct {
+				Code        string   `xml:"Rsn>Cd"`
+				Proprietary string   `xml:"Rsn>Prtry"`
+				Info        []string `xml:"AddtlInf"`
+			} `xml:"StsRsnInf"`
+		} `xml:"OrgnlPmtInfAndSts>TxInfAndSts"`
+	} `xml:"CstmrPmtStsRpt"`
+}
+
+// pain002Statuses maps the final transaction statuses of pain.002 reports to
+// payout outcomes; other statuses, such as ACSP for a payout still in
+// progress, are not final and are left out
+var pain002Statuses = map[string]Status{
+	"ACSC": StatusPaid,
+	"ACCC": StatusPaid,
+	"RJCT": StatusFailed,
+}
+
+// decodePain002 decodes the final payout outcomes of a pain.002 report. The
+// reason of a rejection is its reason code followed by any additional
+// information.
+func decodePain002(data []byte) ([]Result, error) {
+	var doc pain002
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid XML: %w", err)
+	}
+	if doc.Report == nil {
+		return nil, errors.New("not a pain.002 payment status report")
+	}
+
+	var results []Result
+	for _, tx := range doc.Report.Transactions {
+		status, ok := pain002Statuses[tx.Status]
+		if !ok {
+			continue
+		}
+		result := Result{Reference: tx.EndToEndID, Status: status}
+		var reasons []string
+		for _, reason := range tx.Reasons {
+			parts := []string{reason.Code + reason.Proprietary}
+			parts = append(parts, reason.Info...)
+			reasons = append(reasons, strings.TrimSpace(strings.Join(parts, " ")))
+		}
+		result.Reason = strings.Join(reasons, "; ")
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,81 @@
+// Package payout sends settlement payouts to the bank and reads back what
+// became of them.
+//
+// A provider takes batches of payouts, each identified by a unique reference,
+// and later reports each payout paid or failed with a reason. A payout paid
+// may still fail later, when the bank returns it.
+package payout
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// ErrUnknownFormat is returned for a bank file format that is not supported
+var ErrUnknownFormat = errors.New("unknown payout file format")
+
+// Payout is an instruction to pay a merchant into its bank account
+// BankAccountID, whose details are Beneficiary
+type Payout struct {
+	Reference     string
+	SettlementID  uuid.UUID
+	MerchantID    uuid.UUID
+	BankAccountID string
+	Beneficiary   bankaccount.Account
+	Amount        money.Decimal
+	Currency      string
+	// ExecutionDate is the day the bank is asked to pay on
+	ExecutionDate time.Time
+	Narration     string
+}
+
+// Batch is a set of payouts submitted together, such as in one bank file
+type Batch struct {
+	ID        string
+	CreatedAt time.Time
+	Payouts   []Payout
+}
+
+// Status is the outcome of a payout
+type Status string
+
+// Payout outcomes
+const (
+	StatusPaid   Status = "PAID"
+	StatusFailed Status = "FAILED"
+)
+
+// Result is what the bank reported of a payout
+type Result struct {
+	Reference string
+	Status    Status
+	// Reason is why a payout failed
+	Reason string
+}
+
+// Response is a set of results the bank reported together, such as a response
+// or return file
+type Response struct {
+	Name    string
+	Results []Result
+}
+
+// Provider sends payouts to the bank and reports their outcomes
+type Provider interface {
+	// Submit sends a batch of payouts to the bank
+	Submit(batch Batch) error
+
+	// Submitted reports whether a batch was sent to the bank, so payouts
+	// recorded for a batch that never was can be failed and retried
+	Submitted(batchID string) (bool, error)
+
+	// Responses returns the bank's responses not handled yet, the oldest first
+	Responses() ([]Response, error)
+
+	// Handled marks a response handled, so it is not returned again
+	Handled(response Response) error
+}
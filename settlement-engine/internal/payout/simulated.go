@@ -0,0 +1,56 @@
+package payout
+
+import "sync"
+
+// SimulatedProvider pays every payout it is given, for running without a
+// bank. The outcome of each batch is reported as a response of its own.
+type SimulatedProvider struct {
+	mu        sync.Mutex
+	batches   map[string]bool
+	responses []Response
+}
+
+// NewSimulatedProvider creates a provider that pays every payout
+func NewSimulatedProvider() *SimulatedProvider {
+	return &SimulatedProvider{batches: make(map[string]bool)}
+}
+
+// Submit reports every payout of the batch paid
+func (p *SimulatedProvider) Submit(batch Batch) error {
+	response := Response{Name: batch.ID, Results: make([]Result, len(batch.Payouts))}
+	for i, payout := range batch.Payouts {
+		response.Results[i] = Result{Reference: payout.Reference, Status: StatusPaid}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches[batch.ID] = true
+	p.responses = append(p.responses, response)
+	return nil
+}
+
+// Submitted reports whether the batch was submitted to this provider
+func (p *SimulatedProvider) Submitted(batchID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.batches[batchID], nil
+}
+
+// Responses returns the outcomes of the batches submitted and not handled yet
+func (p *SimulatedProvider) Responses() ([]Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Response(nil), p.responses...), nil
+}
+
+// Handled forgets a response
+func (p *SimulatedProvider) Handled(response Response) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, r := range p.responses {
+		if r.Name == response.Name {
+			p.responses = append(p.responses[:i], p.responses[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+// Package pricing decides the fees and taxes charged on settled payments.
+//
+// A pricing plan is a list of rates. A rate applies to the payments of one
+// payment method and currency, or of any when either is left out, and charges
+// a percentage of the payment plus a fixed fee, bounded by a minimum and a
+// maximum fee. Its tiers lower the price as the merchant's volume in the month
+// grows. Tax is charged on the fee at the rate of the merchant's tax
+// jurisdiction. Plans and tax rules are loaded from a YAML file:
+//
+//	plans:
+//	  - name: standard
+//	    rates:
+//	      - payment_method: CREDIT_CARD
+//	        currency: USD
+//	        minimum_fee: 0.50
+//	        maximum_fee: 50
+//	        tiers:
+//	          - percent: 2.9
+//	            fixed: 0.30
+//	          - from: 100000
+//	            percent: 2.5
+//	            fixed: 0.30
+//	      - percent: 2
+//	taxes:
+//	  - jurisdiction: IN
+//	    name: GST
+//	    percent: 18
+//	  - jurisdiction: GB
+//	    name: VAT
+//	    percent: 20
+//
+// A rate without tiers has a single tier of its own percent and fixed fee; a
+// rate with tiers cannot have them too. Fixed, minimum and maximum fees are
+// amounts in the rate's currency, so a rate that charges them must have one.
+// No fee is more than the payment it is charged on.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/yourusername/fortexa/pkg/money"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoRate is returned when a plan has no rate for a payment's method and
+// currency
+var ErrNoRate = errors.New("pricing plan has no rate for the payment")
+
+// Pricing is a set of pricing plans and tax rules
+type Pricing struct {
+	plans map[string]*Plan
+	taxes map[string]TaxRule
+}
+
+// Plan is a pricing plan
+type Plan struct {
+	Name  string
+	Rates []Rate
+}
+
+// Rate prices the payments of a payment method and currency; an empty
+// PaymentMethod or Currency matches any. MaximumFee is unbounded when zero.
+type Rate struct {
+	PaymentMethod string
+	Currency      string
+	MinimumFee    money.Decimal
+	MaximumFee    money.Decimal
+	// Tiers are ordered by the monthly volume they start from, the first
+	// from zero
+	Tiers []Tier
+}
+
+// Tier is the price of a rate once the merchant's monthly volume reaches From
+type Tier struct {
+	From    money.Decimal
+	Percent money.Decimal
+	Fixed   money.Decimal
+}
+
+// TaxRule is the tax charged on fees in a jurisdiction
+type TaxRule struct {
+	Jurisdiction string
+	Name         string
+	Percent      money.Decimal
+}
+
+// Fee is the fee charged on a payment and how it was priced
+type Fee struct {
+	Plan    string
+	Percent money.Decimal
+	Fixed   money.Decimal
+	Amount  money.Decimal
+}
+
+// file is the YAML representation of pricing
+type file struct {
+	Plans []struct {
+		Name  string `yaml:"name"`
+		Rates []struct {
+			PaymentMethod string         `yaml:"payment_method"`
+			Currency      string         `yaml:"currency"`
+			Percent       *money.Decimal `yaml:"percent"`
+			Fixed         *money.Decimal `yaml:"fixed"`
+			MinimumFee    money.Decimal  `yaml:"minimum_fee"`
+			MaximumFee    money.Decimal  `yaml:"maximum_fee"`
+			Tiers         []struct {
+				From    money.Decimal `yaml:"from"`
+				Percent money.Decimal `yaml:"percent"`
+				Fixed   money.Decimal `yaml:"fixed"`
+			} `yaml:"tiers"`
+		} `yaml:"rates"`
+	} `yaml:"plans"`
+	Taxes []struct {
+		Jurisdiction string        `yaml:"jurisdiction"`
+		Name         string        `yaml:"name"`
+		Percent      money.Decimal `yaml:"percent"`
+	} `yaml:"taxes"`
+}
+
+// defaultTaxes are the tax rules of pricing that has none: GST of 18% in India
+func defaultTaxes() map[string]TaxRule {
+	return map[string]TaxRule{
+		"IN": {Jurisdiction: "IN", Name: "GST", Percent: money.New(18, 0)},
+	}
+}
+
+// Parse parses pricing from YAML. Pricing without taxes has the default tax
+// rules of Default.
+func Parse(data []byte) (*Pricing, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing: %w", err)
+	}
+
+	var errs []error
+	p := &Pricing{plans: make(map[string]*Plan, len(f.Plans)), taxes: make(map[string]TaxRule, len(f.Taxes))}
+	for _, fp := range f.Plans {
+		if fp.Name == "" {
+			errs = append(errs, errors.New("plan without a name"))
+			continue
+		}
+		if _, ok := p.plans[fp.Name]; ok {
+			errs = append(errs, fmt.Errorf("plan %q: defined twice", fp.Name))
+			continue
+		}
+		if len(fp.Rates) == 0 {
+			errs = append(errs, fmt.Errorf("plan %q: no rates", fp.Name))
+			continue
+		}
+		plan := &Plan{Name: fp.Name}
+		for i, fr := range fp.Rates {
+			rate := Rate{
+				PaymentMethod: fr.PaymentMethod,
+				Currency:      fr.Currency,
+				MinimumFee:    fr.MinimumFee,
+				MaximumFee:    fr.MaximumFee,
+			}
+			if len(fr.Tiers) > 0 && (fr.Percent != nil || fr.Fixed != nil) {
+				errs = append(errs, fmt.Errorf("plan %q: rate %d: percent or fixed fee besides tiers", fp.Name, i+1))
+				continue
+			}
+			if len(fr.Tiers) == 0 {
+				tier := Tier{}
+				if fr.Percent != nil {
+					tier.Percent = *fr.Percent
+				}
+				if fr.Fixed != nil {
+					tier.Fixed = *fr.Fixed
+				}
+				rate.Tiers = []Tier{tier}
+			}
+			for _, ft := range fr.Tiers {
+				rate.Tiers = append(rate.Tiers, Tier{From: ft.From, Percent: ft.Percent, Fixed: ft.Fixed})
+			}
+			if err := rate.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("plan %q: rate %d: %w", fp.Name, i+1, err))
+				continue
+			}
+			plan.Rates = append(plan.Rates, rate)
+		}
+		p.plans[plan.Name] = plan
+	}
+
+	if f.Taxes == nil {
+		p.taxes = defaultTaxes()
+	}
+	for _, ft := range f.Taxes {
+		switch {
+		case ft.Jurisdiction == "":
+			errs = append(errs, fmt.Errorf("tax %q without a jurisdiction", ft.Name))
+		case ft.Percent.Sign() < 0 || ft.Percent.Cmp(money.New(100, 0)) > 0:
+			errs = append(errs, fmt.Errorf("tax of %s: percent %s is not between 0 and 100", ft.Jurisdiction, ft.Percent))
+		default:
+			if _, ok := p.taxes[ft.Jurisdiction]; ok {
+				errs = append(errs, fmt.Errorf("tax of %s: defined twice", ft.Jurisdiction))
+				continue
+			}
+			p.taxes[ft.Jurisdiction] = TaxRule{Jurisdiction: ft.Jurisdiction, Name: ft.Name, Percent: ft.Percent}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadFile loads pricing from a YAML file
+func LoadFile(path string) (*Pricing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Default returns the pricing used when none is configured, without plans and
+// with GST of 18% in India
+func Default() *Pricing {
+	return &Pricing{plans: map[string]*Plan{}, taxes: defaultTaxes()}
+}
+
+// Plan returns the plan of a name and whether there is one
+func (p *Pricing) Plan(name string) (*Plan, bool) {
+	plan, ok := p.plans[name]
+	return plan, ok
+}
+
+// Tax returns the tax rule of a jurisdiction and whether there is one
+func (p *Pricing) Tax(jurisdiction string) (TaxRule, bool) {
+	rule, ok := p.taxes[jurisdiction]
+	return rule, ok
+}
+
+// Flat returns a plan named flat charging percent of every payment
+func Flat(percent money.Decimal) *Plan {
+	return &Plan{
+		Name:  "flat",
+		Rates: []Rate{{Tiers: []Tier{{Percent: percent}}}},
+	}
+}
+
+// validate checks that a rate's amounts are consistent
+func (r Rate) validate() error {
+	hundred := money.New(100, 0)
+	if r.MinimumFee.Sign() < 0 || r.MaximumFee.Sign() < 0 {
+		return errors.New("negative minimum or maximum fee")
+	}
+	if !r.MaximumFee.IsZero() && r.MaximumFee.Cmp(r.MinimumFee) < 0 {
+		return fmt.Errorf("maximum fee %s is below minimum fee %s", r.MaximumFee, r.MinimumFee)
+	}
+	fixed := !r.MinimumFee.IsZero() || !r.MaximumFee.IsZero()
+	for i, tier := range r.Tiers {
+		if tier.Percent.Sign() < 0 || tier.Percent.Cmp(hundred) > 0 {
+			return fmt.Errorf("tier %d: percent %s is not between 0 and 100", i+1, tier.Percent)
+		}
+		if tier.Fixed.Sign() < 0 {
+			return fmt.Errorf("tier %d: negative fixed fee", i+1)
+		}
+		if i == 0 && !tier.From.IsZero() {
+			return errors.New("first tier does not start from zero")
+		}
+		if i > 0 && tier.From.Cmp(r.Tiers[i-1].From) <= 0 {
+			return fmt.Errorf("tier %d: volumes are not increasing", i+1)
+		}
+		fixed = fixed || !tier.Fixed.IsZero()
+	}
+	if fixed && r.Currency == "" {
+		return errors.New("fixed, minimum or maximum fees without a currency")
+	}
+	return nil
+}
+
+// rate returns the most specific rate of the plan for a payment method and
+// currency: one for both, then for the method, then for the currency, then
+// for any payment, the first listed of each
+func (p *Plan) rate(paymentMethod, currency string) (Rate, bool) {
+	best, bestScore := Rate{}, -1
+	for _, rate := range p.Rates {
+		if (rate.PaymentMethod != "" && rate.PaymentMethod != paymentMethod) ||
+			(rate.Currency != "" && rate.Currency != currency) {
+			continue
+		}
+		score := 0
+		if rate.PaymentMethod != "" {
+			score += 2
+		}
+		if rate.Currency != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rate, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// Fee returns the fee of a payment of amount in a currency by a payment
+// method, for a merchant whose volume in the month before the payment is
+// volume. The fee is rounded to the currency's minor unit with mode, then
+// bounded by the rate's minimum and maximum fees, and by the amount itself.
+func (p *Plan) Fee(paymentMethod, currency string, amount, volume money.Decimal, mode money.RoundingMode) (Fee, error) {
+	rate, ok := p.rate(paymentMethod, currency)
+	if !ok {
+		return Fee{}, fmt.Errorf("%w: plan %q, %s in %s", ErrNoRate, p.Name, paymentMethod, currency)
+	}
+	tier := rate.Tiers[0]
+	for _, t := range rate.Tiers[1:] {
+		if volume.Cmp(t.From) < 0 {
+			break
+		}
+		tier = t
+	}
+
+	fee := amount.Percent(tier.Percent).Add(tier.Fixed).RoundTo(currency, mode)
+	if fee.Cmp(rate.MinimumFee) < 0 {
+		fee = rate.MinimumFee.RoundTo(currency, mode)
+	}
+	if !rate.MaximumFee.IsZero() && fee.Cmp(rate.MaximumFee) > 0 {
+		fee = rate.MaximumFee.RoundTo(currency, mode)
+	}
+	if fee.Cmp(amount) > 0 {
+		fee = amount.RoundTo(currency, money.RoundDown)
+	}
+	return Fee{Plan: p.Name, Percent: tier.Percent, Fixed: tier.Fixed, Amount: fee}, nil
+}
+
+// Tax returns the tax on a fee in a currency, rounded to the currency's minor
+// unit with mode
+func (r TaxRule) Tax(fee money.Decimal, currency string, mode money.RoundingMode) money.Decimal {
+	return fee.Percent(r.Percent).RoundTo(currency, mode)
+}
@@ -0,0 +1,249 @@
+package pricing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// standard is the pricing of the package documentation
+const standard = `
+plans:
+  - name: standard
+    rates:
+      - payment_method: CREDIT_CARD
+        currency: USD
+        minimum_fee: 0.50
+        maximum_fee: 50
+        tiers:
+          - percent: 2.9
+            fixed: 0.30
+          - from: 100000
+            percent: 2.5
+            fixed: 0.30
+      - percent: 2
+taxes:
+  - jurisdiction: IN
+    name: GST
+    percent: 18
+  - jurisdiction: GB
+    name: VAT
+    percent: 20
+`
+
+// mustParse parses pricing or fails the test
+func mustParse(t *testing.T, data string) *Pricing {
+	t.Helper()
+	p, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return p
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		rate string
+		want string
+	}{
+		{
+			name: "percent besides tiers",
+			rate: "{percent: 2, tiers: [{percent: 2.9}]}",
+			want: "percent or fixed fee besides tiers",
+		},
+		{
+			name: "fixed besides tiers",
+			rate: "{currency: USD, fixed: 0.30, tiers: [{percent: 2.9}]}",
+			want: "percent or fixed fee besides tiers",
+		},
+		{
+			name: "zero percent besides tiers",
+			rate: "{percent: 0, tiers: [{percent: 2.9}]}",
+			want: "percent or fixed fee besides tiers",
+		},
+		{
+			name: "decreasing tiers",
+			rate: "{tiers: [{percent: 3}, {from: 5000, percent: 2}, {from: 1000, percent: 1}]}",
+			want: "tier 3: volumes are not increasing",
+		},
+		{
+			name: "repeated tier",
+			rate: "{tiers: [{percent: 3}, {from: 1000, percent: 2}, {from: 1000, percent: 1}]}",
+			want: "tier 3: volumes are not increasing",
+		},
+		{
+			name: "first tier above zero",
+			rate: "{tiers: [{from: 10, percent: 3}]}",
+			want: "first tier does not start from zero",
+		},
+		{
+			name: "percent above 100",
+			rate: "{percent: 101}",
+			want: "not between 0 and 100",
+		},
+		{
+			name: "fixed fee without currency",
+			rate: "{fixed: 0.30}",
+			want: "without a currency",
+		},
+		{
+			name: "maximum below minimum",
+			rate: "{currency: USD, minimum_fee: 5, maximum_fee: 1}",
+			want: "maximum fee 1 is below minimum fee 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte("plans: [{name: p, rates: [" + tt.rate + "]}]"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTaxes(t *testing.T) {
+	p := mustParse(t, standard)
+	if rule, ok := p.Tax("GB"); !ok || rule.Percent.Cmp(money.New(20, 0)) != 0 {
+		t.Errorf("Tax(GB) = %v, %t, want VAT of 20", rule, ok)
+	}
+	if _, ok := mustParse(t, "plans: []").Tax("IN"); !ok {
+		t.Error("pricing without taxes has no default tax for IN")
+	}
+	if _, err := Parse([]byte("taxes: [{jurisdiction: IN, percent: 18}, {jurisdiction: IN, percent: 5}]")); err == nil {
+		t.Error("Parse() accepted a jurisdiction taxed twice")
+	}
+}
+
+func TestFee(t *testing.T) {
+	plan, _ := mustParse(t, standard).Plan("standard")
+	tests := []struct {
+		name    string
+		method  string
+		amount  string
+		volume  string
+		want    string
+		percent string
+	}{
+		{"first tier", "CREDIT_CARD", "100.00", "0", "3.20", "2.9"},
+		{"below second tier", "CREDIT_CARD", "100.00", "99999.99", "3.20", "2.9"},
+		{"second tier from its volume", "CREDIT_CARD", "100.00", "100000", "2.80", "2.5"},
+		{"beyond second tier", "CREDIT_CARD", "100.00", "250000", "2.80", "2.5"},
+		{"raised to minimum", "CREDIT_CARD", "5.00", "0", "0.50", "2.9"},
+		{"lowered to maximum", "CREDIT_CARD", "5000.00", "0", "50.00", "2.9"},
+		{"minimum above amount", "CREDIT_CARD", "0.20", "0", "0.20", "2.9"},
+		{"other method", "UPI", "1000.00", "0", "20.00", "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee, err := plan.Fee(tt.method, "USD", money.MustParse(tt.amount), money.MustParse(tt.volume), money.RoundHalfEven)
+			if err != nil {
+				t.Fatalf("Fee() error = %v", err)
+			}
+			if got := fee.Amount.String(); got != tt.want {
+				t.Errorf("Fee(%s) = %s, want %s", tt.amount, got, tt.want)
+			}
+			if fee.Percent.Cmp(money.MustParse(tt.percent)) != 0 {
+				t.Errorf("Fee(%s) percent = %s, want %s", tt.amount, fee.Percent, tt.percent)
+			}
+		})
+	}
+}
+
+func TestFeeRounding(t *testing.T) {
+	plan := Flat(money.New(2, 0))
+	tests := []struct {
+		amount   string
+		currency string
+		mode     money.RoundingMode
+		want     string
+	}{
+		{"10.25", "USD", money.RoundHalfEven, "0.20"},
+		{"10.25", "USD", money.RoundHalfUp, "0.21"},
+		{"10.25", "USD", money.RoundDown, "0.20"},
+		{"10.26", "USD", money.RoundUp, "0.21"},
+		{"1025", "JPY", money.RoundHalfEven, "20"},
+		{"1025", "JPY", money.RoundHalfUp, "21"},
+		{"1.025", "KWD", money.RoundHalfEven, "0.020"},
+		{"1.025", "KWD", money.RoundCeiling, "0.021"},
+	}
+	for _, tt := range tests {
+		fee, err := plan.Fee("CREDIT_CARD", tt.currency, money.MustParse(tt.amount), money.Zero, tt.mode)
+		if err != nil {
+			t.Fatalf("Fee() error = %v", err)
+		}
+		if got := fee.Amount.String(); got != tt.want {
+			t.Errorf("Fee(%s %s, %s) = %s, want %s", tt.amount, tt.currency, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestFeeRateSelection(t *testing.T) {
+	p := mustParse(t, `
+plans:
+  - name: specific
+    rates:
+      - percent: 4
+      - currency: USD
+        percent: 3
+      - payment_method: UPI
+        percent: 2
+      - payment_method: UPI
+        currency: INR
+        percent: 1
+  - name: cards
+    rates:
+      - payment_method: CREDIT_CARD
+        percent: 3
+`)
+	specific, _ := p.Plan("specific")
+	tests := []struct {
+		method   string
+		currency string
+		want     string
+	}{
+		{"UPI", "INR", "1.00"},
+		{"UPI", "USD", "2.00"},
+		{"CREDIT_CARD", "USD", "3.00"},
+		{"CREDIT_CARD", "EUR", "4.00"},
+	}
+	for _, tt := range tests {
+		fee, err := specific.Fee(tt.method, tt.currency, money.New(100, 0), money.Zero, money.RoundHalfEven)
+		if err != nil {
+			t.Fatalf("Fee() error = %v", err)
+		}
+		if got := fee.Amount.String(); got != tt.want {
+			t.Errorf("Fee(%s %s) = %s, want %s", tt.method, tt.currency, got, tt.want)
+		}
+	}
+
+	cards, _ := p.Plan("cards")
+	if _, err := cards.Fee("UPI", "INR", money.New(100, 0), money.Zero, money.RoundHalfEven); !errors.Is(err, ErrNoRate) {
+		t.Errorf("Fee() error = %v, want ErrNoRate", err)
+	}
+}
+
+func TestTax(t *testing.T) {
+	gst := TaxRule{Jurisdiction: "IN", Name: "GST", Percent: money.New(18, 0)}
+	tests := []struct {
+		fee      string
+		currency string
+		mode     money.RoundingMode
+		want     string
+	}{
+		{"3.20", "INR", money.RoundHalfEven, "0.58"},
+		{"0.25", "INR", money.RoundHalfEven, "0.04"},
+		{"0.25", "INR", money.RoundHalfUp, "0.05"},
+		{"25", "JPY", money.RoundHalfEven, "4"},
+		{"0.250", "KWD", money.RoundHalfEven, "0.045"},
+		{"0", "INR", money.RoundHalfEven, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := gst.Tax(money.MustParse(tt.fee), tt.currency, tt.mode).String(); got != tt.want {
+			t.Errorf("Tax(%s %s, %s) = %s, want %s", tt.fee, tt.currency, tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// settleBalance settles what a settlement leaves after its credits, debits,
+// the balance brought forward and its reserve movements. A negative payout is
+// carried forward to the merchant's next settlement in the currency, negative
+// since the cut-off it first went negative at, unless it has been negative for
+// the merchant's negative balance days and it is recovered from the merchant
+// with its recovery method instead. Either way nothing is paid out.
+func settleBalance(settlement *models.Settlement, config models.MerchantSettlementConfig) {
+	if settlement.PayoutAmount.Sign() >= 0 {
+		settlement.NegativeSince = time.Time{}
+		return
+	}
+	if settlement.NegativeSince.IsZero() {
+		settlement.NegativeSince = settlement.CutoffAt
+	}
+
+	balance := settlement.PayoutAmount
+	settlement.PayoutAmount = money.Zero
+	recoverable := config.NegativeBalanceRecovery != models.RecoveryMethodNone &&
+		!settlement.CutoffAt.Before(settlement.NegativeSince.AddDate(0, 0, config.NegativeBalanceDays))
+	if recoverable {
+		settlement.RecoveryMethod = config.NegativeBalanceRecovery
+		settlement.RecoveryAmount = balance.Neg()
+		settlement.NegativeSince = time.Time{}
+		return
+	}
+	settlement.BalanceCarriedForward = balance
+}
+
+// settlementCurrencies returns the currencies a merchant is settled in at a
+// cut-off: those of its payments, then those of its debits, its negative
+// balances carried forward and its reserves due for release
+func settlementCurrencies(
+	summaries []models.PaymentSummary,
+	debits []models.MerchantDebit,
+	balances []models.CarriedBalance,
+	holds []models.ReserveHold,
+	cutoff time.Time,
+) []string {
+	var currencies []string
+	seen := make(map[string]bool)
+	add := func(currency string) {
+		if !seen[currency] {
+			seen[currency] = true
+			currencies = append(currencies, currency)
+		}
+	}
+	for _, summary := range summaries {
+		add(summary.Currency)
+	}
+	for _, debit := range debits {
+		add(debit.Currency)
+	}
+	for _, balance := range balances {
+		add(balance.Currency)
+	}
+	for _, currency := range releasableCurrencies(holds, cutoff) {
+		add(currency)
+	}
+	return currencies
+}
+
+// carriedBalance returns the negative balance carried forward in a currency,
+// if any
+func carriedBalance(balances []models.CarriedBalance, currency string) (models.CarriedBalance, bool) {
+	for _, balance := range balances {
+		if balance.Currency == currency {
+			return balance, true
+		}
+	}
+	return models.CarriedBalance{}, false
+}
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/pricing"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// feeSchedule prices a merchant's payments in one currency. volume is the
+// merchant's volume in the month before the next payment priced, so each
+// payment is priced at the tier the volume before it reached.
+type feeSchedule struct {
+	plan   *pricing.Plan
+	tax    pricing.TaxRule
+	volume money.Decimal
+}
+
+// feeSchedule returns the fee schedule of a merchant's payments in a currency
+// settled up to a cut-off. The monthly volume is the merchant's settled volume
+// in the currency since the start of the cut-off's month, in the cut-off's
+// time zone.
+func (p *SettlementProcessor) feeSchedule(config models.MerchantSettlementConfig, currency string, cutoff time.Time) (feeSchedule, error) {
+	plan := pricing.Flat(config.FeePercent)
+	if config.PricingPlan != "" {
+		var ok bool
+		if plan, ok = p.pricing.Plan(config.PricingPlan); !ok {
+			return feeSchedule{}, fmt.Errorf("unknown pricing plan %q", config.PricingPlan)
+		}
+	}
+	tax, ok := p.pricing.Tax(config.TaxJurisdiction)
+	if !ok {
+		return feeSchedule{}, fmt.Errorf("no tax rule for jurisdiction %q", config.TaxJurisdiction)
+	}
+
+	monthStart := time.Date(cutoff.Year(), cutoff.Month(), 1, 0, 0, 0, 0, cutoff.Location())
+	volume, err := p.repository.GetSettledVolume(config.MerchantID, currency, monthStart, cutoff)
+	if err != nil {
+		return feeSchedule{}, err
+	}
+	return feeSchedule{plan: plan, tax: tax, volume: volume}, nil
+}
+
+// price charges the fee and the tax on the fee on a settlement item, and adds
+// its amount to the monthly volume. The fee and tax are rounded to the
+// currency's minor unit with the processor's rounding mode, and the net amount
+// is what remains of the amount, so fee, tax and net always add up to it
+// exactly. Where the tax would take the net amount below zero, the fee is
+// lowered by the shortfall, so the merchant is never charged more than the
+// payment.
+func (p *SettlementProcessor) price(item *models.SettlementItem, fees *feeSchedule) error {
+	fee, err := fees.plan.Fee(item.PaymentMethod, item.Currency, item.Amount, fees.volume, p.roundingMode)
+	if err != nil {
+		return err
+	}
+	item.PricingPlan = fee.Plan
+	item.FeePercent = fee.Percent
+	item.FixedFee = fee.Fixed
+	item.FeeAmount = fee.Amount
+	item.TaxJurisdiction = fees.tax.Jurisdiction
+	item.TaxPercent = fees.tax.Percent
+	item.TaxAmount = fees.tax.Tax(fee.Amount, item.Currency, p.roundingMode)
+	item.NetAmount = item.Amount.Sub(item.FeeAmount).Sub(item.TaxAmount)
+	if item.NetAmount.Sign() < 0 {
+		// The tax on the lower fee is at most that on the fee, so the net
+		// amount is no longer negative
+		item.FeeAmount = item.FeeAmount.Add(item.NetAmount)
+		item.TaxAmount = fees.tax.Tax(item.FeeAmount, item.Currency, p.roundingMode)
+		item.NetAmount = item.Amount.Sub(item.FeeAmount).Sub(item.TaxAmount)
+	}
+	fees.volume = fees.volume.Add(item.Amount)
+	return nil
+}
@@ -0,0 +1,297 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/payout"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/repository"
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+)
+
+// PayDueSettlements pays out the pending settlements whose payout date is at
+// or before now, and retries the failed payouts of settlements that failed at
+// least the retry interval ago and have attempts left. The payouts are
+// submitted to the payout provider in one batch; the settlements stay
+// PROCESSING until ProcessPayoutResponses reads what became of them. The
+// settlements paid out are returned even when others failed.
+func (p *SettlementProcessor) PayDueSettlements(now time.Time) ([]models.Settlement, error) {
+	settlements, err := p.repository.GetPayableSettlements(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payable settlements: %w", err)
+	}
+	retryable, err := p.repository.GetRetryableSettlements(now.Add(-p.payoutRetryInterval), p.maxPayoutAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retryable settlements: %w", err)
+	}
+	settlements = append(settlements, retryable...)
+
+	return p.paySettlements(settlements, now)
+}
+
+// errPayoutHeld is returned for a payout held until the merchant's bank
+// account can be paid to
+var errPayoutHeld = errors.New("payout held")
+
+// paySettlements submits the payouts of settlements in one batch, returning
+// the settlements paid out. Settlements with nothing to pay out complete at
+// once, recovering any negative balance from the merchant. Payouts to
+// merchants without a verified bank account, or whose account was replaced
+// within the cooldown, are held until a later run.
+func (p *SettlementProcessor) paySettlements(settlements []models.Settlement, now time.Time) ([]models.Settlement, error) {
+	batch := payout.Batch{
+		ID:        fmt.Sprintf("PAYOUT_%s_%s", now.UTC().Format("20060102T150405"), uuid.New().String()[:8]),
+		CreatedAt: now,
+	}
+
+	var (
+		paid      []models.Settlement
+		submitted []models.Settlement
+		errs      []error
+	)
+	for _, settlement := range settlements {
+		if settlement.PayoutAmount.Sign() <= 0 {
+			if err := p.completeSettlement(settlement, now); err != nil {
+				errs = append(errs, fmt.Errorf("settlement %s: %w", settlement.ID, err))
+				continue
+			}
+			paid = append(paid, settlement)
+			continue
+		}
+
+		instruction, err := p.startPayout(settlement, batch.ID, now)
+		if errors.Is(err, errPayoutHeld) {
+			log.Printf("Holding payout of settlement %s: %v", settlement.ID, err)
+			continue
+		}
+		if errors.Is(err, repository.ErrSettlementNotPayable) {
+			log.Printf("Settlement %s is already being paid out, skipping", settlement.ID)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("settlement %s: %w", settlement.ID, err))
+			continue
+		}
+		batch.Payouts = append(batch.Payouts, instruction)
+		submitted = append(submitted, settlement)
+	}
+	if len(batch.Payouts) == 0 {
+		return paid, errors.Join(errs...)
+	}
+
+	if err := p.payouts.Submit(batch); err != nil {
+		// Nothing reached the bank; fail the payouts so they are retried
+		reason := fmt.Sprintf("submission failed: %v", err)
+		for _, instruction := range batch.Payouts {
+			if err := p.repository.FailPayout(instruction.Reference, reason, now); err != nil {
+				errs = append(errs, fmt.Errorf("payout %s: %w", instruction.Reference, err))
+			}
+		}
+		errs = append(errs, fmt.Errorf("failed to submit payout batch %s: %w", batch.ID, err))
+		return paid, errors.Join(errs...)
+	}
+
+	log.Printf("Submitted payout batch %s with %d payouts", batch.ID, len(batch.Payouts))
+	return append(paid, submitted...), errors.Join(errs...)
+}
+
+// FailUnsubmittedPayouts fails the payouts recorded for a batch that did not
+// reach the payout provider within the submit timeout, such as when the
+// process stopped between recording the payouts and submitting them, so their
+// settlements are retried. Payouts of batches that were submitted keep
+// awaiting the bank's response. It returns the payouts failed.
+func (p *SettlementProcessor) FailUnsubmittedPayouts(now time.Time) ([]models.SettlementPayout, error) {
+	payouts, err := p.repository.GetSubmittedPayouts(now.Add(-p.payoutSubmitTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitted payouts: %w", err)
+	}
+
+	var (
+		failed []models.SettlementPayout
+		errs   []error
+	)
+	batches := make(map[string]bool)
+	for _, record := range payouts {
+		submitted, ok := batches[record.BatchID]
+		if !ok {
+			if submitted, err = p.payouts.Submitted(record.BatchID); err != nil {
+				errs = append(errs, fmt.Errorf("payout batch %s: %w", record.BatchID, err))
+				continue
+			}
+			batches[record.BatchID] = submitted
+		}
+		if submitted {
+			continue
+		}
+
+		reason := fmt.Sprintf("batch %s was not submitted", record.BatchID)
+		err := p.repository.FailPayout(record.Reference, reason, now)
+		if errors.Is(err, repository.ErrPayoutNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("payout %s: %w", record.Reference, err))
+			continue
+		}
+		log.Printf("Payout %s failed: %s", record.Reference, reason)
+		failed = append(failed, record)
+	}
+	return failed, errors.Join(errs...)
+}
+
+// startPayout records the next payout attempt of a settlement to the
+// merchant's payout account, moving it to PROCESSING, and returns the
+// instruction to pay it. A retried payout is paid on the day it is submitted.
+func (p *SettlementProcessor) startPayout(settlement models.Settlement, batchID string, now time.Time) (payout.Payout, error) {
+	account, err := p.payoutAccount(settlement.MerchantID, now)
+	if err != nil {
+		return payout.Payout{}, err
+	}
+	number, err := p.accounts.Decrypt(account.AccountNumberEncrypted)
+	if err != nil {
+		return payout.Payout{}, fmt.Errorf("bank account %s: %w", account.ID, err)
+	}
+
+	attempt := settlement.PayoutAttempts + 1
+	record := models.SettlementPayout{
+		ID:            uuid.New(),
+		SettlementID:  settlement.ID,
+		MerchantID:    settlement.MerchantID,
+		Reference:     fmt.Sprintf("%s-%d", settlement.Reference, attempt),
+		BatchID:       batchID,
+		Attempt:       attempt,
+		Amount:        settlement.PayoutAmount,
+		Currency:      settlement.Currency,
+		BankAccountID: account.ID.String(),
+		Status:        models.PayoutStatusSubmitted,
+		SubmittedAt:   now,
+	}
+	if err := p.repository.StartPayout(record); err != nil {
+		return payout.Payout{}, err
+	}
+
+	executionDate := settlement.PayoutDate
+	if executionDate.Before(now) {
+		executionDate = now
+	}
+	log.Printf("Paying out %s %s to bank account %s (%s) for merchant %s, reference %s (attempt %d)",
+		record.Amount, record.Currency, record.BankAccountID, bankaccount.Mask(number), record.MerchantID, record.Reference, attempt)
+	return payout.Payout{
+		Reference:     record.Reference,
+		SettlementID:  settlement.ID,
+		MerchantID:    settlement.MerchantID,
+		BankAccountID: record.BankAccountID,
+		Beneficiary: bankaccount.Account{
+			HolderName: account.HolderName,
+			Scheme:     account.Scheme,
+			Number:     number,
+			IFSC:       account.IFSC,
+			BIC:        account.BIC,
+		},
+		Amount:        record.Amount,
+		Currency:      record.Currency,
+		ExecutionDate: executionDate,
+		Narration:     fmt.Sprintf("Settlement %s", settlement.Reference),
+	}, nil
+}
+
+// payoutAccount returns the bank account to pay a merchant out to at now, or
+// errPayoutHeld if the merchant has no verified account or its account
+// replaced another less than the cooldown ago
+func (p *SettlementProcessor) payoutAccount(merchantID uuid.UUID, now time.Time) (models.BankAccount, error) {
+	account, err := p.repository.GetPayoutAccount(merchantID)
+	if errors.Is(err, repository.ErrBankAccountNotFound) {
+		return models.BankAccount{}, fmt.Errorf("%w: merchant %s has no verified bank account", errPayoutHeld, merchantID)
+	}
+	if err != nil {
+		return models.BankAccount{}, fmt.Errorf("failed to get payout account: %w", err)
+	}
+	if until := account.VerifiedAt.Add(p.accountCooldown); account.ReplacesAccountID != uuid.Nil && now.Before(until) {
+		return models.BankAccount{}, fmt.Errorf("%w: bank account %s of merchant %s changed, cooldown until %s",
+			errPayoutHeld, account.ID, merchantID, until.Format(time.RFC3339))
+	}
+	return account, nil
+}
+
+// completeSettlement completes a settlement with nothing to pay out,
+// recovering a persistent negative balance from the merchant. A direct debit
+// is taken from the merchant's payout account; without one the merchant is
+// invoiced instead.
+func (p *SettlementProcessor) completeSettlement(settlement models.Settlement, now time.Time) error {
+	recovery := settlement.RecoveryMethod
+	var account models.BankAccount
+	if recovery == models.RecoveryMethodDirectDebit {
+		var err error
+		account, err = p.payoutAccount(settlement.MerchantID, now)
+		if errors.Is(err, errPayoutHeld) {
+			log.Printf("Cannot direct debit settlement %s, invoicing instead: %v", settlement.ID, err)
+			recovery = models.RecoveryMethodInvoice
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Simulate recovering the balance; the settlement reference identifies
+	// the direct debit or invoice
+	switch recovery {
+	case models.RecoveryMethodDirectDebit:
+		log.Printf("Direct debiting %s %s from bank account %s of merchant %s, reference %s",
+			settlement.RecoveryAmount, settlement.Currency, account.ID, settlement.MerchantID, settlement.Reference)
+	case models.RecoveryMethodInvoice:
+		log.Printf("Invoicing merchant %s for %s %s, reference %s",
+			settlement.MerchantID, settlement.RecoveryAmount, settlement.Currency, settlement.Reference)
+	}
+
+	if err := p.repository.UpdateSettlementStatus(settlement.ID, models.SettlementStatusCompleted); err != nil {
+		return fmt.Errorf("failed to update settlement status to completed: %w", err)
+	}
+	log.Printf("Settlement %s completed with nothing to pay out", settlement.ID)
+	return nil
+}
+
+// ProcessPayoutResponses records the outcomes the payout provider reported:
+// the settlement of a payout paid completes, and that of a payout failed or
+// returned fails, to be retried by PayDueSettlements. Results for unknown or
+// already finished payouts are skipped. A response is marked handled once all
+// its results are recorded, so one that fails part way is read again.
+func (p *SettlementProcessor) ProcessPayoutResponses(now time.Time) ([]payout.Result, error) {
+	responses, err := p.payouts.Responses()
+	if err != nil {
+		// Handle the responses that could be read anyway
+		log.Printf("Error reading payout responses: %v", err)
+	}
+
+	var recorded []payout.Result
+	for _, response := range responses {
+		for _, result := range response.Results {
+			var err error
+			switch result.Status {
+			case payout.StatusPaid:
+				err = p.repository.CompletePayout(result.Reference, now)
+			case payout.StatusFailed:
+				err = p.repository.FailPayout(result.Reference, result.Reason, now)
+			default:
+				err = fmt.Errorf("unknown payout status %q", result.Status)
+			}
+			if errors.Is(err, repository.ErrPayoutNotFound) {
+				log.Printf("Payout %s in response %s is unknown or already finished, skipping %s", result.Reference, response.Name, result.Status)
+				continue
+			}
+			if err != nil {
+				return recorded, fmt.Errorf("payout response %s: payout %s: %w", response.Name, result.Reference, err)
+			}
+			if result.Status == payout.StatusFailed {
+				log.Printf("Payout %s failed: %s", result.Reference, result.Reason)
+			}
+			recorded = append(recorded, result)
+		}
+		if err := p.payouts.Handled(response); err != nil {
+			return recorded, fmt.Errorf("payout response %s: %w", response.Name, err)
+		}
+	}
+	return recorded, nil
+}
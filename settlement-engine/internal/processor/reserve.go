@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/money"
+)
+
+// reserveMovements returns the reserve ledger movements of a settlement. The
+// settlement holds the merchant's reserve percentage of what is available to
+// pay out after its debits and the balance brought forward, and more if that
+// leaves the reserve below its minimum, until its hold days have passed since
+// the cut-off. It then releases the merchant's holds in its currency that are
+// due by the cut-off, oldest first, as far as the reserve stays at its
+// minimum. A settlement never holds more than is available, and holds nothing
+// when its balance is negative.
+func (p *SettlementProcessor) reserveMovements(settlement models.Settlement, holds []models.ReserveHold, config models.MerchantSettlementConfig) []models.ReserveMovement {
+	currency := settlement.Currency
+	minimum := config.MinimumReserveAmount.RoundTo(currency, money.RoundUp)
+	balance := money.Zero
+	for _, hold := range holds {
+		if hold.Currency == currency {
+			balance = balance.Add(hold.Outstanding)
+		}
+	}
+
+	// Hold a share of what is available, topped up to the minimum reserve
+	available := settlement.PayoutAmount
+	held := money.Zero
+	if available.Sign() > 0 {
+		held = available.Percent(config.ReservePercent).RoundTo(currency, p.roundingMode)
+		if shortfall := minimum.Sub(balance.Add(held)); shortfall.Sign() > 0 {
+			held = held.Add(shortfall)
+		}
+		if held.Cmp(available) > 0 {
+			held = available
+		}
+	}
+
+	var movements []models.ReserveMovement
+	if held.Sign() > 0 {
+		movements = append(movements, models.ReserveMovement{
+			ID:           uuid.New(),
+			MerchantID:   settlement.MerchantID,
+			SettlementID: settlement.ID,
+			Currency:     currency,
+			Type:         models.ReserveMovementHold,
+			Amount:       held,
+			ReleaseAt:    settlement.CutoffAt.AddDate(0, 0, config.ReserveHoldDays),
+			CreatedAt:    settlement.CreatedAt,
+		})
+		balance = balance.Add(held)
+	}
+
+	// Release the holds that are due, keeping the minimum reserve
+	for _, hold := range holds {
+		room := balance.Sub(minimum)
+		if room.Sign() <= 0 {
+			break
+		}
+		if hold.Currency != currency || hold.ReleaseAt.After(settlement.CutoffAt) {
+			continue
+		}
+		amount := hold.Outstanding
+		if amount.Cmp(room) > 0 {
+			amount = room
+		}
+		movements = append(movements, models.ReserveMovement{
+			ID:           uuid.New(),
+			MerchantID:   settlement.MerchantID,
+			SettlementID: settlement.ID,
+			Currency:     currency,
+			Type:         models.ReserveMovementRelease,
+			Amount:       amount,
+			HoldID:       hold.ID,
+			CreatedAt:    settlement.CreatedAt,
+		})
+		balance = balance.Sub(amount)
+	}
+
+	return movements
+}
+
+// releasableCurrencies returns the currencies of the holds due for release by
+// a cut-off, in the order of the holds
+func releasableCurrencies(holds []models.ReserveHold, cutoff time.Time) []string {
+	var currencies []string
+	seen := make(map[string]bool)
+	for _, hold := range holds {
+		if hold.ReleaseAt.After(cutoff) || seen[hold.Currency] {
+			continue
+		}
+		seen[hold.Currency] = true
+		currencies = append(currencies, hold.Currency)
+	}
+	return currencies
+}
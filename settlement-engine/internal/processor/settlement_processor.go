@@ -1,195 +1,383 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"math"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/calendar"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/payout"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/pricing"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/repository"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/schedule"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/money"
 )
 
 // SettlementProcessor processes payments and creates settlements
 type SettlementProcessor struct {
-	repository              repository.Repository
-	defaultFeePercent       float64
-	minimumSettlementAmount float64
+	repository      repository.Repository
+	defaults        models.MerchantSettlementConfig
+	calendar        *calendar.Calendar
+	pricing         *pricing.Pricing
+	roundingMode    money.RoundingMode
+	producer        events.Producer
+	settlementTopic string
+
+	// payouts pays settlements out; a failed payout is retried after
+	// payoutRetryInterval, up to maxPayoutAttempts payouts per settlement. A
+	// payout whose batch did not reach the provider within payoutSubmitTimeout
+	// fails.
+	payouts             payout.Provider
+	maxPayoutAttempts   int
+	payoutRetryInterval time.Duration
+	payoutSubmitTimeout time.Duration
+
+	// accounts decrypts the account numbers of merchants' bank accounts;
+	// payouts to an account that replaced another wait accountCooldown after
+	// it was verified
+	accounts        *bankaccount.Cipher
+	accountCooldown time.Duration
+
+	// settledThrough is the latest cut-off each merchant was settled up to by
+	// this processor, so merchants are not looked at again until their next one
+	mu             sync.Mutex
+	settledThrough map[uuid.UUID]time.Time
 }
 
-// NewSettlementProcessor creates a new settlement processor
+// NewSettlementProcessor creates a new settlement processor. defaults is the
+// settlement configuration of merchants without their own, and fills in what
+// theirs leaves unset. Cut-offs on weekends and holidays of the calendar move
+// to the next business day. Merchants' fees are charged by the pricing plans
+// of prices and taxed by its tax rules, rounded to the minor unit of the
+// settlement currency with roundingMode. Settlements are paid out through
+// payouts, and a failed payout is retried retryInterval after it failed, until
+// a settlement was paid out maxAttempts times. Payouts recorded for a batch
+// payouts had not received submitTimeout later fail, to be retried. Payouts go to merchants'
+// verified bank accounts, whose account numbers accounts decrypts, and are
+// held for accountCooldown after a merchant's account was replaced.
 func NewSettlementProcessor(
 	repository repository.Repository,
-	defaultFeePercent float64,
-	minimumSettlementAmount float64,
+	defaults models.MerchantSettlementConfig,
+	cal *calendar.Calendar,
+	prices *pricing.Pricing,
+	roundingMode money.RoundingMode,
+	producer events.Producer,
+	settlementTopic string,
+	payouts payout.Provider,
+	maxAttempts int,
+	retryInterval time.Duration,
+	submitTimeout time.Duration,
+	accounts *bankaccount.Cipher,
+	accountCooldown time.Duration,
 ) *SettlementProcessor {
 	return &SettlementProcessor{
-		repository:              repository,
-		defaultFeePercent:       defaultFeePercent,
-		minimumSettlementAmount: minimumSettlementAmount,
+		repository:          repository,
+		defaults:            defaults,
+		calendar:            cal,
+		pricing:             prices,
+		roundingMode:        roundingMode,
+		producer:            producer,
+		settlementTopic:     settlementTopic,
+		payouts:             payouts,
+		maxPayoutAttempts:   maxAttempts,
+		payoutRetryInterval: retryInterval,
+		payoutSubmitTimeout: submitTimeout,
+		accounts:            accounts,
+		accountCooldown:     accountCooldown,
+		settledThrough:      make(map[uuid.UUID]time.Time),
 	}
 }
 
-// ProcessPayment processes a payment event and marks it for settlement if eligible
-func (p *SettlementProcessor) ProcessPayment(event models.PaymentEvent) error {
-	log.Printf("Processing payment event: %s, type: %s", event.ID, event.Type)
+// ProcessPayment marks a captured payment for settlement if eligible
+func (p *SettlementProcessor) ProcessPayment(payment models.Payment) error {
+	log.Printf("Processing payment: %s", payment.ID)
 
 	// Check if the payment is captured (eligible for settlement)
-	if event.Payment.Status != models.PaymentStatusCaptured {
-		log.Printf("Payment %s is not captured, status: %s, skipping", event.Payment.ID, event.Payment.Status)
+	if payment.Status != models.PaymentStatusCaptured {
+		log.Printf("Payment %s is not captured, status: %s, skipping", payment.ID, payment.Status)
 		return nil
 	}
 
 	// Mark the payment for settlement
-	if err := p.repository.MarkPaymentForSettlement(event.Payment.ID); err != nil {
+	if err := p.repository.MarkPaymentForSettlement(payment.ID); err != nil {
 		return fmt.Errorf("failed to mark payment for settlement: %w", err)
 	}
 
-	log.Printf("Payment %s marked for settlement", event.Payment.ID)
+	log.Printf("Payment %s marked for settlement", payment.ID)
 	return nil
 }
 
-// CreateSettlementBatch creates settlements for eligible payments
-func (p *SettlementProcessor) CreateSettlementBatch(startDate, endDate time.Time) ([]models.Settlement, error) {
-	log.Printf("Creating settlement batch for period: %s to %s", 
-		startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
-
-	// Get eligible payments for settlement
-	payments, err := p.repository.GetEligiblePayments(startDate, endDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get eligible payments: %w", err)
+// ProcessDebit records a refund or chargeback of a payment, deducted from the
+// merchant's next settlement in its currency
+func (p *SettlementProcessor) ProcessDebit(debit models.MerchantDebit) error {
+	if err := p.repository.RecordDebit(debit); err != nil {
+		return fmt.Errorf("failed to record merchant debit: %w", err)
 	}
 
-	log.Printf("Found %d eligible payment groups for settlement", len(payments))
-	if len(payments) == 0 {
-		return []models.Settlement{}, nil
+	log.Printf("Recorded %s debit of %s %s for payment %s of merchant %s",
+		debit.Type, debit.Amount, debit.Currency, debit.PaymentID, debit.MerchantID)
+	return nil
+}
+
+// CreateDueSettlements settles every merchant whose settlement cycle passed a
+// cut-off it was not settled up to yet. Each merchant gets one settlement per
+// currency of its payments created up to its latest cut-off; fees and taxes
+// are charged on each payment and recorded on the settlement item linking it
+// to its settlement. Payments below the merchant's minimum settlement amount
+// are left for a later cut-off, but the refunds and chargebacks that occurred
+// up to the cut-off are always deducted, along with the negative balance
+// carried forward from the merchant's previous settlement. Settlements hold
+// part of what is left in the merchant's rolling reserve and release reserves
+// held earlier that are due; they are paid out later by PayDueSettlements.
+func (p *SettlementProcessor) CreateDueSettlements(now time.Time) ([]models.Settlement, error) {
+	merchantIDs, err := p.repository.GetMerchantsToSettle(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchants to settle: %w", err)
 	}
 
 	var settlements []models.Settlement
-
-	// Process each payment group (merchant + currency)
-	for _, paymentSummary := range payments {
-		// Get merchant settlement configuration
-		config, err := p.repository.GetMerchantSettlementConfig(paymentSummary.MerchantID)
+	for _, merchantID := range merchantIDs {
+		merchantSettlements, err := p.settleMerchant(merchantID, now)
 		if err != nil {
-			log.Printf("Error getting settlement config for merchant %s: %v, using defaults", 
-				paymentSummary.MerchantID, err)
-			// Continue with default config
+			log.Printf("Error settling merchant %s: %v", merchantID, err)
 		}
+		settlements = append(settlements, merchantSettlements...)
+	}
+
+	return settlements, nil
+}
 
-		// Check if amount meets minimum threshold
-		if paymentSummary.TotalAmount < config.MinimumSettlementAmount {
-			log.Printf("Payment amount %.2f is below minimum settlement amount %.2f for merchant %s, skipping", 
-				paymentSummary.TotalAmount, config.MinimumSettlementAmount, paymentSummary.MerchantID)
+// settleMerchant creates a merchant's settlements for its latest cut-off at
+// or before now, if it was not settled up to it yet
+func (p *SettlementProcessor) settleMerchant(merchantID uuid.UUID, now time.Time) ([]models.Settlement, error) {
+	config, err := p.merchantConfig(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	cycle, err := schedule.New(config.SettlementCycle, config.PreferredSettlementDay, config.CutoffTime, config.Timezone, p.calendar)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settlement cycle: %w", err)
+	}
+
+	cutoff, ok := cycle.Last(now)
+	if !ok || !cutoff.After(p.lastCutoff(merchantID)) {
+		return nil, nil
+	}
+	// Another instance, or an earlier run that failed part way, may have
+	// settled some of the merchant's currencies up to the cut-off
+	settledCurrencies, err := p.repository.GetSettledCurrencies(merchantID, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settled currencies: %w", err)
+	}
+	settled := make(map[string]bool, len(settledCurrencies))
+	for _, currency := range settledCurrencies {
+		settled[currency] = true
+	}
+
+	log.Printf("Settling merchant %s up to cut-off %s", merchantID, cutoff.Format(time.RFC3339))
+	payments, err := p.repository.GetEligiblePayments(merchantID, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get eligible payments: %w", err)
+	}
+	debits, err := p.repository.GetPendingDebits(merchantID, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending debits: %w", err)
+	}
+	balances, err := p.repository.GetCarriedBalances(merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get carried balances: %w", err)
+	}
+	holds, err := p.repository.GetReserveHolds(merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reserve holds: %w", err)
+	}
+
+	// Settle every currency with payments, debits, a negative balance or
+	// reserves due for release
+	paymentSummaries := models.SummarizePayments(payments)
+	summaries := make(map[string]models.PaymentSummary, len(paymentSummaries))
+	for _, summary := range paymentSummaries {
+		summaries[summary.Currency] = summary
+	}
+
+	var (
+		settlements []models.Settlement
+		errs        []error
+	)
+	for _, currency := range settlementCurrencies(paymentSummaries, debits, balances, holds, cutoff) {
+		if settled[currency] {
 			continue
 		}
+		paymentSummary, ok := summaries[currency]
+		if !ok {
+			paymentSummary = models.PaymentSummary{MerchantID: merchantID, Currency: currency}
+		}
+		// Check if amount meets minimum threshold; the payments carry over to
+		// the next cut-off
+		if paymentSummary.PaymentCount > 0 && paymentSummary.TotalAmount.Cmp(config.MinimumSettlementAmount) < 0 {
+			log.Printf("Payment amount %s %s is below minimum settlement amount %s for merchant %s, carrying over",
+				paymentSummary.TotalAmount, paymentSummary.Currency, config.MinimumSettlementAmount, merchantID)
+			paymentSummary = models.PaymentSummary{MerchantID: merchantID, Currency: paymentSummary.Currency}
+		}
 
-		// Get payment IDs for this batch - this is for informational and tracking purposes
-		_, err = p.repository.GetPaymentIDs(
-			paymentSummary.MerchantID,
-			paymentSummary.Currency,
-			startDate,
-			endDate,
-		)
+		fees, err := p.feeSchedule(config, currency, cutoff)
 		if err != nil {
-			log.Printf("Error getting payment IDs for merchant %s: %v, skipping", 
-				paymentSummary.MerchantID, err)
+			errs = append(errs, fmt.Errorf("%s settlement: %w", currency, err))
 			continue
 		}
-
-		// Calculate fees and net amount
-		feePercent := config.FeePercent
-		if feePercent <= 0 {
-			feePercent = p.defaultFeePercent
+		settlement, items, settlementDebits, movements, err := p.buildSettlement(paymentSummary, debits, balances, holds, config, fees, cutoff)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s settlement: %w", currency, err))
+			continue
 		}
-
-		feeAmount := roundToTwoDecimals(paymentSummary.TotalAmount * feePercent / 100)
-		taxAmount := roundToTwoDecimals(feeAmount * 0.18) // 18% GST on fees
-		netAmount := roundToTwoDecimals(paymentSummary.TotalAmount - feeAmount - taxAmount)
-
-		// Create settlement
-		settlement := models.Settlement{
-			ID:               uuid.New(),
-			MerchantID:       paymentSummary.MerchantID,
-			Amount:           paymentSummary.TotalAmount,
-			Currency:         paymentSummary.Currency,
-			Status:           models.SettlementStatusPending,
-			PaymentCount:     paymentSummary.PaymentCount,
-			FeeAmount:        feeAmount,
-			TaxAmount:        taxAmount,
-			NetAmount:        netAmount,
-			SettlementDate:   time.Now(),
-			BankAccountID:    config.BankAccountID,
-			SettlementMethod: config.SettlementMethod,
-			Reference:        fmt.Sprintf("SET_%s", uuid.New().String()[:8]),
-			CreatedAt:        time.Now(),
-			UpdatedAt:        time.Now(),
+		if settlement.PaymentCount == 0 && settlement.DebitCount == 0 &&
+			settlement.ReserveReleased.IsZero() && settlement.RecoveryAmount.IsZero() {
+			continue
 		}
-
-		// Save settlement to database
-		if err := p.repository.CreateSettlement(settlement); err != nil {
-			log.Printf("Error creating settlement for merchant %s: %v", 
-				paymentSummary.MerchantID, err)
+		if err := p.saveSettlement(settlement, items, settlementDebits, movements); err != nil {
+			errs = append(errs, fmt.Errorf("%s settlement: %w", currency, err))
 			continue
 		}
-
 		settlements = append(settlements, settlement)
-		log.Printf("Created settlement %s for merchant %s, amount: %.2f %s, net: %.2f", 
-			settlement.ID, settlement.MerchantID, settlement.Amount, 
-			settlement.Currency, settlement.NetAmount)
 	}
 
+	// Try the cut-off again on the next run if any settlement failed
+	if err := errors.Join(errs...); err != nil {
+		return settlements, err
+	}
+	p.settled(merchantID, cutoff)
+	log.Printf("Merchant %s settled up to %s, next cut-off %s", merchantID,
+		cutoff.Format(time.RFC3339), cycle.Next(now).Format(time.RFC3339))
 	return settlements, nil
 }
 
-// ProcessSettlement processes a settlement and completes the funds transfer
-func (p *SettlementProcessor) ProcessSettlement(settlement models.Settlement) error {
-	log.Printf("Processing settlement %s for merchant %s", settlement.ID, settlement.MerchantID)
+// buildSettlement builds the settlement of a merchant's payments and debits in
+// one currency up to a cut-off, with its items, the debits it deducts and its
+// reserve movements. Fees are charged with the merchant's fee schedule in the
+// currency. The settlement is paid out the merchant's payout delay in business
+// days after the cut-off.
+func (p *SettlementProcessor) buildSettlement(
+	paymentSummary models.PaymentSummary,
+	debits []models.MerchantDebit,
+	balances []models.CarriedBalance,
+	holds []models.ReserveHold,
+	config models.MerchantSettlementConfig,
+	fees feeSchedule,
+	cutoff time.Time,
+) (models.Settlement, []models.SettlementItem, []models.MerchantDebit, []models.ReserveMovement, error) {
+	now := time.Now()
+	settlement := models.Settlement{
+		ID:               uuid.New(),
+		MerchantID:       paymentSummary.MerchantID,
+		Currency:         paymentSummary.Currency,
+		Status:           models.SettlementStatusPending,
+		SettlementDate:   now,
+		CutoffAt:         cutoff,
+		PayoutDate:       p.calendar.AddBusinessDays(cutoff, config.PayoutDelayDays),
+		SettlementMethod: config.SettlementMethod,
+		Reference:        fmt.Sprintf("SET_%s", uuid.New().String()[:8]),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	// Charge fees on each payment; the settlement's amounts are the sums
+	// of its items', so they never drift from them
+	items := make([]models.SettlementItem, 0, len(paymentSummary.Payments))
+	for _, payment := range paymentSummary.Payments {
+		item := models.SettlementItem{
+			ID:            uuid.New(),
+			SettlementID:  settlement.ID,
+			PaymentID:     payment.ID,
+			Amount:        payment.Amount,
+			Currency:      payment.Currency,
+			PaymentMethod: payment.PaymentMethod,
+			CreatedAt:     now,
+		}
+		if err := p.price(&item, &fees); err != nil {
+			return models.Settlement{}, nil, nil, nil, fmt.Errorf("failed to price payment %s: %w", payment.ID, err)
+		}
+		settlement.AddItem(item)
+		items = append(items, item)
+	}
+
+	// Deduct the refunds and chargebacks, and the negative balance left by
+	// the previous settlement
+	var settlementDebits []models.MerchantDebit
+	for _, debit := range debits {
+		if debit.Currency == settlement.Currency {
+			settlement.AddDebit(debit)
+			settlementDebits = append(settlementDebits, debit)
+		}
+	}
+	if balance, ok := carriedBalance(balances, settlement.Currency); ok {
+		settlement.BringForward(balance)
+	}
 
-	// Update status to processing
-	if err := p.repository.UpdateSettlementStatus(settlement.ID, models.SettlementStatusProcessing); err != nil {
-		return fmt.Errorf("failed to update settlement status to processing: %w", err)
+	movements := p.reserveMovements(settlement, holds, config)
+	for _, movement := range movements {
+		settlement.AddReserveMovement(movement)
 	}
+	settleBalance(&settlement, config)
+
+	return settlement, items, settlementDebits, movements, nil
+}
 
-	// Simulate funds transfer to merchant bank account
-	log.Printf("Transferring %.2f %s to bank account %s for merchant %s", 
-		settlement.NetAmount, settlement.Currency, settlement.BankAccountID, settlement.MerchantID)
-	
-	// Simulate processing time
-	time.Sleep(100 * time.Millisecond)
+// saveSettlement saves a settlement with its items, debits, reserve movements
+// and event, settling its payments
+func (p *SettlementProcessor) saveSettlement(settlement models.Settlement, items []models.SettlementItem, debits []models.MerchantDebit, movements []models.ReserveMovement) error {
+	// Create the settlement event, published through the outbox
+	event, err := p.producer.New(events.TypeSettlementCreated, settlement.Event())
+	if err != nil {
+		return fmt.Errorf("failed to create settlement event: %w", err)
+	}
+	message, err := event.Message(p.settlementTopic, settlement.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to create settlement event: %w", err)
+	}
 
-	// Update status to completed
-	if err := p.repository.UpdateSettlementStatus(settlement.ID, models.SettlementStatusCompleted); err != nil {
-		return fmt.Errorf("failed to update settlement status to completed: %w", err)
+	// Save settlement, its items, debits, reserve movements and event to
+	// database, settling the payments
+	if err := p.repository.CreateSettlement(settlement, items, debits, movements, message); err != nil {
+		return fmt.Errorf("failed to create settlement: %w", err)
 	}
 
-	log.Printf("Settlement %s completed", settlement.ID)
+	log.Printf("Created settlement %s for merchant %s, amount: %s %s, net: %s, debits: %s, brought forward: %s, reserve held: %s, released: %s, carried forward: %s, recovered: %s, payout: %s on %s",
+		settlement.ID, settlement.MerchantID, settlement.Amount, settlement.Currency,
+		settlement.NetAmount, settlement.DebitAmount, settlement.BalanceBroughtForward,
+		settlement.ReserveHeld, settlement.ReserveReleased, settlement.BalanceCarriedForward,
+		settlement.RecoveryAmount, settlement.PayoutAmount, settlement.PayoutDate.Format(time.RFC3339))
 	return nil
 }
 
-// Helper function to round to two decimal places
-func roundToTwoDecimals(value float64) float64 {
-	return math.Round(value*100) / 100
+// merchantConfig returns a merchant's settlement configuration, with what it
+// leaves unset taken from the defaults
+func (p *SettlementProcessor) merchantConfig(merchantID uuid.UUID) (models.MerchantSettlementConfig, error) {
+	settings, err := p.repository.GetMerchantSettlementConfig(merchantID)
+	if err != nil && !errors.Is(err, repository.ErrMerchantConfigNotFound) {
+		return models.MerchantSettlementConfig{}, fmt.Errorf("failed to get settlement config: %w", err)
+	}
+	settings.MerchantID = merchantID
+	return settings.WithDefaults(p.defaults), nil
 }
 
-// getMerchantFeePercent returns the fee percentage for a merchant
-// In a real implementation, this would fetch the merchant's fee configuration from the database
-func (p *SettlementProcessor) getMerchantFeePercent(merchantID uuid.UUID) float64 {
-	// For the MVP, we'll use the default fee percentage
-	return p.defaultFeePercent
+// lastCutoff returns the latest cut-off the processor settled a merchant up to
+func (p *SettlementProcessor) lastCutoff(merchantID uuid.UUID) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.settledThrough[merchantID]
 }
 
-// getMerchantSettlementMethod returns the preferred settlement method for a merchant
-// In a real implementation, this would fetch the merchant's settlement configuration from the database
-func (p *SettlementProcessor) getMerchantSettlementMethod(merchantID uuid.UUID) models.SettlementMethod {
-	// For the MVP, we'll use bank transfer as the default
-	return models.SettlementMethodBankTransfer
+// settled records that a merchant was settled up to a cut-off
+func (p *SettlementProcessor) settled(merchantID uuid.UUID, cutoff time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cutoff.After(p.settledThrough[merchantID]) {
+		p.settledThrough[merchantID] = cutoff
+	}
 }
-
-// getMerchantBankAccountID returns the bank account ID for a merchant
-// In a real implementation, this would fetch the merchant's bank details from the database
-func (p *SettlementProcessor) getMerchantBankAccountID(merchantID uuid.UUID) string {
-	// For the MVP, we'll use a placeholder value
-	return "bank_acc_" + merchantID.String()[:8]
-} 
\ No newline at end of file
@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/calendar"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/payout"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/pricing"
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/repository"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/events"
+	"github.com/yourusername/fortexa/pkg/money"
+	"github.com/yourusername/fortexa/pkg/outbox"
+)
+
+// failingRepository fails creating the first settlement in a currency
+type failingRepository struct {
+	repository.Repository
+	currency string
+	failed   bool
+}
+
+func (r *failingRepository) CreateSettlement(settlement models.Settlement, items []models.SettlementItem, debits []models.MerchantDebit, movements []models.ReserveMovement, messages ...outbox.Message) error {
+	if settlement.Currency == r.currency && !r.failed {
+		r.failed = true
+		return errors.New("connection reset")
+	}
+	return r.Repository.CreateSettlement(settlement, items, debits, movements, messages...)
+}
+
+// newTestProcessor creates a processor settling with the mock repository
+// wrapped by wrap
+func newTestProcessor(t *testing.T, wrap func(repository.Repository) repository.Repository) *SettlementProcessor {
+	t.Helper()
+	accounts, err := bankaccount.NewCipher(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	return NewSettlementProcessor(
+		wrap(repository.NewMockRepository(accounts)),
+		models.MerchantSettlementConfig{TaxJurisdiction: "IN"},
+		calendar.Default(),
+		pricing.Default(),
+		money.RoundHalfEven,
+		events.NewProducer("settlement-engine-test"),
+		"settlements",
+		payout.NewSimulatedProvider(),
+		3,
+		time.Hour,
+		time.Hour,
+		accounts,
+		0,
+	)
+}
+
+// currencies returns the currencies of settlements
+func currencies(settlements []models.Settlement) []string {
+	var result []string
+	for _, settlement := range settlements {
+		result = append(result, settlement.Currency)
+	}
+	return result
+}
+
+func TestCreateDueSettlementsRetriesFailedCurrency(t *testing.T) {
+	repo := &failingRepository{currency: "USD"}
+	p := newTestProcessor(t, func(mock repository.Repository) repository.Repository {
+		repo.Repository = mock
+		return repo
+	})
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	first, err := p.CreateDueSettlements(now)
+	if err != nil {
+		t.Fatalf("CreateDueSettlements() error = %v", err)
+	}
+	if got := currencies(first); len(got) != 1 || got[0] != "EUR" {
+		t.Fatalf("first run settled %v, want [EUR]", got)
+	}
+
+	// The USD settlement of the same cut-off is created on the next run,
+	// without settling EUR again
+	second, err := p.CreateDueSettlements(now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("CreateDueSettlements() error = %v", err)
+	}
+	if got := currencies(second); len(got) != 1 || got[0] != "USD" {
+		t.Fatalf("second run settled %v, want [USD]", got)
+	}
+	if !second[0].CutoffAt.Equal(first[0].CutoffAt) {
+		t.Errorf("USD cut-off = %s, want %s", second[0].CutoffAt, first[0].CutoffAt)
+	}
+
+	third, err := p.CreateDueSettlements(now.Add(2 * time.Minute))
+	if err != nil {
+		t.Fatalf("CreateDueSettlements() error = %v", err)
+	}
+	if len(third) != 0 {
+		t.Errorf("third run settled %v, want nothing", currencies(third))
+	}
+}
+
+func TestCreateDueSettlementsSkipsCurrenciesSettledElsewhere(t *testing.T) {
+	var shared repository.Repository
+	p := newTestProcessor(t, func(mock repository.Repository) repository.Repository {
+		shared = mock
+		return mock
+	})
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	if _, err := p.CreateDueSettlements(now); err != nil {
+		t.Fatalf("CreateDueSettlements() error = %v", err)
+	}
+
+	// Another instance sharing the repository finds the cut-off settled
+	other := newTestProcessor(t, func(repository.Repository) repository.Repository { return shared })
+	settlements, err := other.CreateDueSettlements(now)
+	if err != nil {
+		t.Fatalf("CreateDueSettlements() error = %v", err)
+	}
+	if len(settlements) != 0 {
+		t.Errorf("other instance settled %v, want nothing", currencies(settlements))
+	}
+}
@@ -1,18 +1,26 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/yourusername/fortexa/pkg/money"
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
+// uniqueViolation is the PostgreSQL error code of a unique constraint violation
+const uniqueViolation = "23505"
+
 // DBRepository handles database operations
 type DBRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	outbox *outbox.PostgresStore
 }
 
 // NewDBRepository creates a new database repository
@@ -34,7 +42,7 @@ func NewDBRepository(connectionString string) (*DBRepository, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	return &DBRepository{db: db}, nil
+	return &DBRepository{db: db, outbox: outbox.NewPostgresStore(db)}, nil
 }
 
 // Close closes the database connection
@@ -42,90 +50,372 @@ func (r *DBRepository) Close() error {
 	return r.db.Close()
 }
 
-// MarkPaymentForSettlement marks a payment as ready for settlement
+// Outbox returns the store the outbox relay reads from
+func (r *DBRepository) Outbox() outbox.Store {
+	return r.outbox
+}
+
+// MarkPaymentForSettlement marks a payment as ready for settlement. A payment
+// refunded or charged back since it was captured is still credited to its
+// merchant.
 func (r *DBRepository) MarkPaymentForSettlement(paymentID uuid.UUID) error {
 	query := `
         UPDATE payments 
         SET settlement_ready = true, updated_at = $1 
-        WHERE id = $2 AND status = $3
+        WHERE id = $2 AND status IN ($3, $4, $5)
     `
-	_, err := r.db.Exec(query, time.Now(), paymentID, models.PaymentStatusCaptured)
+	_, err := r.db.Exec(query, time.Now(), paymentID,
+		models.PaymentStatusCaptured, models.PaymentStatusRefunded, models.PaymentStatusChargeback)
 	if err != nil {
 		return fmt.Errorf("failed to mark payment for settlement: %w", err)
 	}
 	return nil
 }
 
-// GetEligiblePayments gets eligible payments for settlement
-func (r *DBRepository) GetEligiblePayments(startDate, endDate time.Time) ([]models.PaymentSummary, error) {
+// RecordDebit records a refund or chargeback to deduct from the merchant's
+// next settlement, ignoring a debit recorded before under the same ID
+func (r *DBRepository) RecordDebit(debit models.MerchantDebit) error {
+	query := `
+        INSERT INTO merchant_debits (
+            id, merchant_id, payment_id, debit_type, amount, currency,
+            occurred_at, created_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (id) DO NOTHING
+    `
+	_, err := r.db.Exec(
+		query,
+		debit.ID,
+		debit.MerchantID,
+		debit.PaymentID,
+		debit.Type,
+		debit.Amount,
+		debit.Currency,
+		debit.OccurredAt,
+		debit.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record merchant debit: %w", err)
+	}
+	return nil
+}
+
+// GetMerchantsToSettle gets the merchants with payments marked for settlement,
+// debits occurred by now, negative balances carried forward or reserves due
+// for release by now
+func (r *DBRepository) GetMerchantsToSettle(now time.Time) ([]uuid.UUID, error) {
+	query := `
+        SELECT merchant_id
+        FROM payments p
+        WHERE settlement_ready = true AND status IN ($1, $2, $3)
+            AND NOT EXISTS (SELECT 1 FROM settlement_items si WHERE si.payment_id = p.id)
+        UNION
+        SELECT merchant_id
+        FROM merchant_debits
+        WHERE settlement_id IS NULL AND occurred_at <= $4
+        UNION
+        SELECT merchant_id
+        FROM (
+            SELECT DISTINCT ON (merchant_id, currency) merchant_id, balance_carried_forward
+            FROM settlements
+            ORDER BY merchant_id, currency, cutoff_at DESC
+        ) latest
+        WHERE balance_carried_forward < 0
+        UNION
+        SELECT h.merchant_id
+        FROM reserve_movements h
+        LEFT JOIN reserve_movements r ON r.hold_id = h.id
+        WHERE h.movement_type = $5 AND h.release_at <= $4
+        GROUP BY h.id
+        HAVING h.amount > COALESCE(SUM(r.amount), 0)
+    `
+
+	rows, err := r.db.Query(query,
+		models.PaymentStatusCaptured, models.PaymentStatusRefunded, models.PaymentStatusChargeback,
+		now, models.ReserveMovementHold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchants to settle: %w", err)
+	}
+	defer rows.Close()
+
+	var merchantIDs []uuid.UUID
+	for rows.Next() {
+		var merchantID uuid.UUID
+		if err := rows.Scan(&merchantID); err != nil {
+			return nil, fmt.Errorf("failed to scan merchant row: %w", err)
+		}
+		merchantIDs = append(merchantIDs, merchantID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating merchant rows: %w", err)
+	}
+
+	return merchantIDs, nil
+}
+
+// GetEligiblePayments gets a merchant's payments marked for settlement that
+// were created up to cutoff and not settled yet, oldest first. A payment
+// refunded or charged back before it was settled is still eligible.
+func (r *DBRepository) GetEligiblePayments(merchantID uuid.UUID, cutoff time.Time) ([]models.Payment, error) {
 	query := `
-        SELECT 
-            merchant_id,
-            currency,
-            SUM(amount) as total_amount,
-            COUNT(*) as payment_count,
-            MIN(created_at) as earliest_payment,
-            MAX(created_at) as latest_payment
-        FROM payments
+        SELECT id, merchant_id, reference_id, amount, currency, payment_method_type,
+            status, created_at, updated_at
+        FROM payments p
         WHERE 
-            settlement_ready = true 
-            AND status = $1
-            AND created_at BETWEEN $2 AND $3
-        GROUP BY merchant_id, currency
+            merchant_id = $1
+            AND settlement_ready = true 
+            AND status IN ($2, $3, $4)
+            AND created_at <= $5
+            AND NOT EXISTS (SELECT 1 FROM settlement_items si WHERE si.payment_id = p.id)
+        ORDER BY created_at
     `
 
-	rows, err := r.db.Query(query, models.PaymentStatusCaptured, startDate, endDate)
+	rows, err := r.db.Query(query, merchantID,
+		models.PaymentStatusCaptured, models.PaymentStatusRefunded, models.PaymentStatusChargeback, cutoff)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query eligible payments: %w", err)
 	}
 	defer rows.Close()
 
-	var summaries []models.PaymentSummary
+	var payments []models.Payment
 	for rows.Next() {
-		var summary models.PaymentSummary
-		var merchantIDStr string
-
+		var (
+			payment     models.Payment
+			referenceID sql.NullString
+		)
 		err := rows.Scan(
-			&merchantIDStr,
-			&summary.Currency,
-			&summary.TotalAmount,
-			&summary.PaymentCount,
-			&summary.EarliestPayment,
-			&summary.LatestPayment,
+			&payment.ID,
+			&payment.MerchantID,
+			&referenceID,
+			&payment.Amount,
+			&payment.Currency,
+			&payment.PaymentMethod,
+			&payment.Status,
+			&payment.CreatedAt,
+			&payment.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan payment summary row: %w", err)
+			return nil, fmt.Errorf("failed to scan eligible payment row: %w", err)
 		}
+		payment.OrderID = referenceID.String
+		// Amount columns have three decimals; settle in the currency's
+		payment.Amount = payment.Amount.RoundTo(payment.Currency, money.RoundHalfEven)
+		payment.SettlementReady = true
 
-		merchantID, err := uuid.Parse(merchantIDStr)
+		payments = append(payments, payment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating eligible payment rows: %w", err)
+	}
+
+	return payments, nil
+}
+
+// GetSettledCurrencies gets the currencies a merchant has settlements in with
+// a cut-off at or after cutoff
+func (r *DBRepository) GetSettledCurrencies(merchantID uuid.UUID, cutoff time.Time) ([]string, error) {
+	rows, err := r.db.Query(
+		`SELECT DISTINCT currency FROM settlements WHERE merchant_id = $1 AND cutoff_at >= $2`,
+		merchantID,
+		cutoff,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query settled currencies: %w", err)
+	}
+	defer rows.Close()
+
+	var currencies []string
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			return nil, fmt.Errorf("failed to scan settled currency: %w", err)
+		}
+		currencies = append(currencies, currency)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating settled currency rows: %w", err)
+	}
+
+	return currencies, nil
+}
+
+// GetPendingDebits gets a merchant's debits that occurred up to cutoff and no
+// settlement deducted yet, in every currency, oldest first
+func (r *DBRepository) GetPendingDebits(merchantID uuid.UUID, cutoff time.Time) ([]models.MerchantDebit, error) {
+	query := `
+        SELECT id, merchant_id, payment_id, debit_type, amount, currency,
+            occurred_at, created_at
+        FROM merchant_debits
+        WHERE merchant_id = $1 AND settlement_id IS NULL AND occurred_at <= $2
+        ORDER BY occurred_at
+    `
+
+	rows, err := r.db.Query(query, merchantID, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending debits: %w", err)
+	}
+	defer rows.Close()
+
+	var debits []models.MerchantDebit
+	for rows.Next() {
+		var debit models.MerchantDebit
+		err := rows.Scan(
+			&debit.ID,
+			&debit.MerchantID,
+			&debit.PaymentID,
+			&debit.Type,
+			&debit.Amount,
+			&debit.Currency,
+			&debit.OccurredAt,
+			&debit.CreatedAt,
+		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse merchant ID: %w", err)
+			return nil, fmt.Errorf("failed to scan pending debit row: %w", err)
+		}
+		debit.Amount = debit.Amount.RoundTo(debit.Currency, money.RoundHalfEven)
+		debits = append(debits, debit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending debit rows: %w", err)
+	}
+
+	return debits, nil
+}
+
+// GetCarriedBalances gets the negative balances the merchant's latest
+// settlement in each currency carried forward
+func (r *DBRepository) GetCarriedBalances(merchantID uuid.UUID) ([]models.CarriedBalance, error) {
+	query := `
+        SELECT currency, balance_carried_forward, negative_since
+        FROM (
+            SELECT DISTINCT ON (currency) currency, balance_carried_forward, negative_since
+            FROM settlements
+            WHERE merchant_id = $1
+            ORDER BY currency, cutoff_at DESC
+        ) latest
+        WHERE balance_carried_forward < 0
+    `
+
+	rows, err := r.db.Query(query, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query carried balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []models.CarriedBalance
+	for rows.Next() {
+		var (
+			balance       models.CarriedBalance
+			negativeSince sql.NullTime
+		)
+		if err := rows.Scan(&balance.Currency, &balance.Amount, &negativeSince); err != nil {
+			return nil, fmt.Errorf("failed to scan carried balance row: %w", err)
 		}
-		summary.MerchantID = merchantID
+		balance.Amount = balance.Amount.RoundTo(balance.Currency, money.RoundHalfEven)
+		balance.NegativeSince = negativeSince.Time
+		balances = append(balances, balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating carried balance rows: %w", err)
+	}
+
+	return balances, nil
+}
+
+// GetSettledVolume gets the total amount of a merchant's settlements in a
+// currency with cut-offs from from up to but excluding to
+func (r *DBRepository) GetSettledVolume(merchantID uuid.UUID, currency string, from, to time.Time) (money.Decimal, error) {
+	var volume money.Decimal
+	err := r.db.QueryRow(
+		`SELECT COALESCE(SUM(amount), 0) FROM settlements
+        WHERE merchant_id = $1 AND currency = $2 AND cutoff_at >= $3 AND cutoff_at < $4`,
+		merchantID, currency, from, to,
+	).Scan(&volume)
+	if err != nil {
+		return money.Zero, fmt.Errorf("failed to get settled volume: %w", err)
+	}
+	return volume.RoundTo(currency, money.RoundHalfEven), nil
+}
+
+// GetReserveHolds gets a merchant's reserve holds that are not fully released
+// yet, in every currency, the earliest due for release first
+func (r *DBRepository) GetReserveHolds(merchantID uuid.UUID) ([]models.ReserveHold, error) {
+	query := `
+        SELECT h.id, h.settlement_id, h.currency,
+            h.amount - COALESCE(SUM(r.amount), 0), h.release_at, h.created_at
+        FROM reserve_movements h
+        LEFT JOIN reserve_movements r ON r.hold_id = h.id
+        WHERE h.merchant_id = $1 AND h.movement_type = $2
+        GROUP BY h.id
+        HAVING h.amount > COALESCE(SUM(r.amount), 0)
+        ORDER BY h.release_at, h.created_at
+    `
 
-		summaries = append(summaries, summary)
+	rows, err := r.db.Query(query, merchantID, models.ReserveMovementHold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reserve holds: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []models.ReserveHold
+	for rows.Next() {
+		var hold models.ReserveHold
+		err := rows.Scan(
+			&hold.ID,
+			&hold.SettlementID,
+			&hold.Currency,
+			&hold.Outstanding,
+			&hold.ReleaseAt,
+			&hold.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reserve hold row: %w", err)
+		}
+		hold.Outstanding = hold.Outstanding.RoundTo(hold.Currency, money.RoundHalfEven)
+		holds = append(holds, hold)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating payment summary rows: %w", err)
+		return nil, fmt.Errorf("error iterating reserve hold rows: %w", err)
 	}
 
-	return summaries, nil
+	return holds, nil
 }
 
-// CreateSettlement creates a new settlement record
-func (r *DBRepository) CreateSettlement(settlement models.Settlement) error {
+// CreateSettlement creates a new settlement record with its items and reserve
+// movements, marks its debits deducted, moves the items' captured payments to
+// SETTLED and enqueues the messages announcing the settlement in the same
+// transaction
+func (r *DBRepository) CreateSettlement(settlement models.Settlement, items []models.SettlementItem, debits []models.MerchantDebit, movements []models.ReserveMovement, messages ...outbox.Message) error {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
         INSERT INTO settlements (
             id, merchant_id, amount, currency, status, payment_count,
-            fee_amount, tax_amount, net_amount, settlement_date,
-            bank_account_id, settlement_method, reference, created_at, updated_at
+            fee_amount, tax_amount, net_amount, reserve_held, reserve_released,
+            debit_amount, debit_count, balance_brought_forward,
+            balance_carried_forward, negative_since, recovery_method,
+            recovery_amount, payout_amount, settlement_date, cutoff_at,
+            payout_date, bank_account_id, settlement_method, reference,
+            created_at, updated_at
         ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+            $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27
         )
     `
 
-	_, err := r.db.Exec(
+	_, err = tx.ExecContext(
+		ctx,
 		query,
 		settlement.ID,
 		settlement.MerchantID,
@@ -136,7 +426,19 @@ func (r *DBRepository) CreateSettlement(settlement models.Settlement) error {
 		settlement.FeeAmount,
 		settlement.TaxAmount,
 		settlement.NetAmount,
+		settlement.ReserveHeld,
+		settlement.ReserveReleased,
+		settlement.DebitAmount,
+		settlement.DebitCount,
+		settlement.BalanceBroughtForward,
+		settlement.BalanceCarriedForward,
+		sql.NullTime{Time: settlement.NegativeSince, Valid: !settlement.NegativeSince.IsZero()},
+		sql.NullString{String: string(settlement.RecoveryMethod), Valid: settlement.RecoveryMethod != ""},
+		settlement.RecoveryAmount,
+		settlement.PayoutAmount,
 		settlement.SettlementDate,
+		settlement.CutoffAt,
+		settlement.PayoutDate,
 		settlement.BankAccountID,
 		settlement.SettlementMethod,
 		settlement.Reference,
@@ -148,6 +450,451 @@ func (r *DBRepository) CreateSettlement(settlement models.Settlement) error {
 		return fmt.Errorf("failed to create settlement: %w", err)
 	}
 
+	// Settle the captured payments; refunded and charged back ones keep
+	// their status, and are credited once by the unique settlement items
+	paymentIDs := make([]string, len(items))
+	for i, item := range items {
+		paymentIDs[i] = item.PaymentID.String()
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE payments SET status = $1, updated_at = $2
+        WHERE id = ANY($3::uuid[]) AND status = $4 AND settlement_ready = true`,
+		models.PaymentStatusSettled,
+		settlement.CreatedAt,
+		pq.Array(paymentIDs),
+		models.PaymentStatusCaptured,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to settle payments: %w", err)
+	}
+
+	for _, item := range items {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO settlement_items (
+                id, settlement_id, payment_id, amount, currency, payment_method,
+                pricing_plan, fee_percent, fixed_fee, fee_amount,
+                tax_jurisdiction, tax_percent, tax_amount, net_amount, created_at
+            ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			item.ID,
+			item.SettlementID,
+			item.PaymentID,
+			item.Amount,
+			item.Currency,
+			item.PaymentMethod,
+			item.PricingPlan,
+			item.FeePercent,
+			item.FixedFee,
+			item.FeeAmount,
+			item.TaxJurisdiction,
+			item.TaxPercent,
+			item.TaxAmount,
+			item.NetAmount,
+			item.CreatedAt,
+		)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrPaymentNotEligible
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create settlement item for payment %s: %w", item.PaymentID, err)
+		}
+	}
+
+	// Deduct the debits, unless another settlement already deducted one
+	if len(debits) > 0 {
+		debitIDs := make([]string, len(debits))
+		for i, debit := range debits {
+			debitIDs[i] = debit.ID.String()
+		}
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE merchant_debits SET settlement_id = $1
+            WHERE id = ANY($2::uuid[]) AND settlement_id IS NULL`,
+			settlement.ID,
+			pq.Array(debitIDs),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to deduct merchant debits: %w", err)
+		}
+		deducted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to deduct merchant debits: %w", err)
+		}
+		if deducted != int64(len(debits)) {
+			return ErrDebitNotPending
+		}
+	}
+
+	for _, movement := range movements {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO reserve_movements (
+                id, merchant_id, settlement_id, currency, movement_type, amount,
+                release_at, hold_id, created_at
+            ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			movement.ID,
+			movement.MerchantID,
+			movement.SettlementID,
+			movement.Currency,
+			movement.Type,
+			movement.Amount,
+			sql.NullTime{Time: movement.ReleaseAt, Valid: !movement.ReleaseAt.IsZero()},
+			uuid.NullUUID{UUID: movement.HoldID, Valid: movement.HoldID != uuid.Nil},
+			movement.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create reserve %s movement: %w", movement.Type, err)
+		}
+	}
+
+	if err := outbox.Insert(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit settlement: %w", err)
+	}
+
+	return nil
+}
+
+// settlementColumns are the columns of settlements scanned by scanSettlements,
+// with s as the settlements table, followed by the number of payout attempts
+const settlementColumns = `s.id, s.merchant_id, s.amount, s.currency, s.status, s.payment_count,
+            s.fee_amount, s.tax_amount, s.net_amount, s.reserve_held, s.reserve_released,
+            s.debit_amount, s.debit_count, s.balance_brought_forward,
+            s.balance_carried_forward, s.negative_since, s.recovery_method,
+            s.recovery_amount, s.payout_amount, s.settlement_date, s.cutoff_at,
+            s.payout_date, s.bank_account_id, s.settlement_method, s.reference,
+            s.created_at, s.updated_at`
+
+// GetPayableSettlements gets the pending settlements whose payout date is at or
+// before now, the earliest first
+func (r *DBRepository) GetPayableSettlements(now time.Time) ([]models.Settlement, error) {
+	query := `
+        SELECT ` + settlementColumns + `, 0
+        FROM settlements s
+        WHERE s.status = $1 AND s.payout_date <= $2
+        ORDER BY s.payout_date
+    `
+
+	rows, err := r.db.Query(query, models.SettlementStatusPending, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payable settlements: %w", err)
+	}
+	return scanSettlements(rows)
+}
+
+// GetRetryableSettlements gets the failed settlements with fewer than
+// maxAttempts payouts, whose last payout failed at or before failedBefore, the
+// earliest failed first
+func (r *DBRepository) GetRetryableSettlements(failedBefore time.Time, maxAttempts int) ([]models.Settlement, error) {
+	query := `
+        SELECT ` + settlementColumns + `, COUNT(sp.id)
+        FROM settlements s
+        JOIN settlement_payouts sp ON sp.settlement_id = s.id
+        WHERE s.status = $1
+        GROUP BY s.id
+        HAVING COUNT(sp.id) < $2 AND MAX(sp.completed_at) <= $3
+        ORDER BY MAX(sp.completed_at)
+    `
+
+	rows, err := r.db.Query(query, models.SettlementStatusFailed, maxAttempts, failedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retryable settlements: %w", err)
+	}
+	return scanSettlements(rows)
+}
+
+// scanSettlements scans and closes rows of settlementColumns
+func scanSettlements(rows *sql.Rows) ([]models.Settlement, error) {
+	defer rows.Close()
+
+	var settlements []models.Settlement
+	for rows.Next() {
+		var (
+			settlement       models.Settlement
+			cutoffAt         sql.NullTime
+			negativeSince    sql.NullTime
+			recoveryMethod   sql.NullString
+			bankAccountID    sql.NullString
+			settlementMethod sql.NullString
+			reference        sql.NullString
+		)
+		err := rows.Scan(
+			&settlement.ID,
+			&settlement.MerchantID,
+			&settlement.Amount,
+			&settlement.Currency,
+			&settlement.Status,
+			&settlement.PaymentCount,
+			&settlement.FeeAmount,
+			&settlement.TaxAmount,
+			&settlement.NetAmount,
+			&settlement.ReserveHeld,
+			&settlement.ReserveReleased,
+			&settlement.DebitAmount,
+			&settlement.DebitCount,
+			&settlement.BalanceBroughtForward,
+			&settlement.BalanceCarriedForward,
+			&negativeSince,
+			&recoveryMethod,
+			&settlement.RecoveryAmount,
+			&settlement.PayoutAmount,
+			&settlement.SettlementDate,
+			&cutoffAt,
+			&settlement.PayoutDate,
+			&bankAccountID,
+			&settlementMethod,
+			&reference,
+			&settlement.CreatedAt,
+			&settlement.UpdatedAt,
+			&settlement.PayoutAttempts,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan settlement row: %w", err)
+		}
+		settlement.CutoffAt = cutoffAt.Time
+		settlement.NegativeSince = negativeSince.Time
+		settlement.RecoveryMethod = models.RecoveryMethod(recoveryMethod.String)
+		settlement.BankAccountID = bankAccountID.String
+		settlement.SettlementMethod = models.SettlementMethod(settlementMethod.String)
+		settlement.Reference = reference.String
+		// Amount columns have three decimals; pay out in the currency's
+		for _, amount := range []*money.Decimal{
+			&settlement.Amount, &settlement.FeeAmount, &settlement.TaxAmount, &settlement.NetAmount,
+			&settlement.ReserveHeld, &settlement.ReserveReleased, &settlement.DebitAmount,
+			&settlement.BalanceBroughtForward, &settlement.BalanceCarriedForward,
+			&settlement.RecoveryAmount, &settlement.PayoutAmount,
+		} {
+			*amount = amount.RoundTo(settlement.Currency, money.RoundHalfEven)
+		}
+
+		settlements = append(settlements, settlement)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating settlement rows: %w", err)
+	}
+
+	return settlements, nil
+}
+
+// GetPayoutAccount gets a merchant's verified bank account
+func (r *DBRepository) GetPayoutAccount(merchantID uuid.UUID) (models.BankAccount, error) {
+	query := `
+        SELECT id, merchant_id, holder_name, scheme, account_number_encrypted,
+            ifsc, bic, verified_at, replaces_account_id
+        FROM merchant_bank_accounts
+        WHERE merchant_id = $1 AND status = 'VERIFIED'
+    `
+
+	var (
+		account  models.BankAccount
+		ifsc     sql.NullString
+		bic      sql.NullString
+		replaces uuid.NullUUID
+	)
+	err := r.db.QueryRow(query, merchantID).Scan(
+		&account.ID,
+		&account.MerchantID,
+		&account.HolderName,
+		&account.Scheme,
+		&account.AccountNumberEncrypted,
+		&ifsc,
+		&bic,
+		&account.VerifiedAt,
+		&replaces,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.BankAccount{}, ErrBankAccountNotFound
+	}
+	if err != nil {
+		return models.BankAccount{}, fmt.Errorf("failed to get payout account: %w", err)
+	}
+	account.IFSC = ifsc.String
+	account.BIC = bic.String
+	account.ReplacesAccountID = replaces.UUID
+	return account, nil
+}
+
+// StartPayout records a payout submitted for a pending or failed settlement,
+// setting the settlement's bank account to the payout's and moving it to
+// PROCESSING in the same transaction
+func (r *DBRepository) StartPayout(payout models.SettlementPayout) error {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Take the settlement, unless another instance already did
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE settlements SET status = $1, bank_account_id = $2, updated_at = $3
+        WHERE id = $4 AND status IN ($5, $6)`,
+		models.SettlementStatusProcessing,
+		payout.BankAccountID,
+		payout.SubmittedAt,
+		payout.SettlementID,
+		models.SettlementStatusPending,
+		models.SettlementStatusFailed,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to start settlement payout: %w", err)
+	}
+	taken, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to start settlement payout: %w", err)
+	}
+	if taken != 1 {
+		return ErrSettlementNotPayable
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO settlement_payouts (
+            id, settlement_id, merchant_id, reference, batch_id, attempt, amount,
+            currency, bank_account_id, status, submitted_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		payout.ID,
+		payout.SettlementID,
+		payout.MerchantID,
+		payout.Reference,
+		payout.BatchID,
+		payout.Attempt,
+		payout.Amount,
+		payout.Currency,
+		payout.BankAccountID,
+		payout.Status,
+		payout.SubmittedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create settlement payout: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit settlement payout: %w", err)
+	}
+	return nil
+}
+
+// GetSubmittedPayouts gets the payouts awaiting an outcome submitted at or
+// before submittedBefore
+func (r *DBRepository) GetSubmittedPayouts(submittedBefore time.Time) ([]models.SettlementPayout, error) {
+	query := `
+        SELECT id, settlement_id, merchant_id, reference, batch_id, attempt, amount,
+            currency, bank_account_id, status, submitted_at
+        FROM settlement_payouts
+        WHERE status = $1 AND submitted_at <= $2
+        ORDER BY submitted_at
+    `
+
+	rows, err := r.db.Query(query, models.PayoutStatusSubmitted, submittedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submitted payouts: %w", err)
+	}
+	defer rows.Close()
+
+	var payouts []models.SettlementPayout
+	for rows.Next() {
+		var payout models.SettlementPayout
+		err := rows.Scan(
+			&payout.ID,
+			&payout.SettlementID,
+			&payout.MerchantID,
+			&payout.Reference,
+			&payout.BatchID,
+			&payout.Attempt,
+			&payout.Amount,
+			&payout.Currency,
+			&payout.BankAccountID,
+			&payout.Status,
+			&payout.SubmittedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan submitted payout: %w", err)
+		}
+		payouts = append(payouts, payout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating submitted payout rows: %w", err)
+	}
+	return payouts, nil
+}
+
+// CompletePayout records a submitted payout paid and completes its settlement
+func (r *DBRepository) CompletePayout(reference string, at time.Time) error {
+	return r.finishPayout(reference, models.PayoutStatusPaid, "", at,
+		[]models.PayoutStatus{models.PayoutStatusSubmitted}, models.SettlementStatusCompleted)
+}
+
+// FailPayout records a submitted or paid payout failed and fails its
+// settlement
+func (r *DBRepository) FailPayout(reference, reason string, at time.Time) error {
+	return r.finishPayout(reference, models.PayoutStatusFailed, reason, at,
+		[]models.PayoutStatus{models.PayoutStatusSubmitted, models.PayoutStatusPaid}, models.SettlementStatusFailed)
+}
+
+// finishPayout moves a payout in one of the from statuses to status, and its
+// settlement to settlementStatus, in one transaction
+func (r *DBRepository) finishPayout(
+	reference string,
+	status models.PayoutStatus,
+	reason string,
+	at time.Time,
+	from []models.PayoutStatus,
+	settlementStatus models.SettlementStatus,
+) error {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	fromStatuses := make([]string, len(from))
+	for i, s := range from {
+		fromStatuses[i] = string(s)
+	}
+	var settlementID uuid.UUID
+	err = tx.QueryRowContext(
+		ctx,
+		`UPDATE settlement_payouts SET status = $1, failure_reason = $2, completed_at = $3
+        WHERE reference = $4 AND status = ANY($5)
+        RETURNING settlement_id`,
+		status,
+		sql.NullString{String: reason, Valid: reason != ""},
+		at,
+		reference,
+		pq.Array(fromStatuses),
+	).Scan(&settlementID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPayoutNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record payout %s: %w", status, err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE settlements SET status = $1, updated_at = $2 WHERE id = $3`,
+		settlementStatus,
+		at,
+		settlementID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update settlement status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payout %s: %w", status, err)
+	}
 	return nil
 }
 
@@ -165,60 +912,107 @@ func (r *DBRepository) UpdateSettlementStatus(settlementID uuid.UUID, status mod
 	return nil
 }
 
-// GetMerchantSettlementConfig gets the settlement configuration for a merchant
-func (r *DBRepository) GetMerchantSettlementConfig(merchantID uuid.UUID) (models.MerchantSettlementConfig, error) {
-	// For MVP, we'll just return a default configuration
-	// In a real implementation, this would fetch from the database
-	return models.MerchantSettlementConfig{
-		MerchantID:              merchantID,
-		SettlementCycle:         "DAILY",
-		PreferredSettlementDay:  1,
-		SettlementMethod:        models.SettlementMethodBankTransfer,
-		BankAccountID:           fmt.Sprintf("bank_acc_%s", merchantID.String()[:8]),
-		FeePercent:              2.5,
-		MinimumSettlementAmount: 100,
-		CreatedAt:               time.Now(),
-		UpdatedAt:               time.Now(),
-	}, nil
-}
-
-// GetPaymentIDs gets all payment IDs for a given merchant, currency, and time range
-func (r *DBRepository) GetPaymentIDs(merchantID uuid.UUID, currency string, startDate, endDate time.Time) ([]uuid.UUID, error) {
+// GetMerchantSettlementConfig gets the settlement settings of a merchant.
+// NULL columns are left unset for the caller's defaults.
+func (r *DBRepository) GetMerchantSettlementConfig(merchantID uuid.UUID) (models.MerchantSettlementSettings, error) {
 	query := `
-        SELECT id 
-        FROM payments
-        WHERE 
-            merchant_id = $1 
-            AND currency = $2
-            AND settlement_ready = true
-            AND status = $3
-            AND created_at BETWEEN $4 AND $5
+        SELECT merchant_id, settlement_cycle, preferred_settlement_day, timezone,
+            cutoff_time, settlement_method, fee_percent,
+            pricing_plan, tax_jurisdiction, minimum_settlement_amount, payout_delay_days, reserve_percent,
+            reserve_hold_days, minimum_reserve_amount, negative_balance_recovery,
+            negative_balance_days, created_at, updated_at
+        FROM merchant_settlement_configs
+        WHERE merchant_id = $1
     `
 
-	rows, err := r.db.Query(query, merchantID, currency, models.PaymentStatusCaptured, startDate, endDate)
+	var (
+		settings          models.MerchantSettlementSettings
+		cycle             sql.NullString
+		day               sql.NullInt64
+		timezone          sql.NullString
+		cutoffTime        sql.NullString
+		settlementMethod  sql.NullString
+		feePercent        nullDecimal
+		pricingPlan       sql.NullString
+		taxJurisdiction   sql.NullString
+		minimumSettlement nullDecimal
+		payoutDelayDays   sql.NullInt64
+		reservePercent    nullDecimal
+		reserveHoldDays   sql.NullInt64
+		minimumReserve    nullDecimal
+		recovery          sql.NullString
+		negativeDays      sql.NullInt64
+	)
+	err := r.db.QueryRow(query, merchantID).Scan(
+		&settings.MerchantID,
+		&cycle,
+		&day,
+		&timezone,
+		&cutoffTime,
+		&settlementMethod,
+		&feePercent,
+		&pricingPlan,
+		&taxJurisdiction,
+		&minimumSettlement,
+		&payoutDelayDays,
+		&reservePercent,
+		&reserveHoldDays,
+		&minimumReserve,
+		&recovery,
+		&negativeDays,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.MerchantSettlementSettings{}, ErrMerchantConfigNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to query payment IDs: %w", err)
+		return models.MerchantSettlementSettings{}, fmt.Errorf("failed to get merchant settlement config: %w", err)
 	}
-	defer rows.Close()
+	settings.SettlementCycle = cycle.String
+	settings.PreferredSettlementDay = nullInt(day)
+	settings.Timezone = timezone.String
+	settings.CutoffTime = cutoffTime.String
+	settings.SettlementMethod = models.SettlementMethod(settlementMethod.String)
+	settings.FeePercent = feePercent.ptr()
+	settings.PricingPlan = pricingPlan.String
+	settings.TaxJurisdiction = taxJurisdiction.String
+	settings.MinimumSettlementAmount = minimumSettlement.ptr()
+	settings.PayoutDelayDays = nullInt(payoutDelayDays)
+	settings.ReservePercent = reservePercent.ptr()
+	settings.ReserveHoldDays = nullInt(reserveHoldDays)
+	settings.MinimumReserveAmount = minimumReserve.ptr()
+	settings.NegativeBalanceRecovery = models.RecoveryMethod(recovery.String)
+	settings.NegativeBalanceDays = nullInt(negativeDays)
 
-	var paymentIDs []uuid.UUID
-	for rows.Next() {
-		var idStr string
-		if err := rows.Scan(&idStr); err != nil {
-			return nil, fmt.Errorf("failed to scan payment ID: %w", err)
-		}
+	return settings, nil
+}
 
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse payment ID: %w", err)
-		}
+// nullDecimal scans a nullable DECIMAL column
+type nullDecimal struct {
+	decimal money.Decimal
+	valid   bool
+}
 
-		paymentIDs = append(paymentIDs, id)
-	}
+// Scan reads the column, remembering whether it was NULL
+func (n *nullDecimal) Scan(src interface{}) error {
+	n.valid = src != nil
+	return n.decimal.Scan(src)
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating payment ID rows: %w", err)
+// ptr returns the decimal scanned, or nil for NULL
+func (n nullDecimal) ptr() *money.Decimal {
+	if !n.valid {
+		return nil
 	}
+	return &n.decimal
+}
 
-	return paymentIDs, nil
-} 
\ No newline at end of file
+// nullInt returns the integer scanned, or nil for NULL
+func nullInt(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	i := int(n.Int64)
+	return &i
+}
@@ -1,21 +1,53 @@
 package repository
 
 import (
-	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
+	"github.com/yourusername/fortexa/pkg/bankaccount"
+	"github.com/yourusername/fortexa/pkg/money"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
 // MockRepository is a mock implementation of the Repository interface
-type MockRepository struct{}
+type MockRepository struct {
+	outbox     *outbox.MemoryStore
+	merchantID uuid.UUID
+	accounts   *bankaccount.Cipher
+	accountID  uuid.UUID
 
-// NewMockRepository creates a new mock repository for demonstration
-func NewMockRepository() Repository {
+	mu          sync.Mutex
+	settlements map[uuid.UUID]models.Settlement
+	movements   []models.ReserveMovement
+	debits      map[uuid.UUID]models.MerchantDebit
+	payouts     map[string]models.SettlementPayout
+}
+
+// NewMockRepository creates a new mock repository for demonstration, with
+// one merchant that always has payments to settle and a refund to deduct, and
+// a bank account verified long ago whose account number is encrypted with
+// accounts. Settlements, reserve movements, recorded debits and payouts are
+// kept in memory.
+func NewMockRepository(accounts *bankaccount.Cipher) Repository {
 	log.Println("Using mock repository for database operations")
-	return &MockRepository{}
+	return &MockRepository{
+		outbox:      outbox.NewMemoryStore(),
+		merchantID:  uuid.New(),
+		accounts:    accounts,
+		accountID:   uuid.New(),
+		settlements: make(map[uuid.UUID]models.Settlement),
+		debits:      make(map[uuid.UUID]models.MerchantDebit),
+		payouts:     make(map[string]models.SettlementPayout),
+	}
+}
+
+// Outbox returns the in-memory outbox store
+func (r *MockRepository) Outbox() outbox.Store {
+	return r.outbox
 }
 
 // MarkPaymentForSettlement mocks marking a payment for settlement
@@ -24,71 +56,343 @@ func (r *MockRepository) MarkPaymentForSettlement(paymentID uuid.UUID) error {
 	return nil
 }
 
+// RecordDebit mocks recording a merchant debit in memory
+func (r *MockRepository) RecordDebit(debit models.MerchantDebit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.debits[debit.ID]; ok {
+		return nil
+	}
+	r.debits[debit.ID] = debit
+	log.Printf("[MOCK] Recorded %s debit of %s %s for payment %s", debit.Type, debit.Amount, debit.Currency, debit.PaymentID)
+	return nil
+}
+
+// GetMerchantsToSettle mocks retrieving the merchants to settle
+func (r *MockRepository) GetMerchantsToSettle(now time.Time) ([]uuid.UUID, error) {
+	return []uuid.UUID{r.merchantID}, nil
+}
+
 // GetEligiblePayments mocks retrieving eligible payments
-func (r *MockRepository) GetEligiblePayments(startDate, endDate time.Time) ([]models.PaymentSummary, error) {
-	log.Printf("[MOCK] Getting eligible payments between %s and %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
-	
-	// Create some mock payment summaries
-	merchantID := uuid.New()
-	return []models.PaymentSummary{
-		{
-			MerchantID:      merchantID,
-			TotalAmount:     1000.50,
-			Currency:        "USD",
-			PaymentCount:    5,
-			EarliestPayment: startDate,
-			LatestPayment:   endDate,
-		},
-		{
+func (r *MockRepository) GetEligiblePayments(merchantID uuid.UUID, cutoff time.Time) ([]models.Payment, error) {
+	log.Printf("[MOCK] Getting eligible payments of merchant %s up to %s", merchantID, cutoff.Format(time.RFC3339))
+
+	// Create some mock payments for the merchant: 1000.50 USD and 750.25 EUR
+	createdAt := cutoff.Add(-time.Hour)
+	var payments []models.Payment
+	for i, amount := range []string{"200.10", "200.10", "200.10", "200.10", "200.10", "250.25", "250.00", "250.00"} {
+		currency := "USD"
+		if i >= 5 {
+			currency = "EUR"
+		}
+		payments = append(payments, models.Payment{
+			ID:              uuid.New(),
 			MerchantID:      merchantID,
-			TotalAmount:     750.25,
-			Currency:        "EUR",
-			PaymentCount:    3,
-			EarliestPayment: startDate,
-			LatestPayment:   endDate,
-		},
+			Amount:          money.MustParse(amount),
+			Currency:        currency,
+			PaymentMethod:   "CREDIT_CARD",
+			Status:          models.PaymentStatusCaptured,
+			CreatedAt:       createdAt,
+			UpdatedAt:       createdAt,
+			SettlementReady: true,
+		})
+	}
+	return payments, nil
+}
+
+// GetPendingDebits mocks retrieving a merchant's pending debits: the ones
+// recorded in memory, and a refund of 50.00 USD
+func (r *MockRepository) GetPendingDebits(merchantID uuid.UUID, cutoff time.Time) ([]models.MerchantDebit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	occurredAt := cutoff.Add(-time.Hour)
+	debits := []models.MerchantDebit{{
+		ID:         uuid.New(),
+		MerchantID: merchantID,
+		PaymentID:  uuid.New(),
+		Type:       models.DebitTypeRefund,
+		Amount:     money.New(5000, 2),
+		Currency:   "USD",
+		OccurredAt: occurredAt,
+		CreatedAt:  occurredAt,
+	}}
+	for _, debit := range r.debits {
+		if debit.MerchantID == merchantID && debit.SettlementID == uuid.Nil && !debit.OccurredAt.After(cutoff) {
+			debits = append(debits, debit)
+		}
+	}
+	sort.SliceStable(debits, func(i, j int) bool { return debits[i].OccurredAt.Before(debits[j].OccurredAt) })
+	return debits, nil
+}
+
+// GetCarriedBalances mocks getting the negative balances carried forward by
+// the merchant's latest in-memory settlements
+func (r *MockRepository) GetCarriedBalances(merchantID uuid.UUID) ([]models.CarriedBalance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest := make(map[string]models.Settlement)
+	for _, settlement := range r.settlements {
+		if settlement.MerchantID != merchantID {
+			continue
+		}
+		if previous, ok := latest[settlement.Currency]; !ok || settlement.CutoffAt.After(previous.CutoffAt) {
+			latest[settlement.Currency] = settlement
+		}
+	}
+	var balances []models.CarriedBalance
+	for currency, settlement := range latest {
+		if settlement.BalanceCarriedForward.Sign() < 0 {
+			balances = append(balances, models.CarriedBalance{
+				Currency:      currency,
+				Amount:        settlement.BalanceCarriedForward,
+				NegativeSince: settlement.NegativeSince,
+			})
+		}
+	}
+	return balances, nil
+}
+
+// GetSettledCurrencies mocks getting the currencies of a merchant's in-memory
+// settlements with a cut-off at or after cutoff
+func (r *MockRepository) GetSettledCurrencies(merchantID uuid.UUID, cutoff time.Time) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool)
+	var currencies []string
+	for _, settlement := range r.settlements {
+		if settlement.MerchantID == merchantID && !settlement.CutoffAt.Before(cutoff) && !seen[settlement.Currency] {
+			seen[settlement.Currency] = true
+			currencies = append(currencies, settlement.Currency)
+		}
+	}
+	return currencies, nil
+}
+
+// GetSettledVolume mocks getting a merchant's settled volume from the
+// in-memory settlements
+func (r *MockRepository) GetSettledVolume(merchantID uuid.UUID, currency string, from, to time.Time) (money.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	volume := money.Zero
+	for _, settlement := range r.settlements {
+		if settlement.MerchantID == merchantID && settlement.Currency == currency &&
+			!settlement.CutoffAt.Before(from) && settlement.CutoffAt.Before(to) {
+			volume = volume.Add(settlement.Amount)
+		}
+	}
+	return volume, nil
+}
+
+// GetReserveHolds mocks getting a merchant's outstanding reserve holds from the in-memory ledger
+func (r *MockRepository) GetReserveHolds(merchantID uuid.UUID) ([]models.ReserveHold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	released := make(map[uuid.UUID]money.Decimal)
+	for _, movement := range r.movements {
+		if movement.Type == models.ReserveMovementRelease {
+			released[movement.HoldID] = released[movement.HoldID].Add(movement.Amount)
+		}
+	}
+	var holds []models.ReserveHold
+	for _, movement := range r.movements {
+		if movement.MerchantID != merchantID || movement.Type != models.ReserveMovementHold {
+			continue
+		}
+		outstanding := movement.Amount.Sub(released[movement.ID])
+		if outstanding.Sign() <= 0 {
+			continue
+		}
+		holds = append(holds, models.ReserveHold{
+			ID:           movement.ID,
+			SettlementID: movement.SettlementID,
+			Currency:     movement.Currency,
+			Outstanding:  outstanding,
+			ReleaseAt:    movement.ReleaseAt,
+			CreatedAt:    movement.CreatedAt,
+		})
+	}
+	sort.SliceStable(holds, func(i, j int) bool { return holds[i].ReleaseAt.Before(holds[j].ReleaseAt) })
+	return holds, nil
+}
+
+// CreateSettlement mocks creating a settlement record with its items, debits
+// and reserve movements and enqueues its messages in memory
+func (r *MockRepository) CreateSettlement(settlement models.Settlement, items []models.SettlementItem, debits []models.MerchantDebit, movements []models.ReserveMovement, messages ...outbox.Message) error {
+	log.Printf("[MOCK] Created settlement %s for merchant %s (%s %s) settling %d payments and %d debits, reserve held %s, released %s", 
+		settlement.ID, settlement.MerchantID, settlement.Currency, settlement.Amount, len(items), len(debits),
+		settlement.ReserveHeld, settlement.ReserveReleased)
+	r.mu.Lock()
+	r.settlements[settlement.ID] = settlement
+	r.movements = append(r.movements, movements...)
+	for _, debit := range debits {
+		if recorded, ok := r.debits[debit.ID]; ok {
+			recorded.SettlementID = settlement.ID
+			r.debits[debit.ID] = recorded
+		}
+	}
+	r.mu.Unlock()
+	r.outbox.Add(messages...)
+	return nil
+}
+
+// GetPayableSettlements mocks getting the settlements due for payout from memory
+func (r *MockRepository) GetPayableSettlements(now time.Time) ([]models.Settlement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var settlements []models.Settlement
+	for _, settlement := range r.settlements {
+		if settlement.Status == models.SettlementStatusPending && !settlement.PayoutDate.After(now) {
+			settlements = append(settlements, settlement)
+		}
+	}
+	sort.Slice(settlements, func(i, j int) bool { return settlements[i].PayoutDate.Before(settlements[j].PayoutDate) })
+	return settlements, nil
+}
+
+// GetRetryableSettlements mocks getting the failed settlements due for
+// another payout from memory
+func (r *MockRepository) GetRetryableSettlements(failedBefore time.Time, maxAttempts int) ([]models.Settlement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempts := make(map[uuid.UUID]int)
+	failedAt := make(map[uuid.UUID]time.Time)
+	for _, payout := range r.payouts {
+		attempts[payout.SettlementID]++
+		if payout.CompletedAt.After(failedAt[payout.SettlementID]) {
+			failedAt[payout.SettlementID] = payout.CompletedAt
+		}
+	}
+	var settlements []models.Settlement
+	for _, settlement := range r.settlements {
+		n := attempts[settlement.ID]
+		if settlement.Status == models.SettlementStatusFailed && n > 0 && n < maxAttempts &&
+			!failedAt[settlement.ID].After(failedBefore) {
+			settlement.PayoutAttempts = n
+			settlements = append(settlements, settlement)
+		}
+	}
+	sort.Slice(settlements, func(i, j int) bool {
+		return failedAt[settlements[i].ID].Before(failedAt[settlements[j].ID])
+	})
+	return settlements, nil
+}
+
+// GetPayoutAccount mocks getting the verified bank account of the mock
+// merchant
+func (r *MockRepository) GetPayoutAccount(merchantID uuid.UUID) (models.BankAccount, error) {
+	if merchantID != r.merchantID {
+		return models.BankAccount{}, ErrBankAccountNotFound
+	}
+	encrypted, err := r.accounts.Encrypt("123456789012")
+	if err != nil {
+		return models.BankAccount{}, err
+	}
+	log.Printf("[MOCK] Retrieved payout account for merchant %s", merchantID)
+	return models.BankAccount{
+		ID:                     r.accountID,
+		MerchantID:             merchantID,
+		HolderName:             "Mock Merchant Pvt Ltd",
+		Scheme:                 bankaccount.SchemeIFSC,
+		AccountNumberEncrypted: encrypted,
+		IFSC:                   "HDFC0001234",
+		VerifiedAt:             time.Now().AddDate(0, -1, 0),
 	}, nil
 }
 
-// CreateSettlement mocks creating a settlement record
-func (r *MockRepository) CreateSettlement(settlement models.Settlement) error {
-	log.Printf("[MOCK] Created settlement %s for merchant %s (%s %.2f)", 
-		settlement.ID, settlement.MerchantID, settlement.Currency, settlement.Amount)
+// StartPayout mocks recording a payout submitted for a settlement in memory
+func (r *MockRepository) StartPayout(payout models.SettlementPayout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	settlement, ok := r.settlements[payout.SettlementID]
+	if !ok || (settlement.Status != models.SettlementStatusPending && settlement.Status != models.SettlementStatusFailed) {
+		return ErrSettlementNotPayable
+	}
+	settlement.Status = models.SettlementStatusProcessing
+	settlement.BankAccountID = payout.BankAccountID
+	r.settlements[settlement.ID] = settlement
+	r.payouts[payout.Reference] = payout
+	log.Printf("[MOCK] Started payout %s of settlement %s", payout.Reference, payout.SettlementID)
+	return nil
+}
+
+// GetSubmittedPayouts mocks getting the payouts awaiting an outcome from memory
+func (r *MockRepository) GetSubmittedPayouts(submittedBefore time.Time) ([]models.SettlementPayout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var payouts []models.SettlementPayout
+	for _, payout := range r.payouts {
+		if payout.Status == models.PayoutStatusSubmitted && !payout.SubmittedAt.After(submittedBefore) {
+			payouts = append(payouts, payout)
+		}
+	}
+	sort.Slice(payouts, func(i, j int) bool { return payouts[i].SubmittedAt.Before(payouts[j].SubmittedAt) })
+	return payouts, nil
+}
+
+// CompletePayout mocks recording a submitted payout paid in memory
+func (r *MockRepository) CompletePayout(reference string, at time.Time) error {
+	return r.finishPayout(reference, models.PayoutStatusPaid, "", at, models.SettlementStatusCompleted)
+}
+
+// FailPayout mocks recording a submitted or paid payout failed in memory
+func (r *MockRepository) FailPayout(reference, reason string, at time.Time) error {
+	return r.finishPayout(reference, models.PayoutStatusFailed, reason, at, models.SettlementStatusFailed)
+}
+
+// finishPayout moves an in-memory payout to status, and its settlement to
+// settlementStatus
+func (r *MockRepository) finishPayout(reference string, status models.PayoutStatus, reason string, at time.Time, settlementStatus models.SettlementStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	payout, ok := r.payouts[reference]
+	if !ok || payout.Status == models.PayoutStatusFailed ||
+		(payout.Status == models.PayoutStatusPaid && status == models.PayoutStatusPaid) {
+		return ErrPayoutNotFound
+	}
+	payout.Status = status
+	payout.FailureReason = reason
+	payout.CompletedAt = at
+	r.payouts[reference] = payout
+	if settlement, ok := r.settlements[payout.SettlementID]; ok {
+		settlement.Status = settlementStatus
+		r.settlements[settlement.ID] = settlement
+	}
+	log.Printf("[MOCK] Payout %s of settlement %s %s", reference, payout.SettlementID, status)
 	return nil
 }
 
 // UpdateSettlementStatus mocks updating settlement status
 func (r *MockRepository) UpdateSettlementStatus(settlementID uuid.UUID, status models.SettlementStatus) error {
+	r.mu.Lock()
+	if settlement, ok := r.settlements[settlementID]; ok {
+		settlement.Status = status
+		r.settlements[settlementID] = settlement
+	}
+	r.mu.Unlock()
 	log.Printf("[MOCK] Updated settlement %s status to %s", settlementID, status)
 	return nil
 }
 
 // GetMerchantSettlementConfig mocks getting merchant settlement config
-func (r *MockRepository) GetMerchantSettlementConfig(merchantID uuid.UUID) (models.MerchantSettlementConfig, error) {
+func (r *MockRepository) GetMerchantSettlementConfig(merchantID uuid.UUID) (models.MerchantSettlementSettings, error) {
 	log.Printf("[MOCK] Retrieved settlement config for merchant %s", merchantID)
-	return models.MerchantSettlementConfig{
+	day, payoutDelay, holdDays, negativeDays := 1, 1, 7, 30
+	fee, minimum, reserve := money.MustParse("2.5"), money.New(100, 0), money.New(5, 0)
+	return models.MerchantSettlementSettings{
 		MerchantID:              merchantID,
 		SettlementCycle:         "DAILY",
-		PreferredSettlementDay:  1,
+		PreferredSettlementDay:  &day,
+		Timezone:                "Asia/Kolkata",
+		CutoffTime:              "18:00",
 		SettlementMethod:        models.SettlementMethodBankTransfer,
-		BankAccountID:           fmt.Sprintf("bank_acc_%s", merchantID.String()[:8]),
-		FeePercent:              2.5,
-		MinimumSettlementAmount: 100,
+		FeePercent:              &fee,
+		MinimumSettlementAmount: &minimum,
+		PayoutDelayDays:         &payoutDelay,
+		ReservePercent:          &reserve,
+		ReserveHoldDays:         &holdDays,
+		NegativeBalanceRecovery: models.RecoveryMethodInvoice,
+		NegativeBalanceDays:     &negativeDays,
 		CreatedAt:               time.Now(),
 		UpdatedAt:               time.Now(),
 	}, nil
 }
-
-// GetPaymentIDs mocks getting payment IDs for settlement
-func (r *MockRepository) GetPaymentIDs(merchantID uuid.UUID, currency string, startDate, endDate time.Time) ([]uuid.UUID, error) {
-	log.Printf("[MOCK] Retrieved payment IDs for merchant %s, currency %s between %s and %s", 
-		merchantID, currency, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
-	
-	// Generate some mock payment IDs
-	ids := make([]uuid.UUID, 5)
-	for i := 0; i < 5; i++ {
-		ids[i] = uuid.New()
-	}
-	
-	return ids, nil
-}
\ No newline at end of file
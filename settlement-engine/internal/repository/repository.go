@@ -1,31 +1,130 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"github.com/adarshagupta/fortexa/settlement-engine/internal/models"
 	"github.com/google/uuid"
+	"github.com/yourusername/fortexa/pkg/money"
+	"github.com/yourusername/fortexa/pkg/outbox"
 )
 
+// ErrPaymentNotEligible is returned when a payment to settle is no longer
+// eligible for settlement
+var ErrPaymentNotEligible = errors.New("payment is no longer eligible for settlement")
+
+// ErrMerchantConfigNotFound is returned when a merchant has no settlement
+// configuration of its own
+var ErrMerchantConfigNotFound = errors.New("merchant settlement config not found")
+
+// ErrDebitNotPending is returned when a debit to deduct was already deducted
+// by another settlement
+var ErrDebitNotPending = errors.New("debit was already deducted by another settlement")
+
+// ErrSettlementNotPayable is returned when a settlement to pay out is no
+// longer pending or failed, such as when another instance took it
+var ErrSettlementNotPayable = errors.New("settlement is no longer payable")
+
+// ErrPayoutNotFound is returned when the bank reports on a payout that is
+// unknown or whose outcome cannot change that way, such as one reported
+// twice
+var ErrPayoutNotFound = errors.New("no payout awaiting the outcome")
+
+// ErrBankAccountNotFound is returned when a merchant has no verified bank
+// account to pay out to
+var ErrBankAccountNotFound = errors.New("merchant has no verified bank account")
+
 // Repository defines the interface for database operations
 type Repository interface {
 	// MarkPaymentForSettlement marks a payment as ready for settlement
 	MarkPaymentForSettlement(paymentID uuid.UUID) error
 	
-	// GetEligiblePayments gets eligible payments for settlement
-	GetEligiblePayments(startDate, endDate time.Time) ([]models.PaymentSummary, error)
+	// RecordDebit records a refund or chargeback to deduct from the
+	// merchant's next settlement. A debit of a payment recorded before is
+	// ignored.
+	RecordDebit(debit models.MerchantDebit) error
+	
+	// GetMerchantsToSettle gets the merchants with payments marked for
+	// settlement, debits occurred by now, negative balances carried forward
+	// or reserves due for release by now
+	GetMerchantsToSettle(now time.Time) ([]uuid.UUID, error)
+	
+	// GetEligiblePayments gets a merchant's payments marked for settlement
+	// that were created up to cutoff and not settled yet, oldest first. A
+	// payment refunded or charged back before it was settled is still
+	// eligible; its debit is deducted separately.
+	GetEligiblePayments(merchantID uuid.UUID, cutoff time.Time) ([]models.Payment, error)
+	
+	// GetPendingDebits gets a merchant's debits that occurred up to cutoff
+	// and no settlement deducted yet, in every currency, oldest first
+	GetPendingDebits(merchantID uuid.UUID, cutoff time.Time) ([]models.MerchantDebit, error)
+	
+	// GetCarriedBalances gets the negative balances the merchant's latest
+	// settlement in each currency carried forward
+	GetCarriedBalances(merchantID uuid.UUID) ([]models.CarriedBalance, error)
+	
+	// GetSettledCurrencies gets the currencies a merchant has settlements in
+	// with a cut-off at or after cutoff
+	GetSettledCurrencies(merchantID uuid.UUID, cutoff time.Time) ([]string, error)
 	
-	// CreateSettlement creates a new settlement record
-	CreateSettlement(settlement models.Settlement) error
+	// GetSettledVolume gets the total amount of a merchant's settlements in a
+	// currency with cut-offs from from up to but excluding to
+	GetSettledVolume(merchantID uuid.UUID, currency string, from, to time.Time) (money.Decimal, error)
+	
+	// GetReserveHolds gets a merchant's reserve holds that are not fully
+	// released yet, in every currency, the earliest due for release first
+	GetReserveHolds(merchantID uuid.UUID) ([]models.ReserveHold, error)
+	
+	// CreateSettlement creates a new settlement record with its items and
+	// reserve movements, marks its debits deducted by it, moves the items'
+	// captured payments to SETTLED and enqueues the messages announcing the
+	// settlement, all in one transaction. It returns ErrPaymentNotEligible,
+	// creating nothing, if another settlement already took any of the
+	// payments, and ErrDebitNotPending if one already deducted any of the
+	// debits.
+	CreateSettlement(settlement models.Settlement, items []models.SettlementItem, debits []models.MerchantDebit, movements []models.ReserveMovement, messages ...outbox.Message) error
+	
+	// GetPayableSettlements gets the pending settlements whose payout date is
+	// at or before now, the earliest first
+	GetPayableSettlements(now time.Time) ([]models.Settlement, error)
+	
+	// GetRetryableSettlements gets the failed settlements with fewer than
+	// maxAttempts payouts, whose last payout failed at or before
+	// failedBefore, the earliest failed first
+	GetRetryableSettlements(failedBefore time.Time, maxAttempts int) ([]models.Settlement, error)
+	
+	// GetPayoutAccount gets a merchant's verified bank account, or
+	// ErrBankAccountNotFound if it has none
+	GetPayoutAccount(merchantID uuid.UUID) (models.BankAccount, error)
+	
+	// StartPayout records a payout submitted for a pending or failed
+	// settlement, sets the settlement's bank account to the payout's and
+	// moves the settlement to PROCESSING, or returns ErrSettlementNotPayable
+	StartPayout(payout models.SettlementPayout) error
+	
+	// GetSubmittedPayouts gets the payouts still awaiting an outcome that were
+	// submitted at or before submittedBefore, the earliest first
+	GetSubmittedPayouts(submittedBefore time.Time) ([]models.SettlementPayout, error)
+	
+	// CompletePayout records a submitted payout paid at a time and completes
+	// its settlement, or returns ErrPayoutNotFound
+	CompletePayout(reference string, at time.Time) error
+	
+	// FailPayout records a submitted or paid payout failed at a time for a
+	// reason and fails its settlement, or returns ErrPayoutNotFound
+	FailPayout(reference, reason string, at time.Time) error
 	
 	// UpdateSettlementStatus updates the status of a settlement
 	UpdateSettlementStatus(settlementID uuid.UUID, status models.SettlementStatus) error
 	
-	// GetMerchantSettlementConfig gets the settlement configuration for a merchant
-	GetMerchantSettlementConfig(merchantID uuid.UUID) (models.MerchantSettlementConfig, error)
-	
-	// GetPaymentIDs gets all payment IDs for a given merchant, currency, and time range
-	GetPaymentIDs(merchantID uuid.UUID, currency string, startDate, endDate time.Time) ([]uuid.UUID, error)
+	// GetMerchantSettlementConfig gets the settlement settings of a merchant,
+	// or ErrMerchantConfigNotFound if it has none. NULL columns are left unset
+	// for the caller's defaults.
+	GetMerchantSettlementConfig(merchantID uuid.UUID) (models.MerchantSettlementSettings, error)
+
+	// Outbox returns the store the outbox relay reads from
+	Outbox() outbox.Store
 }
 
 // Ensure DBRepository implements Repository interface
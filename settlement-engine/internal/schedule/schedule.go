@@ -0,0 +1,145 @@
+// Package schedule computes the cut-offs of merchants' settlement cycles.
+//
+// A cut-off is the moment up to which a settlement takes a merchant's
+// payments. It falls at the merchant's cut-off time, in the merchant's time
+// zone: every day for a DAILY cycle, on the preferred weekday (1 for Monday
+// to 7 for Sunday) for a WEEKLY cycle, and on the preferred day of the month
+// for a MONTHLY cycle, or on the last day of months too short to have it. A
+// cut-off that falls on a weekend or bank holiday moves to the same time on
+// the next business day.
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adarshagupta/fortexa/settlement-engine/internal/calendar"
+)
+
+// Settlement cycles
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// cutoffLayout is the layout of cut-off times of day
+const cutoffLayout = "15:04"
+
+// lookback is how many days before a moment to look for the cut-offs around
+// it: enough for the longest cycle and a cut-off postponed by holidays
+const lookback = 62
+
+// horizon bounds the search for the next cut-off
+const horizon = 400
+
+// Cycle is a merchant's settlement cycle
+type Cycle struct {
+	frequency string
+	day       int
+	hour      int
+	minute    int
+	location  *time.Location
+	calendar  *calendar.Calendar
+}
+
+// New returns a settlement cycle. frequency is DAILY, WEEKLY or MONTHLY, day
+// the preferred weekday or day of the month (ignored for DAILY cycles), cutoff
+// a time of day as HH:MM and timezone an IANA time zone such as Asia/Kolkata.
+func New(frequency string, day int, cutoff, timezone string, cal *calendar.Calendar) (Cycle, error) {
+	switch frequency {
+	case Daily:
+	case Weekly:
+		if day < 1 || day > 7 {
+			return Cycle{}, fmt.Errorf("invalid weekly settlement day %d, expected 1 (Monday) to 7 (Sunday)", day)
+		}
+	case Monthly:
+		if day < 1 || day > 31 {
+			return Cycle{}, fmt.Errorf("invalid monthly settlement day %d, expected 1 to 31", day)
+		}
+	default:
+		return Cycle{}, fmt.Errorf("unknown settlement cycle %q, expected DAILY, WEEKLY or MONTHLY", frequency)
+	}
+	at, err := time.Parse(cutoffLayout, cutoff)
+	if err != nil {
+		return Cycle{}, fmt.Errorf("invalid cut-off time %q, expected HH:MM", cutoff)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Cycle{}, fmt.Errorf("invalid time zone %q: %w", timezone, err)
+	}
+	return Cycle{
+		frequency: frequency,
+		day:       day,
+		hour:      at.Hour(),
+		minute:    at.Minute(),
+		location:  location,
+		calendar:  cal,
+	}, nil
+}
+
+// Last returns the latest cut-off at or before now, and false if there is
+// none in the last two months, which only happens when holidays postpone a
+// cut-off that long
+func (c Cycle) Last(now time.Time) (time.Time, bool) {
+	var last time.Time
+	for date := c.day0(now).AddDate(0, 0, -lookback); !date.After(now); date = date.AddDate(0, 0, 1) {
+		if !c.scheduled(date) {
+			continue
+		}
+		if cutoff := c.cutoffOn(date); !cutoff.After(now) && cutoff.After(last) {
+			last = cutoff
+		}
+	}
+	return last, !last.IsZero()
+}
+
+// Next returns the first cut-off after now
+func (c Cycle) Next(now time.Time) time.Time {
+	// Cut-offs of later days are never earlier, so the first one after now,
+	// perhaps postponed from a day before it, is the next
+	for date, i := c.day0(now).AddDate(0, 0, -lookback), 0; i < lookback+horizon; date, i = date.AddDate(0, 0, 1), i+1 {
+		if !c.scheduled(date) {
+			continue
+		}
+		if cutoff := c.cutoffOn(date); cutoff.After(now) {
+			return cutoff
+		}
+	}
+	return time.Time{}
+}
+
+// day0 returns the start of the day of t in the cycle's time zone
+func (c Cycle) day0(t time.Time) time.Time {
+	year, month, day := t.In(c.location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, c.location)
+}
+
+// scheduled reports whether the cycle has a cut-off on a date, before
+// postponing it past weekends and holidays
+func (c Cycle) scheduled(date time.Time) bool {
+	switch c.frequency {
+	case Weekly:
+		return int(date.Weekday()+6)%7+1 == c.day
+	case Monthly:
+		day := c.day
+		if last := daysIn(date.Year(), date.Month()); day > last {
+			day = last
+		}
+		return date.Day() == day
+	default:
+		return true
+	}
+}
+
+// cutoffOn returns the cut-off scheduled on a date, postponed to the next
+// business day if the date is not one
+func (c Cycle) cutoffOn(date time.Time) time.Time {
+	cutoff := time.Date(date.Year(), date.Month(), date.Day(), c.hour, c.minute, 0, 0, c.location)
+	return c.calendar.NextBusinessDay(cutoff)
+}
+
+// daysIn returns the number of days in a month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}